@@ -290,6 +290,7 @@ deploy-example: deploy-example-openshift
 deploy-example-openshift: ## Deploy an example Istio resource on OpenShift
 	kubectl create ns istio-system || echo "namespace istio-system already exists"
 	kubectl apply -n istio-system -f chart/samples/istio-sample-openshift.yaml
+	kubectl apply -f chart/samples/istiocni-sample.yaml
 
 .PHONY: deploy-example-kubernetes
 deploy-example-kubernetes: ## Deploy an example Istio resource on Kubernetes
@@ -22,6 +22,11 @@ import (
 
 const IstioKind = "Istio"
 
+// ProfileName is the name of a built-in or user-defined profile. It's validated the same way as spec.profile.
+// +kubebuilder:validation:MaxLength=63
+// +kubebuilder:validation:Pattern=`^[a-zA-Z0-9]([-_.a-zA-Z0-9]*[a-zA-Z0-9])?$`
+type ProfileName string
+
 type UpdateStrategyType string
 
 const (
@@ -30,31 +35,49 @@ const (
 
 	DefaultRevisionDeletionGracePeriodSeconds = 30
 	MinRevisionDeletionGracePeriodSeconds     = 30
+
+	DefaultUpdateWorkloadsBatchSize = 1
 )
 
+// AllowDowngradeAnnotation can be set to "true" on an Istio object to allow changing spec.version to an older version.
+// Downgrades are rejected by default.
+const AllowDowngradeAnnotation = "operator.istio.io/allow-downgrade"
+
 // IstioSpec defines the desired state of Istio
 // +kubebuilder:validation:XValidation:rule="!has(self.values) || !has(self.values.global) || !has(self.values.global.istioNamespace) || self.values.global.istioNamespace == self.__namespace__",message="spec.values.global.istioNamespace must match spec.namespace"
 type IstioSpec struct {
 	// +sail:version
-	// Defines the version of Istio to install.
-	// Must be one of: v1.20.3, v1.20.2, v1.20.1, v1.19.7, v1.19.6, latest, gwAPIControllerMode.
-	// +operator-sdk:csv:customresourcedefinitions:type=spec,order=1,displayName="Istio Version",xDescriptors={"urn:alm:descriptor:com.tectonic.ui:fieldGroup:General", "urn:alm:descriptor:com.tectonic.ui:select:v1.20.3", "urn:alm:descriptor:com.tectonic.ui:select:v1.20.2", "urn:alm:descriptor:com.tectonic.ui:select:v1.20.1", "urn:alm:descriptor:com.tectonic.ui:select:v1.19.7", "urn:alm:descriptor:com.tectonic.ui:select:v1.19.6", "urn:alm:descriptor:com.tectonic.ui:select:latest", "urn:alm:descriptor:com.tectonic.ui:select:gwAPIControllerMode"}
-	// +kubebuilder:validation:Enum=v1.20.3;v1.20.2;v1.20.1;v1.19.7;v1.19.6;latest;gwAPIControllerMode
-	Version string `json:"version"`
+	// Defines the version of Istio to install. If not set, the operator's default version is used.
+	// Besides an exact version, the version can be a minor version alias such as "v1.20", which
+	// resolves to the most recent patch release of that minor version, or "stable", which resolves
+	// to the most recent release supported by the operator. When an operator upgrade brings a newer
+	// patch release, the control plane is updated to it using the configured update strategy.
+	// Must be one of: v1.20.3, v1.20.2, v1.20.1, v1.19.7, v1.19.6, latest, gwAPIControllerMode, v1.20, v1.19, stable.
+	// +operator-sdk:csv:customresourcedefinitions:type=spec,order=1,displayName="Istio Version",xDescriptors={"urn:alm:descriptor:com.tectonic.ui:fieldGroup:General", "urn:alm:descriptor:com.tectonic.ui:select:v1.20.3", "urn:alm:descriptor:com.tectonic.ui:select:v1.20.2", "urn:alm:descriptor:com.tectonic.ui:select:v1.20.1", "urn:alm:descriptor:com.tectonic.ui:select:v1.19.7", "urn:alm:descriptor:com.tectonic.ui:select:v1.19.6", "urn:alm:descriptor:com.tectonic.ui:select:latest", "urn:alm:descriptor:com.tectonic.ui:select:gwAPIControllerMode", "urn:alm:descriptor:com.tectonic.ui:select:v1.20", "urn:alm:descriptor:com.tectonic.ui:select:v1.19", "urn:alm:descriptor:com.tectonic.ui:select:stable"}
+	// +kubebuilder:validation:Enum=v1.20.3;v1.20.2;v1.20.1;v1.19.7;v1.19.6;latest;gwAPIControllerMode;v1.20;v1.19;stable
+	Version string `json:"version,omitempty"`
 
 	// Defines the update strategy to use when the version in the Istio CR is updated.
 	// +operator-sdk:csv:customresourcedefinitions:type=spec,displayName="Update Strategy"
 	UpdateStrategy *IstioUpdateStrategy `json:"updateStrategy,omitempty"`
 
 	// +sail:profile
-	// The built-in installation configuration profile to use.
+	// The installation configuration profile to use.
 	// The 'default' profile is always applied. On OpenShift, the 'openshift' profile is also applied on top of 'default'.
-	// Must be one of: ambient, default, demo, empty, external, minimal, openshift-ambient, openshift, preview, remote.
+	// The built-in profiles are: ambient, default, demo, empty, external, minimal, openshift-ambient, openshift, preview, remote.
+	// A user-defined profile, defined in a ConfigMap in the operator's namespace, can also be used.
 	// +++PROFILES-DROPDOWN-HIDDEN-UNTIL-WE-FULLY-IMPLEMENT-THEM+++operator-sdk:csv:customresourcedefinitions:type=spec,displayName="Profile",xDescriptors={"urn:alm:descriptor:com.tectonic.ui:fieldGroup:General", "urn:alm:descriptor:com.tectonic.ui:select:ambient", "urn:alm:descriptor:com.tectonic.ui:select:default", "urn:alm:descriptor:com.tectonic.ui:select:demo", "urn:alm:descriptor:com.tectonic.ui:select:empty", "urn:alm:descriptor:com.tectonic.ui:select:external", "urn:alm:descriptor:com.tectonic.ui:select:minimal", "urn:alm:descriptor:com.tectonic.ui:select:preview", "urn:alm:descriptor:com.tectonic.ui:select:remote"}
 	// +operator-sdk:csv:customresourcedefinitions:type=spec,xDescriptors={"urn:alm:descriptor:com.tectonic.ui:hidden"}
-	// +kubebuilder:validation:Enum=ambient;default;demo;empty;external;minimal;openshift-ambient;openshift;preview;remote
+	// +kubebuilder:validation:MaxLength=63
+	// +kubebuilder:validation:Pattern=`^[a-zA-Z0-9]([-_.a-zA-Z0-9]*[a-zA-Z0-9])?$`
 	Profile string `json:"profile,omitempty"`
 
+	// Additional installation configuration profiles to apply after spec.profile, in the given order.
+	// Each of them can be a built-in or a user-defined profile. Values in a profile overwrite the values
+	// of the profiles applied before it; a profile that is listed more than once is only applied the first time.
+	// +operator-sdk:csv:customresourcedefinitions:type=spec,xDescriptors={"urn:alm:descriptor:com.tectonic.ui:hidden"}
+	Profiles []ProfileName `json:"profiles,omitempty"`
+
 	// Namespace to which the Istio components should be installed.
 	// +operator-sdk:csv:customresourcedefinitions:type=spec,xDescriptors={"urn:alm:descriptor:io.kubernetes:Namespace"}
 	Namespace string `json:"namespace"`
@@ -62,6 +85,16 @@ type IstioSpec struct {
 	// Defines the values to be passed to the Helm charts when installing Istio.
 	// +operator-sdk:csv:customresourcedefinitions:type=spec,displayName="Helm Values"
 	Values *Values `json:"values,omitempty"`
+
+	// Defines how the operator handles changes made to the deployed components outside of the operator.
+	// The policy is applied to all revisions of this Istio.
+	// +operator-sdk:csv:customresourcedefinitions:type=spec,displayName="Drift Policy"
+	DriftPolicy *DriftPolicy `json:"driftPolicy,omitempty"`
+
+	// Defines whether and when the operator rolls back an upgrade of the components that leaves istiod unhealthy.
+	// The policy is applied to all revisions of this Istio.
+	// +operator-sdk:csv:customresourcedefinitions:type=spec,displayName="Rollback Policy"
+	RollbackPolicy *RollbackPolicy `json:"rollbackPolicy,omitempty"`
 }
 
 // IstioUpdateStrategy defines how the control plane should be updated when the version in
@@ -94,6 +127,14 @@ type IstioUpdateStrategy struct {
 	// Defaults to false.
 	// +operator-sdk:csv:customresourcedefinitions:type=spec,order=3,displayName="Update Workloads Automatically",xDescriptors={"urn:alm:descriptor:com.tectonic.ui:booleanSwitch"}
 	UpdateWorkloads bool `json:"updateWorkloads,omitempty"`
+
+	// Defines how many workloads the operator restarts at the same time when moving them to a new
+	// control plane instance. The operator waits for the pods of each batch to become ready before
+	// it restarts the next batch. Only used when updateWorkloads is true.
+	// The minimum and the default value is 1.
+	// +operator-sdk:csv:customresourcedefinitions:type=spec,order=4,displayName="Workload Update Batch Size",xDescriptors={"urn:alm:descriptor:com.tectonic.ui:number"}
+	// +kubebuilder:validation:Minimum=1
+	UpdateWorkloadsBatchSize *int32 `json:"updateWorkloadsBatchSize,omitempty"`
 }
 
 // IstioStatus defines the observed state of Istio
@@ -110,10 +151,58 @@ type IstioStatus struct {
 	// Reports the current state of the object.
 	State IstioConditionReason `json:"state,omitempty"`
 
+	// Name of the IstioRevision that is currently active.
+	ActiveRevisionName string `json:"activeRevisionName,omitempty"`
+
+	// The concrete Istio version that spec.version resolves to. Differs from spec.version
+	// when spec.version is an alias such as "v1.20" or "stable".
+	ResolvedVersion string `json:"resolvedVersion,omitempty"`
+
 	// Reports information about the underlying IstioRevisions.
 	Revisions RevisionSummary `json:"revisions,omitempty"`
+
+	// Reports the progress of the automatic migration of workloads to the active revision.
+	// Only set when spec.updateStrategy.updateWorkloads is true.
+	WorkloadMigration *WorkloadMigrationStatus `json:"workloadMigration,omitempty"`
+
+	// Reports the status of each component of the active revision.
+	// +listType=map
+	// +listMapKey=name
+	Components []ComponentStatus `json:"components,omitempty"`
+
+	// Reports the profiles that were applied to compute the values of the active revision.
+	Profiles *ProfilesStatus `json:"profiles,omitempty"`
+}
+
+// ProfilesStatus reports the profiles that were applied to compute the values of the active revision.
+type ProfilesStatus struct {
+	// The profiles that were applied, in the order in which they were applied: the operator's default profiles,
+	// followed by spec.profile and spec.profiles.
+	Applied []string `json:"applied,omitempty"`
+
+	// Reports, for each top-level key of the values, the profile that last set it. Keys set in spec.values
+	// are reported with the source "spec.values", since those values overwrite the values of all profiles.
+	// +listType=map
+	// +listMapKey=key
+	ValuesSources []ValuesSource `json:"valuesSources,omitempty"`
+
+	// Lists the fields of profiles in the IstioOperator format that couldn't be converted to helm values
+	// and were therefore ignored.
+	Warnings []string `json:"warnings,omitempty"`
+}
+
+// ValuesSource identifies where the value of a top-level key of the values comes from.
+type ValuesSource struct {
+	// The top-level key of the values, e.g. "global" or "pilot".
+	Key string `json:"key"`
+
+	// The name of the profile that last set the key, or "spec.values".
+	Source string `json:"source"`
 }
 
+// ValuesSourceSpecValues is the source reported for the top-level keys of the values that are set in spec.values.
+const ValuesSourceSpecValues = "spec.values"
+
 // IstioRevisions contains information on the number of IstioRevisions associated with this Istio.
 type RevisionSummary struct {
 	// Total number of IstioRevisions currently associated with this Istio.
@@ -126,6 +215,51 @@ type RevisionSummary struct {
 	InUse int32 `json:"inUse"`
 }
 
+// WorkloadMigrationState represents the state of the automatic workload migration.
+type WorkloadMigrationState string
+
+const (
+	// WorkloadMigrationStatePending indicates that the workloads will be migrated once the active revision is ready.
+	WorkloadMigrationStatePending WorkloadMigrationState = "Pending"
+
+	// WorkloadMigrationStateInProgress indicates that the workloads are being moved to the active revision.
+	WorkloadMigrationStateInProgress WorkloadMigrationState = "InProgress"
+
+	// WorkloadMigrationStateCompleted indicates that all workloads have been moved to the active revision.
+	WorkloadMigrationStateCompleted WorkloadMigrationState = "Completed"
+
+	// WorkloadMigrationStateFailed indicates that all other workloads have been moved to the active revision, but
+	// the rollout of some restarted workloads exceeded their progress deadline.
+	WorkloadMigrationStateFailed WorkloadMigrationState = "Failed"
+)
+
+// WorkloadMigrationStatus contains information about the automatic migration of workloads
+// from the inactive revisions to the active revision.
+type WorkloadMigrationStatus struct {
+	// Name of the IstioRevision the workloads are being moved to.
+	TargetRevision string `json:"targetRevision"`
+
+	// Current state of the migration.
+	State WorkloadMigrationState `json:"state"`
+
+	// Number of namespaces that reference the target revision via the istio.io/rev label.
+	Namespaces int32 `json:"namespaces"`
+
+	// Total number of workloads that must be restarted to move to the target revision.
+	TotalWorkloads int32 `json:"totalWorkloads"`
+
+	// Number of workloads that were restarted and whose pods are ready.
+	UpdatedWorkloads int32 `json:"updatedWorkloads"`
+
+	// Number of workloads that are currently being restarted.
+	UpdatingWorkloads int32 `json:"updatingWorkloads"`
+
+	// Number of workloads that were restarted, but whose rollout didn't progress within the Deployment's
+	// spec.progressDeadlineSeconds. They don't hold back the migration of the other workloads and are counted
+	// as updated once their rollout completes.
+	FailedWorkloads int32 `json:"failedWorkloads,omitempty"`
+}
+
 // GetCondition returns the condition of the specified type
 func (s *IstioStatus) GetCondition(conditionType IstioConditionType) IstioCondition {
 	if s != nil {
@@ -203,6 +337,22 @@ const (
 
 	// IstioConditionReasonReconcileError indicates that the reconciliation of the resource has failed, but will be retried.
 	IstioConditionReasonReconcileError IstioConditionReason = "ReconcileError"
+
+	// IstioConditionReasonUpgradePolicyViolation indicates that the change to spec.version isn't allowed by the
+	// operator's upgrade policy. The previously installed version remains active.
+	IstioConditionReasonUpgradePolicyViolation IstioConditionReason = "UpgradePolicyViolation"
+
+	// IstioConditionReasonPaused indicates that the reconciliation of the resource has been paused through the
+	// operator.istio.io/paused annotation.
+	IstioConditionReasonPaused IstioConditionReason = "Paused"
+
+	// IstioConditionReasonDryRun indicates that the components of the active revision are only rendered, since
+	// the dry-run mode was enabled through the operator.istio.io/dry-run annotation.
+	IstioConditionReasonDryRun IstioConditionReason = "DryRun"
+
+	// IstioConditionReasonRolledBack indicates that the components of the active revision were rolled back, because
+	// istiod didn't become ready after they were upgraded. The components are upgraded again when the spec changes.
+	IstioConditionReasonRolledBack IstioConditionReason = "RolledBack"
 )
 
 const (
@@ -218,6 +368,27 @@ const (
 
 	// IstioConditionReasonCNINotReady indicates that the control plane is fully reconciled, but istio-cni-node is not ready.
 	IstioConditionReasonCNINotReady IstioConditionReason = "CNINotReady"
+
+	// IstioConditionReasonZTunnelNotReady indicates that the control plane is fully reconciled, but ztunnel is not ready.
+	IstioConditionReasonZTunnelNotReady IstioConditionReason = "ZTunnelNotReady"
+
+	// IstioConditionReasonZTunnelHandoverBlocked indicates that ztunnel can't be handed over to the active revision,
+	// since it runs in another namespace and moving it would interrupt the traffic of the ambient workloads.
+	IstioConditionReasonZTunnelHandoverBlocked IstioConditionReason = "ZTunnelHandoverBlocked"
+)
+
+const (
+	// IstioConditionTypeDrifted signifies whether the live objects of the active revision's components differ from
+	// the manifests the operator last applied.
+	IstioConditionTypeDrifted IstioConditionType = "Drifted"
+
+	// IstioConditionReasonDriftDetected indicates that some objects were changed outside of the operator and, as
+	// defined by the drift policy, the changes were left in place. The message lists the changes.
+	IstioConditionReasonDriftDetected IstioConditionReason = "DriftDetected"
+
+	// IstioConditionReasonDriftReverted indicates that the objects were restored to the state defined by the
+	// manifests. The message lists the most recently reverted changes.
+	IstioConditionReasonDriftReverted IstioConditionReason = "DriftReverted"
 )
 
 const (
@@ -0,0 +1,206 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v1alpha1
+
+import (
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	IstioCNIKind = "IstioCNI"
+
+	// IstioCNIName is the only allowed name of the IstioCNI object, since the
+	// Istio CNI node agent can only be installed once per cluster.
+	IstioCNIName = "default"
+)
+
+// IstioCNISpec defines the desired state of IstioCNI
+type IstioCNISpec struct {
+	// +sail:version
+	// Defines the version of Istio to install.
+	// Must be one of: v1.20.3, v1.20.2, v1.20.1, v1.19.7, v1.19.6, latest, gwAPIControllerMode.
+	// +operator-sdk:csv:customresourcedefinitions:type=spec,order=1,displayName="Istio Version",xDescriptors={"urn:alm:descriptor:com.tectonic.ui:fieldGroup:General", "urn:alm:descriptor:com.tectonic.ui:select:v1.20.3", "urn:alm:descriptor:com.tectonic.ui:select:v1.20.2", "urn:alm:descriptor:com.tectonic.ui:select:v1.20.1", "urn:alm:descriptor:com.tectonic.ui:select:v1.19.7", "urn:alm:descriptor:com.tectonic.ui:select:v1.19.6", "urn:alm:descriptor:com.tectonic.ui:select:latest", "urn:alm:descriptor:com.tectonic.ui:select:gwAPIControllerMode"}
+	// +kubebuilder:validation:Enum=v1.20.3;v1.20.2;v1.20.1;v1.19.7;v1.19.6;latest;gwAPIControllerMode
+	Version string `json:"version"`
+
+	// +sail:profile
+	// The installation configuration profile to use.
+	// The 'default' profile is always applied. On OpenShift, the 'openshift' profile is also applied on top of 'default'.
+	// The built-in profiles are: ambient, default, demo, empty, external, minimal, openshift-ambient, openshift, preview, remote.
+	// A user-defined profile, defined in a ConfigMap in the operator's namespace, can also be used.
+	// +operator-sdk:csv:customresourcedefinitions:type=spec,xDescriptors={"urn:alm:descriptor:com.tectonic.ui:hidden"}
+	// +kubebuilder:validation:MaxLength=63
+	// +kubebuilder:validation:Pattern=`^[a-zA-Z0-9]([-_.a-zA-Z0-9]*[a-zA-Z0-9])?$`
+	Profile string `json:"profile,omitempty"`
+
+	// Defines the values to be passed to the Helm chart when installing Istio CNI.
+	// +operator-sdk:csv:customresourcedefinitions:type=spec,displayName="Helm Values"
+	Values *CNIValues `json:"values,omitempty"`
+}
+
+// CNIValues defines the values that can be passed to the cni Helm chart.
+type CNIValues struct {
+	// Configuration of the Istio CNI node agent.
+	Cni *CNIConfig `json:"cni,omitempty"`
+
+	// Global configuration applied to the Istio CNI component.
+	Global *GlobalConfig `json:"global,omitempty"`
+}
+
+// IstioCNIStatus defines the observed state of IstioCNI
+type IstioCNIStatus struct {
+	// ObservedGeneration is the most recent generation observed for this
+	// IstioCNI object. It corresponds to the object's generation, which is
+	// updated on mutation by the API Server. The information in the status
+	// pertains to this particular generation of the object.
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+
+	// Represents the latest available observations of the object's current state.
+	Conditions []IstioCNICondition `json:"conditions,omitempty"`
+
+	// Reports the current state of the object.
+	State IstioCNIConditionReason `json:"state,omitempty"`
+
+	// Lists the fields of profiles in the IstioOperator format that couldn't be converted to helm values
+	// and were therefore ignored.
+	Warnings []string `json:"warnings,omitempty"`
+}
+
+// GetCondition returns the condition of the specified type
+func (s *IstioCNIStatus) GetCondition(conditionType IstioCNIConditionType) IstioCNICondition {
+	if s != nil {
+		for i := range s.Conditions {
+			if s.Conditions[i].Type == conditionType {
+				return s.Conditions[i]
+			}
+		}
+	}
+	return IstioCNICondition{Type: conditionType, Status: metav1.ConditionUnknown}
+}
+
+// SetCondition sets a specific condition in the list of conditions
+func (s *IstioCNIStatus) SetCondition(condition IstioCNICondition) {
+	var now time.Time
+	if testTime == nil {
+		now = time.Now()
+	} else {
+		now = *testTime
+	}
+
+	// The lastTransitionTime only gets serialized out to the second.  This can
+	// break update skipping, as the time in the resource returned from the client
+	// may not match the time in our cached status during a reconcile.  We truncate
+	// here to save any problems down the line.
+	lastTransitionTime := metav1.NewTime(now.Truncate(time.Second))
+
+	for i, prevCondition := range s.Conditions {
+		if prevCondition.Type == condition.Type {
+			if prevCondition.Status != condition.Status {
+				condition.LastTransitionTime = lastTransitionTime
+			} else {
+				condition.LastTransitionTime = prevCondition.LastTransitionTime
+			}
+			s.Conditions[i] = condition
+			return
+		}
+	}
+
+	// If the condition does not exist, initialize the lastTransitionTime
+	condition.LastTransitionTime = lastTransitionTime
+	s.Conditions = append(s.Conditions, condition)
+}
+
+// A Condition represents a specific observation of the object's state.
+type IstioCNICondition struct {
+	// The type of this condition.
+	Type IstioCNIConditionType `json:"type,omitempty"`
+
+	// The status of this condition. Can be True, False or Unknown.
+	Status metav1.ConditionStatus `json:"status,omitempty"`
+
+	// Unique, single-word, CamelCase reason for the condition's last transition.
+	Reason IstioCNIConditionReason `json:"reason,omitempty"`
+
+	// Human-readable message indicating details about the last transition.
+	Message string `json:"message,omitempty"`
+
+	// Last time the condition transitioned from one status to another.
+	LastTransitionTime metav1.Time `json:"lastTransitionTime,omitempty"`
+}
+
+// IstioCNIConditionType represents the type of the condition.  Condition stages are:
+// Reconciled, Ready
+type IstioCNIConditionType string
+
+// IstioCNIConditionReason represents a short message indicating how the condition came
+// to be in its present state.
+type IstioCNIConditionReason string
+
+const (
+	// IstioCNIConditionTypeReconciled signifies whether the controller has
+	// successfully reconciled the resources defined through the CR.
+	IstioCNIConditionTypeReconciled IstioCNIConditionType = "Reconciled"
+
+	// IstioCNIConditionReasonReconcileError indicates that the reconciliation of the resource has failed, but will be retried.
+	IstioCNIConditionReasonReconcileError IstioCNIConditionReason = "ReconcileError"
+)
+
+const (
+	// IstioCNIConditionTypeReady signifies whether the istio-cni-node DaemonSet is ready.
+	IstioCNIConditionTypeReady IstioCNIConditionType = "Ready"
+
+	// IstioCNIConditionReasonCNINotReady indicates that the istio-cni-node DaemonSet is not ready.
+	IstioCNIConditionReasonCNINotReady IstioCNIConditionReason = "CNINotReady"
+)
+
+const (
+	// IstioCNIConditionReasonHealthy indicates that the Istio CNI component is fully reconciled and that all its pods are ready.
+	IstioCNIConditionReasonHealthy IstioCNIConditionReason = "Healthy"
+)
+
+// +kubebuilder:object:root=true
+// +kubebuilder:resource:scope=Cluster,categories=istio-io
+// +kubebuilder:subresource:status
+// +kubebuilder:printcolumn:name="Ready",type="string",JSONPath=".status.conditions[?(@.type==\"Ready\")].status",description="Whether the Istio CNI installation is ready to handle requests."
+// +kubebuilder:printcolumn:name="Status",type="string",JSONPath=".status.state",description="The current state of this object."
+// +kubebuilder:printcolumn:name="Version",type="string",JSONPath=".spec.version",description="The version of the Istio CNI installation."
+// +kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp",description="The age of the object"
+// +kubebuilder:validation:XValidation:rule="self.metadata.name == 'default'",message="metadata.name must be 'default'"
+
+// IstioCNI represents a deployment of the Istio CNI component. The Istio CNI
+// node agent is installed once per cluster into the namespace of the operator
+// and is shared by all IstioRevisions that have CNI enabled.
+type IstioCNI struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   IstioCNISpec   `json:"spec,omitempty"`
+	Status IstioCNIStatus `json:"status,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// IstioCNIList contains a list of IstioCNI
+type IstioCNIList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []IstioCNI `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&IstioCNI{}, &IstioCNIList{})
+}
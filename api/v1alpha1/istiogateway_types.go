@@ -0,0 +1,185 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v1alpha1
+
+import (
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const IstioGatewayKind = "IstioGateway"
+
+// IstioGatewaySpec defines the desired state of IstioGateway
+type IstioGatewaySpec struct {
+	// Reference to the Istio or IstioRevision object whose control plane the gateway connects to.
+	// The gateway is installed from the chart of the referenced revision's version. When the
+	// gateway references an Istio object, it follows the Istio's active revision and is moved
+	// to the new revision when the Istio is upgraded.
+	// +operator-sdk:csv:customresourcedefinitions:type=spec,order=1,displayName="Target Reference"
+	TargetRef TargetReference `json:"targetRef"`
+
+	// Defines the values to be passed to the Helm chart when installing the gateway.
+	// +operator-sdk:csv:customresourcedefinitions:type=spec,displayName="Helm Values"
+	Values *GatewayValues `json:"values,omitempty"`
+}
+
+// IstioGatewayStatus defines the observed state of IstioGateway
+type IstioGatewayStatus struct {
+	// ObservedGeneration is the most recent generation observed for this
+	// IstioGateway object. It corresponds to the object's generation, which is
+	// updated on mutation by the API Server. The information in the status
+	// pertains to this particular generation of the object.
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+
+	// Represents the latest available observations of the object's current state.
+	Conditions []IstioGatewayCondition `json:"conditions,omitempty"`
+
+	// Reports the current state of the object.
+	State IstioGatewayConditionReason `json:"state,omitempty"`
+
+	// Name of the IstioRevision the gateway is currently connected to.
+	IstioRevision string `json:"istioRevision,omitempty"`
+}
+
+// GetCondition returns the condition of the specified type
+func (s *IstioGatewayStatus) GetCondition(conditionType IstioGatewayConditionType) IstioGatewayCondition {
+	if s != nil {
+		for i := range s.Conditions {
+			if s.Conditions[i].Type == conditionType {
+				return s.Conditions[i]
+			}
+		}
+	}
+	return IstioGatewayCondition{Type: conditionType, Status: metav1.ConditionUnknown}
+}
+
+// SetCondition sets a specific condition in the list of conditions
+func (s *IstioGatewayStatus) SetCondition(condition IstioGatewayCondition) {
+	var now time.Time
+	if testTime == nil {
+		now = time.Now()
+	} else {
+		now = *testTime
+	}
+
+	// The lastTransitionTime only gets serialized out to the second.  This can
+	// break update skipping, as the time in the resource returned from the client
+	// may not match the time in our cached status during a reconcile.  We truncate
+	// here to save any problems down the line.
+	lastTransitionTime := metav1.NewTime(now.Truncate(time.Second))
+
+	for i, prevCondition := range s.Conditions {
+		if prevCondition.Type == condition.Type {
+			if prevCondition.Status != condition.Status {
+				condition.LastTransitionTime = lastTransitionTime
+			} else {
+				condition.LastTransitionTime = prevCondition.LastTransitionTime
+			}
+			s.Conditions[i] = condition
+			return
+		}
+	}
+
+	// If the condition does not exist, initialize the lastTransitionTime
+	condition.LastTransitionTime = lastTransitionTime
+	s.Conditions = append(s.Conditions, condition)
+}
+
+// A Condition represents a specific observation of the object's state.
+type IstioGatewayCondition struct {
+	// The type of this condition.
+	Type IstioGatewayConditionType `json:"type,omitempty"`
+
+	// The status of this condition. Can be True, False or Unknown.
+	Status metav1.ConditionStatus `json:"status,omitempty"`
+
+	// Unique, single-word, CamelCase reason for the condition's last transition.
+	Reason IstioGatewayConditionReason `json:"reason,omitempty"`
+
+	// Human-readable message indicating details about the last transition.
+	Message string `json:"message,omitempty"`
+
+	// Last time the condition transitioned from one status to another.
+	LastTransitionTime metav1.Time `json:"lastTransitionTime,omitempty"`
+}
+
+// IstioGatewayConditionType represents the type of the condition.  Condition stages are:
+// Reconciled, Ready
+type IstioGatewayConditionType string
+
+// IstioGatewayConditionReason represents a short message indicating how the condition came
+// to be in its present state.
+type IstioGatewayConditionReason string
+
+const (
+	// IstioGatewayConditionTypeReconciled signifies whether the controller has
+	// successfully reconciled the resources defined through the CR.
+	IstioGatewayConditionTypeReconciled IstioGatewayConditionType = "Reconciled"
+
+	// IstioGatewayConditionReasonReconcileError indicates that the reconciliation of the resource has failed, but will be retried.
+	IstioGatewayConditionReasonReconcileError IstioGatewayConditionReason = "ReconcileError"
+
+	// IstioGatewayConditionReasonReferenceNotFound indicates that the referenced Istio or IstioRevision doesn't exist.
+	IstioGatewayConditionReasonReferenceNotFound IstioGatewayConditionReason = "ReferenceNotFound"
+)
+
+const (
+	// IstioGatewayConditionTypeReady signifies whether the gateway's workload and Service are ready.
+	IstioGatewayConditionTypeReady IstioGatewayConditionType = "Ready"
+
+	// IstioGatewayConditionReasonGatewayNotReady indicates that the gateway's Deployment or DaemonSet is not ready.
+	IstioGatewayConditionReasonGatewayNotReady IstioGatewayConditionReason = "GatewayNotReady"
+
+	// IstioGatewayConditionReasonServiceNotReady indicates that the gateway's Service doesn't exist or hasn't been assigned an address.
+	IstioGatewayConditionReasonServiceNotReady IstioGatewayConditionReason = "ServiceNotReady"
+)
+
+const (
+	// IstioGatewayConditionReasonHealthy indicates that the gateway is fully reconciled and that all its pods are ready.
+	IstioGatewayConditionReasonHealthy IstioGatewayConditionReason = "Healthy"
+)
+
+// +kubebuilder:object:root=true
+// +kubebuilder:resource:shortName=istiogw,categories=istio-io
+// +kubebuilder:subresource:status
+// +kubebuilder:printcolumn:name="Ready",type="string",JSONPath=".status.conditions[?(@.type==\"Ready\")].status",description="Whether the gateway is ready to handle requests."
+// +kubebuilder:printcolumn:name="Status",type="string",JSONPath=".status.state",description="The current state of this object."
+// +kubebuilder:printcolumn:name="Revision",type="string",JSONPath=".status.istioRevision",description="The IstioRevision the gateway is connected to."
+// +kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp",description="The age of the object"
+
+// IstioGateway represents an ingress or egress gateway deployed from the gateway chart
+// that ships with the referenced Istio version. The gateway's resources are created in
+// the namespace of the IstioGateway object.
+type IstioGateway struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   IstioGatewaySpec   `json:"spec,omitempty"`
+	Status IstioGatewayStatus `json:"status,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// IstioGatewayList contains a list of IstioGateway
+type IstioGatewayList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []IstioGateway `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&IstioGateway{}, &IstioGatewayList{})
+}
@@ -42,6 +42,73 @@ type IstioRevisionSpec struct {
 	// Defines the values to be passed to the Helm charts when installing Istio.
 	// +operator-sdk:csv:customresourcedefinitions:type=spec,displayName="Helm Values"
 	Values *Values `json:"values,omitempty"`
+
+	// Defines how the operator handles changes made to the deployed components outside of the operator.
+	// +operator-sdk:csv:customresourcedefinitions:type=spec,displayName="Drift Policy"
+	DriftPolicy *DriftPolicy `json:"driftPolicy,omitempty"`
+
+	// Defines whether and when the operator rolls back an upgrade of the components that leaves istiod unhealthy.
+	// +operator-sdk:csv:customresourcedefinitions:type=spec,displayName="Rollback Policy"
+	RollbackPolicy *RollbackPolicy `json:"rollbackPolicy,omitempty"`
+}
+
+// RollbackPolicy defines whether and when the operator rolls back an upgrade of the components that leaves istiod
+// unhealthy. After rolling back, the operator doesn't apply the same values again until they are changed.
+type RollbackPolicy struct {
+	// Enables the automatic rollback of failed upgrades. Disabled by default.
+	// +operator-sdk:csv:customresourcedefinitions:type=spec,order=1,displayName="Enabled",xDescriptors={"urn:alm:descriptor:com.tectonic.ui:booleanSwitch"}
+	Enabled bool `json:"enabled,omitempty"`
+
+	// Defines how many seconds the operator waits for istiod to become ready after an upgrade before rolling
+	// the upgrade back. The minimum is 30 and the default value is 300.
+	// +operator-sdk:csv:customresourcedefinitions:type=spec,order=2,displayName="Progress Deadline (seconds)",xDescriptors={"urn:alm:descriptor:com.tectonic.ui:number"}
+	// +kubebuilder:validation:Minimum=30
+	ProgressDeadlineSeconds *int32 `json:"progressDeadlineSeconds,omitempty"`
+
+	// Defines how many revisions of each Helm release the operator keeps. The minimum is 2 and the default value is 3.
+	// +operator-sdk:csv:customresourcedefinitions:type=spec,order=3,displayName="Max History",xDescriptors={"urn:alm:descriptor:com.tectonic.ui:number"}
+	// +kubebuilder:validation:Minimum=2
+	MaxHistory *int32 `json:"maxHistory,omitempty"`
+}
+
+// DriftPolicy defines how the operator handles changes made to the deployed components outside of the
+// operator, i.e. differences between the live objects and the manifests the operator last applied.
+type DriftPolicy struct {
+	// Defines what the operator does when it detects that a deployed object was changed. With "Revert",
+	// the object is restored to the state defined by the manifests. With "Report", the change is only
+	// recorded in the Drifted condition and remains in place until the manifests themselves change.
+	// The default is "Revert".
+	// +operator-sdk:csv:customresourcedefinitions:type=spec,order=1,displayName="Action",xDescriptors={"urn:alm:descriptor:com.tectonic.ui:select:Revert", "urn:alm:descriptor:com.tectonic.ui:select:Report"}
+	// +kubebuilder:validation:Enum=Revert;Report
+	Action DriftAction `json:"action,omitempty"`
+
+	// Defines the fields whose changes are neither reverted nor reported.
+	// +operator-sdk:csv:customresourcedefinitions:type=spec,order=2,displayName="Ignored Fields"
+	IgnoredFields []DriftIgnoreRule `json:"ignoredFields,omitempty"`
+}
+
+// DriftAction defines what the operator does when it detects a drifted object
+type DriftAction string
+
+const (
+	DriftActionRevert DriftAction = "Revert"
+	DriftActionReport DriftAction = "Report"
+)
+
+// DriftIgnoreRule defines the fields whose changes are ignored in the matching objects
+type DriftIgnoreRule struct {
+	// The kind of the objects the rule applies to. If not set, the rule applies to objects of all kinds.
+	Kind string `json:"kind,omitempty"`
+
+	// The name of the object the rule applies to. If not set, the rule applies to all objects of the given kind.
+	Name string `json:"name,omitempty"`
+
+	// The paths of the ignored fields, e.g. "spec.replicas" or "spec.template.spec.containers[*].resources".
+	// Array elements are selected by their index or by "[*]"; map keys that contain dots are written in
+	// brackets, e.g. "metadata.annotations[example.com/owner]". Changes to any field below an ignored
+	// path are ignored as well.
+	// +kubebuilder:validation:MinItems=1
+	Paths []string `json:"paths"`
 }
 
 // IstioRevisionStatus defines the observed state of IstioRevision
@@ -57,8 +124,128 @@ type IstioRevisionStatus struct {
 
 	// Reports the current state of the object.
 	State IstioRevisionConditionReason `json:"state,omitempty"`
+
+	// Tracks the upgrades of the components while spec.rollbackPolicy is enabled.
+	Rollback *RollbackStatus `json:"rollback,omitempty"`
+
+	// Reports the status of each component of the revision, i.e. istiod, its webhooks, the CNI node agent,
+	// ztunnel and the gateways connected to the revision.
+	// +listType=map
+	// +listMapKey=name
+	Components []ComponentStatus `json:"components,omitempty"`
+
+	// Reports the namespaces and pods that reference the revision, either directly or through an
+	// IstioRevisionTag.
+	Workloads *WorkloadInventory `json:"workloads,omitempty"`
 }
 
+// ComponentStatus reports the status of a single component of a revision
+type ComponentStatus struct {
+	// The name of the component: istiod, sidecar-injector-webhook, validation-webhook, cni, ztunnel or
+	// gateway/<namespace>/<name>.
+	Name string `json:"name"`
+
+	// The kind of the object that implements the component, e.g. Deployment or DaemonSet.
+	Kind string `json:"kind,omitempty"`
+
+	// The number of pods that should be running. Not set for components that don't run any pods.
+	DesiredReplicas *int32 `json:"desiredReplicas,omitempty"`
+
+	// The number of ready pods. Not set for components that don't run any pods.
+	ReadyReplicas *int32 `json:"readyReplicas,omitempty"`
+
+	// The image of the component's main container.
+	Image string `json:"image,omitempty"`
+
+	// The version of the Helm chart the component is deployed from.
+	ChartVersion string `json:"chartVersion,omitempty"`
+
+	// Whether the component is ready. Can be True, False or Unknown.
+	Ready metav1.ConditionStatus `json:"ready"`
+
+	// Unique, single-word, CamelCase reason why the component isn't ready.
+	Reason IstioRevisionConditionReason `json:"reason,omitempty"`
+
+	// Human-readable message indicating why the component isn't ready.
+	Message string `json:"message,omitempty"`
+}
+
+// RollbackStatus tracks the upgrades of the components, so that an upgrade that leaves istiod unhealthy can be
+// rolled back
+type RollbackStatus struct {
+	// The revisions of the Helm releases that were last verified to be healthy, by chart name.
+	LastGoodReleases map[string]int `json:"lastGoodReleases,omitempty"`
+
+	// The hash of the version and values of the releases that were last verified to be healthy.
+	LastGoodValuesHash string `json:"lastGoodValuesHash,omitempty"`
+
+	// The hash of the version and values applied by the most recent upgrade, while istiod isn't ready yet.
+	PendingValuesHash string `json:"pendingValuesHash,omitempty"`
+
+	// The time of the most recent upgrade, while istiod isn't ready yet.
+	PendingSince *metav1.Time `json:"pendingSince,omitempty"`
+
+	// The hash of the version and values whose upgrade was rolled back. The operator doesn't apply them again.
+	FailedValuesHash string `json:"failedValuesHash,omitempty"`
+}
+
+// MaxListedWorkloads is the maximum number of namespaces and pods listed in the WorkloadInventory
+const MaxListedWorkloads = 20
+
+// WorkloadInventory reports the namespaces and pods that reference a revision
+type WorkloadInventory struct {
+	// Number of namespaces that reference the revision via the istio-injection or istio.io/rev label.
+	Namespaces int32 `json:"namespaces"`
+
+	// Number of pods that reference the revision.
+	Pods int32 `json:"pods"`
+
+	// Number of pods that were injected by the revision.
+	InjectedPods int32 `json:"injectedPods"`
+
+	// Number of pods that weren't injected by the revision yet, but will be once they're restarted, because
+	// their namespace references the revision.
+	PendingInjectionPods int32 `json:"pendingInjectionPods"`
+
+	// Number of pods that weren't injected by the revision yet, but reference it via their own labels.
+	PodLabelPods int32 `json:"podLabelPods"`
+
+	// Names of the namespaces that reference the revision, sorted by name. At most 20 are listed.
+	NamespaceNames []string `json:"namespaceNames,omitempty"`
+
+	// The pods that reference the revision, sorted by namespace and name. At most 20 are listed.
+	PodReferences []PodReference `json:"podReferences,omitempty"`
+}
+
+// PodReference identifies a pod that references a revision and describes how it references it
+type PodReference struct {
+	// The namespace of the pod.
+	Namespace string `json:"namespace"`
+
+	// The name of the pod.
+	Name string `json:"name"`
+
+	// How the pod references the revision.
+	Type WorkloadReferenceType `json:"type"`
+}
+
+// WorkloadReferenceType describes how a pod references a revision
+type WorkloadReferenceType string
+
+const (
+	// WorkloadReferenceTypeInjected means the pod's sidecar was injected by the revision, as recorded in the
+	// pod's istio.io/rev annotation.
+	WorkloadReferenceTypeInjected WorkloadReferenceType = "Injected"
+
+	// WorkloadReferenceTypePendingInjection means the pod wasn't injected by the revision, but its namespace
+	// references the revision via the istio-injection or istio.io/rev label.
+	WorkloadReferenceTypePendingInjection WorkloadReferenceType = "PendingInjection"
+
+	// WorkloadReferenceTypePodLabel means the pod wasn't injected by the revision, but references it via its
+	// own istio.io/rev or sidecar.istio.io/inject label.
+	WorkloadReferenceTypePodLabel WorkloadReferenceType = "PodLabel"
+)
+
 // GetCondition returns the condition of the specified type
 func (s *IstioRevisionStatus) GetCondition(conditionType IstioRevisionConditionType) IstioRevisionCondition {
 	if s != nil {
@@ -136,6 +323,19 @@ const (
 
 	// IstioRevisionConditionReasonReconcileError indicates that the reconciliation of the resource has failed, but will be retried.
 	IstioRevisionConditionReasonReconcileError IstioRevisionConditionReason = "ReconcileError"
+
+	// IstioRevisionConditionReasonPaused indicates that the reconciliation of the resource has been paused through the
+	// operator.istio.io/paused annotation.
+	IstioRevisionConditionReasonPaused IstioRevisionConditionReason = "Paused"
+
+	// IstioRevisionConditionReasonDryRun indicates that the components are only rendered, since the dry-run mode
+	// was enabled through the operator.istio.io/dry-run annotation.
+	IstioRevisionConditionReasonDryRun IstioRevisionConditionReason = "DryRun"
+
+	// IstioRevisionConditionReasonRolledBack indicates that the components were rolled back, because istiod didn't
+	// become ready after they were upgraded to the current version and values. The components are upgraded again
+	// when the spec changes.
+	IstioRevisionConditionReasonRolledBack IstioRevisionConditionReason = "RolledBack"
 )
 
 const (
@@ -148,6 +348,35 @@ const (
 
 	// IstioRevisionConditionReasonCNINotReady indicates that the control plane is fully reconciled, but istio-cni-node is not ready.
 	IstioRevisionConditionReasonCNINotReady IstioRevisionConditionReason = "CNINotReady"
+
+	// IstioRevisionConditionReasonZTunnelNotReady indicates that the control plane is fully reconciled, but ztunnel is not ready.
+	IstioRevisionConditionReasonZTunnelNotReady IstioRevisionConditionReason = "ZTunnelNotReady"
+
+	// IstioRevisionConditionReasonZTunnelHandoverBlocked indicates that ztunnel can't be handed over to the revision,
+	// since it runs in another namespace and moving it would interrupt the traffic of the ambient workloads.
+	IstioRevisionConditionReasonZTunnelHandoverBlocked IstioRevisionConditionReason = "ZTunnelHandoverBlocked"
+
+	// IstioRevisionConditionReasonWebhookNotReady indicates that istiod hasn't configured a webhook yet.
+	// Only reported in status.components.
+	IstioRevisionConditionReasonWebhookNotReady IstioRevisionConditionReason = "WebhookNotReady"
+
+	// IstioRevisionConditionReasonGatewayNotReady indicates that a gateway connected to the revision is not ready.
+	// Only reported in status.components.
+	IstioRevisionConditionReasonGatewayNotReady IstioRevisionConditionReason = "GatewayNotReady"
+)
+
+const (
+	// IstioRevisionConditionTypeDrifted signifies whether the live objects of the deployed components differ from
+	// the manifests the operator last applied.
+	IstioRevisionConditionTypeDrifted IstioRevisionConditionType = "Drifted"
+
+	// IstioRevisionConditionReasonDriftDetected indicates that some objects were changed outside of the operator
+	// and, as defined by the drift policy, the changes were left in place. The message lists the changes.
+	IstioRevisionConditionReasonDriftDetected IstioRevisionConditionReason = "DriftDetected"
+
+	// IstioRevisionConditionReasonDriftReverted indicates that the objects were restored to the state defined by the
+	// manifests. The message lists the most recently reverted changes.
+	IstioRevisionConditionReasonDriftReverted IstioRevisionConditionReason = "DriftReverted"
 )
 
 const (
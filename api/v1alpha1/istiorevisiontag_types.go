@@ -0,0 +1,199 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v1alpha1
+
+import (
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const IstioRevisionTagKind = "IstioRevisionTag"
+
+// IstioRevisionTagSpec defines the desired state of IstioRevisionTag
+type IstioRevisionTagSpec struct {
+	// Reference to the Istio or IstioRevision object that the tag points to.
+	// +operator-sdk:csv:customresourcedefinitions:type=spec,order=1,displayName="Target Reference"
+	TargetRef TargetReference `json:"targetRef"`
+}
+
+// TargetReference can reference either Istio or IstioRevision objects in the cluster.
+type TargetReference struct {
+	// Kind is the kind of the target resource. Can be Istio or IstioRevision.
+	// When an Istio object is referenced, the reference always resolves to the active revision of that Istio.
+	// +operator-sdk:csv:customresourcedefinitions:type=spec,order=1,displayName="Kind",xDescriptors={"urn:alm:descriptor:com.tectonic.ui:select:Istio", "urn:alm:descriptor:com.tectonic.ui:select:IstioRevision"}
+	// +kubebuilder:validation:Enum=Istio;IstioRevision
+	Kind string `json:"kind"`
+
+	// Name of the target resource.
+	// +operator-sdk:csv:customresourcedefinitions:type=spec,order=2,displayName="Name"
+	// +kubebuilder:validation:MinLength=1
+	Name string `json:"name"`
+}
+
+// IstioRevisionTagStatus defines the observed state of IstioRevisionTag
+type IstioRevisionTagStatus struct {
+	// ObservedGeneration is the most recent generation observed for this
+	// IstioRevisionTag object. It corresponds to the object's generation, which is
+	// updated on mutation by the API Server. The information in the status
+	// pertains to this particular generation of the object.
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+
+	// Represents the latest available observations of the object's current state.
+	Conditions []IstioRevisionTagCondition `json:"conditions,omitempty"`
+
+	// Reports the current state of the object.
+	State IstioRevisionTagConditionReason `json:"state,omitempty"`
+
+	// Name of the IstioRevision the tag currently points to.
+	IstioRevision string `json:"istioRevision,omitempty"`
+
+	// Namespace of the istiod instance the tag's webhook sends injection requests to.
+	IstiodNamespace string `json:"istiodNamespace,omitempty"`
+}
+
+// GetCondition returns the condition of the specified type
+func (s *IstioRevisionTagStatus) GetCondition(conditionType IstioRevisionTagConditionType) IstioRevisionTagCondition {
+	if s != nil {
+		for i := range s.Conditions {
+			if s.Conditions[i].Type == conditionType {
+				return s.Conditions[i]
+			}
+		}
+	}
+	return IstioRevisionTagCondition{Type: conditionType, Status: metav1.ConditionUnknown}
+}
+
+// SetCondition sets a specific condition in the list of conditions
+func (s *IstioRevisionTagStatus) SetCondition(condition IstioRevisionTagCondition) {
+	var now time.Time
+	if testTime == nil {
+		now = time.Now()
+	} else {
+		now = *testTime
+	}
+
+	// The lastTransitionTime only gets serialized out to the second.  This can
+	// break update skipping, as the time in the resource returned from the client
+	// may not match the time in our cached status during a reconcile.  We truncate
+	// here to save any problems down the line.
+	lastTransitionTime := metav1.NewTime(now.Truncate(time.Second))
+
+	for i, prevCondition := range s.Conditions {
+		if prevCondition.Type == condition.Type {
+			if prevCondition.Status != condition.Status {
+				condition.LastTransitionTime = lastTransitionTime
+			} else {
+				condition.LastTransitionTime = prevCondition.LastTransitionTime
+			}
+			s.Conditions[i] = condition
+			return
+		}
+	}
+
+	// If the condition does not exist, initialize the lastTransitionTime
+	condition.LastTransitionTime = lastTransitionTime
+	s.Conditions = append(s.Conditions, condition)
+}
+
+// A Condition represents a specific observation of the object's state.
+type IstioRevisionTagCondition struct {
+	// The type of this condition.
+	Type IstioRevisionTagConditionType `json:"type,omitempty"`
+
+	// The status of this condition. Can be True, False or Unknown.
+	Status metav1.ConditionStatus `json:"status,omitempty"`
+
+	// Unique, single-word, CamelCase reason for the condition's last transition.
+	Reason IstioRevisionTagConditionReason `json:"reason,omitempty"`
+
+	// Human-readable message indicating details about the last transition.
+	Message string `json:"message,omitempty"`
+
+	// Last time the condition transitioned from one status to another.
+	LastTransitionTime metav1.Time `json:"lastTransitionTime,omitempty"`
+}
+
+// IstioRevisionTagConditionType represents the type of the condition.  Condition stages are:
+// Reconciled, InUse
+type IstioRevisionTagConditionType string
+
+// IstioRevisionTagConditionReason represents a short message indicating how the condition came
+// to be in its present state.
+type IstioRevisionTagConditionReason string
+
+const (
+	// IstioRevisionTagConditionTypeReconciled signifies whether the controller has
+	// successfully reconciled the resources defined through the CR.
+	IstioRevisionTagConditionTypeReconciled IstioRevisionTagConditionType = "Reconciled"
+
+	// IstioRevisionTagConditionReasonReconcileError indicates that the reconciliation of the resource has failed, but will be retried.
+	IstioRevisionTagConditionReasonReconcileError IstioRevisionTagConditionReason = "ReconcileError"
+
+	// IstioRevisionTagConditionReasonReferenceNotFound indicates that the resource referenced by the tag's targetRef was not found.
+	IstioRevisionTagConditionReasonReferenceNotFound IstioRevisionTagConditionReason = "ReferenceNotFound"
+
+	// IstioRevisionTagConditionReasonNameAlreadyExists indicates that an IstioRevision with the same name as the tag already exists.
+	IstioRevisionTagConditionReasonNameAlreadyExists IstioRevisionTagConditionReason = "NameAlreadyExists"
+)
+
+const (
+	// IstioRevisionTagConditionTypeInUse signifies whether any workload is configured to use the tag.
+	IstioRevisionTagConditionTypeInUse IstioRevisionTagConditionType = "InUse"
+
+	// IstioRevisionTagConditionReasonReferencedByWorkloads indicates that the tag is referenced by at least one pod or namespace.
+	IstioRevisionTagConditionReasonReferencedByWorkloads IstioRevisionTagConditionReason = "ReferencedByWorkloads"
+
+	// IstioRevisionTagConditionReasonNotReferenced indicates that the tag is not referenced by any pod or namespace.
+	IstioRevisionTagConditionReasonNotReferenced IstioRevisionTagConditionReason = "NotReferencedByAnything"
+)
+
+const (
+	// IstioRevisionTagConditionReasonHealthy indicates that the tag's webhook is installed and points to the referenced revision.
+	IstioRevisionTagConditionReasonHealthy IstioRevisionTagConditionReason = "Healthy"
+)
+
+// +kubebuilder:object:root=true
+// +kubebuilder:resource:scope=Cluster,categories=istio-io
+// +kubebuilder:subresource:status
+// +kubebuilder:printcolumn:name="Status",type="string",JSONPath=".status.state",description="The current state of this object."
+// +kubebuilder:printcolumn:name="In use",type="string",JSONPath=".status.conditions[?(@.type==\"InUse\")].status",description="Whether the tag is being used by workloads."
+// +kubebuilder:printcolumn:name="Revision",type="string",JSONPath=".status.istioRevision",description="The IstioRevision this object is referencing."
+// +kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp",description="The age of the object"
+
+// IstioRevisionTag references an Istio or IstioRevision object and serves as an alias for sidecar injection.
+// Workloads and namespaces can reference the tag's name in the istio.io/rev label instead of the name of a
+// specific revision. This allows them to be moved to a new revision by retargeting the tag, without the need
+// to relabel them. The operator installs a sidecar injector webhook for each tag.
+type IstioRevisionTag struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   IstioRevisionTagSpec   `json:"spec,omitempty"`
+	Status IstioRevisionTagStatus `json:"status,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// IstioRevisionTagList contains a list of IstioRevisionTag
+type IstioRevisionTagList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []IstioRevisionTag `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&IstioRevisionTag{}, &IstioRevisionTagList{})
+}
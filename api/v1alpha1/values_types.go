@@ -61,6 +61,14 @@ type CNIConfig struct {
 	// K8s rolling update strategy
 	// +kubebuilder:validation:XIntOrString
 	RollingMaxUnavailable *intstr.IntOrString `json:"rollingMaxUnavailable,omitempty"`
+	// Configuration of the ambient mode redirection.
+	Ambient *CNIAmbientConfig `json:"ambient,omitempty"`
+}
+
+// Configuration for ambient mode in the Istio CNI node agent.
+type CNIAmbientConfig struct {
+	// Controls whether the CNI node agent redirects traffic of ambient workloads to ztunnel.
+	Enabled bool `json:"enabled,omitempty"`
 }
 
 type CNIRepairConfig struct {
@@ -79,6 +87,50 @@ type CNIRepairConfig struct {
 	InitContainerName   string `json:"initContainerName,omitempty"`
 }
 
+// Configuration for the ztunnel node proxy used in ambient mode.
+type ZTunnelConfig struct {
+	// Controls whether ztunnel is installed. Ztunnel is also installed when the
+	// ambient or openshift-ambient profile is used.
+	Enabled bool `json:"enabled,omitempty"`
+	// Hub to pull the image from. Image will be `Hub/Image:Tag-Variant`.
+	Hub string `json:"hub,omitempty"`
+	// Tag to pull the image with. Image will be `Hub/Image:Tag-Variant`.
+	// +kubebuilder:validation:XIntOrString
+	Tag *intstr.IntOrString `json:"tag,omitempty"`
+	// Variant to pull. Options are "debug" or "distroless". Unset will use the default for the given version.
+	Variant string `json:"variant,omitempty"`
+	// Image name to pull. If Image contains a "/", it will replace the entire `image` in the pod.
+	Image string `json:"image,omitempty"`
+	// Labels to apply to all top level resources.
+	Labels map[string]string `json:"labels,omitempty"`
+	// Annotations to apply to all top level resources.
+	Annotations map[string]string `json:"annotations,omitempty"`
+	// Additional volumeMounts for the ztunnel container.
+	VolumeMounts []k8sv1.VolumeMount `json:"volumeMounts,omitempty"`
+	// Additional volumes for the ztunnel pod.
+	Volumes []k8sv1.Volume `json:"volumes,omitempty"`
+	// Annotations added to each pod.
+	PodAnnotations map[string]string `json:"podAnnotations,omitempty"`
+	// Additional labels to apply on the pod level.
+	PodLabels map[string]string `json:"podLabels,omitempty"`
+	// K8s resources settings.
+	//
+	// See https://kubernetes.io/docs/concepts/configuration/manage-compute-resources-container/#resource-requests-and-limits-of-pod-and-container
+	Resources *k8sv1.ResourceRequirements `json:"resources,omitempty"`
+	// List of secret names to add to the service account as image pull secrets.
+	ImagePullSecrets []string `json:"imagePullSecrets,omitempty"`
+	// A `key: value` mapping of environment variables to add to the pod.
+	Env map[string]string `json:"env,omitempty"`
+	// Specifies the image pull policy.
+	ImagePullPolicy k8sv1.PullPolicy `json:"imagePullPolicy,omitempty"`
+	// Ambient redirection mode: "iptables" or "ebpf".
+	RedirectMode string `json:"redirectMode,omitempty"`
+	// How many seconds kube waits for the ztunnel pod to gracefully exit before forcibly terminating it.
+	TerminationGracePeriodSeconds *int64 `json:"terminationGracePeriodSeconds,omitempty"`
+	// The customized CA address to retrieve certificates for the pods in the cluster.
+	CAAddress string `json:"caAddress,omitempty"`
+}
+
 type ResourceQuotas struct {
 	// Controls whether to create resource quotas or not for the CNI DaemonSet.
 	Enabled bool  `json:"enabled,omitempty"`
@@ -621,6 +673,7 @@ type Values struct {
 	Telemetry              *TelemetryConfig       `json:"telemetry,omitempty"`
 	SidecarInjectorWebhook *SidecarInjectorConfig `json:"sidecarInjectorWebhook,omitempty"`
 	IstioCni               *CNIConfig             `json:"istio_cni,omitempty"`
+	ZTunnel                *ZTunnelConfig         `json:"ztunnel,omitempty"`
 	Revision               string                 `json:"revision,omitempty"`
 	MeshConfig             *MeshConfig            `json:"meshConfig,omitempty"`
 	Base                   *BaseConfig            `json:"base,omitempty"`
@@ -638,6 +691,124 @@ type ZeroVPNConfig struct {
 	Suffix  string `json:"suffix,omitempty"`
 }
 
+// GatewayValues defines the values that can be passed to the gateway Helm chart.
+type GatewayValues struct {
+	// Controls the spec.replicas setting for the gateway Deployment. If not set, the
+	// Kubernetes Deployment default is used.
+	ReplicaCount *int32 `json:"replicaCount,omitempty"`
+	// The kind of workload to deploy the gateway as.
+	// +kubebuilder:validation:Enum=Deployment;DaemonSet
+	Kind string `json:"kind,omitempty"`
+	// Configuration of the Role and RoleBinding that allow the gateway to read the TLS credentials.
+	Rbac *GatewayRBACConfig `json:"rbac,omitempty"`
+	// Configuration of the gateway's ServiceAccount.
+	ServiceAccount *GatewayServiceAccountConfig `json:"serviceAccount,omitempty"`
+	// Annotations added to each pod.
+	PodAnnotations map[string]string `json:"podAnnotations,omitempty"`
+	// The security context of the gateway pods. If not set, the minimum privileges required
+	// to bind to ports 80 and 443 are used.
+	SecurityContext *k8sv1.PodSecurityContext `json:"securityContext,omitempty"`
+	// The security context of the gateway container.
+	ContainerSecurityContext *k8sv1.SecurityContext `json:"containerSecurityContext,omitempty"`
+	// Configuration of the gateway's Service.
+	Service *GatewayServiceConfig `json:"service,omitempty"`
+	// K8s resources settings.
+	//
+	// See https://kubernetes.io/docs/concepts/configuration/manage-compute-resources-container/#resource-requests-and-limits-of-pod-and-container
+	Resources *k8sv1.ResourceRequirements `json:"resources,omitempty"`
+	// Configuration of the gateway's HorizontalPodAutoscaler.
+	Autoscaling *GatewayAutoscalingConfig `json:"autoscaling,omitempty"`
+	// A `key: value` mapping of environment variables to add to the pod.
+	Env map[string]string `json:"env,omitempty"`
+	// Labels to apply to all resources.
+	Labels map[string]string `json:"labels,omitempty"`
+	// Annotations to apply to all resources.
+	Annotations map[string]string `json:"annotations,omitempty"`
+	// K8s node selector.
+	//
+	// See https://kubernetes.io/docs/concepts/configuration/assign-pod-node/#nodeselector
+	NodeSelector map[string]string `json:"nodeSelector,omitempty"`
+	// K8s tolerations.
+	//
+	// See https://kubernetes.io/docs/concepts/scheduling-eviction/taint-and-toleration/
+	Tolerations []k8sv1.Toleration `json:"tolerations,omitempty"`
+	// K8s topology spread constraints.
+	TopologySpreadConstraints []k8sv1.TopologySpreadConstraint `json:"topologySpreadConstraints,omitempty"`
+	// K8s affinity.
+	//
+	// See https://kubernetes.io/docs/concepts/scheduling-eviction/assign-pod-node/#affinity-and-anti-affinity
+	Affinity *k8sv1.Affinity `json:"affinity,omitempty"`
+	// If specified, the gateway acts as a network gateway for the given network.
+	NetworkGateway string `json:"networkGateway,omitempty"`
+	// Specifies the image pull policy.
+	ImagePullPolicy k8sv1.PullPolicy `json:"imagePullPolicy,omitempty"`
+	// References to secrets used to pull the gateway image.
+	ImagePullSecrets []k8sv1.LocalObjectReference `json:"imagePullSecrets,omitempty"`
+	// Configuration of the gateway's PodDisruptionBudget. No PodDisruptionBudget is created if not set.
+	PodDisruptionBudget *GatewayPodDisruptionBudgetConfig `json:"podDisruptionBudget,omitempty"`
+	// How many seconds kube waits for a gateway pod to gracefully exit before forcibly terminating it.
+	TerminationGracePeriodSeconds *int64 `json:"terminationGracePeriodSeconds,omitempty"`
+	// Additional volumes for the gateway pods.
+	Volumes []k8sv1.Volume `json:"volumes,omitempty"`
+	// Additional volumeMounts for the gateway container.
+	VolumeMounts []k8sv1.VolumeMount `json:"volumeMounts,omitempty"`
+	// The priority class of the gateway pods.
+	PriorityClassName string `json:"priorityClassName,omitempty"`
+}
+
+// Configuration of the gateway's RBAC resources.
+type GatewayRBACConfig struct {
+	// Controls whether the Role and RoleBinding that allow the gateway to read TLS credentials
+	// are created. Not needed when the gateway is only used with the Kubernetes Gateway API.
+	Enabled *bool `json:"enabled,omitempty"`
+}
+
+// Configuration of the gateway's ServiceAccount.
+type GatewayServiceAccountConfig struct {
+	// Controls whether a ServiceAccount is created. Otherwise, the default ServiceAccount is used.
+	Create *bool `json:"create,omitempty"`
+	// Annotations to add to the ServiceAccount.
+	Annotations map[string]string `json:"annotations,omitempty"`
+	// The name of the ServiceAccount. If not set, the name of the IstioGateway is used.
+	Name string `json:"name,omitempty"`
+}
+
+// Configuration of the gateway's Service.
+type GatewayServiceConfig struct {
+	// Type of the Service. Set to "None" to disable the Service entirely.
+	// +kubebuilder:validation:Enum=ClusterIP;NodePort;LoadBalancer;None
+	Type string `json:"type,omitempty"`
+	// The ports exposed by the Service.
+	Ports []k8sv1.ServicePort `json:"ports,omitempty"`
+	// Annotations to add to the Service.
+	Annotations              map[string]string                  `json:"annotations,omitempty"`
+	LoadBalancerIP           string                             `json:"loadBalancerIP,omitempty"`
+	LoadBalancerSourceRanges []string                           `json:"loadBalancerSourceRanges,omitempty"`
+	ExternalTrafficPolicy    k8sv1.ServiceExternalTrafficPolicy `json:"externalTrafficPolicy,omitempty"`
+	ExternalIPs              []string                           `json:"externalIPs,omitempty"`
+	IPFamilyPolicy           k8sv1.IPFamilyPolicy               `json:"ipFamilyPolicy,omitempty"`
+	IPFamilies               []k8sv1.IPFamily                   `json:"ipFamilies,omitempty"`
+}
+
+// Configuration of the gateway's HorizontalPodAutoscaler.
+type GatewayAutoscalingConfig struct {
+	// Controls whether a HorizontalPodAutoscaler is created.
+	Enabled                        *bool  `json:"enabled,omitempty"`
+	MinReplicas                    *int32 `json:"minReplicas,omitempty"`
+	MaxReplicas                    *int32 `json:"maxReplicas,omitempty"`
+	TargetCPUUtilizationPercentage *int32 `json:"targetCPUUtilizationPercentage,omitempty"`
+}
+
+// Configuration of the gateway's PodDisruptionBudget.
+type GatewayPodDisruptionBudgetConfig struct {
+	// +kubebuilder:validation:XIntOrString
+	MinAvailable *intstr.IntOrString `json:"minAvailable,omitempty"`
+	// +kubebuilder:validation:XIntOrString
+	MaxUnavailable *intstr.IntOrString `json:"maxUnavailable,omitempty"`
+	// +kubebuilder:validation:Enum=IfHealthyBudget;AlwaysAllow
+	UnhealthyPodEvictionPolicy string `json:"unhealthyPodEvictionPolicy,omitempty"`
+}
+
 func (v *Values) ToHelmValues() helm.HelmValues {
 	var obj helm.HelmValues
 	data, err := json.Marshal(v)
@@ -650,6 +821,30 @@ func (v *Values) ToHelmValues() helm.HelmValues {
 	return obj
 }
 
+func (v *CNIValues) ToHelmValues() helm.HelmValues {
+	var obj helm.HelmValues
+	data, err := json.Marshal(v)
+	if err != nil {
+		panic(err)
+	}
+	if err = json.Unmarshal(data, &obj); err != nil {
+		panic(err)
+	}
+	return obj
+}
+
+func (v *GatewayValues) ToHelmValues() helm.HelmValues {
+	var obj helm.HelmValues
+	data, err := json.Marshal(v)
+	if err != nil {
+		panic(err)
+	}
+	if err = json.Unmarshal(data, &obj); err != nil {
+		panic(err)
+	}
+	return obj
+}
+
 func ValuesFromHelmValues(helmValues helm.HelmValues) (*Values, error) {
 	data, err := json.Marshal(helmValues)
 	if err != nil {
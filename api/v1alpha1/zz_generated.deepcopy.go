@@ -65,6 +65,21 @@ func (in *CA) DeepCopy() *CA {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CNIAmbientConfig) DeepCopyInto(out *CNIAmbientConfig) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CNIAmbientConfig.
+func (in *CNIAmbientConfig) DeepCopy() *CNIAmbientConfig {
+	if in == nil {
+		return nil
+	}
+	out := new(CNIAmbientConfig)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *CNIConfig) DeepCopyInto(out *CNIConfig) {
 	*out = *in
@@ -105,6 +120,11 @@ func (in *CNIConfig) DeepCopyInto(out *CNIConfig) {
 		*out = new(intstr.IntOrString)
 		**out = **in
 	}
+	if in.Ambient != nil {
+		in, out := &in.Ambient, &out.Ambient
+		*out = new(CNIAmbientConfig)
+		**out = **in
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CNIConfig.
@@ -137,6 +157,31 @@ func (in *CNIRepairConfig) DeepCopy() *CNIRepairConfig {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CNIValues) DeepCopyInto(out *CNIValues) {
+	*out = *in
+	if in.Cni != nil {
+		in, out := &in.Cni, &out.Cni
+		*out = new(CNIConfig)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Global != nil {
+		in, out := &in.Global, &out.Global
+		*out = new(GlobalConfig)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CNIValues.
+func (in *CNIValues) DeepCopy() *CNIValues {
+	if in == nil {
+		return nil
+	}
+	out := new(CNIValues)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *CertificateData) DeepCopyInto(out *CertificateData) {
 	*out = *in
@@ -182,6 +227,31 @@ func (in *ClientTLSSettings) DeepCopy() *ClientTLSSettings {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ComponentStatus) DeepCopyInto(out *ComponentStatus) {
+	*out = *in
+	if in.DesiredReplicas != nil {
+		in, out := &in.DesiredReplicas, &out.DesiredReplicas
+		*out = new(int32)
+		**out = **in
+	}
+	if in.ReadyReplicas != nil {
+		in, out := &in.ReadyReplicas, &out.ReadyReplicas
+		*out = new(int32)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ComponentStatus.
+func (in *ComponentStatus) DeepCopy() *ComponentStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(ComponentStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ConfigSource) DeepCopyInto(out *ConfigSource) {
 	*out = *in
@@ -277,6 +347,48 @@ func (in *DefaultProviders) DeepCopy() *DefaultProviders {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DriftIgnoreRule) DeepCopyInto(out *DriftIgnoreRule) {
+	*out = *in
+	if in.Paths != nil {
+		in, out := &in.Paths, &out.Paths
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DriftIgnoreRule.
+func (in *DriftIgnoreRule) DeepCopy() *DriftIgnoreRule {
+	if in == nil {
+		return nil
+	}
+	out := new(DriftIgnoreRule)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DriftPolicy) DeepCopyInto(out *DriftPolicy) {
+	*out = *in
+	if in.IgnoredFields != nil {
+		in, out := &in.IgnoredFields, &out.IgnoredFields
+		*out = make([]DriftIgnoreRule, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DriftPolicy.
+func (in *DriftPolicy) DeepCopy() *DriftPolicy {
+	if in == nil {
+		return nil
+	}
+	out := new(DriftPolicy)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ExtensionProvider) DeepCopyInto(out *ExtensionProvider) {
 	*out = *in
@@ -736,243 +848,916 @@ func (in *ExtensionProviderZipkinTracingProvider) DeepCopy() *ExtensionProviderZ
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *GlobalConfig) DeepCopyInto(out *GlobalConfig) {
+func (in *GatewayAutoscalingConfig) DeepCopyInto(out *GatewayAutoscalingConfig) {
 	*out = *in
-	if in.CertSigners != nil {
-		in, out := &in.CertSigners, &out.CertSigners
-		*out = make([]string, len(*in))
-		copy(*out, *in)
-	}
-	if in.DefaultConfigVisibilitySettings != nil {
-		in, out := &in.DefaultConfigVisibilitySettings, &out.DefaultConfigVisibilitySettings
-		*out = make([]string, len(*in))
-		copy(*out, *in)
-	}
-	if in.DefaultNodeSelector != nil {
-		in, out := &in.DefaultNodeSelector, &out.DefaultNodeSelector
-		*out = new(corev1.NodeSelector)
-		(*in).DeepCopyInto(*out)
-	}
-	if in.DefaultPodDisruptionBudget != nil {
-		in, out := &in.DefaultPodDisruptionBudget, &out.DefaultPodDisruptionBudget
-		*out = new(DefaultPodDisruptionBudgetConfig)
-		**out = **in
-	}
-	if in.DefaultResources != nil {
-		in, out := &in.DefaultResources, &out.DefaultResources
-		*out = new(corev1.ResourceRequirements)
-		(*in).DeepCopyInto(*out)
-	}
-	if in.ImagePullSecrets != nil {
-		in, out := &in.ImagePullSecrets, &out.ImagePullSecrets
-		*out = make([]string, len(*in))
-		copy(*out, *in)
-	}
-	if in.Logging != nil {
-		in, out := &in.Logging, &out.Logging
-		*out = new(GlobalLoggingConfig)
-		**out = **in
-	}
-	if in.MeshNetworks != nil {
-		in, out := &in.MeshNetworks, &out.MeshNetworks
-		*out = make(map[string]string, len(*in))
-		for key, val := range *in {
-			(*out)[key] = val
-		}
-	}
-	if in.MultiCluster != nil {
-		in, out := &in.MultiCluster, &out.MultiCluster
-		*out = new(MultiClusterConfig)
-		**out = **in
-	}
-	if in.PodDNSSearchNamespaces != nil {
-		in, out := &in.PodDNSSearchNamespaces, &out.PodDNSSearchNamespaces
-		*out = make([]string, len(*in))
-		copy(*out, *in)
-	}
-	if in.Proxy != nil {
-		in, out := &in.Proxy, &out.Proxy
-		*out = new(ProxyConfig)
-		(*in).DeepCopyInto(*out)
-	}
-	if in.ProxyInit != nil {
-		in, out := &in.ProxyInit, &out.ProxyInit
-		*out = new(ProxyInitConfig)
-		**out = **in
-	}
-	if in.Sds != nil {
-		in, out := &in.Sds, &out.Sds
-		*out = new(SDSConfig)
+	if in.Enabled != nil {
+		in, out := &in.Enabled, &out.Enabled
+		*out = new(bool)
 		**out = **in
 	}
-	if in.Tag != nil {
-		in, out := &in.Tag, &out.Tag
-		*out = new(intstr.IntOrString)
+	if in.MinReplicas != nil {
+		in, out := &in.MinReplicas, &out.MinReplicas
+		*out = new(int32)
 		**out = **in
 	}
-	if in.Tracer != nil {
-		in, out := &in.Tracer, &out.Tracer
-		*out = new(TracerConfig)
-		(*in).DeepCopyInto(*out)
-	}
-	if in.Istiod != nil {
-		in, out := &in.Istiod, &out.Istiod
-		*out = new(IstiodConfig)
+	if in.MaxReplicas != nil {
+		in, out := &in.MaxReplicas, &out.MaxReplicas
+		*out = new(int32)
 		**out = **in
 	}
-	if in.Sts != nil {
-		in, out := &in.Sts, &out.Sts
-		*out = new(STSConfig)
+	if in.TargetCPUUtilizationPercentage != nil {
+		in, out := &in.TargetCPUUtilizationPercentage, &out.TargetCPUUtilizationPercentage
+		*out = new(int32)
 		**out = **in
 	}
-	if in.IPFamilies != nil {
-		in, out := &in.IPFamilies, &out.IPFamilies
-		*out = make([]string, len(*in))
-		copy(*out, *in)
-	}
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GlobalConfig.
-func (in *GlobalConfig) DeepCopy() *GlobalConfig {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GatewayAutoscalingConfig.
+func (in *GatewayAutoscalingConfig) DeepCopy() *GatewayAutoscalingConfig {
 	if in == nil {
 		return nil
 	}
-	out := new(GlobalConfig)
+	out := new(GatewayAutoscalingConfig)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *GlobalLoggingConfig) DeepCopyInto(out *GlobalLoggingConfig) {
+func (in *GatewayPodDisruptionBudgetConfig) DeepCopyInto(out *GatewayPodDisruptionBudgetConfig) {
 	*out = *in
+	if in.MinAvailable != nil {
+		in, out := &in.MinAvailable, &out.MinAvailable
+		*out = new(intstr.IntOrString)
+		**out = **in
+	}
+	if in.MaxUnavailable != nil {
+		in, out := &in.MaxUnavailable, &out.MaxUnavailable
+		*out = new(intstr.IntOrString)
+		**out = **in
+	}
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GlobalLoggingConfig.
-func (in *GlobalLoggingConfig) DeepCopy() *GlobalLoggingConfig {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GatewayPodDisruptionBudgetConfig.
+func (in *GatewayPodDisruptionBudgetConfig) DeepCopy() *GatewayPodDisruptionBudgetConfig {
 	if in == nil {
 		return nil
 	}
-	out := new(GlobalLoggingConfig)
+	out := new(GatewayPodDisruptionBudgetConfig)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *HTTPRetry) DeepCopyInto(out *HTTPRetry) {
+func (in *GatewayRBACConfig) DeepCopyInto(out *GatewayRBACConfig) {
 	*out = *in
-	if in.PerTryTimeout != nil {
-		in, out := &in.PerTryTimeout, &out.PerTryTimeout
-		*out = new(v1.Duration)
+	if in.Enabled != nil {
+		in, out := &in.Enabled, &out.Enabled
+		*out = new(bool)
 		**out = **in
 	}
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HTTPRetry.
-func (in *HTTPRetry) DeepCopy() *HTTPRetry {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GatewayRBACConfig.
+func (in *GatewayRBACConfig) DeepCopy() *GatewayRBACConfig {
 	if in == nil {
 		return nil
 	}
-	out := new(HTTPRetry)
+	out := new(GatewayRBACConfig)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *Istio) DeepCopyInto(out *Istio) {
+func (in *GatewayServiceAccountConfig) DeepCopyInto(out *GatewayServiceAccountConfig) {
 	*out = *in
-	out.TypeMeta = in.TypeMeta
-	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
-	in.Spec.DeepCopyInto(&out.Spec)
-	in.Status.DeepCopyInto(&out.Status)
+	if in.Create != nil {
+		in, out := &in.Create, &out.Create
+		*out = new(bool)
+		**out = **in
+	}
+	if in.Annotations != nil {
+		in, out := &in.Annotations, &out.Annotations
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Istio.
-func (in *Istio) DeepCopy() *Istio {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GatewayServiceAccountConfig.
+func (in *GatewayServiceAccountConfig) DeepCopy() *GatewayServiceAccountConfig {
 	if in == nil {
 		return nil
 	}
-	out := new(Istio)
+	out := new(GatewayServiceAccountConfig)
 	in.DeepCopyInto(out)
 	return out
 }
 
-// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
-func (in *Istio) DeepCopyObject() runtime.Object {
-	if c := in.DeepCopy(); c != nil {
-		return c
-	}
-	return nil
-}
-
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *IstioCondition) DeepCopyInto(out *IstioCondition) {
+func (in *GatewayServiceConfig) DeepCopyInto(out *GatewayServiceConfig) {
 	*out = *in
-	in.LastTransitionTime.DeepCopyInto(&out.LastTransitionTime)
+	if in.Ports != nil {
+		in, out := &in.Ports, &out.Ports
+		*out = make([]corev1.ServicePort, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Annotations != nil {
+		in, out := &in.Annotations, &out.Annotations
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.LoadBalancerSourceRanges != nil {
+		in, out := &in.LoadBalancerSourceRanges, &out.LoadBalancerSourceRanges
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.ExternalIPs != nil {
+		in, out := &in.ExternalIPs, &out.ExternalIPs
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.IPFamilies != nil {
+		in, out := &in.IPFamilies, &out.IPFamilies
+		*out = make([]corev1.IPFamily, len(*in))
+		copy(*out, *in)
+	}
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new IstioCondition.
-func (in *IstioCondition) DeepCopy() *IstioCondition {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GatewayServiceConfig.
+func (in *GatewayServiceConfig) DeepCopy() *GatewayServiceConfig {
 	if in == nil {
 		return nil
 	}
-	out := new(IstioCondition)
+	out := new(GatewayServiceConfig)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *IstioList) DeepCopyInto(out *IstioList) {
+func (in *GatewayValues) DeepCopyInto(out *GatewayValues) {
+	*out = *in
+	if in.ReplicaCount != nil {
+		in, out := &in.ReplicaCount, &out.ReplicaCount
+		*out = new(int32)
+		**out = **in
+	}
+	if in.Rbac != nil {
+		in, out := &in.Rbac, &out.Rbac
+		*out = new(GatewayRBACConfig)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.ServiceAccount != nil {
+		in, out := &in.ServiceAccount, &out.ServiceAccount
+		*out = new(GatewayServiceAccountConfig)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.PodAnnotations != nil {
+		in, out := &in.PodAnnotations, &out.PodAnnotations
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.SecurityContext != nil {
+		in, out := &in.SecurityContext, &out.SecurityContext
+		*out = new(corev1.PodSecurityContext)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.ContainerSecurityContext != nil {
+		in, out := &in.ContainerSecurityContext, &out.ContainerSecurityContext
+		*out = new(corev1.SecurityContext)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Service != nil {
+		in, out := &in.Service, &out.Service
+		*out = new(GatewayServiceConfig)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Resources != nil {
+		in, out := &in.Resources, &out.Resources
+		*out = new(corev1.ResourceRequirements)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Autoscaling != nil {
+		in, out := &in.Autoscaling, &out.Autoscaling
+		*out = new(GatewayAutoscalingConfig)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Env != nil {
+		in, out := &in.Env, &out.Env
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.Labels != nil {
+		in, out := &in.Labels, &out.Labels
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.Annotations != nil {
+		in, out := &in.Annotations, &out.Annotations
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.NodeSelector != nil {
+		in, out := &in.NodeSelector, &out.NodeSelector
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.Tolerations != nil {
+		in, out := &in.Tolerations, &out.Tolerations
+		*out = make([]corev1.Toleration, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.TopologySpreadConstraints != nil {
+		in, out := &in.TopologySpreadConstraints, &out.TopologySpreadConstraints
+		*out = make([]corev1.TopologySpreadConstraint, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Affinity != nil {
+		in, out := &in.Affinity, &out.Affinity
+		*out = new(corev1.Affinity)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.ImagePullSecrets != nil {
+		in, out := &in.ImagePullSecrets, &out.ImagePullSecrets
+		*out = make([]corev1.LocalObjectReference, len(*in))
+		copy(*out, *in)
+	}
+	if in.PodDisruptionBudget != nil {
+		in, out := &in.PodDisruptionBudget, &out.PodDisruptionBudget
+		*out = new(GatewayPodDisruptionBudgetConfig)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.TerminationGracePeriodSeconds != nil {
+		in, out := &in.TerminationGracePeriodSeconds, &out.TerminationGracePeriodSeconds
+		*out = new(int64)
+		**out = **in
+	}
+	if in.Volumes != nil {
+		in, out := &in.Volumes, &out.Volumes
+		*out = make([]corev1.Volume, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.VolumeMounts != nil {
+		in, out := &in.VolumeMounts, &out.VolumeMounts
+		*out = make([]corev1.VolumeMount, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GatewayValues.
+func (in *GatewayValues) DeepCopy() *GatewayValues {
+	if in == nil {
+		return nil
+	}
+	out := new(GatewayValues)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GlobalConfig) DeepCopyInto(out *GlobalConfig) {
+	*out = *in
+	if in.CertSigners != nil {
+		in, out := &in.CertSigners, &out.CertSigners
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.DefaultConfigVisibilitySettings != nil {
+		in, out := &in.DefaultConfigVisibilitySettings, &out.DefaultConfigVisibilitySettings
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.DefaultNodeSelector != nil {
+		in, out := &in.DefaultNodeSelector, &out.DefaultNodeSelector
+		*out = new(corev1.NodeSelector)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.DefaultPodDisruptionBudget != nil {
+		in, out := &in.DefaultPodDisruptionBudget, &out.DefaultPodDisruptionBudget
+		*out = new(DefaultPodDisruptionBudgetConfig)
+		**out = **in
+	}
+	if in.DefaultResources != nil {
+		in, out := &in.DefaultResources, &out.DefaultResources
+		*out = new(corev1.ResourceRequirements)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.ImagePullSecrets != nil {
+		in, out := &in.ImagePullSecrets, &out.ImagePullSecrets
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Logging != nil {
+		in, out := &in.Logging, &out.Logging
+		*out = new(GlobalLoggingConfig)
+		**out = **in
+	}
+	if in.MeshNetworks != nil {
+		in, out := &in.MeshNetworks, &out.MeshNetworks
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.MultiCluster != nil {
+		in, out := &in.MultiCluster, &out.MultiCluster
+		*out = new(MultiClusterConfig)
+		**out = **in
+	}
+	if in.PodDNSSearchNamespaces != nil {
+		in, out := &in.PodDNSSearchNamespaces, &out.PodDNSSearchNamespaces
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Proxy != nil {
+		in, out := &in.Proxy, &out.Proxy
+		*out = new(ProxyConfig)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.ProxyInit != nil {
+		in, out := &in.ProxyInit, &out.ProxyInit
+		*out = new(ProxyInitConfig)
+		**out = **in
+	}
+	if in.Sds != nil {
+		in, out := &in.Sds, &out.Sds
+		*out = new(SDSConfig)
+		**out = **in
+	}
+	if in.Tag != nil {
+		in, out := &in.Tag, &out.Tag
+		*out = new(intstr.IntOrString)
+		**out = **in
+	}
+	if in.Tracer != nil {
+		in, out := &in.Tracer, &out.Tracer
+		*out = new(TracerConfig)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Istiod != nil {
+		in, out := &in.Istiod, &out.Istiod
+		*out = new(IstiodConfig)
+		**out = **in
+	}
+	if in.Sts != nil {
+		in, out := &in.Sts, &out.Sts
+		*out = new(STSConfig)
+		**out = **in
+	}
+	if in.IPFamilies != nil {
+		in, out := &in.IPFamilies, &out.IPFamilies
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GlobalConfig.
+func (in *GlobalConfig) DeepCopy() *GlobalConfig {
+	if in == nil {
+		return nil
+	}
+	out := new(GlobalConfig)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GlobalLoggingConfig) DeepCopyInto(out *GlobalLoggingConfig) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GlobalLoggingConfig.
+func (in *GlobalLoggingConfig) DeepCopy() *GlobalLoggingConfig {
+	if in == nil {
+		return nil
+	}
+	out := new(GlobalLoggingConfig)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HTTPRetry) DeepCopyInto(out *HTTPRetry) {
+	*out = *in
+	if in.PerTryTimeout != nil {
+		in, out := &in.PerTryTimeout, &out.PerTryTimeout
+		*out = new(v1.Duration)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HTTPRetry.
+func (in *HTTPRetry) DeepCopy() *HTTPRetry {
+	if in == nil {
+		return nil
+	}
+	out := new(HTTPRetry)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Istio) DeepCopyInto(out *Istio) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Istio.
+func (in *Istio) DeepCopy() *Istio {
+	if in == nil {
+		return nil
+	}
+	out := new(Istio)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *Istio) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *IstioCNI) DeepCopyInto(out *IstioCNI) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new IstioCNI.
+func (in *IstioCNI) DeepCopy() *IstioCNI {
+	if in == nil {
+		return nil
+	}
+	out := new(IstioCNI)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *IstioCNI) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *IstioCNICondition) DeepCopyInto(out *IstioCNICondition) {
+	*out = *in
+	in.LastTransitionTime.DeepCopyInto(&out.LastTransitionTime)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new IstioCNICondition.
+func (in *IstioCNICondition) DeepCopy() *IstioCNICondition {
+	if in == nil {
+		return nil
+	}
+	out := new(IstioCNICondition)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *IstioCNIList) DeepCopyInto(out *IstioCNIList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]IstioCNI, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new IstioCNIList.
+func (in *IstioCNIList) DeepCopy() *IstioCNIList {
+	if in == nil {
+		return nil
+	}
+	out := new(IstioCNIList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *IstioCNIList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *IstioCNISpec) DeepCopyInto(out *IstioCNISpec) {
+	*out = *in
+	if in.Values != nil {
+		in, out := &in.Values, &out.Values
+		*out = new(CNIValues)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new IstioCNISpec.
+func (in *IstioCNISpec) DeepCopy() *IstioCNISpec {
+	if in == nil {
+		return nil
+	}
+	out := new(IstioCNISpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *IstioCNIStatus) DeepCopyInto(out *IstioCNIStatus) {
+	*out = *in
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]IstioCNICondition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Warnings != nil {
+		in, out := &in.Warnings, &out.Warnings
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new IstioCNIStatus.
+func (in *IstioCNIStatus) DeepCopy() *IstioCNIStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(IstioCNIStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *IstioCondition) DeepCopyInto(out *IstioCondition) {
+	*out = *in
+	in.LastTransitionTime.DeepCopyInto(&out.LastTransitionTime)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new IstioCondition.
+func (in *IstioCondition) DeepCopy() *IstioCondition {
+	if in == nil {
+		return nil
+	}
+	out := new(IstioCondition)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *IstioGateway) DeepCopyInto(out *IstioGateway) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new IstioGateway.
+func (in *IstioGateway) DeepCopy() *IstioGateway {
+	if in == nil {
+		return nil
+	}
+	out := new(IstioGateway)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *IstioGateway) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *IstioGatewayCondition) DeepCopyInto(out *IstioGatewayCondition) {
+	*out = *in
+	in.LastTransitionTime.DeepCopyInto(&out.LastTransitionTime)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new IstioGatewayCondition.
+func (in *IstioGatewayCondition) DeepCopy() *IstioGatewayCondition {
+	if in == nil {
+		return nil
+	}
+	out := new(IstioGatewayCondition)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *IstioGatewayList) DeepCopyInto(out *IstioGatewayList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]IstioGateway, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new IstioGatewayList.
+func (in *IstioGatewayList) DeepCopy() *IstioGatewayList {
+	if in == nil {
+		return nil
+	}
+	out := new(IstioGatewayList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *IstioGatewayList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *IstioGatewaySpec) DeepCopyInto(out *IstioGatewaySpec) {
+	*out = *in
+	out.TargetRef = in.TargetRef
+	if in.Values != nil {
+		in, out := &in.Values, &out.Values
+		*out = new(GatewayValues)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new IstioGatewaySpec.
+func (in *IstioGatewaySpec) DeepCopy() *IstioGatewaySpec {
+	if in == nil {
+		return nil
+	}
+	out := new(IstioGatewaySpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *IstioGatewayStatus) DeepCopyInto(out *IstioGatewayStatus) {
+	*out = *in
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]IstioGatewayCondition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new IstioGatewayStatus.
+func (in *IstioGatewayStatus) DeepCopy() *IstioGatewayStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(IstioGatewayStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *IstioList) DeepCopyInto(out *IstioList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]Istio, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new IstioList.
+func (in *IstioList) DeepCopy() *IstioList {
+	if in == nil {
+		return nil
+	}
+	out := new(IstioList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *IstioList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *IstioRevision) DeepCopyInto(out *IstioRevision) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new IstioRevision.
+func (in *IstioRevision) DeepCopy() *IstioRevision {
+	if in == nil {
+		return nil
+	}
+	out := new(IstioRevision)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *IstioRevision) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *IstioRevisionCondition) DeepCopyInto(out *IstioRevisionCondition) {
+	*out = *in
+	in.LastTransitionTime.DeepCopyInto(&out.LastTransitionTime)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new IstioRevisionCondition.
+func (in *IstioRevisionCondition) DeepCopy() *IstioRevisionCondition {
+	if in == nil {
+		return nil
+	}
+	out := new(IstioRevisionCondition)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *IstioRevisionList) DeepCopyInto(out *IstioRevisionList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]IstioRevision, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new IstioRevisionList.
+func (in *IstioRevisionList) DeepCopy() *IstioRevisionList {
+	if in == nil {
+		return nil
+	}
+	out := new(IstioRevisionList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *IstioRevisionList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *IstioRevisionSpec) DeepCopyInto(out *IstioRevisionSpec) {
+	*out = *in
+	if in.Values != nil {
+		in, out := &in.Values, &out.Values
+		*out = new(Values)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.DriftPolicy != nil {
+		in, out := &in.DriftPolicy, &out.DriftPolicy
+		*out = new(DriftPolicy)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.RollbackPolicy != nil {
+		in, out := &in.RollbackPolicy, &out.RollbackPolicy
+		*out = new(RollbackPolicy)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new IstioRevisionSpec.
+func (in *IstioRevisionSpec) DeepCopy() *IstioRevisionSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(IstioRevisionSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *IstioRevisionStatus) DeepCopyInto(out *IstioRevisionStatus) {
 	*out = *in
-	out.TypeMeta = in.TypeMeta
-	in.ListMeta.DeepCopyInto(&out.ListMeta)
-	if in.Items != nil {
-		in, out := &in.Items, &out.Items
-		*out = make([]Istio, len(*in))
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]IstioRevisionCondition, len(*in))
 		for i := range *in {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
+	if in.Rollback != nil {
+		in, out := &in.Rollback, &out.Rollback
+		*out = new(RollbackStatus)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Components != nil {
+		in, out := &in.Components, &out.Components
+		*out = make([]ComponentStatus, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Workloads != nil {
+		in, out := &in.Workloads, &out.Workloads
+		*out = new(WorkloadInventory)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new IstioList.
-func (in *IstioList) DeepCopy() *IstioList {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new IstioRevisionStatus.
+func (in *IstioRevisionStatus) DeepCopy() *IstioRevisionStatus {
 	if in == nil {
 		return nil
 	}
-	out := new(IstioList)
+	out := new(IstioRevisionStatus)
 	in.DeepCopyInto(out)
 	return out
 }
 
-// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
-func (in *IstioList) DeepCopyObject() runtime.Object {
-	if c := in.DeepCopy(); c != nil {
-		return c
-	}
-	return nil
-}
-
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *IstioRevision) DeepCopyInto(out *IstioRevision) {
+func (in *IstioRevisionTag) DeepCopyInto(out *IstioRevisionTag) {
 	*out = *in
 	out.TypeMeta = in.TypeMeta
 	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
-	in.Spec.DeepCopyInto(&out.Spec)
+	out.Spec = in.Spec
 	in.Status.DeepCopyInto(&out.Status)
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new IstioRevision.
-func (in *IstioRevision) DeepCopy() *IstioRevision {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new IstioRevisionTag.
+func (in *IstioRevisionTag) DeepCopy() *IstioRevisionTag {
 	if in == nil {
 		return nil
 	}
-	out := new(IstioRevision)
+	out := new(IstioRevisionTag)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
-func (in *IstioRevision) DeepCopyObject() runtime.Object {
+func (in *IstioRevisionTag) DeepCopyObject() runtime.Object {
 	if c := in.DeepCopy(); c != nil {
 		return c
 	}
@@ -980,47 +1765,47 @@ func (in *IstioRevision) DeepCopyObject() runtime.Object {
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *IstioRevisionCondition) DeepCopyInto(out *IstioRevisionCondition) {
+func (in *IstioRevisionTagCondition) DeepCopyInto(out *IstioRevisionTagCondition) {
 	*out = *in
 	in.LastTransitionTime.DeepCopyInto(&out.LastTransitionTime)
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new IstioRevisionCondition.
-func (in *IstioRevisionCondition) DeepCopy() *IstioRevisionCondition {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new IstioRevisionTagCondition.
+func (in *IstioRevisionTagCondition) DeepCopy() *IstioRevisionTagCondition {
 	if in == nil {
 		return nil
 	}
-	out := new(IstioRevisionCondition)
+	out := new(IstioRevisionTagCondition)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *IstioRevisionList) DeepCopyInto(out *IstioRevisionList) {
+func (in *IstioRevisionTagList) DeepCopyInto(out *IstioRevisionTagList) {
 	*out = *in
 	out.TypeMeta = in.TypeMeta
 	in.ListMeta.DeepCopyInto(&out.ListMeta)
 	if in.Items != nil {
 		in, out := &in.Items, &out.Items
-		*out = make([]IstioRevision, len(*in))
+		*out = make([]IstioRevisionTag, len(*in))
 		for i := range *in {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new IstioRevisionList.
-func (in *IstioRevisionList) DeepCopy() *IstioRevisionList {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new IstioRevisionTagList.
+func (in *IstioRevisionTagList) DeepCopy() *IstioRevisionTagList {
 	if in == nil {
 		return nil
 	}
-	out := new(IstioRevisionList)
+	out := new(IstioRevisionTagList)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
-func (in *IstioRevisionList) DeepCopyObject() runtime.Object {
+func (in *IstioRevisionTagList) DeepCopyObject() runtime.Object {
 	if c := in.DeepCopy(); c != nil {
 		return c
 	}
@@ -1028,43 +1813,39 @@ func (in *IstioRevisionList) DeepCopyObject() runtime.Object {
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *IstioRevisionSpec) DeepCopyInto(out *IstioRevisionSpec) {
+func (in *IstioRevisionTagSpec) DeepCopyInto(out *IstioRevisionTagSpec) {
 	*out = *in
-	if in.Values != nil {
-		in, out := &in.Values, &out.Values
-		*out = new(Values)
-		(*in).DeepCopyInto(*out)
-	}
+	out.TargetRef = in.TargetRef
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new IstioRevisionSpec.
-func (in *IstioRevisionSpec) DeepCopy() *IstioRevisionSpec {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new IstioRevisionTagSpec.
+func (in *IstioRevisionTagSpec) DeepCopy() *IstioRevisionTagSpec {
 	if in == nil {
 		return nil
 	}
-	out := new(IstioRevisionSpec)
+	out := new(IstioRevisionTagSpec)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *IstioRevisionStatus) DeepCopyInto(out *IstioRevisionStatus) {
+func (in *IstioRevisionTagStatus) DeepCopyInto(out *IstioRevisionTagStatus) {
 	*out = *in
 	if in.Conditions != nil {
 		in, out := &in.Conditions, &out.Conditions
-		*out = make([]IstioRevisionCondition, len(*in))
+		*out = make([]IstioRevisionTagCondition, len(*in))
 		for i := range *in {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new IstioRevisionStatus.
-func (in *IstioRevisionStatus) DeepCopy() *IstioRevisionStatus {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new IstioRevisionTagStatus.
+func (in *IstioRevisionTagStatus) DeepCopy() *IstioRevisionTagStatus {
 	if in == nil {
 		return nil
 	}
-	out := new(IstioRevisionStatus)
+	out := new(IstioRevisionTagStatus)
 	in.DeepCopyInto(out)
 	return out
 }
@@ -1077,11 +1858,26 @@ func (in *IstioSpec) DeepCopyInto(out *IstioSpec) {
 		*out = new(IstioUpdateStrategy)
 		(*in).DeepCopyInto(*out)
 	}
+	if in.Profiles != nil {
+		in, out := &in.Profiles, &out.Profiles
+		*out = make([]ProfileName, len(*in))
+		copy(*out, *in)
+	}
 	if in.Values != nil {
 		in, out := &in.Values, &out.Values
 		*out = new(Values)
 		(*in).DeepCopyInto(*out)
 	}
+	if in.DriftPolicy != nil {
+		in, out := &in.DriftPolicy, &out.DriftPolicy
+		*out = new(DriftPolicy)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.RollbackPolicy != nil {
+		in, out := &in.RollbackPolicy, &out.RollbackPolicy
+		*out = new(RollbackPolicy)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new IstioSpec.
@@ -1105,6 +1901,23 @@ func (in *IstioStatus) DeepCopyInto(out *IstioStatus) {
 		}
 	}
 	out.Revisions = in.Revisions
+	if in.WorkloadMigration != nil {
+		in, out := &in.WorkloadMigration, &out.WorkloadMigration
+		*out = new(WorkloadMigrationStatus)
+		**out = **in
+	}
+	if in.Components != nil {
+		in, out := &in.Components, &out.Components
+		*out = make([]ComponentStatus, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Profiles != nil {
+		in, out := &in.Profiles, &out.Profiles
+		*out = new(ProfilesStatus)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new IstioStatus.
@@ -1125,6 +1938,11 @@ func (in *IstioUpdateStrategy) DeepCopyInto(out *IstioUpdateStrategy) {
 		*out = new(int64)
 		**out = **in
 	}
+	if in.UpdateWorkloadsBatchSize != nil {
+		in, out := &in.UpdateWorkloadsBatchSize, &out.UpdateWorkloadsBatchSize
+		*out = new(int32)
+		**out = **in
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new IstioUpdateStrategy.
@@ -1580,6 +2398,21 @@ func (in *PilotPolicyConfig) DeepCopy() *PilotPolicyConfig {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PodReference) DeepCopyInto(out *PodReference) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PodReference.
+func (in *PodReference) DeepCopy() *PodReference {
+	if in == nil {
+		return nil
+	}
+	out := new(PodReference)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *PortsConfig) DeepCopyInto(out *PortsConfig) {
 	*out = *in
@@ -1595,6 +2428,36 @@ func (in *PortsConfig) DeepCopy() *PortsConfig {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ProfilesStatus) DeepCopyInto(out *ProfilesStatus) {
+	*out = *in
+	if in.Applied != nil {
+		in, out := &in.Applied, &out.Applied
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.ValuesSources != nil {
+		in, out := &in.ValuesSources, &out.ValuesSources
+		*out = make([]ValuesSource, len(*in))
+		copy(*out, *in)
+	}
+	if in.Warnings != nil {
+		in, out := &in.Warnings, &out.Warnings
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ProfilesStatus.
+func (in *ProfilesStatus) DeepCopy() *ProfilesStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(ProfilesStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ProxyConfig) DeepCopyInto(out *ProxyConfig) {
 	*out = *in
@@ -1702,6 +2565,57 @@ func (in *RevisionSummary) DeepCopy() *RevisionSummary {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RollbackPolicy) DeepCopyInto(out *RollbackPolicy) {
+	*out = *in
+	if in.ProgressDeadlineSeconds != nil {
+		in, out := &in.ProgressDeadlineSeconds, &out.ProgressDeadlineSeconds
+		*out = new(int32)
+		**out = **in
+	}
+	if in.MaxHistory != nil {
+		in, out := &in.MaxHistory, &out.MaxHistory
+		*out = new(int32)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RollbackPolicy.
+func (in *RollbackPolicy) DeepCopy() *RollbackPolicy {
+	if in == nil {
+		return nil
+	}
+	out := new(RollbackPolicy)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RollbackStatus) DeepCopyInto(out *RollbackStatus) {
+	*out = *in
+	if in.LastGoodReleases != nil {
+		in, out := &in.LastGoodReleases, &out.LastGoodReleases
+		*out = make(map[string]int, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.PendingSince != nil {
+		in, out := &in.PendingSince, &out.PendingSince
+		*out = (*in).DeepCopy()
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RollbackStatus.
+func (in *RollbackStatus) DeepCopy() *RollbackStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(RollbackStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *SDSConfig) DeepCopyInto(out *SDSConfig) {
 	*out = *in
@@ -1854,6 +2768,21 @@ func (in *TLSConfig) DeepCopy() *TLSConfig {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TargetReference) DeepCopyInto(out *TargetReference) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TargetReference.
+func (in *TargetReference) DeepCopy() *TargetReference {
+	if in == nil {
+		return nil
+	}
+	out := new(TargetReference)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *TargetUtilizationConfig) DeepCopyInto(out *TargetUtilizationConfig) {
 	*out = *in
@@ -2072,6 +3001,11 @@ func (in *Values) DeepCopyInto(out *Values) {
 		*out = new(CNIConfig)
 		(*in).DeepCopyInto(*out)
 	}
+	if in.ZTunnel != nil {
+		in, out := &in.ZTunnel, &out.ZTunnel
+		*out = new(ZTunnelConfig)
+		(*in).DeepCopyInto(*out)
+	}
 	if in.MeshConfig != nil {
 		in, out := &in.MeshConfig, &out.MeshConfig
 		*out = new(MeshConfig)
@@ -2104,6 +3038,145 @@ func (in *Values) DeepCopy() *Values {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ValuesSource) DeepCopyInto(out *ValuesSource) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ValuesSource.
+func (in *ValuesSource) DeepCopy() *ValuesSource {
+	if in == nil {
+		return nil
+	}
+	out := new(ValuesSource)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *WorkloadInventory) DeepCopyInto(out *WorkloadInventory) {
+	*out = *in
+	if in.NamespaceNames != nil {
+		in, out := &in.NamespaceNames, &out.NamespaceNames
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.PodReferences != nil {
+		in, out := &in.PodReferences, &out.PodReferences
+		*out = make([]PodReference, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new WorkloadInventory.
+func (in *WorkloadInventory) DeepCopy() *WorkloadInventory {
+	if in == nil {
+		return nil
+	}
+	out := new(WorkloadInventory)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *WorkloadMigrationStatus) DeepCopyInto(out *WorkloadMigrationStatus) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new WorkloadMigrationStatus.
+func (in *WorkloadMigrationStatus) DeepCopy() *WorkloadMigrationStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(WorkloadMigrationStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ZTunnelConfig) DeepCopyInto(out *ZTunnelConfig) {
+	*out = *in
+	if in.Tag != nil {
+		in, out := &in.Tag, &out.Tag
+		*out = new(intstr.IntOrString)
+		**out = **in
+	}
+	if in.Labels != nil {
+		in, out := &in.Labels, &out.Labels
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.Annotations != nil {
+		in, out := &in.Annotations, &out.Annotations
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.VolumeMounts != nil {
+		in, out := &in.VolumeMounts, &out.VolumeMounts
+		*out = make([]corev1.VolumeMount, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Volumes != nil {
+		in, out := &in.Volumes, &out.Volumes
+		*out = make([]corev1.Volume, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.PodAnnotations != nil {
+		in, out := &in.PodAnnotations, &out.PodAnnotations
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.PodLabels != nil {
+		in, out := &in.PodLabels, &out.PodLabels
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.Resources != nil {
+		in, out := &in.Resources, &out.Resources
+		*out = new(corev1.ResourceRequirements)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.ImagePullSecrets != nil {
+		in, out := &in.ImagePullSecrets, &out.ImagePullSecrets
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Env != nil {
+		in, out := &in.Env, &out.Env
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.TerminationGracePeriodSeconds != nil {
+		in, out := &in.TerminationGracePeriodSeconds, &out.TerminationGracePeriodSeconds
+		*out = new(int64)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ZTunnelConfig.
+func (in *ZTunnelConfig) DeepCopy() *ZTunnelConfig {
+	if in == nil {
+		return nil
+	}
+	out := new(ZTunnelConfig)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ZeroVPNConfig) DeepCopyInto(out *ZeroVPNConfig) {
 	*out = *in
@@ -0,0 +1,208 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// iop-import converts IstioOperator resources, as used by istioctl and the upstream in-cluster Istio operator,
+// to the equivalent Istio resources. The IstioOperator resources are read from a file or from the cluster, and
+// the Istio resources are either written as YAML for review or applied to the cluster.
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	k8syaml "k8s.io/apimachinery/pkg/util/yaml"
+	_ "k8s.io/client-go/plugin/pkg/client/auth"
+	"maistra.io/istio-operator/api/v1alpha1"
+	"maistra.io/istio-operator/pkg/iopimport"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/yaml"
+)
+
+func main() {
+	var file string
+	var name string
+	var namespace string
+	var version string
+	var output string
+	var apply bool
+	flag.StringVar(&file, "f", "", "File containing the IstioOperator resources to import, or - to read them from stdin. "+
+		"If not set, the IstioOperator resources are read from the cluster")
+	flag.StringVar(&name, "name", "", "Name of the IstioOperator resource to import from the cluster. If not set, all of them are imported")
+	flag.StringVar(&namespace, "namespace", "", "Namespace of the IstioOperator resources to import from the cluster. If not set, all namespaces are searched")
+	flag.StringVar(&version, "version", "", "The Istio version to set in the Istio resources. If not set, it is derived from spec.tag of each IstioOperator")
+	flag.StringVar(&output, "o", "", "File to write the Istio resources to. If not set, they are written to stdout")
+	flag.BoolVar(&apply, "apply", false, "Whether to apply the Istio resources to the cluster instead of writing them as YAML")
+	flag.Parse()
+
+	if err := run(context.Background(), file, name, namespace, version, output, apply); err != nil {
+		fmt.Fprintln(os.Stderr, "error:", err)
+		os.Exit(1)
+	}
+}
+
+func run(ctx context.Context, file, name, namespace, version, output string, apply bool) error {
+	var cl client.Client
+	if file == "" || apply {
+		scheme := runtime.NewScheme()
+		utilruntime.Must(v1alpha1.AddToScheme(scheme))
+		cfg, err := ctrl.GetConfig()
+		if err != nil {
+			return err
+		}
+		if cl, err = client.New(cfg, client.Options{Scheme: scheme}); err != nil {
+			return err
+		}
+	}
+
+	var iops []unstructured.Unstructured
+	var err error
+	if file != "" {
+		iops, err = readIstioOperators(file)
+	} else {
+		iops, err = getIstioOperators(ctx, cl, name, namespace)
+	}
+	if err != nil {
+		return err
+	} else if len(iops) == 0 {
+		return errors.New("no IstioOperator resources found")
+	}
+
+	out := os.Stdout
+	if output != "" && !apply {
+		if out, err = os.Create(output); err != nil {
+			return err
+		}
+		defer out.Close()
+	}
+
+	// IstioOperators for the same revision, e.g. one created by the user and the installed-state one created by
+	// istioctl, would overwrite each other's Istio resource
+	importedFrom := map[string]client.ObjectKey{}
+	for i := range iops {
+		iop := &iops[i]
+		result, err := iopimport.Convert(iop, iopimport.Options{Version: version})
+		if err != nil {
+			return fmt.Errorf("failed to convert IstioOperator %s: %w", client.ObjectKeyFromObject(iop), err)
+		}
+		if other, found := importedFrom[result.Istio.Name]; found {
+			return fmt.Errorf("IstioOperators %s and %s both map to Istio %s; use -name to import only one of them",
+				other, client.ObjectKeyFromObject(iop), result.Istio.Name)
+		}
+		importedFrom[result.Istio.Name] = client.ObjectKeyFromObject(iop)
+		for _, field := range result.Unsupported {
+			fmt.Fprintf(os.Stderr, "warning: IstioOperator %s: %s has no equivalent in the Istio resource\n", client.ObjectKeyFromObject(iop), field)
+		}
+
+		if apply {
+			applied, err := iopimport.Apply(ctx, cl, result.Istio)
+			if err != nil {
+				return fmt.Errorf("failed to apply Istio %s: %w", result.Istio.Name, err)
+			}
+			if applied {
+				fmt.Fprintf(os.Stderr, "Istio %s imported from IstioOperator %s\n", result.Istio.Name, client.ObjectKeyFromObject(iop))
+			} else {
+				fmt.Fprintf(os.Stderr, "Istio %s is up to date with IstioOperator %s\n", result.Istio.Name, client.ObjectKeyFromObject(iop))
+			}
+		} else if err := writeIstio(out, result, i > 0); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func readIstioOperators(file string) ([]unstructured.Unstructured, error) {
+	var in io.Reader = os.Stdin
+	if file != "-" {
+		f, err := os.Open(file)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		in = f
+	}
+
+	var iops []unstructured.Unstructured
+	decoder := k8syaml.NewYAMLOrJSONDecoder(bufio.NewReader(in), 4096)
+	for {
+		obj := unstructured.Unstructured{}
+		if err := decoder.Decode(&obj.Object); errors.Is(err, io.EOF) {
+			return iops, nil
+		} else if err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %w", file, err)
+		}
+		// other resources are skipped, so that the output of e.g. "kubectl get all -o yaml" can be used as the input
+		if obj.Object != nil && obj.GroupVersionKind().GroupKind() == iopimport.IstioOperatorGVK.GroupKind() {
+			iops = append(iops, obj)
+		}
+	}
+}
+
+func getIstioOperators(ctx context.Context, cl client.Client, name, namespace string) ([]unstructured.Unstructured, error) {
+	if name != "" {
+		iop := unstructured.Unstructured{}
+		iop.SetGroupVersionKind(iopimport.IstioOperatorGVK)
+		if err := cl.Get(ctx, client.ObjectKey{Name: name, Namespace: namespace}, &iop); err != nil {
+			return nil, err
+		}
+		return []unstructured.Unstructured{iop}, nil
+	}
+
+	iopList := unstructured.UnstructuredList{}
+	iopList.SetGroupVersionKind(iopimport.IstioOperatorGVK.GroupVersion().WithKind(iopimport.IstioOperatorGVK.Kind + "List"))
+	if err := cl.List(ctx, &iopList, client.InNamespace(namespace)); err != nil {
+		return nil, err
+	}
+	return iopList.Items, nil
+}
+
+// writeIstio writes the given Istio resource as a YAML document, preceded by comments that list the fields of the
+// IstioOperator that have no equivalent, so that they can be reviewed before the resource is applied
+func writeIstio(out io.Writer, result *iopimport.Result, separator bool) error {
+	obj, err := runtime.DefaultUnstructuredConverter.ToUnstructured(result.Istio)
+	if err != nil {
+		return err
+	}
+	// the status and the creation timestamp are set by the API server
+	delete(obj, "status")
+	unstructured.RemoveNestedField(obj, "metadata", "creationTimestamp")
+	data, err := yaml.Marshal(obj)
+	if err != nil {
+		return err
+	}
+
+	buf := bytes.Buffer{}
+	if separator {
+		buf.WriteString("---\n")
+	}
+	fmt.Fprintf(&buf, "# Imported from IstioOperator %s\n", result.Istio.Annotations[iopimport.ImportedFromAnnotation])
+	if len(result.Unsupported) > 0 {
+		buf.WriteString("# The following fields of the IstioOperator have no equivalent in the Istio resource:\n")
+		for _, field := range result.Unsupported {
+			fmt.Fprintf(&buf, "#   - %s\n", field)
+		}
+	}
+	buf.Write(data)
+	_, err = out.Write(buf.Bytes())
+	return err
+}
@@ -15,6 +15,7 @@
 package main
 
 import (
+	"context"
 	"flag"
 	"fmt"
 	"net/http"
@@ -28,11 +29,17 @@ import (
 	_ "k8s.io/client-go/plugin/pkg/client/auth"
 	maistraiov1 "maistra.io/istio-operator/api/v1alpha1"
 	"maistra.io/istio-operator/controllers/istio"
+	"maistra.io/istio-operator/controllers/istiocni"
+	"maistra.io/istio-operator/controllers/istiogateway"
+	"maistra.io/istio-operator/controllers/istiooperator"
 	"maistra.io/istio-operator/controllers/istiorevision"
+	"maistra.io/istio-operator/controllers/istiorevisiontag"
+	"maistra.io/istio-operator/controllers/webhook"
 	"maistra.io/istio-operator/pkg/common"
 	"maistra.io/istio-operator/pkg/helm"
 	"maistra.io/istio-operator/pkg/version"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
 	"sigs.k8s.io/controller-runtime/pkg/healthz"
 	logf "sigs.k8s.io/controller-runtime/pkg/log"
 	"sigs.k8s.io/controller-runtime/pkg/log/zap"
@@ -61,13 +68,21 @@ func main() {
 	var configFile string
 	var resourceDirectory string
 	var defaultProfiles string
+	var defaultVersion string
 	var logAPIRequests bool
+	var enableWebhooks bool
+	var importIstioOperators bool
 	var printVersion bool
 	flag.StringVar(&metricsAddr, "metrics-bind-address", ":8080", "The address the metric endpoint binds to.")
 	flag.StringVar(&probeAddr, "health-probe-bind-address", ":8081", "The address the probe endpoint binds to.")
 	flag.StringVar(&configFile, "config-file", "/etc/istio-operator/config.properties", "Location of the config file, propagated by k8s downward APIs")
 	flag.StringVar(&resourceDirectory, "resource-directory", "/var/lib/istio-operator/resources", "Where to find resources (e.g. charts)")
 	flag.StringVar(&defaultProfiles, "default-profiles", "default", "One or more comma-separated profile names that are always applied to each Istio resource")
+	flag.StringVar(&defaultVersion, "default-version", "", "The Istio version that the defaulting webhook sets in Istio resources that don't specify one")
+	flag.BoolVar(&enableWebhooks, "enable-webhooks", false, "Whether to serve the defaulting and validating admission webhooks (requires serving certificates)")
+	flag.BoolVar(&importIstioOperators, "import-istiooperators", false,
+		"Whether to create an Istio resource for each IstioOperator resource, for migrating from the upstream in-cluster Istio operator "+
+			"(requires the IstioOperator CRD)")
 	flag.BoolVar(&logAPIRequests, "log-api-requests", false, "Whether to log each request sent to the Kubernetes API server")
 	flag.BoolVar(&printVersion, "version", printVersion, "Prints version information and exits")
 
@@ -118,6 +133,7 @@ func main() {
 	mgr, err := ctrl.NewManager(cfg, ctrl.Options{
 		Scheme:                  scheme,
 		Metrics:                 metricsserver.Options{BindAddress: metricsAddr},
+		Cache:                   cache.Options{ByObject: istiorevision.CacheByObject()},
 		HealthProbeBindAddress:  probeAddr,
 		LeaderElection:          true,
 		LeaderElectionID:        "8d20bb54.istio.io",
@@ -139,7 +155,13 @@ func main() {
 		os.Exit(1)
 	}
 
-	err = istio.NewIstioReconciler(mgr.GetClient(), mgr.GetScheme(), resourceDirectory, strings.Split(defaultProfiles, ",")).
+	if err := istiorevision.SetupFieldIndexes(context.Background(), mgr.GetFieldIndexer()); err != nil {
+		setupLog.Error(err, "unable to set up field indexes")
+		os.Exit(1)
+	}
+
+	err = istio.NewIstioReconciler(mgr.GetClient(), mgr.GetScheme(), resourceDirectory, strings.Split(defaultProfiles, ","), operatorNamespace,
+		mgr.GetEventRecorderFor("istio-controller")).
 		SetupWithManager(mgr)
 	if err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "Istio")
@@ -147,12 +169,51 @@ func main() {
 	}
 
 	helm.ResourceDirectory = resourceDirectory
-	err = istiorevision.NewIstioRevisionReconciler(mgr.GetClient(), mgr.GetScheme(), mgr.GetConfig(), operatorNamespace).
+	err = istiorevision.NewIstioRevisionReconciler(mgr.GetClient(), mgr.GetScheme(), mgr.GetConfig(),
+		mgr.GetEventRecorderFor("istiorevision-controller")).
 		SetupWithManager(mgr)
 	if err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "IstioRevision")
 		os.Exit(1)
 	}
+
+	err = istiocni.NewIstioCNIReconciler(mgr.GetClient(), mgr.GetScheme(), mgr.GetConfig(), resourceDirectory,
+		strings.Split(defaultProfiles, ","), operatorNamespace).
+		SetupWithManager(mgr)
+	if err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "IstioCNI")
+		os.Exit(1)
+	}
+
+	err = istiorevisiontag.NewIstioRevisionTagReconciler(mgr.GetClient(), mgr.GetScheme(), mgr.GetConfig()).
+		SetupWithManager(mgr)
+	if err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "IstioRevisionTag")
+		os.Exit(1)
+	}
+
+	err = istiogateway.NewIstioGatewayReconciler(mgr.GetClient(), mgr.GetScheme(), mgr.GetConfig()).
+		SetupWithManager(mgr)
+	if err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "IstioGateway")
+		os.Exit(1)
+	}
+
+	if importIstioOperators {
+		err = istiooperator.NewIstioOperatorReconciler(mgr.GetClient(), mgr.GetScheme(), mgr.GetEventRecorderFor("istiooperator-controller")).
+			SetupWithManager(mgr)
+		if err != nil {
+			setupLog.Error(err, "unable to create controller", "controller", "IstioOperator")
+			os.Exit(1)
+		}
+	}
+
+	if enableWebhooks {
+		if err := webhook.SetupWithManager(mgr, resourceDirectory, defaultVersion, operatorNamespace); err != nil {
+			setupLog.Error(err, "unable to create webhooks")
+			os.Exit(1)
+		}
+	}
 	// +kubebuilder:scaffold:builder
 
 	if err := mgr.AddHealthzCheck("healthz", healthz.Ping); err != nil {
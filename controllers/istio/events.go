@@ -0,0 +1,46 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package istio
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"maistra.io/istio-operator/api/v1alpha1"
+)
+
+// reasons of the Events recorded for Istio resources
+const (
+	EventReasonIstioRevisionCreated = "IstioRevisionCreated"
+	EventReasonIstioRevisionPruned  = "IstioRevisionPruned"
+	EventReasonPruningDeferred      = "PruningDeferred"
+	EventReasonReady                = "Ready"
+	EventReasonNotReady             = "NotReady"
+)
+
+// recordReadyTransition records an Event when the Istio's Ready condition changes between True and False.
+// The previous status must be read before the new status is written, since writing it updates the Istio.
+func (r *IstioReconciler) recordReadyTransition(istio *v1alpha1.Istio, previous metav1.ConditionStatus, status *v1alpha1.IstioStatus) {
+	readyCondition := status.GetCondition(v1alpha1.IstioConditionTypeReady)
+	if previous == readyCondition.Status {
+		return
+	}
+	switch readyCondition.Status {
+	case metav1.ConditionTrue:
+		r.EventRecorder.TransitionEvent(istio, corev1.EventTypeNormal, EventReasonReady, "all components of the active revision are ready")
+	case metav1.ConditionFalse:
+		r.EventRecorder.TransitionEventf(istio, corev1.EventTypeWarning, EventReasonNotReady,
+			"components of the active revision are not ready: %s", readyCondition.Message)
+	}
+}
@@ -17,53 +17,64 @@ package istio
 import (
 	"context"
 	"fmt"
-	"os"
+	"maps"
 	"path"
 	"reflect"
+	"slices"
 	"strings"
 	"time"
 
 	"github.com/go-logr/logr"
-	"gopkg.in/yaml.v3"
+	"github.com/prometheus/client_golang/prometheus"
+	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
-	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
 	"maistra.io/istio-operator/api/v1alpha1"
 	"maistra.io/istio-operator/pkg/common"
-	"maistra.io/istio-operator/pkg/helm"
+	"maistra.io/istio-operator/pkg/istiovalues"
+	"maistra.io/istio-operator/pkg/istioversion"
 	"maistra.io/istio-operator/pkg/kube"
+	"maistra.io/istio-operator/pkg/metrics"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
 	logf "sigs.k8s.io/controller-runtime/pkg/log"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 
 	"istio.io/istio/pkg/ptr"
-	"istio.io/istio/pkg/util/sets"
 )
 
 // IstioReconciler reconciles an Istio object
 type IstioReconciler struct {
 	ResourceDirectory string
 	DefaultProfiles   []string
+	Namespace         string
 	client.Client
-	Scheme *runtime.Scheme
+	Scheme        *runtime.Scheme
+	EventRecorder *kube.EventRecorder
 }
 
-func NewIstioReconciler(client client.Client, scheme *runtime.Scheme, resourceDir string, defaultProfiles []string) *IstioReconciler {
+func NewIstioReconciler(client client.Client, scheme *runtime.Scheme, resourceDir string, defaultProfiles []string, namespace string,
+	recorder record.EventRecorder,
+) *IstioReconciler {
 	return &IstioReconciler{
 		ResourceDirectory: resourceDir,
 		DefaultProfiles:   defaultProfiles,
+		Namespace:         namespace,
 		Client:            client,
 		Scheme:            scheme,
+		EventRecorder:     kube.NewEventRecorder(recorder),
 	}
 }
 
 // +kubebuilder:rbac:groups=operator.istio.io,resources=istios,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=operator.istio.io,resources=istios/status,verbs=get;update;patch
 // +kubebuilder:rbac:groups=operator.istio.io,resources=istios/finalizers,verbs=update
+// +kubebuilder:rbac:groups="",resources=events,verbs=create;patch
 
 // Reconcile is part of the main kubernetes reconciliation loop which aims to
 // move the current state of the cluster closer to the desired state.
@@ -77,6 +88,7 @@ func (r *IstioReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl
 	if err := r.Client.Get(ctx, req.NamespacedName, &istio); err != nil {
 		if errors.IsNotFound(err) {
 			log.V(2).Info("Istio not found. Skipping reconciliation")
+			metrics.ForgetIstio(req.Name)
 			return ctrl.Result{}, nil
 		}
 		log.Error(err, "failed to get Istio from cluster")
@@ -86,35 +98,105 @@ func (r *IstioReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl
 		return ctrl.Result{}, nil
 	}
 
-	log.Info("Reconciling")
-	result, err := r.doReconcile(ctx, istio)
+	var result ctrl.Result
+	var resolvedVersion string
+	var profiles *v1alpha1.ProfilesStatus
+	var migration *workloadMigration
+	var err error
+	if kube.IsPaused(&istio) {
+		log.Info("Reconciliation paused. Skipping")
+	} else {
+		log.Info("Reconciling")
+		resolvedVersion, err = r.resolveVersion(istio)
+		if err == nil {
+			// from here on, work with the concrete version that spec.version resolves to, so that the
+			// revisions are always created with (and named after) an exact version
+			istio.Spec.Version = resolvedVersion
+			result, profiles, migration, err = r.doReconcile(ctx, istio)
+		}
+	}
 
 	log.Info("Reconciliation done. Updating status.")
-	err = r.updateStatus(ctx, &istio, err)
+	err = r.updateStatus(ctx, &istio, resolvedVersion, profiles, migration, err)
 
+	if _, ok := err.(*upgradePolicyError); ok {
+		// retrying won't help; the Istio is reconciled again when its spec changes
+		return ctrl.Result{}, nil
+	}
 	return result, err
 }
 
 // doReconcile is the function that actually reconciles the Istio object. Any error reported by this
-// function should get reported in the status of the Istio object by the caller.
-func (r *IstioReconciler) doReconcile(ctx context.Context, istio v1alpha1.Istio) (result ctrl.Result, err error) {
+// function should get reported in the status of the Istio object by the caller, along with the returned
+// profiles, which are set as soon as the values have been computed, and the workload migration.
+func (r *IstioReconciler) doReconcile(ctx context.Context, istio v1alpha1.Istio) (result ctrl.Result, profiles *v1alpha1.ProfilesStatus,
+	migration *workloadMigration, err error,
+) {
 	if istio.Spec.Version == "" {
-		return ctrl.Result{}, fmt.Errorf("no spec.version set")
+		return ctrl.Result{}, nil, nil, fmt.Errorf("no spec.version set")
 	}
 	if istio.Spec.Namespace == "" {
-		return ctrl.Result{}, fmt.Errorf("no spec.namespace set")
+		return ctrl.Result{}, nil, nil, fmt.Errorf("no spec.namespace set")
+	}
+
+	// user-defined profiles are read from the ConfigMaps in the operator namespace
+	profileConfigMaps, err := istiovalues.ListProfileConfigMaps(ctx, r.Client, r.Namespace, istio.Spec.Version)
+	if err != nil {
+		return ctrl.Result{}, nil, nil, err
 	}
 
 	var values *v1alpha1.Values
-	if values, err = computeIstioRevisionValues(istio, r.DefaultProfiles, r.ResourceDirectory); err != nil {
-		return ctrl.Result{}, err
+	if values, profiles, err = computeIstioRevisionValues(istio, r.DefaultProfiles, r.ResourceDirectory, profileConfigMaps); err != nil {
+		return ctrl.Result{}, nil, nil, err
+	}
+
+	if err = r.checkUpgradePolicy(ctx, &istio, common.Config.UpgradePolicy); err != nil {
+		return ctrl.Result{}, profiles, nil, err
 	}
 
 	if err = r.reconcileActiveRevision(ctx, &istio, values); err != nil {
-		return ctrl.Result{}, err
+		return ctrl.Result{}, profiles, nil, err
 	}
 
-	return r.pruneInactiveRevisions(ctx, &istio)
+	if kube.IsDryRun(&istio) {
+		// workloads must not be moved to a revision whose components aren't installed, and the
+		// inactive revisions must be kept, since they might still be the ones actually serving them
+		return ctrl.Result{}, profiles, nil, nil
+	}
+
+	migrationResult, migration, err := r.migrateWorkloads(ctx, &istio)
+	if err != nil {
+		return ctrl.Result{}, profiles, nil, err
+	}
+
+	pruneResult, err := r.pruneInactiveRevisions(ctx, &istio)
+	if err != nil {
+		return ctrl.Result{}, profiles, nil, err
+	}
+	return lowestRequeueAfter(migrationResult, pruneResult), profiles, migration, nil
+}
+
+// resolveVersion returns the concrete version that the version alias in spec.version refers to
+// (e.g. "v1.20" resolves to the most recent v1.20.x version in the resource directory). Since the
+// alias is re-resolved on every reconciliation, the control plane is moved to a newer patch
+// version as soon as an operator upgrade makes it available.
+func (r *IstioReconciler) resolveVersion(istio v1alpha1.Istio) (string, error) {
+	if istio.Spec.Version == "" {
+		return "", fmt.Errorf("no spec.version set")
+	}
+	return istioversion.Resolve(r.ResourceDirectory, istio.Spec.Version)
+}
+
+// lowestRequeueAfter combines the given results so that the object is requeued at the earliest requested time
+func lowestRequeueAfter(results ...ctrl.Result) ctrl.Result {
+	var result ctrl.Result
+	for _, r := range results {
+		if r.RequeueAfter > 0 && (result.RequeueAfter == 0 || r.RequeueAfter < result.RequeueAfter) {
+			result.RequeueAfter = r.RequeueAfter
+		}
+		result.Requeue = result.Requeue || r.Requeue
+	}
+	return result
 }
 
 func (r *IstioReconciler) reconcileActiveRevision(ctx context.Context, istio *v1alpha1.Istio, values *v1alpha1.Values) error {
@@ -128,6 +210,10 @@ func (r *IstioReconciler) reconcileActiveRevision(ctx context.Context, istio *v1
 		// update
 		rev.Spec.Version = istio.Spec.Version
 		rev.Spec.Values = values
+		rev.Spec.DriftPolicy = istio.Spec.DriftPolicy
+		rev.Spec.RollbackPolicy = istio.Spec.RollbackPolicy
+		propagateDryRun(istio, &rev)
+		propagateAdoptedHelmRelease(istio, &rev)
 		log.Info("Updating IstioRevision")
 		return r.Client.Update(ctx, &rev)
 	} else if errors.IsNotFound(err) {
@@ -147,17 +233,52 @@ func (r *IstioReconciler) reconcileActiveRevision(ctx context.Context, istio *v1
 				},
 			},
 			Spec: v1alpha1.IstioRevisionSpec{
-				Version:   istio.Spec.Version,
-				Namespace: istio.Spec.Namespace,
-				Values:    values,
+				Version:        istio.Spec.Version,
+				Namespace:      istio.Spec.Namespace,
+				Values:         values,
+				DriftPolicy:    istio.Spec.DriftPolicy,
+				RollbackPolicy: istio.Spec.RollbackPolicy,
 			},
 		}
+		propagateDryRun(istio, &rev)
+		propagateAdoptedHelmRelease(istio, &rev)
 		log.Info("Creating IstioRevision")
-		return r.Client.Create(ctx, &rev)
+		if err := r.Client.Create(ctx, &rev); err != nil {
+			return err
+		}
+		r.EventRecorder.TransitionEventf(istio, corev1.EventTypeNormal, EventReasonIstioRevisionCreated,
+			"created IstioRevision %s for version %s", rev.Name, rev.Spec.Version)
+		return nil
 	}
 	return err
 }
 
+// propagateDryRun copies the dry-run annotation of the Istio to its active revision, so that the
+// revision's components are only rendered while the dry-run mode of the Istio is enabled
+func propagateDryRun(istio *v1alpha1.Istio, rev *v1alpha1.IstioRevision) {
+	if kube.IsDryRun(istio) {
+		if rev.Annotations == nil {
+			rev.Annotations = map[string]string{}
+		}
+		rev.Annotations[common.DryRunKey] = "true"
+	} else {
+		delete(rev.Annotations, common.DryRunKey)
+	}
+}
+
+// propagateAdoptedHelmRelease copies the annotation that names an existing istiod Helm release to adopt from the
+// Istio to its active revision, which takes over the release when it installs its components
+func propagateAdoptedHelmRelease(istio *v1alpha1.Istio, rev *v1alpha1.IstioRevision) {
+	if releaseName := istio.Annotations[common.AdoptHelmReleaseKey]; releaseName != "" {
+		if rev.Annotations == nil {
+			rev.Annotations = map[string]string{}
+		}
+		rev.Annotations[common.AdoptHelmReleaseKey] = releaseName
+	} else {
+		delete(rev.Annotations, common.AdoptHelmReleaseKey)
+	}
+}
+
 func (r *IstioReconciler) pruneInactiveRevisions(ctx context.Context, istio *v1alpha1.Istio) (ctrl.Result, error) {
 	log := logf.FromContext(ctx)
 	revisions, err := r.getRevisions(ctx, istio)
@@ -168,6 +289,9 @@ func (r *IstioReconciler) pruneInactiveRevisions(ctx context.Context, istio *v1a
 	// the following code does two things:
 	// - prunes revisions whose grace period has expired
 	// - finds the time when the next revision is to be pruned
+	// the deadlines of the revisions that are no longer pending pruning must not be reported
+	metrics.IstioRevisionPruneDeadline.DeletePartialMatch(prometheus.Labels{"istio": istio.Name})
+
 	var nextPruneTimestamp *time.Time
 	for _, rev := range revisions {
 		if isActiveRevision(istio, &rev) {
@@ -178,6 +302,8 @@ func (r *IstioReconciler) pruneInactiveRevisions(ctx context.Context, istio *v1a
 		inUse := inUseCondition.Status == metav1.ConditionTrue
 		if inUse {
 			log.V(2).Info("IstioRevision is in use", "IstioRevision", rev.Name)
+			r.EventRecorder.Eventf(istio, corev1.EventTypeNormal, EventReasonPruningDeferred,
+				"IstioRevision %s is no longer active, but is still in use; it will be pruned once no workloads reference it", rev.Name)
 			continue
 		}
 
@@ -189,8 +315,11 @@ func (r *IstioReconciler) pruneInactiveRevisions(ctx context.Context, istio *v1a
 			if err != nil {
 				return ctrl.Result{}, err
 			}
+			r.EventRecorder.TransitionEventf(istio, corev1.EventTypeNormal, EventReasonIstioRevisionPruned,
+				"deleted IstioRevision %s, which was not in use for %s", rev.Name, getPruningGracePeriod(istio))
 		} else {
 			log.V(2).Info("IstioRevision is not in use, but hasn't yet expired", "IstioRevision", rev.Name, "InUseLastTransitionTime", inUseCondition.LastTransitionTime)
+			metrics.IstioRevisionPruneDeadline.WithLabelValues(istio.Name, rev.Name).Set(float64(pruneTimestamp.Unix()))
 			if nextPruneTimestamp == nil || nextPruneTimestamp.After(pruneTimestamp) {
 				nextPruneTimestamp = &pruneTimestamp
 			}
@@ -264,12 +393,7 @@ func getActiveRevisionKey(istio *v1alpha1.Istio) types.NamespacedName {
 }
 
 func getActiveRevisionName(istio *v1alpha1.Istio) string {
-	var strategy v1alpha1.UpdateStrategyType
-	if istio.Spec.UpdateStrategy != nil {
-		strategy = istio.Spec.UpdateStrategy.Type
-	}
-
-	switch strategy {
+	switch getUpdateStrategyType(istio) {
 	default:
 		fallthrough
 	case v1alpha1.UpdateStrategyTypeInPlace:
@@ -279,7 +403,17 @@ func getActiveRevisionName(istio *v1alpha1.Istio) string {
 	}
 }
 
-func computeIstioRevisionValues(istio v1alpha1.Istio, defaultProfiles []string, resourceDir string) (*v1alpha1.Values, error) {
+func getUpdateStrategyType(istio *v1alpha1.Istio) v1alpha1.UpdateStrategyType {
+	if istio.Spec.UpdateStrategy == nil || istio.Spec.UpdateStrategy.Type == "" {
+		return v1alpha1.UpdateStrategyTypeInPlace
+	}
+	return istio.Spec.UpdateStrategy.Type
+}
+
+// computeIstioRevisionValues returns the values for the active revision, along with the profiles that were applied
+// to compute them
+func computeIstioRevisionValues(istio v1alpha1.Istio, defaultProfiles []string, resourceDir string, profileConfigMaps []corev1.ConfigMap,
+) (*v1alpha1.Values, *v1alpha1.ProfilesStatus, error) {
 	// get userValues from Istio.spec.values
 	userValues := istio.Spec.Values
 
@@ -287,25 +421,52 @@ func computeIstioRevisionValues(istio v1alpha1.Istio, defaultProfiles []string,
 	userValues = applyImageDigests(&istio, userValues, common.Config)
 
 	// apply userValues on top of defaultValues from profiles
-	defaultValues, err := getValuesFromProfiles(getProfilesDir(resourceDir, istio), getProfiles(istio, defaultProfiles))
+	profiles, err := istiovalues.MergeProfiles(getProfilesDir(resourceDir, istio), getProfiles(istio, defaultProfiles), profileConfigMaps)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
-	mergedHelmValues := mergeOverwrite(defaultValues, userValues.ToHelmValues())
+	mergedHelmValues := istiovalues.MergeOverwrite(profiles.Values, userValues.ToHelmValues())
 	values, err := v1alpha1.ValuesFromHelmValues(mergedHelmValues)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
 	// override values that are not configurable by the user
-	return applyOverrides(&istio, values)
+	values, err = applyOverrides(&istio, values)
+	if err != nil {
+		return nil, nil, err
+	}
+	return values, getProfilesStatus(istio, profiles), nil
 }
 
+// getProfiles returns the profiles to apply, in order: the default profiles, followed by spec.profile and spec.profiles
 func getProfiles(istio v1alpha1.Istio, defaultProfiles []string) []string {
-	if istio.Spec.Profile == "" {
-		return defaultProfiles
+	profiles := slices.Clone(defaultProfiles)
+	if istio.Spec.Profile != "" {
+		profiles = append(profiles, istio.Spec.Profile)
+	}
+	for _, profile := range istio.Spec.Profiles {
+		profiles = append(profiles, string(profile))
 	}
-	return append(defaultProfiles, istio.Spec.Profile)
+	return profiles
+}
+
+// getProfilesStatus reports the applied profiles and the source of each top-level key of the values. The values
+// the user sets in spec.values overwrite those of the profiles, so the keys set there are attributed to spec.values.
+func getProfilesStatus(istio v1alpha1.Istio, profiles *istiovalues.MergedProfiles) *v1alpha1.ProfilesStatus {
+	sources := maps.Clone(profiles.Sources)
+	for key := range istio.Spec.Values.ToHelmValues() {
+		sources[key] = v1alpha1.ValuesSourceSpecValues
+	}
+
+	status := &v1alpha1.ProfilesStatus{Applied: profiles.Applied, Warnings: profiles.Warnings}
+	for key, source := range sources {
+		status.ValuesSources = append(status.ValuesSources, v1alpha1.ValuesSource{Key: key, Source: source})
+	}
+	slices.SortFunc(status.ValuesSources, func(a, b v1alpha1.ValuesSource) int {
+		return strings.Compare(a.Key, b.Key)
+	})
+	return status
 }
 
 func getProfilesDir(resourceDir string, istio v1alpha1.Istio) string {
@@ -375,10 +536,12 @@ func applyImageDigests(istio *v1alpha1.Istio, values *v1alpha1.Values, config co
 		values.Global.ProxyInit.Image = imageDigests.ProxyImage
 	}
 
-	// TODO: add this once the API supports ambient
-	// if !hasUserDefinedImage("ztunnel", values) {
-	// 	values.ZTunnel.Image = imageDigests.ZTunnelImage
-	// }
+	if values.ZTunnel == nil {
+		values.ZTunnel = &v1alpha1.ZTunnelConfig{}
+	}
+	if values.ZTunnel.Image == "" && values.ZTunnel.Hub == "" && values.ZTunnel.Tag == nil {
+		values.ZTunnel.Image = imageDigests.ZTunnelImage
+	}
 	return values
 }
 
@@ -396,30 +559,79 @@ func (r *IstioReconciler) SetupWithManager(mgr ctrl.Manager) error {
 		}).
 		For(&v1alpha1.Istio{}).
 		Owns(&v1alpha1.IstioRevision{}).
+
+		// the values must be recomputed when a user-defined profile changes
+		Watches(&corev1.ConfigMap{}, handler.EnqueueRequestsFromMapFunc(r.mapProfileConfigMapToReconcileRequests)).
 		Complete(r)
 }
 
-func (r *IstioReconciler) updateStatus(ctx context.Context, istio *v1alpha1.Istio, reconciliationErr error) error {
+// mapProfileConfigMapToReconcileRequests returns the Istios that use the user-defined profile in the given ConfigMap
+func (r *IstioReconciler) mapProfileConfigMapToReconcileRequests(ctx context.Context, obj client.Object) []reconcile.Request {
+	profile, isProfile := obj.GetLabels()[istiovalues.ProfileLabel]
+	if !isProfile || obj.GetNamespace() != r.Namespace {
+		return nil
+	}
+
+	log := logf.FromContext(ctx)
+	istioList := v1alpha1.IstioList{}
+	if err := r.Client.List(ctx, &istioList); err != nil {
+		log.Error(err, "Could not list Istios")
+		return nil
+	}
+
+	var requests []reconcile.Request
+	for _, istio := range istioList.Items {
+		if slices.Contains(getProfiles(istio, r.DefaultProfiles), profile) {
+			requests = append(requests, reconcile.Request{NamespacedName: types.NamespacedName{Name: istio.Name}})
+		}
+	}
+	return requests
+}
+
+func (r *IstioReconciler) updateStatus(ctx context.Context, istio *v1alpha1.Istio, resolvedVersion string, profiles *v1alpha1.ProfilesStatus,
+	migration *workloadMigration, reconciliationErr error,
+) error {
 	status := istio.Status.DeepCopy()
 	status.ObservedGeneration = istio.Generation
 
+	// when the upgrade is blocked by the upgrade policy or the reconciliation is paused, the previously
+	// installed revision remains active
+	_, upgradeBlocked := reconciliationErr.(*upgradePolicyError)
+	paused := kube.IsPaused(istio)
+	if !upgradeBlocked && !paused {
+		if resolvedVersion != "" {
+			status.ResolvedVersion = resolvedVersion
+		}
+		if profiles != nil {
+			status.Profiles = profiles
+		}
+		status.ActiveRevisionName = getActiveRevisionName(istio)
+	} else if status.ActiveRevisionName == "" {
+		status.ActiveRevisionName = getActiveRevisionName(istio)
+	}
+
 	// set Reconciled and Ready conditions
 	if reconciliationErr != nil {
+		reason := v1alpha1.IstioConditionReasonReconcileError
+		if upgradeBlocked {
+			reason = v1alpha1.IstioConditionReasonUpgradePolicyViolation
+		}
 		status.SetCondition(v1alpha1.IstioCondition{
 			Type:    v1alpha1.IstioConditionTypeReconciled,
 			Status:  metav1.ConditionFalse,
-			Reason:  v1alpha1.IstioConditionReasonReconcileError,
+			Reason:  reason,
 			Message: reconciliationErr.Error(),
 		})
 		status.SetCondition(v1alpha1.IstioCondition{
 			Type:    v1alpha1.IstioConditionTypeReady,
 			Status:  metav1.ConditionUnknown,
-			Reason:  v1alpha1.IstioConditionReasonReconcileError,
+			Reason:  reason,
 			Message: "cannot determine readiness due to reconciliation error",
 		})
-		status.State = v1alpha1.IstioConditionReasonReconcileError
+		status.State = reason
 	} else {
-		rev, err := r.getActiveRevision(ctx, istio)
+		rev := v1alpha1.IstioRevision{}
+		err := r.Client.Get(ctx, types.NamespacedName{Name: status.ActiveRevisionName}, &rev)
 		if errors.IsNotFound(err) {
 			revisionNotFound := func(conditionType v1alpha1.IstioConditionType) v1alpha1.IstioCondition {
 				return v1alpha1.IstioCondition{
@@ -433,13 +645,28 @@ func (r *IstioReconciler) updateStatus(ctx context.Context, istio *v1alpha1.Isti
 			status.SetCondition(revisionNotFound(v1alpha1.IstioConditionTypeReconciled))
 			status.SetCondition(revisionNotFound(v1alpha1.IstioConditionTypeReady))
 			status.State = v1alpha1.IstioConditionReasonIstioRevisionNotFound
+			status.Components = nil
 		} else if err == nil {
 			status.SetCondition(convertCondition(rev.Status.GetCondition(v1alpha1.IstioRevisionConditionTypeReconciled)))
 			status.SetCondition(convertCondition(rev.Status.GetCondition(v1alpha1.IstioRevisionConditionTypeReady)))
 			status.State = convertConditionReason(rev.Status.State)
+			status.Components = rev.Status.Components
+			if drifted := rev.Status.GetCondition(v1alpha1.IstioRevisionConditionTypeDrifted); drifted.Status != metav1.ConditionUnknown {
+				status.SetCondition(convertCondition(drifted))
+			}
 		} else {
 			return err
 		}
+
+		if paused {
+			status.SetCondition(v1alpha1.IstioCondition{
+				Type:    v1alpha1.IstioConditionTypeReconciled,
+				Status:  metav1.ConditionFalse,
+				Reason:  v1alpha1.IstioConditionReasonPaused,
+				Message: fmt.Sprintf("reconciliation is paused by the %s annotation", common.PausedKey),
+			})
+			status.State = v1alpha1.IstioConditionReasonPaused
+		}
 	}
 
 	// count the ready, in-use, and total revisions
@@ -455,18 +682,30 @@ func (r *IstioReconciler) updateStatus(ctx context.Context, istio *v1alpha1.Isti
 				status.Revisions.InUse++
 			}
 		}
+		metrics.SetIstioRevisions(istio.Name, status.Revisions.Total, status.Revisions.Ready, status.Revisions.InUse)
 	} else {
 		return err
 	}
 
+	// the migration is only known when the reconciliation got to migrating the workloads; otherwise, the
+	// previously reported migration status is kept
+	if !upgradeBlocked && !paused && reconciliationErr == nil {
+		status.WorkloadMigration = nil
+		if migration != nil {
+			status.WorkloadMigration = migration.status()
+		}
+	}
+
 	if reflect.DeepEqual(istio.Status, *status) {
 		return nil
 	}
 
+	previousReady := istio.Status.GetCondition(v1alpha1.IstioConditionTypeReady).Status
 	statusErr := r.Client.Status().Patch(ctx, istio, kube.NewStatusPatch(*status))
 	if statusErr != nil {
 		return statusErr
 	}
+	r.recordReadyTransition(istio, previousReady, status)
 	return reconciliationErr
 }
 
@@ -485,6 +724,8 @@ func convertConditionType(condition v1alpha1.IstioRevisionCondition) v1alpha1.Is
 		return v1alpha1.IstioConditionTypeReconciled
 	case v1alpha1.IstioRevisionConditionTypeReady:
 		return v1alpha1.IstioConditionTypeReady
+	case v1alpha1.IstioRevisionConditionTypeDrifted:
+		return v1alpha1.IstioConditionTypeDrifted
 	default:
 		panic(fmt.Sprintf("can't convert IstioRevisionConditionType: %s", condition.Type))
 	}
@@ -498,92 +739,30 @@ func convertConditionReason(reason v1alpha1.IstioRevisionConditionReason) v1alph
 		return v1alpha1.IstioConditionReasonIstiodNotReady
 	case v1alpha1.IstioRevisionConditionReasonCNINotReady:
 		return v1alpha1.IstioConditionReasonCNINotReady
+	case v1alpha1.IstioRevisionConditionReasonZTunnelNotReady:
+		return v1alpha1.IstioConditionReasonZTunnelNotReady
+	case v1alpha1.IstioRevisionConditionReasonZTunnelHandoverBlocked:
+		return v1alpha1.IstioConditionReasonZTunnelHandoverBlocked
 	case v1alpha1.IstioRevisionConditionReasonHealthy:
 		return v1alpha1.IstioConditionReasonHealthy
 	case v1alpha1.IstioRevisionConditionReasonReconcileError:
 		return v1alpha1.IstioConditionReasonReconcileError
+	case v1alpha1.IstioRevisionConditionReasonPaused:
+		return v1alpha1.IstioConditionReasonPaused
+	case v1alpha1.IstioRevisionConditionReasonDryRun:
+		return v1alpha1.IstioConditionReasonDryRun
+	case v1alpha1.IstioRevisionConditionReasonRolledBack:
+		return v1alpha1.IstioConditionReasonRolledBack
+	case v1alpha1.IstioRevisionConditionReasonDriftDetected:
+		return v1alpha1.IstioConditionReasonDriftDetected
+	case v1alpha1.IstioRevisionConditionReasonDriftReverted:
+		return v1alpha1.IstioConditionReasonDriftReverted
+	case v1alpha1.IstioRevisionConditionReasonWebhookNotReady, v1alpha1.IstioRevisionConditionReasonGatewayNotReady,
+		v1alpha1.IstioRevisionConditionReasonReferencedByWorkloads, v1alpha1.IstioRevisionConditionReasonNotReferenced:
+		// these reasons are only used in status.components and in the InUse condition, which the Istio doesn't
+		// mirror; they're converted by name so that the conversion never fails
+		return v1alpha1.IstioConditionReason(reason)
 	default:
 		panic(fmt.Sprintf("can't convert IstioRevisionConditionReason: %s", reason))
 	}
 }
-
-func getValuesFromProfiles(profilesDir string, profiles []string) (helm.HelmValues, error) {
-	// start with an empty values map
-	values := helm.HelmValues{}
-
-	// apply profiles in order, overwriting values from previous profiles
-	alreadyApplied := sets.New[string]()
-	for _, profile := range profiles {
-		if profile == "" {
-			return nil, fmt.Errorf("profile name cannot be empty")
-		}
-		if alreadyApplied.Contains(profile) {
-			continue
-		}
-		alreadyApplied.Insert(profile)
-
-		file := path.Join(profilesDir, profile+".yaml")
-		// prevent path traversal attacks
-		if path.Dir(file) != profilesDir {
-			return nil, fmt.Errorf("invalid profile name %s", profile)
-		}
-
-		profileValues, err := getProfileValues(file)
-		if err != nil {
-			return nil, err
-		}
-		values = mergeOverwrite(values, profileValues)
-	}
-
-	return values, nil
-}
-
-func getProfileValues(file string) (helm.HelmValues, error) {
-	fileContents, err := os.ReadFile(file)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read profile file %v: %v", file, err)
-	}
-
-	var profile map[string]any
-	err = yaml.Unmarshal(fileContents, &profile)
-	if err != nil {
-		return nil, fmt.Errorf("failed to unmarshal profile YAML %s: %v", file, err)
-	}
-
-	val, found, err := unstructured.NestedFieldNoCopy(profile, "spec", "values")
-	if !found || err != nil {
-		return nil, err
-	}
-	m, ok := val.(map[string]any)
-	if !ok {
-		return nil, fmt.Errorf("spec.values is not a map[string]any")
-	}
-	return m, nil
-}
-
-func mergeOverwrite(base map[string]any, overrides map[string]any) map[string]any {
-	if base == nil {
-		base = make(map[string]any, 1)
-	}
-
-	for key, value := range overrides {
-		// if the key doesn't already exist, add it
-		if _, exists := base[key]; !exists {
-			base[key] = value
-			continue
-		}
-
-		// At this point, key exists in both base and overrides.
-		// If both are maps, recurse so that we override only specific values in the map.
-		// If only override value is a map, overwrite base value completely.
-		// If both are values, overwrite base.
-		childOverrides, overrideValueIsMap := value.(map[string]any)
-		childBase, baseValueIsMap := base[key].(map[string]any)
-		if baseValueIsMap && overrideValueIsMap {
-			base[key] = mergeOverwrite(childBase, childOverrides)
-		} else {
-			base[key] = value
-		}
-	}
-	return base
-}
@@ -17,27 +17,34 @@ package istio
 import (
 	"context"
 	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
 	"os"
 	"path"
 	"reflect"
 	"runtime/debug"
+	"strconv"
 	"testing"
 	"time"
 
 	"github.com/google/go-cmp/cmp"
+	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/client-go/tools/record"
 	"k8s.io/kubectl/pkg/scheme"
 	v1alpha1 "maistra.io/istio-operator/api/v1alpha1"
 	"maistra.io/istio-operator/pkg/common"
-	"maistra.io/istio-operator/pkg/helm"
+	"maistra.io/istio-operator/pkg/istiovalues"
 	"maistra.io/istio-operator/pkg/test"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/client/fake"
 	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 
 	"istio.io/istio/pkg/ptr"
 )
@@ -58,6 +65,7 @@ var (
 func TestReconcile(t *testing.T) {
 	test.SetupScheme()
 	resourceDir := t.TempDir()
+	Must(t, os.MkdirAll(path.Join(resourceDir, "my-version"), 0o755))
 
 	req := ctrl.Request{NamespacedName: istioKey}
 
@@ -65,7 +73,7 @@ func TestReconcile(t *testing.T) {
 		cl := newFakeClientBuilder().
 			WithInterceptorFuncs(noWrites(t)).
 			Build()
-		reconciler := NewIstioReconciler(cl, scheme.Scheme, resourceDir, nil)
+		reconciler := NewIstioReconciler(cl, scheme.Scheme, resourceDir, nil, "", nil)
 
 		_, err := reconciler.Reconcile(ctx, req)
 		if err != nil {
@@ -86,7 +94,7 @@ func TestReconcile(t *testing.T) {
 			WithObjects(istio).
 			WithInterceptorFuncs(noWrites(t)).
 			Build()
-		reconciler := NewIstioReconciler(cl, scheme.Scheme, resourceDir, nil)
+		reconciler := NewIstioReconciler(cl, scheme.Scheme, resourceDir, nil, "", nil)
 
 		_, err := reconciler.Reconcile(ctx, req)
 		if err != nil {
@@ -102,7 +110,7 @@ func TestReconcile(t *testing.T) {
 				},
 			}).
 			Build()
-		reconciler := NewIstioReconciler(cl, scheme.Scheme, resourceDir, nil)
+		reconciler := NewIstioReconciler(cl, scheme.Scheme, resourceDir, nil, "", nil)
 
 		_, err := reconciler.Reconcile(ctx, req)
 		if err == nil {
@@ -118,7 +126,7 @@ func TestReconcile(t *testing.T) {
 		cl := newFakeClientBuilder().
 			WithObjects(istio).
 			Build()
-		reconciler := NewIstioReconciler(cl, scheme.Scheme, resourceDir, nil)
+		reconciler := NewIstioReconciler(cl, scheme.Scheme, resourceDir, nil, "", nil)
 
 		_, err := reconciler.Reconcile(ctx, req)
 		if err == nil {
@@ -142,6 +150,125 @@ func TestReconcile(t *testing.T) {
 		}
 	})
 
+	t.Run("returns error when Istio version can't be resolved", func(t *testing.T) {
+		istio := &v1alpha1.Istio{
+			ObjectMeta: objectMeta,
+			Spec: v1alpha1.IstioSpec{
+				Version:   "v1.99",
+				Namespace: istioNamespace,
+			},
+		}
+
+		cl := newFakeClientBuilder().
+			WithStatusSubresource(&v1alpha1.Istio{}).
+			WithObjects(istio).
+			Build()
+		reconciler := NewIstioReconciler(cl, scheme.Scheme, resourceDir, nil, "", nil)
+
+		_, err := reconciler.Reconcile(ctx, req)
+		if err == nil {
+			t.Errorf("Expected an error, but got nil")
+		}
+
+		Must(t, cl.Get(ctx, istioKey, istio))
+
+		if istio.Status.State != v1alpha1.IstioConditionReasonReconcileError {
+			t.Errorf("Expected status.state to be %q, but got %q", v1alpha1.IstioConditionReasonReconcileError, istio.Status.State)
+		}
+		if istio.Status.ResolvedVersion != "" {
+			t.Errorf("Expected status.resolvedVersion to be empty, but got %q", istio.Status.ResolvedVersion)
+		}
+	})
+
+	t.Run("resolves version alias", func(t *testing.T) {
+		resourceDir := t.TempDir()
+		for _, version := range []string{"v1.20.1", "v1.20.3", "v1.19.7"} {
+			Must(t, os.MkdirAll(path.Join(resourceDir, version), 0o755))
+		}
+
+		istio := &v1alpha1.Istio{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: istioName,
+				UID:  istioUID,
+			},
+			Spec: v1alpha1.IstioSpec{
+				Version:   "v1.20",
+				Namespace: istioNamespace,
+				UpdateStrategy: &v1alpha1.IstioUpdateStrategy{
+					Type: v1alpha1.UpdateStrategyTypeRevisionBased,
+				},
+			},
+		}
+
+		cl := newFakeClientBuilder().
+			WithStatusSubresource(&v1alpha1.Istio{}).
+			WithObjects(istio).
+			Build()
+		reconciler := NewIstioReconciler(cl, scheme.Scheme, resourceDir, nil, "", nil)
+
+		_, err := reconciler.Reconcile(ctx, req)
+		if err != nil {
+			t.Errorf("Expected no error, but got: %v", err)
+		}
+
+		rev := &v1alpha1.IstioRevision{}
+		Must(t, cl.Get(ctx, types.NamespacedName{Name: istioName + "-v1-20-3"}, rev))
+		if rev.Spec.Version != "v1.20.3" {
+			t.Errorf("Expected IstioRevision version to be %q, but got %q", "v1.20.3", rev.Spec.Version)
+		}
+
+		Must(t, cl.Get(ctx, istioKey, istio))
+		if istio.Spec.Version != "v1.20" {
+			t.Errorf("Expected spec.version to remain %q, but got %q", "v1.20", istio.Spec.Version)
+		}
+		if istio.Status.ResolvedVersion != "v1.20.3" {
+			t.Errorf("Expected status.resolvedVersion to be %q, but got %q", "v1.20.3", istio.Status.ResolvedVersion)
+		}
+		if istio.Status.ActiveRevisionName != istioName+"-v1-20-3" {
+			t.Errorf("Expected status.activeRevisionName to be %q, but got %q", istioName+"-v1-20-3", istio.Status.ActiveRevisionName)
+		}
+	})
+
+	t.Run("skips revision creation when paused", func(t *testing.T) {
+		istio := &v1alpha1.Istio{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        istioName,
+				UID:         istioUID,
+				Annotations: map[string]string{common.PausedKey: "true"},
+			},
+			Spec: v1alpha1.IstioSpec{
+				Version:   "my-version",
+				Namespace: istioNamespace,
+			},
+		}
+
+		cl := newFakeClientBuilder().
+			WithStatusSubresource(&v1alpha1.Istio{}).
+			WithObjects(istio).
+			Build()
+		reconciler := NewIstioReconciler(cl, scheme.Scheme, resourceDir, nil, "", nil)
+
+		_, err := reconciler.Reconcile(ctx, req)
+		if err != nil {
+			t.Errorf("Expected no error, but got: %v", err)
+		}
+
+		revList := &v1alpha1.IstioRevisionList{}
+		Must(t, cl.List(ctx, revList))
+		if len(revList.Items) != 0 {
+			t.Errorf("Expected no IstioRevisions to be created, but got %d", len(revList.Items))
+		}
+
+		Must(t, cl.Get(ctx, istioKey, istio))
+		if istio.Status.State != v1alpha1.IstioConditionReasonPaused {
+			t.Errorf("Expected status.state to be %q, but got %q", v1alpha1.IstioConditionReasonPaused, istio.Status.State)
+		}
+		reconciledCond := istio.Status.GetCondition(v1alpha1.IstioConditionTypeReconciled)
+		if reconciledCond.Reason != v1alpha1.IstioConditionReasonPaused {
+			t.Errorf("Expected Reconciled condition reason to be %q, but got %q", v1alpha1.IstioConditionReasonPaused, reconciledCond.Reason)
+		}
+	})
+
 	t.Run("returns error when computeIstioRevisionValues fails", func(t *testing.T) {
 		istio := &v1alpha1.Istio{
 			ObjectMeta: objectMeta,
@@ -154,7 +281,7 @@ func TestReconcile(t *testing.T) {
 			WithStatusSubresource(&v1alpha1.Istio{}).
 			WithObjects(istio).
 			Build()
-		reconciler := NewIstioReconciler(cl, scheme.Scheme, resourceDir, []string{"invalid-profile"})
+		reconciler := NewIstioReconciler(cl, scheme.Scheme, resourceDir, []string{"invalid-profile"}, "", nil)
 
 		_, err := reconciler.Reconcile(ctx, req)
 		if err == nil {
@@ -194,7 +321,7 @@ func TestReconcile(t *testing.T) {
 				},
 			}).
 			Build()
-		reconciler := NewIstioReconciler(cl, scheme.Scheme, resourceDir, nil)
+		reconciler := NewIstioReconciler(cl, scheme.Scheme, resourceDir, nil, "", nil)
 
 		_, err := reconciler.Reconcile(ctx, req)
 		if err == nil {
@@ -265,6 +392,8 @@ func TestUpdateStatus(t *testing.T) {
 	testCases := []struct {
 		name              string
 		reconciliationErr error
+		resolvedVersion   string
+		profiles          *v1alpha1.ProfilesStatus
 		istio             *v1alpha1.Istio
 		revisions         []v1alpha1.IstioRevision
 		interceptorFuncs  *interceptor.Funcs
@@ -279,6 +408,65 @@ func TestUpdateStatus(t *testing.T) {
 			expectedStatus: v1alpha1.IstioStatus{
 				State:              v1alpha1.IstioConditionReasonReconcileError,
 				ObservedGeneration: generation,
+				ActiveRevisionName: istioName,
+				Conditions: []v1alpha1.IstioCondition{
+					{
+						Type:    v1alpha1.IstioConditionTypeReconciled,
+						Status:  metav1.ConditionFalse,
+						Reason:  v1alpha1.IstioConditionReasonReconcileError,
+						Message: "reconciliation error",
+					},
+					{
+						Type:    v1alpha1.IstioConditionTypeReady,
+						Status:  metav1.ConditionUnknown,
+						Reason:  v1alpha1.IstioConditionReasonReconcileError,
+						Message: "cannot determine readiness due to reconciliation error",
+					},
+				},
+			},
+		},
+		{
+			name:              "records resolved version",
+			reconciliationErr: fmt.Errorf("reconciliation error"),
+			resolvedVersion:   "my-version",
+			wantErr:           true,
+			expectedStatus: v1alpha1.IstioStatus{
+				State:              v1alpha1.IstioConditionReasonReconcileError,
+				ObservedGeneration: generation,
+				ActiveRevisionName: istioName,
+				ResolvedVersion:    "my-version",
+				Conditions: []v1alpha1.IstioCondition{
+					{
+						Type:    v1alpha1.IstioConditionTypeReconciled,
+						Status:  metav1.ConditionFalse,
+						Reason:  v1alpha1.IstioConditionReasonReconcileError,
+						Message: "reconciliation error",
+					},
+					{
+						Type:    v1alpha1.IstioConditionTypeReady,
+						Status:  metav1.ConditionUnknown,
+						Reason:  v1alpha1.IstioConditionReasonReconcileError,
+						Message: "cannot determine readiness due to reconciliation error",
+					},
+				},
+			},
+		},
+		{
+			name:              "records applied profiles",
+			reconciliationErr: fmt.Errorf("reconciliation error"),
+			profiles: &v1alpha1.ProfilesStatus{
+				Applied:       []string{"default", "tenant-a"},
+				ValuesSources: []v1alpha1.ValuesSource{{Key: "global", Source: "tenant-a"}},
+			},
+			wantErr: true,
+			expectedStatus: v1alpha1.IstioStatus{
+				State:              v1alpha1.IstioConditionReasonReconcileError,
+				ObservedGeneration: generation,
+				ActiveRevisionName: istioName,
+				Profiles: &v1alpha1.ProfilesStatus{
+					Applied:       []string{"default", "tenant-a"},
+					ValuesSources: []v1alpha1.ValuesSource{{Key: "global", Source: "tenant-a"}},
+				},
 				Conditions: []v1alpha1.IstioCondition{
 					{
 						Type:    v1alpha1.IstioConditionTypeReconciled,
@@ -323,6 +511,9 @@ func TestUpdateStatus(t *testing.T) {
 								Message: "ready message",
 							},
 						},
+						Components: []v1alpha1.ComponentStatus{
+							{Name: "istiod", Kind: "Deployment", Ready: metav1.ConditionTrue},
+						},
 					},
 				},
 				{
@@ -355,6 +546,7 @@ func TestUpdateStatus(t *testing.T) {
 			expectedStatus: v1alpha1.IstioStatus{
 				State:              v1alpha1.IstioConditionReasonHealthy,
 				ObservedGeneration: generation,
+				ActiveRevisionName: istioName,
 				Conditions: []v1alpha1.IstioCondition{
 					{
 						Type:    v1alpha1.IstioConditionTypeReconciled,
@@ -369,6 +561,9 @@ func TestUpdateStatus(t *testing.T) {
 						Message: "ready message",
 					},
 				},
+				Components: []v1alpha1.ComponentStatus{
+					{Name: "istiod", Kind: "Deployment", Ready: metav1.ConditionTrue},
+				},
 				Revisions: v1alpha1.RevisionSummary{
 					Total: 2,
 					Ready: 1,
@@ -376,6 +571,66 @@ func TestUpdateStatus(t *testing.T) {
 				},
 			},
 		},
+		{
+			name:    "mirrors Drifted condition of active revision",
+			wantErr: false,
+			revisions: []v1alpha1.IstioRevision{
+				{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:            istioKey.Name,
+						OwnerReferences: []metav1.OwnerReference{ownedByIstio},
+					},
+					Spec: v1alpha1.IstioRevisionSpec{
+						Namespace: istioNamespace,
+					},
+					Status: v1alpha1.IstioRevisionStatus{
+						State: v1alpha1.IstioRevisionConditionReasonHealthy,
+						Conditions: []v1alpha1.IstioRevisionCondition{
+							{
+								Type:   v1alpha1.IstioRevisionConditionTypeReconciled,
+								Status: metav1.ConditionTrue,
+							},
+							{
+								Type:   v1alpha1.IstioRevisionConditionTypeReady,
+								Status: metav1.ConditionTrue,
+							},
+							{
+								Type:    v1alpha1.IstioRevisionConditionTypeDrifted,
+								Status:  metav1.ConditionTrue,
+								Reason:  v1alpha1.IstioRevisionConditionReasonDriftDetected,
+								Message: "drifted message",
+							},
+						},
+					},
+				},
+			},
+			expectedStatus: v1alpha1.IstioStatus{
+				State:              v1alpha1.IstioConditionReasonHealthy,
+				ObservedGeneration: generation,
+				ActiveRevisionName: istioName,
+				Conditions: []v1alpha1.IstioCondition{
+					{
+						Type:   v1alpha1.IstioConditionTypeReconciled,
+						Status: metav1.ConditionTrue,
+					},
+					{
+						Type:   v1alpha1.IstioConditionTypeReady,
+						Status: metav1.ConditionTrue,
+					},
+					{
+						Type:    v1alpha1.IstioConditionTypeDrifted,
+						Status:  metav1.ConditionTrue,
+						Reason:  v1alpha1.IstioConditionReasonDriftDetected,
+						Message: "drifted message",
+					},
+				},
+				Revisions: v1alpha1.RevisionSummary{
+					Total: 1,
+					Ready: 1,
+					InUse: 0,
+				},
+			},
+		},
 		{
 			name:    "shows correct revision counts",
 			wantErr: false,
@@ -390,6 +645,7 @@ func TestUpdateStatus(t *testing.T) {
 			expectedStatus: v1alpha1.IstioStatus{
 				State:              v1alpha1.IstioConditionReasonHealthy,
 				ObservedGeneration: generation,
+				ActiveRevisionName: istioName,
 				Conditions: []v1alpha1.IstioCondition{
 					{
 						Type:   v1alpha1.IstioConditionTypeReconciled,
@@ -407,12 +663,59 @@ func TestUpdateStatus(t *testing.T) {
 				},
 			},
 		},
+		{
+			name: "paused",
+			istio: &v1alpha1.Istio{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:        istioKey.Name,
+					UID:         istioUID,
+					Generation:  100,
+					Annotations: map[string]string{common.PausedKey: "true"},
+				},
+				Spec: v1alpha1.IstioSpec{
+					Version:   "my-version",
+					Namespace: istioNamespace,
+				},
+				Status: v1alpha1.IstioStatus{
+					ActiveRevisionName: istioName + "-previous",
+					ResolvedVersion:    "my-previous-version",
+				},
+			},
+			revisions: []v1alpha1.IstioRevision{
+				revision(istioName+"-previous", ownedByIstio, true, true, true),
+			},
+			wantErr: false,
+			expectedStatus: v1alpha1.IstioStatus{
+				State:              v1alpha1.IstioConditionReasonPaused,
+				ObservedGeneration: generation,
+				ActiveRevisionName: istioName + "-previous",
+				ResolvedVersion:    "my-previous-version",
+				Conditions: []v1alpha1.IstioCondition{
+					{
+						Type:    v1alpha1.IstioConditionTypeReconciled,
+						Status:  metav1.ConditionFalse,
+						Reason:  v1alpha1.IstioConditionReasonPaused,
+						Message: "reconciliation is paused by the operator.istio.io/paused annotation",
+					},
+					{
+						Type:   v1alpha1.IstioConditionTypeReady,
+						Status: metav1.ConditionTrue,
+					},
+				},
+				Revisions: v1alpha1.RevisionSummary{
+					Total: 1,
+					Ready: 1,
+					InUse: 1,
+				},
+			},
+		},
 		{
 			name:    "active revision not found",
 			wantErr: false,
 			expectedStatus: v1alpha1.IstioStatus{
 				State:              v1alpha1.IstioConditionReasonIstioRevisionNotFound,
 				ObservedGeneration: generation,
+				ActiveRevisionName: istioName,
 				Conditions: []v1alpha1.IstioCondition{
 					{
 						Type:    v1alpha1.IstioConditionTypeReconciled,
@@ -455,6 +758,7 @@ func TestUpdateStatus(t *testing.T) {
 				},
 				Status: v1alpha1.IstioStatus{
 					ObservedGeneration: 100,
+					ActiveRevisionName: istioName,
 					State:              v1alpha1.IstioConditionReasonHealthy,
 					Conditions: []v1alpha1.IstioCondition{
 						{
@@ -506,6 +810,7 @@ func TestUpdateStatus(t *testing.T) {
 			expectedStatus: v1alpha1.IstioStatus{
 				State:              v1alpha1.IstioConditionReasonHealthy,
 				ObservedGeneration: generation,
+				ActiveRevisionName: istioName,
 				Conditions: []v1alpha1.IstioCondition{
 					{
 						Type:    v1alpha1.IstioConditionTypeReconciled,
@@ -572,9 +877,9 @@ func TestUpdateStatus(t *testing.T) {
 				WithObjects(initObjs...).
 				WithInterceptorFuncs(interceptorFuncs).
 				Build()
-			reconciler := NewIstioReconciler(cl, scheme.Scheme, resourceDir, nil)
+			reconciler := NewIstioReconciler(cl, scheme.Scheme, resourceDir, nil, "", nil)
 
-			err := reconciler.updateStatus(ctx, istio, tc.reconciliationErr)
+			err := reconciler.updateStatus(ctx, istio, tc.resolvedVersion, tc.profiles, nil, tc.reconciliationErr)
 			if (err != nil) != tc.wantErr {
 				t.Errorf("updateStatus() error = %v, wantErr %v", err, tc.wantErr)
 			}
@@ -591,6 +896,79 @@ func TestUpdateStatus(t *testing.T) {
 	}
 }
 
+func TestUpdateStatusRecordsReadyTransition(t *testing.T) {
+	test.SetupScheme()
+
+	istio := &v1alpha1.Istio{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:       istioKey.Name,
+			UID:        istioUID,
+			Generation: 1,
+		},
+		Spec: v1alpha1.IstioSpec{
+			Version:   "my-version",
+			Namespace: istioNamespace,
+		},
+	}
+	rev := &v1alpha1.IstioRevision{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: istioName,
+			OwnerReferences: []metav1.OwnerReference{{
+				APIVersion: v1alpha1.GroupVersion.String(),
+				Kind:       v1alpha1.IstioKind,
+				Name:       istioName,
+				UID:        istioUID,
+				Controller: ptr.Of(true),
+			}},
+		},
+		Spec: v1alpha1.IstioRevisionSpec{Namespace: istioNamespace},
+		Status: v1alpha1.IstioRevisionStatus{
+			Conditions: []v1alpha1.IstioRevisionCondition{
+				{Type: v1alpha1.IstioRevisionConditionTypeReconciled, Status: metav1.ConditionTrue},
+				{Type: v1alpha1.IstioRevisionConditionTypeReady, Status: metav1.ConditionTrue},
+			},
+		},
+	}
+
+	cl := newFakeClientBuilder().
+		WithObjects(istio, rev).
+		WithStatusSubresource(&v1alpha1.IstioRevision{}).
+		Build()
+	recorder := record.NewFakeRecorder(10)
+	reconciler := NewIstioReconciler(cl, scheme.Scheme, t.TempDir(), nil, "", recorder)
+
+	expectEvent := func(expected string) {
+		t.Helper()
+		select {
+		case event := <-recorder.Events:
+			if event != expected {
+				t.Errorf("expected event %q, but got %q", expected, event)
+			}
+		default:
+			if expected != "" {
+				t.Errorf("expected event %q, but no event was recorded", expected)
+			}
+		}
+	}
+
+	Must(t, reconciler.updateStatus(ctx, istio, "", nil, nil, nil))
+	expectEvent("Normal Ready all components of the active revision are ready")
+
+	Must(t, cl.Get(ctx, istioKey, istio))
+	Must(t, reconciler.updateStatus(ctx, istio, "", nil, nil, nil))
+	expectEvent("")
+
+	rev.Status.SetCondition(v1alpha1.IstioRevisionCondition{
+		Type:    v1alpha1.IstioRevisionConditionTypeReady,
+		Status:  metav1.ConditionFalse,
+		Message: "not all istiod pods are ready",
+	})
+	Must(t, cl.Status().Update(ctx, rev))
+	Must(t, cl.Get(ctx, istioKey, istio))
+	Must(t, reconciler.updateStatus(ctx, istio, "", nil, nil, nil))
+	expectEvent("Warning NotReady components of the active revision are not ready: not all istiod pods are ready")
+}
+
 func toConditionStatus(b bool) metav1.ConditionStatus {
 	if b {
 		return metav1.ConditionTrue
@@ -672,6 +1050,12 @@ func TestReconcileActiveRevision(t *testing.T) {
 						Spec: v1alpha1.IstioSpec{
 							Version: version,
 							Values:  &tc.istioValues,
+							DriftPolicy: &v1alpha1.DriftPolicy{
+								Action: v1alpha1.DriftActionReport,
+							},
+							RollbackPolicy: &v1alpha1.RollbackPolicy{
+								Enabled: true,
+							},
 						},
 					}
 					if sc.updateStrategyType != nil {
@@ -697,7 +1081,7 @@ func TestReconcileActiveRevision(t *testing.T) {
 					}
 
 					cl := newFakeClientBuilder().WithObjects(initObjs...).Build()
-					reconciler := NewIstioReconciler(cl, scheme.Scheme, resourceDir, nil)
+					reconciler := NewIstioReconciler(cl, scheme.Scheme, resourceDir, nil, "", nil)
 
 					err := reconciler.reconcileActiveRevision(ctx, istio, &tc.istioValues)
 					if err != nil {
@@ -732,12 +1116,88 @@ func TestReconcileActiveRevision(t *testing.T) {
 					if diff := cmp.Diff(tc.istioValues.ToHelmValues(), rev.Spec.Values.ToHelmValues()); diff != "" {
 						t.Errorf("IstioRevision.spec.values don't match Istio.spec.values; diff (-expected, +actual):\n%v", diff)
 					}
+
+					if diff := cmp.Diff(istio.Spec.DriftPolicy, rev.Spec.DriftPolicy); diff != "" {
+						t.Errorf("IstioRevision.spec.driftPolicy doesn't match Istio.spec.driftPolicy; diff (-expected, +actual):\n%v", diff)
+					}
+
+					if diff := cmp.Diff(istio.Spec.RollbackPolicy, rev.Spec.RollbackPolicy); diff != "" {
+						t.Errorf("IstioRevision.spec.rollbackPolicy doesn't match Istio.spec.rollbackPolicy; diff (-expected, +actual):\n%v", diff)
+					}
 				})
 			}
 		})
 	}
 }
 
+func TestPropagateDryRun(t *testing.T) {
+	testCases := []struct {
+		name                string
+		istioAnnotations    map[string]string
+		revAnnotations      map[string]string
+		expectedAnnotations map[string]string
+	}{
+		{
+			name:                "enables dry run",
+			istioAnnotations:    map[string]string{common.DryRunKey: "true"},
+			expectedAnnotations: map[string]string{common.DryRunKey: "true"},
+		},
+		{
+			name:                "disables dry run",
+			revAnnotations:      map[string]string{common.DryRunKey: "true", "other": "value"},
+			expectedAnnotations: map[string]string{"other": "value"},
+		},
+		{
+			name:                "ignores annotation not set to true",
+			istioAnnotations:    map[string]string{common.DryRunKey: "false"},
+			revAnnotations:      map[string]string{common.DryRunKey: "true"},
+			expectedAnnotations: map[string]string{},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			istio := &v1alpha1.Istio{ObjectMeta: metav1.ObjectMeta{Name: istioName, Annotations: tc.istioAnnotations}}
+			rev := &v1alpha1.IstioRevision{ObjectMeta: metav1.ObjectMeta{Name: istioName, Annotations: tc.revAnnotations}}
+			propagateDryRun(istio, rev)
+			if diff := cmp.Diff(tc.expectedAnnotations, rev.Annotations); diff != "" {
+				t.Errorf("unexpected annotations; diff (-expected, +actual):\n%v", diff)
+			}
+		})
+	}
+}
+
+func TestPropagateAdoptedHelmRelease(t *testing.T) {
+	testCases := []struct {
+		name                string
+		istioAnnotations    map[string]string
+		revAnnotations      map[string]string
+		expectedAnnotations map[string]string
+	}{
+		{
+			name:                "sets release to adopt",
+			istioAnnotations:    map[string]string{common.AdoptHelmReleaseKey: "istiod"},
+			expectedAnnotations: map[string]string{common.AdoptHelmReleaseKey: "istiod"},
+		},
+		{
+			name:                "removes release to adopt",
+			revAnnotations:      map[string]string{common.AdoptHelmReleaseKey: "istiod", "other": "value"},
+			expectedAnnotations: map[string]string{"other": "value"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			istio := &v1alpha1.Istio{ObjectMeta: metav1.ObjectMeta{Name: istioName, Annotations: tc.istioAnnotations}}
+			rev := &v1alpha1.IstioRevision{ObjectMeta: metav1.ObjectMeta{Name: istioName, Annotations: tc.revAnnotations}}
+			propagateAdoptedHelmRelease(istio, rev)
+			if diff := cmp.Diff(tc.expectedAnnotations, rev.Annotations); diff != "" {
+				t.Errorf("unexpected annotations; diff (-expected, +actual):\n%v", diff)
+			}
+		})
+	}
+}
+
 func TestPruneInactiveRevisions(t *testing.T) {
 	test.SetupScheme()
 	resourceDir := t.TempDir()
@@ -775,6 +1235,7 @@ func TestPruneInactiveRevisions(t *testing.T) {
 		expectDeletion      bool
 		expectRequeueAfter  *time.Duration
 		additionalRevisions []*v1alpha1.IstioRevision
+		tags                []*v1alpha1.IstioRevisionTag
 	}{
 		{
 			name:           "preserves active IstioRevision even if not in use",
@@ -824,6 +1285,56 @@ func TestPruneInactiveRevisions(t *testing.T) {
 			expectDeletion:     false,
 			expectRequeueAfter: nil,
 		},
+		{
+			name:           "preserves non-active IstioRevision that's in use through an IstioRevisionTag",
+			revName:        istioName + "-non-active",
+			ownerReference: ownedByIstio,
+			inUseCondition: &v1alpha1.IstioRevisionCondition{
+				Type:               v1alpha1.IstioRevisionConditionTypeInUse,
+				Status:             metav1.ConditionTrue,
+				LastTransitionTime: oneMinuteAgo,
+			},
+			tags: []*v1alpha1.IstioRevisionTag{
+				{
+					ObjectMeta: metav1.ObjectMeta{
+						Name: "prod",
+					},
+					Spec: v1alpha1.IstioRevisionTagSpec{
+						TargetRef: v1alpha1.TargetReference{
+							Kind: v1alpha1.IstioRevisionKind,
+							Name: istioName + "-non-active",
+						},
+					},
+				},
+			},
+			expectDeletion:     false,
+			expectRequeueAfter: nil,
+		},
+		{
+			name:           "deletes unused non-active IstioRevision even if an IstioRevisionTag references it",
+			revName:        istioName + "-non-active",
+			ownerReference: ownedByIstio,
+			inUseCondition: &v1alpha1.IstioRevisionCondition{
+				Type:               v1alpha1.IstioRevisionConditionTypeInUse,
+				Status:             metav1.ConditionFalse,
+				LastTransitionTime: oneMinuteAgo,
+			},
+			tags: []*v1alpha1.IstioRevisionTag{
+				{
+					ObjectMeta: metav1.ObjectMeta{
+						Name: "prod",
+					},
+					Spec: v1alpha1.IstioRevisionTagSpec{
+						TargetRef: v1alpha1.TargetReference{
+							Kind: v1alpha1.IstioRevisionKind,
+							Name: istioName + "-non-active",
+						},
+					},
+				},
+			},
+			expectDeletion:     true,
+			expectRequeueAfter: nil,
+		},
 		{
 			name:           "deletes non-active IstioRevision that's not in use",
 			revName:        istioName + "-non-active",
@@ -908,9 +1419,12 @@ func TestPruneInactiveRevisions(t *testing.T) {
 			for _, additionalRev := range tc.additionalRevisions {
 				initObjs = append(initObjs, additionalRev)
 			}
+			for _, tag := range tc.tags {
+				initObjs = append(initObjs, tag)
+			}
 
 			cl := newFakeClientBuilder().WithObjects(initObjs...).Build()
-			reconciler := NewIstioReconciler(cl, scheme.Scheme, resourceDir, nil)
+			reconciler := NewIstioReconciler(cl, scheme.Scheme, resourceDir, nil, "", nil)
 
 			result, err := reconciler.pruneInactiveRevisions(ctx, istio)
 			if err != nil {
@@ -1062,6 +1576,7 @@ func TestGetPruningGracePeriod(t *testing.T) {
 // (with each source overriding the values from the previous sources):
 //   - default profile(s)
 //   - profile selected in IstioRevision.spec.profile
+//   - profiles listed in IstioRevision.spec.profiles
 //   - IstioRevision.spec.values
 //   - other (non-value) fields in the IstioRevision resource (e.g. the value global.istioNamespace is set from IstioRevision.spec.namespace)
 func TestComputeIstioRevisionValues(t *testing.T) {
@@ -1089,11 +1604,20 @@ spec:
       tag: from-my-profile
       image: from-my-profile  # this gets overridden in values`)), 0o644))
 
+	Must(t, os.WriteFile(path.Join(profilesDir, "tenant-a.yaml"), []byte((`
+apiVersion: operator.istio.io/v1alpha1
+kind: IstioRevision
+spec:
+  values:
+    global:
+      logAsJson: true`)), 0o644))
+
 	istio := v1alpha1.Istio{
 		ObjectMeta: objectMeta,
 		Spec: v1alpha1.IstioSpec{
 			Version:   version,
 			Profile:   "my-profile",
+			Profiles:  []v1alpha1.ProfileName{"tenant-a"},
 			Namespace: istioNamespace,
 			Values: &v1alpha1.Values{
 				Pilot: &v1alpha1.PilotConfig{
@@ -1103,7 +1627,7 @@ spec:
 		},
 	}
 
-	result, err := computeIstioRevisionValues(istio, []string{"default"}, resourceDir)
+	result, profiles, err := computeIstioRevisionValues(istio, []string{"default"}, resourceDir, nil)
 	if err != nil {
 		t.Errorf("Expected no error, but got an error: %v", err)
 	}
@@ -1116,6 +1640,7 @@ spec:
 		},
 		Global: &v1alpha1.GlobalConfig{
 			IstioNamespace: istioNamespace, // this value is always added/overridden based on IstioRevision.spec.namespace
+			LogAsJSON:      true,
 		},
 		Revision: objectMeta.Name,
 	}
@@ -1123,208 +1648,16 @@ spec:
 	if !reflect.DeepEqual(result, expected) {
 		t.Errorf("Result does not match the expected Values.\nExpected: %v\nActual: %v", expected, result)
 	}
-}
-
-func TestGetValuesFromProfiles(t *testing.T) {
-	const version = "my-version"
-	resourceDir := t.TempDir()
-	profilesDir := path.Join(resourceDir, version, "profiles")
-	Must(t, os.MkdirAll(profilesDir, 0o755))
-
-	writeProfileFile := func(t *testing.T, path string, values ...string) {
-		yaml := `
-apiVersion: operator.istio.io/v1alpha1
-kind: IstioRevision
-spec:
-  values:`
-		for i, val := range values {
-			if val != "" {
-				yaml += fmt.Sprintf(`
-    value%d: %s`, i+1, val)
-			}
-		}
-		Must(t, os.WriteFile(path, []byte(yaml), 0o644))
-	}
-
-	writeProfileFile(t, path.Join(profilesDir, "default.yaml"), "1-from-default", "2-from-default")
-	writeProfileFile(t, path.Join(profilesDir, "overlay.yaml"), "", "2-from-overlay")
-	writeProfileFile(t, path.Join(profilesDir, "custom.yaml"), "1-from-custom")
-	writeProfileFile(t, path.Join(resourceDir, version, "not-in-profiles-dir.yaml"), "should-not-be-accessible")
-
-	tests := []struct {
-		name         string
-		profiles     []string
-		expectValues helm.HelmValues
-		expectErr    bool
-	}{
-		{
-			name:         "nil default profiles",
-			profiles:     nil,
-			expectValues: helm.HelmValues{},
-		},
-		{
-			name:     "default profile only",
-			profiles: []string{"default"},
-			expectValues: helm.HelmValues{
-				"value1": "1-from-default",
-				"value2": "2-from-default",
-			},
-		},
-		{
-			name:     "default and overlay",
-			profiles: []string{"default", "overlay"},
-			expectValues: helm.HelmValues{
-				"value1": "1-from-default",
-				"value2": "2-from-overlay",
-			},
-		},
-		{
-			name:     "default and overlay and custom",
-			profiles: []string{"default", "overlay", "custom"},
-			expectValues: helm.HelmValues{
-				"value1": "1-from-custom",
-				"value2": "2-from-overlay",
-			},
-		},
-		{
-			name:      "default profile empty",
-			profiles:  []string{""},
-			expectErr: true,
-		},
-		{
-			name:      "profile not found",
-			profiles:  []string{"invalid"},
-			expectErr: true,
-		},
-		{
-			name:      "path-traversal-attack",
-			profiles:  []string{"../not-in-profiles-dir"},
-			expectErr: true,
-		},
-	}
-
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			actual, err := getValuesFromProfiles(profilesDir, tt.profiles)
-			if (err != nil) != tt.expectErr {
-				t.Errorf("applyProfile() error = %v, expectErr %v", err, tt.expectErr)
-			}
 
-			if err == nil {
-				if diff := cmp.Diff(tt.expectValues, actual); diff != "" {
-					t.Errorf("profile wasn't applied properly; diff (-expected, +actual):\n%v", diff)
-				}
-			}
-		})
-	}
-}
-
-func TestMergeOverwrite(t *testing.T) {
-	testCases := []struct {
-		name                    string
-		overrides, base, expect map[string]any
-	}{
-		{
-			name:      "both empty",
-			base:      make(map[string]any),
-			overrides: make(map[string]any),
-			expect:    make(map[string]any),
-		},
-		{
-			name:      "nil overrides",
-			base:      map[string]any{"key1": 42, "key2": "value"},
-			overrides: nil,
-			expect:    map[string]any{"key1": 42, "key2": "value"},
-		},
-		{
-			name:      "nil base",
-			base:      nil,
-			overrides: map[string]any{"key1": 42, "key2": "value"},
-			expect:    map[string]any{"key1": 42, "key2": "value"},
-		},
-		{
-			name: "adds toplevel keys",
-			base: map[string]any{
-				"key2": "from base",
-			},
-			overrides: map[string]any{
-				"key1": "from overrides",
-			},
-			expect: map[string]any{
-				"key1": "from overrides",
-				"key2": "from base",
-			},
-		},
-		{
-			name: "adds nested keys",
-			base: map[string]any{
-				"key1": map[string]any{
-					"nested2": "from base",
-				},
-			},
-			overrides: map[string]any{
-				"key1": map[string]any{
-					"nested1": "from overrides",
-				},
-			},
-			expect: map[string]any{
-				"key1": map[string]any{
-					"nested1": "from overrides",
-					"nested2": "from base",
-				},
-			},
-		},
-		{
-			name: "overrides overrides base",
-			base: map[string]any{
-				"key1": "from base",
-				"key2": map[string]any{
-					"nested1": "from base",
-				},
-			},
-			overrides: map[string]any{
-				"key1": "from overrides",
-				"key2": map[string]any{
-					"nested1": "from overrides",
-				},
-			},
-			expect: map[string]any{
-				"key1": "from overrides",
-				"key2": map[string]any{
-					"nested1": "from overrides",
-				},
-			},
-		},
-		{
-			name: "mismatched types",
-			base: map[string]any{
-				"key1": map[string]any{
-					"desc": "key1 is a map in base",
-				},
-				"key2": "key2 is a string in base",
-			},
-			overrides: map[string]any{
-				"key1": "key1 is a string in overrides",
-				"key2": map[string]any{
-					"desc": "key2 is a map in overrides",
-				},
-			},
-			expect: map[string]any{
-				"key1": "key1 is a string in overrides",
-				"key2": map[string]any{
-					"desc": "key2 is a map in overrides",
-				},
-			},
+	expectedProfiles := &v1alpha1.ProfilesStatus{
+		Applied: []string{"default", "my-profile", "tenant-a"},
+		ValuesSources: []v1alpha1.ValuesSource{
+			{Key: "global", Source: "tenant-a"},
+			{Key: "pilot", Source: v1alpha1.ValuesSourceSpecValues},
 		},
 	}
-
-	for _, tc := range testCases {
-		t.Run(tc.name, func(t *testing.T) {
-			result := mergeOverwrite(tc.base, tc.overrides)
-			if diff := cmp.Diff(tc.expect, result); diff != "" {
-				t.Errorf("unexpected merge result; diff (-expected, +actual):\n%v", diff)
-			}
-		})
+	if diff := cmp.Diff(expectedProfiles, profiles); diff != "" {
+		t.Errorf("unexpected profiles status; diff (-expected, +actual):\n%v", diff)
 	}
 }
 
@@ -1390,9 +1723,9 @@ func TestApplyImageDigests(t *testing.T) {
 				Cni: &v1alpha1.CNIConfig{
 					Image: "cni-test",
 				},
-				// ZTunnel: &v1alpha1.ZTunnelConfig{
-				// 	Image: "ztunnel-test",
-				// },
+				ZTunnel: &v1alpha1.ZTunnelConfig{
+					Image: "ztunnel-test",
+				},
 			},
 		},
 		{
@@ -1432,9 +1765,9 @@ func TestApplyImageDigests(t *testing.T) {
 				Cni: &v1alpha1.CNIConfig{
 					Image: "cni-test",
 				},
-				// ZTunnel: &v1alpha1.ZTunnelConfig{
-				// 	Image: "ztunnel-test",
-				// },
+				ZTunnel: &v1alpha1.ZTunnelConfig{
+					Image: "ztunnel-test",
+				},
 			},
 		},
 		{
@@ -1476,9 +1809,9 @@ func TestApplyImageDigests(t *testing.T) {
 				Cni: &v1alpha1.CNIConfig{
 					Image: "cni-test",
 				},
-				// ZTunnel: &v1alpha1.ZTunnelConfig{
-				// 	Image: "ztunnel-test",
-				// },
+				ZTunnel: &v1alpha1.ZTunnelConfig{
+					Image: "ztunnel-test",
+				},
 			},
 		},
 		{
@@ -1570,3 +1903,151 @@ func oneMinuteAgo() *metav1.Time {
 	t := metav1.NewTime(time.Now().Add(-1 * time.Minute))
 	return &t
 }
+
+func TestConvertConditionReason(t *testing.T) {
+	testCases := []struct {
+		revisionReason v1alpha1.IstioRevisionConditionReason
+		expectedReason v1alpha1.IstioConditionReason
+	}{
+		{"", ""},
+		{v1alpha1.IstioRevisionConditionReasonIstiodNotReady, v1alpha1.IstioConditionReasonIstiodNotReady},
+		{v1alpha1.IstioRevisionConditionReasonCNINotReady, v1alpha1.IstioConditionReasonCNINotReady},
+		{v1alpha1.IstioRevisionConditionReasonZTunnelNotReady, v1alpha1.IstioConditionReasonZTunnelNotReady},
+		{v1alpha1.IstioRevisionConditionReasonZTunnelHandoverBlocked, v1alpha1.IstioConditionReasonZTunnelHandoverBlocked},
+		{v1alpha1.IstioRevisionConditionReasonHealthy, v1alpha1.IstioConditionReasonHealthy},
+		{v1alpha1.IstioRevisionConditionReasonReconcileError, v1alpha1.IstioConditionReasonReconcileError},
+		{v1alpha1.IstioRevisionConditionReasonPaused, v1alpha1.IstioConditionReasonPaused},
+		{v1alpha1.IstioRevisionConditionReasonDryRun, v1alpha1.IstioConditionReasonDryRun},
+		{v1alpha1.IstioRevisionConditionReasonRolledBack, v1alpha1.IstioConditionReasonRolledBack},
+		{v1alpha1.IstioRevisionConditionReasonDriftDetected, v1alpha1.IstioConditionReasonDriftDetected},
+		{v1alpha1.IstioRevisionConditionReasonDriftReverted, v1alpha1.IstioConditionReasonDriftReverted},
+	}
+	for _, tc := range testCases {
+		t.Run(string(tc.revisionReason), func(t *testing.T) {
+			if reason := convertConditionReason(tc.revisionReason); reason != tc.expectedReason {
+				t.Errorf("expected %q, but got %q", tc.expectedReason, reason)
+			}
+		})
+	}
+}
+
+// TestConvertAllConditionReasons converts every IstioRevisionConditionReason declared in the API, so that adding
+// a reason without mapping it in convertConditionReason fails here instead of crashing the controller
+func TestConvertAllConditionReasons(t *testing.T) {
+	reasons := getDeclaredConstants(t, path.Join("..", "..", "api", "v1alpha1", "istiorevision_types.go"), "IstioRevisionConditionReason")
+	if len(reasons) == 0 {
+		t.Fatal("no IstioRevisionConditionReason constants found")
+	}
+	for _, reason := range reasons {
+		t.Run(reason, func(t *testing.T) {
+			defer func() {
+				if r := recover(); r != nil {
+					t.Errorf("convertConditionReason(%q) panicked: %v", reason, r)
+				}
+			}()
+			if converted := convertConditionReason(v1alpha1.IstioRevisionConditionReason(reason)); string(converted) != reason {
+				t.Errorf("expected %q to be converted to the Istio reason of the same name, but got %q", reason, converted)
+			}
+		})
+	}
+}
+
+// getDeclaredConstants returns the values of the string constants of the given type that are declared in the given file
+func getDeclaredConstants(t *testing.T, file, typeName string) []string {
+	f, err := parser.ParseFile(token.NewFileSet(), file, nil, 0)
+	if err != nil {
+		t.Fatalf("failed to parse %s: %v", file, err)
+	}
+	var values []string
+	for _, decl := range f.Decls {
+		genDecl, ok := decl.(*ast.GenDecl)
+		if !ok || genDecl.Tok != token.CONST {
+			continue
+		}
+		for _, spec := range genDecl.Specs {
+			valueSpec := spec.(*ast.ValueSpec)
+			if ident, ok := valueSpec.Type.(*ast.Ident); !ok || ident.Name != typeName {
+				continue
+			}
+			for _, value := range valueSpec.Values {
+				if lit, ok := value.(*ast.BasicLit); ok && lit.Kind == token.STRING {
+					unquoted, err := strconv.Unquote(lit.Value)
+					if err != nil {
+						t.Fatalf("failed to unquote %s: %v", lit.Value, err)
+					}
+					values = append(values, unquoted)
+				}
+			}
+		}
+	}
+	return values
+}
+
+func TestMapProfileConfigMapToReconcileRequests(t *testing.T) {
+	test.SetupScheme()
+
+	newIstio := func(name, profile string, profiles ...v1alpha1.ProfileName) *v1alpha1.Istio {
+		return &v1alpha1.Istio{
+			ObjectMeta: metav1.ObjectMeta{Name: name},
+			Spec:       v1alpha1.IstioSpec{Profile: profile, Profiles: profiles},
+		}
+	}
+	cl := fake.NewClientBuilder().
+		WithScheme(scheme.Scheme).
+		WithObjects(newIstio("hardened", "hardened"), newIstio("demo", "demo"), newIstio("no-profile", ""), newIstio("layered", "demo", "tenant-a")).
+		Build()
+	reconciler := NewIstioReconciler(cl, scheme.Scheme, "", []string{"default"}, "operator", nil)
+
+	testCases := []struct {
+		name      string
+		namespace string
+		labels    map[string]string
+		expected  []reconcile.Request
+	}{
+		{
+			name:      "profile used by Istio",
+			namespace: "operator",
+			labels:    map[string]string{istiovalues.ProfileLabel: "hardened"},
+			expected:  []reconcile.Request{{NamespacedName: types.NamespacedName{Name: "hardened"}}},
+		},
+		{
+			name:      "default profile",
+			namespace: "operator",
+			labels:    map[string]string{istiovalues.ProfileLabel: "default"},
+			expected: []reconcile.Request{
+				{NamespacedName: types.NamespacedName{Name: "demo"}},
+				{NamespacedName: types.NamespacedName{Name: "hardened"}},
+				{NamespacedName: types.NamespacedName{Name: "layered"}},
+				{NamespacedName: types.NamespacedName{Name: "no-profile"}},
+			},
+		},
+		{
+			name:      "profile listed in spec.profiles",
+			namespace: "operator",
+			labels:    map[string]string{istiovalues.ProfileLabel: "tenant-a"},
+			expected:  []reconcile.Request{{NamespacedName: types.NamespacedName{Name: "layered"}}},
+		},
+		{
+			name:      "unused profile",
+			namespace: "operator",
+			labels:    map[string]string{istiovalues.ProfileLabel: "high-scale"},
+		},
+		{
+			name:      "ConfigMap in other namespace",
+			namespace: "other",
+			labels:    map[string]string{istiovalues.ProfileLabel: "hardened"},
+		},
+		{
+			name:      "ConfigMap without profile label",
+			namespace: "operator",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			cm := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "cm", Namespace: tc.namespace, Labels: tc.labels}}
+			if diff := cmp.Diff(tc.expected, reconciler.mapProfileConfigMapToReconcileRequests(ctx, cm)); diff != "" {
+				t.Errorf("unexpected requests; diff (-expected, +actual):\n%v", diff)
+			}
+		})
+	}
+}
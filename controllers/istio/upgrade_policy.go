@@ -0,0 +1,111 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package istio
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/Masterminds/semver/v3"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"maistra.io/istio-operator/api/v1alpha1"
+	"maistra.io/istio-operator/pkg/common"
+	"maistra.io/istio-operator/pkg/istioversion"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// upgradePolicyError is returned when the change to spec.version violates the upgrade policy. Retrying won't
+// help; the Istio is reconciled again when its spec is changed.
+type upgradePolicyError struct {
+	message string
+}
+
+func (e *upgradePolicyError) Error() string {
+	return e.message
+}
+
+// checkUpgradePolicy verifies that moving the control plane from the currently installed version to the
+// version in spec.version is allowed by the given upgrade policy.
+func (r *IstioReconciler) checkUpgradePolicy(ctx context.Context, istio *v1alpha1.Istio, policy common.UpgradePolicy) error {
+	fromVersion, err := r.getInstalledVersion(ctx, istio)
+	if err != nil || fromVersion == nil {
+		return err
+	}
+	toVersion, err := istioversion.Semver(r.ResourceDirectory, istio.Spec.Version)
+	if err != nil {
+		return err
+	}
+	return validateUpgrade(fromVersion, toVersion, istio, policy)
+}
+
+// getInstalledVersion returns the version the control plane is being upgraded from. When the active revision
+// already exists (always the case with the InPlace strategy after the initial installation), this is the
+// version of that revision. Otherwise, it's the most recent version of all the revisions owned by the Istio.
+// Returns nil if no revision exists yet.
+func (r *IstioReconciler) getInstalledVersion(ctx context.Context, istio *v1alpha1.Istio) (*semver.Version, error) {
+	log := logf.FromContext(ctx)
+
+	rev, err := r.getActiveRevision(ctx, istio)
+	if err == nil {
+		return istioversion.Semver(r.ResourceDirectory, rev.Spec.Version)
+	} else if !errors.IsNotFound(err) {
+		return nil, err
+	}
+
+	revisions, err := r.getRevisions(ctx, istio)
+	if err != nil {
+		return nil, err
+	}
+	var installed *semver.Version
+	for _, rev := range revisions {
+		v, err := istioversion.Semver(r.ResourceDirectory, rev.Spec.Version)
+		if err != nil {
+			// the version is no longer shipped with the operator
+			log.Info("Cannot determine version of IstioRevision", "IstioRevision", rev.Name, "error", err)
+			continue
+		}
+		if installed == nil || v.GreaterThan(installed) {
+			installed = v
+		}
+	}
+	return installed, nil
+}
+
+func validateUpgrade(from, to *semver.Version, istio *v1alpha1.Istio, policy common.UpgradePolicy) error {
+	if to.LessThan(from) {
+		if istio.Annotations[v1alpha1.AllowDowngradeAnnotation] == "true" {
+			return nil
+		}
+		return &upgradePolicyError{
+			message: fmt.Sprintf("downgrading from %s to %s is not allowed; set the %s annotation to \"true\" to allow it",
+				from, to, v1alpha1.AllowDowngradeAnnotation),
+		}
+	}
+
+	if to.Major() != from.Major() || to.Minor() > from.Minor()+uint64(policy.MaxMinorVersionSkips)+1 {
+		return &upgradePolicyError{
+			message: fmt.Sprintf("upgrading from %s to %s is not allowed; an upgrade can skip at most %d minor version(s)",
+				from, to, policy.MaxMinorVersionSkips),
+		}
+	}
+
+	if to.Minor() != from.Minor() && !policy.AllowInPlaceMinorUpgrades && getUpdateStrategyType(istio) == v1alpha1.UpdateStrategyTypeInPlace {
+		return &upgradePolicyError{
+			message: fmt.Sprintf("upgrading from %s to %s in place is not allowed; use the %s update strategy to upgrade to a new minor version",
+				from, to, v1alpha1.UpdateStrategyTypeRevisionBased),
+		}
+	}
+	return nil
+}
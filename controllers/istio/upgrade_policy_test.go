@@ -0,0 +1,217 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package istio
+
+import (
+	"os"
+	"path"
+	"testing"
+
+	"github.com/Masterminds/semver/v3"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/kubectl/pkg/scheme"
+	"maistra.io/istio-operator/api/v1alpha1"
+	"maistra.io/istio-operator/pkg/common"
+	"maistra.io/istio-operator/pkg/test"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func TestValidateUpgrade(t *testing.T) {
+	defaultPolicy := common.UpgradePolicy{MaxMinorVersionSkips: 1}
+
+	testCases := []struct {
+		name        string
+		from        string
+		to          string
+		strategy    v1alpha1.UpdateStrategyType
+		annotations map[string]string
+		policy      common.UpgradePolicy
+		expectErr   bool
+	}{
+		{
+			name:     "patch upgrade in place",
+			from:     "1.20.1",
+			to:       "1.20.3",
+			strategy: v1alpha1.UpdateStrategyTypeInPlace,
+			policy:   defaultPolicy,
+		},
+		{
+			name:     "minor upgrade with RevisionBased strategy",
+			from:     "1.19.7",
+			to:       "1.20.3",
+			strategy: v1alpha1.UpdateStrategyTypeRevisionBased,
+			policy:   defaultPolicy,
+		},
+		{
+			name:     "upgrade skipping one minor version",
+			from:     "1.19.7",
+			to:       "1.21.0",
+			strategy: v1alpha1.UpdateStrategyTypeRevisionBased,
+			policy:   defaultPolicy,
+		},
+		{
+			name:      "upgrade skipping two minor versions",
+			from:      "1.19.7",
+			to:        "1.22.0-alpha.d27e3e16",
+			strategy:  v1alpha1.UpdateStrategyTypeRevisionBased,
+			policy:    defaultPolicy,
+			expectErr: true,
+		},
+		{
+			name:      "upgrade skipping one minor version when no skips are allowed",
+			from:      "1.19.7",
+			to:        "1.21.0",
+			strategy:  v1alpha1.UpdateStrategyTypeRevisionBased,
+			policy:    common.UpgradePolicy{MaxMinorVersionSkips: 0},
+			expectErr: true,
+		},
+		{
+			name:      "major upgrade",
+			from:      "1.20.3",
+			to:        "2.0.0",
+			strategy:  v1alpha1.UpdateStrategyTypeRevisionBased,
+			policy:    defaultPolicy,
+			expectErr: true,
+		},
+		{
+			name:      "minor upgrade in place",
+			from:      "1.19.7",
+			to:        "1.20.3",
+			strategy:  v1alpha1.UpdateStrategyTypeInPlace,
+			policy:    defaultPolicy,
+			expectErr: true,
+		},
+		{
+			name:     "minor upgrade in place when allowed by policy",
+			from:     "1.19.7",
+			to:       "1.20.3",
+			strategy: v1alpha1.UpdateStrategyTypeInPlace,
+			policy:   common.UpgradePolicy{MaxMinorVersionSkips: 1, AllowInPlaceMinorUpgrades: true},
+		},
+		{
+			name:      "patch downgrade",
+			from:      "1.20.3",
+			to:        "1.20.1",
+			strategy:  v1alpha1.UpdateStrategyTypeInPlace,
+			policy:    defaultPolicy,
+			expectErr: true,
+		},
+		{
+			name:      "minor downgrade",
+			from:      "1.20.3",
+			to:        "1.19.7",
+			strategy:  v1alpha1.UpdateStrategyTypeRevisionBased,
+			policy:    defaultPolicy,
+			expectErr: true,
+		},
+		{
+			name:        "downgrade with override annotation",
+			from:        "1.20.3",
+			to:          "1.19.7",
+			strategy:    v1alpha1.UpdateStrategyTypeRevisionBased,
+			annotations: map[string]string{v1alpha1.AllowDowngradeAnnotation: "true"},
+			policy:      defaultPolicy,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			istio := &v1alpha1.Istio{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:        istioName,
+					Annotations: tc.annotations,
+				},
+				Spec: v1alpha1.IstioSpec{
+					UpdateStrategy: &v1alpha1.IstioUpdateStrategy{
+						Type: tc.strategy,
+					},
+				},
+			}
+			err := validateUpgrade(semver.MustParse(tc.from), semver.MustParse(tc.to), istio, tc.policy)
+			if tc.expectErr {
+				if _, ok := err.(*upgradePolicyError); !ok {
+					t.Errorf("expected upgradePolicyError, but got: %v", err)
+				}
+			} else if err != nil {
+				t.Errorf("expected no error, but got: %v", err)
+			}
+		})
+	}
+}
+
+func TestReconcileRejectsUpgradePolicyViolation(t *testing.T) {
+	test.SetupScheme()
+	resourceDir := t.TempDir()
+	for _, version := range []string{"v1.19.7", "v1.20.3"} {
+		Must(t, os.MkdirAll(path.Join(resourceDir, version), 0o755))
+	}
+
+	istio := &v1alpha1.Istio{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: istioName,
+			UID:  istioUID,
+		},
+		Spec: v1alpha1.IstioSpec{
+			Version:   "v1.20.3",
+			Namespace: istioNamespace,
+		},
+		Status: v1alpha1.IstioStatus{
+			ActiveRevisionName: istioName,
+			ResolvedVersion:    "v1.19.7",
+		},
+	}
+	rev := &v1alpha1.IstioRevision{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: istioName,
+			OwnerReferences: []metav1.OwnerReference{
+				{APIVersion: v1alpha1.GroupVersion.String(), Kind: v1alpha1.IstioKind, Name: istioName, UID: istioUID},
+			},
+		},
+		Spec: v1alpha1.IstioRevisionSpec{
+			Version:   "v1.19.7",
+			Namespace: istioNamespace,
+		},
+	}
+
+	cl := newFakeClientBuilder().
+		WithStatusSubresource(&v1alpha1.Istio{}).
+		WithObjects(istio, rev).
+		Build()
+	reconciler := NewIstioReconciler(cl, scheme.Scheme, resourceDir, nil, "", nil)
+
+	_, err := reconciler.Reconcile(ctx, ctrl.Request{NamespacedName: istioKey})
+	if err != nil {
+		t.Errorf("Expected no error, but got: %v", err)
+	}
+
+	Must(t, cl.Get(ctx, client.ObjectKeyFromObject(rev), rev))
+	if rev.Spec.Version != "v1.19.7" {
+		t.Errorf("Expected IstioRevision to remain at version %q, but got %q", "v1.19.7", rev.Spec.Version)
+	}
+
+	Must(t, cl.Get(ctx, istioKey, istio))
+	if istio.Status.State != v1alpha1.IstioConditionReasonUpgradePolicyViolation {
+		t.Errorf("Expected status.state to be %q, but got %q", v1alpha1.IstioConditionReasonUpgradePolicyViolation, istio.Status.State)
+	}
+	reconciledCond := istio.Status.GetCondition(v1alpha1.IstioConditionTypeReconciled)
+	if reconciledCond.Status != metav1.ConditionFalse || reconciledCond.Reason != v1alpha1.IstioConditionReasonUpgradePolicyViolation {
+		t.Errorf("Expected Reconciled condition to be False with reason %q, but got %q with reason %q",
+			v1alpha1.IstioConditionReasonUpgradePolicyViolation, reconciledCond.Status, reconciledCond.Reason)
+	}
+	if istio.Status.ResolvedVersion != "v1.19.7" {
+		t.Errorf("Expected status.resolvedVersion to remain %q, but got %q", "v1.19.7", istio.Status.ResolvedVersion)
+	}
+}
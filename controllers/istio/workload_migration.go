@@ -0,0 +1,292 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package istio
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"maistra.io/istio-operator/api/v1alpha1"
+	"maistra.io/istio-operator/controllers/istiorevision"
+	"maistra.io/istio-operator/pkg/common"
+	"maistra.io/istio-operator/pkg/kube"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+
+	"istio.io/istio/pkg/util/sets"
+)
+
+// RestartedForRevisionAnnotation is set on the pod template of each workload that the operator
+// restarts to move it to a new revision. The value is the name of the target revision.
+const RestartedForRevisionAnnotation = common.MetadataNamespace + "/restarted-for-revision"
+
+// workloadMigrationCheckInterval defines how often the operator checks the progress of the
+// workloads it restarted
+const workloadMigrationCheckInterval = 10 * time.Second
+
+// deploymentProgressDeadlineExceededReason is the reason of a Deployment's Progressing condition when its
+// rollout didn't progress within spec.progressDeadlineSeconds
+const deploymentProgressDeadlineExceededReason = "ProgressDeadlineExceeded"
+
+// workloadMigration describes what still needs to be done to move all workloads to the active revision
+type workloadMigration struct {
+	targetRevision    string
+	targetReady       bool
+	namespaces        []metav1.PartialObjectMetadata // namespaces that reference an old revision and must be relabeled
+	targetNamespaces  int32                          // namespaces that already reference the target revision
+	pendingWorkloads  []appsv1.Deployment            // workloads that run on an old revision and haven't been restarted yet
+	updatingWorkloads []appsv1.Deployment            // workloads that were restarted, but whose rollout isn't complete
+	updatedWorkloads  int32                          // workloads that were restarted and whose rollout is complete
+	failedWorkloads   int32                          // workloads that were restarted, but whose rollout exceeded its deadline
+}
+
+func (m *workloadMigration) status() *v1alpha1.WorkloadMigrationStatus {
+	status := &v1alpha1.WorkloadMigrationStatus{
+		TargetRevision:    m.targetRevision,
+		Namespaces:        m.targetNamespaces,
+		TotalWorkloads:    int32(len(m.pendingWorkloads)+len(m.updatingWorkloads)) + m.updatedWorkloads + m.failedWorkloads,
+		UpdatedWorkloads:  m.updatedWorkloads,
+		UpdatingWorkloads: int32(len(m.updatingWorkloads)),
+		FailedWorkloads:   m.failedWorkloads,
+	}
+	switch {
+	case !m.targetReady:
+		status.State = v1alpha1.WorkloadMigrationStatePending
+	case m.done() && m.failedWorkloads > 0:
+		status.State = v1alpha1.WorkloadMigrationStateFailed
+	case m.done():
+		status.State = v1alpha1.WorkloadMigrationStateCompleted
+	default:
+		status.State = v1alpha1.WorkloadMigrationStateInProgress
+	}
+	return status
+}
+
+func (m *workloadMigration) done() bool {
+	return len(m.namespaces) == 0 && len(m.pendingWorkloads) == 0 && len(m.updatingWorkloads) == 0
+}
+
+// migrateWorkloads moves the workloads from the inactive revisions to the active revision when
+// spec.updateStrategy.updateWorkloads is true. It relabels namespaces that reference an inactive
+// revision and then restarts the injected Deployments in those namespaces in batches, waiting for
+// each batch to be rolled out before restarting the next one. A Deployment whose rollout exceeds its
+// progress deadline is counted as failed and doesn't hold back the next batch.
+// The returned migration reflects the changes made, so that it can be reported in the status without
+// planning the migration again. It's nil if the migration is disabled or there's nothing to migrate.
+func (r *IstioReconciler) migrateWorkloads(ctx context.Context, istio *v1alpha1.Istio) (ctrl.Result, *workloadMigration, error) {
+	log := logf.FromContext(ctx)
+	if !isWorkloadMigrationEnabled(istio) {
+		return ctrl.Result{}, nil, nil
+	}
+
+	migration, err := r.planWorkloadMigration(ctx, istio)
+	if err != nil || migration == nil {
+		return ctrl.Result{}, nil, err
+	}
+	if !migration.targetReady {
+		log.V(2).Info("Active IstioRevision not ready; postponing workload migration", "IstioRevision", migration.targetRevision)
+		return ctrl.Result{}, migration, nil
+	}
+	if migration.done() {
+		log.V(2).Info("All workloads use the active IstioRevision", "IstioRevision", migration.targetRevision)
+		return ctrl.Result{}, migration, nil
+	}
+
+	for _, ns := range migration.namespaces {
+		log.Info("Moving Namespace to active IstioRevision", "Namespace", ns.Name, "IstioRevision", migration.targetRevision)
+		// the items of a metadata list don't necessarily carry their kind, which the client needs to patch them
+		ns.SetGroupVersionKind(kube.NamespaceGVK)
+		patch := client.MergeFrom(ns.DeepCopy())
+		ns.Labels[istiorevision.IstioRevLabel] = migration.targetRevision
+		if err := r.Client.Patch(ctx, &ns, patch); err != nil {
+			return ctrl.Result{}, nil, err
+		}
+		migration.targetNamespaces++
+	}
+	migration.namespaces = nil
+
+	// we only restart the next batch once all the pods of the previous batch are ready
+	if len(migration.updatingWorkloads) == 0 {
+		batchSize := min(getUpdateWorkloadsBatchSize(istio), len(migration.pendingWorkloads))
+		for i := 0; i < batchSize; i++ {
+			deployment := migration.pendingWorkloads[i]
+			log.Info("Restarting Deployment to move it to active IstioRevision",
+				"Deployment", client.ObjectKeyFromObject(&deployment), "IstioRevision", migration.targetRevision)
+			patch := client.MergeFrom(deployment.DeepCopy())
+			if deployment.Spec.Template.Annotations == nil {
+				deployment.Spec.Template.Annotations = map[string]string{}
+			}
+			deployment.Spec.Template.Annotations[RestartedForRevisionAnnotation] = migration.targetRevision
+			if err := r.Client.Patch(ctx, &deployment, patch); err != nil {
+				return ctrl.Result{}, nil, err
+			}
+			migration.updatingWorkloads = append(migration.updatingWorkloads, deployment)
+		}
+		migration.pendingWorkloads = migration.pendingWorkloads[batchSize:]
+	}
+
+	// the Istio controller doesn't watch Deployments, so we need to requeue to check the rollout progress
+	return ctrl.Result{RequeueAfter: workloadMigrationCheckInterval}, migration, nil
+}
+
+// planWorkloadMigration determines which namespaces and workloads must be moved to the active revision.
+// It returns nil if the Istio has no inactive revisions.
+func (r *IstioReconciler) planWorkloadMigration(ctx context.Context, istio *v1alpha1.Istio) (*workloadMigration, error) {
+	revisions, err := r.getRevisions(ctx, istio)
+	if err != nil {
+		return nil, err
+	}
+
+	migration := &workloadMigration{
+		targetRevision: getActiveRevisionName(istio),
+	}
+	oldRevisions := sets.New[string]()
+	for _, rev := range revisions {
+		if isActiveRevision(istio, &rev) {
+			migration.targetReady = rev.Status.GetCondition(v1alpha1.IstioRevisionConditionTypeReady).Status == metav1.ConditionTrue
+		} else {
+			oldRevisions.Insert(rev.Name)
+		}
+	}
+
+	nsList := kube.NewMetadataList(kube.NamespaceGVK)
+	if err := r.Client.List(ctx, nsList); err != nil {
+		return nil, err
+	}
+	migratedNamespaces := sets.New[string]()
+	for _, ns := range nsList.Items {
+		// namespaces labeled with istio-injection=enabled always use the default revision, so we can't move them
+		if ns.Labels[istiorevision.IstioInjectionLabel] == istiorevision.IstioInjectionEnabledValue {
+			continue
+		}
+		switch revision := ns.Labels[istiorevision.IstioRevLabel]; {
+		case revision == migration.targetRevision:
+			migration.targetNamespaces++
+			migratedNamespaces.Insert(ns.Name)
+		case oldRevisions.Contains(revision):
+			migration.namespaces = append(migration.namespaces, ns)
+			migratedNamespaces.Insert(ns.Name)
+		}
+	}
+
+	for _, ns := range sets.SortedList(migratedNamespaces) {
+		deployments := appsv1.DeploymentList{}
+		if err := r.Client.List(ctx, &deployments, client.InNamespace(ns)); err != nil {
+			return nil, err
+		}
+		for _, deployment := range deployments.Items {
+			// workloads that explicitly reference a revision in their pod template are left alone
+			if deployment.Spec.Template.Labels[istiorevision.IstioRevLabel] != "" {
+				continue
+			}
+
+			if deployment.Spec.Template.Annotations[RestartedForRevisionAnnotation] == migration.targetRevision {
+				if isRolloutComplete(&deployment) {
+					migration.updatedWorkloads++
+				} else if isRolloutFailed(&deployment) {
+					migration.failedWorkloads++
+				} else {
+					migration.updatingWorkloads = append(migration.updatingWorkloads, deployment)
+				}
+				continue
+			}
+
+			runsOnOldRevision, err := r.hasPodsInjectedByRevision(ctx, &deployment, oldRevisions)
+			if err != nil {
+				return nil, err
+			}
+			if runsOnOldRevision {
+				migration.pendingWorkloads = append(migration.pendingWorkloads, deployment)
+			}
+		}
+	}
+
+	if len(oldRevisions) == 0 && migration.updatedWorkloads == 0 && len(migration.updatingWorkloads) == 0 {
+		return nil, nil
+	}
+
+	sort.Slice(migration.pendingWorkloads, func(i, j int) bool {
+		a, b := migration.pendingWorkloads[i], migration.pendingWorkloads[j]
+		if a.Namespace != b.Namespace {
+			return a.Namespace < b.Namespace
+		}
+		return a.Name < b.Name
+	})
+	return migration, nil
+}
+
+func (r *IstioReconciler) hasPodsInjectedByRevision(ctx context.Context, deployment *appsv1.Deployment, revisions sets.String) (bool, error) {
+	selector, err := metav1.LabelSelectorAsSelector(deployment.Spec.Selector)
+	if err != nil {
+		return false, err
+	}
+	pods := kube.NewMetadataList(kube.PodGVK)
+	if err := r.Client.List(ctx, pods, client.InNamespace(deployment.Namespace), client.MatchingLabelsSelector{Selector: selector}); err != nil {
+		return false, err
+	}
+	for _, pod := range pods.Items {
+		// the istio.io/rev annotation is set by the injector and contains the revision that injected the pod
+		if revisions.Contains(pod.Annotations[istiorevision.IstioRevLabel]) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func isRolloutComplete(deployment *appsv1.Deployment) bool {
+	replicas := int32(1)
+	if deployment.Spec.Replicas != nil {
+		replicas = *deployment.Spec.Replicas
+	}
+	status := deployment.Status
+	return status.ObservedGeneration >= deployment.Generation &&
+		status.UpdatedReplicas == replicas &&
+		status.Replicas == replicas &&
+		status.AvailableReplicas == replicas
+}
+
+// isRolloutFailed returns whether the Deployment's rollout didn't progress within spec.progressDeadlineSeconds
+func isRolloutFailed(deployment *appsv1.Deployment) bool {
+	if deployment.Status.ObservedGeneration < deployment.Generation {
+		return false
+	}
+	for _, condition := range deployment.Status.Conditions {
+		if condition.Type == appsv1.DeploymentProgressing {
+			return condition.Status == corev1.ConditionFalse && condition.Reason == deploymentProgressDeadlineExceededReason
+		}
+	}
+	return false
+}
+
+func isWorkloadMigrationEnabled(istio *v1alpha1.Istio) bool {
+	strategy := istio.Spec.UpdateStrategy
+	return strategy != nil && strategy.Type == v1alpha1.UpdateStrategyTypeRevisionBased && strategy.UpdateWorkloads
+}
+
+func getUpdateWorkloadsBatchSize(istio *v1alpha1.Istio) int {
+	batchSize := int32(v1alpha1.DefaultUpdateWorkloadsBatchSize)
+	if istio.Spec.UpdateStrategy != nil && istio.Spec.UpdateStrategy.UpdateWorkloadsBatchSize != nil {
+		batchSize = *istio.Spec.UpdateStrategy.UpdateWorkloadsBatchSize
+	}
+	if batchSize < 1 {
+		batchSize = 1
+	}
+	return int(batchSize)
+}
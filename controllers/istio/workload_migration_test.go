@@ -0,0 +1,408 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package istio
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/kubectl/pkg/scheme"
+	"maistra.io/istio-operator/api/v1alpha1"
+	"maistra.io/istio-operator/pkg/test"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"istio.io/istio/pkg/ptr"
+)
+
+func TestMigrateWorkloads(t *testing.T) {
+	test.SetupScheme()
+	resourceDir := t.TempDir()
+
+	oldRevision := istioName + "-v1-19-6"
+	activeRevision := istioName + "-v1-20-3"
+
+	ownedByIstio := metav1.OwnerReference{
+		APIVersion:         v1alpha1.GroupVersion.String(),
+		Kind:               v1alpha1.IstioKind,
+		Name:               istioName,
+		UID:                istioUID,
+		Controller:         ptr.Of(true),
+		BlockOwnerDeletion: ptr.Of(true),
+	}
+
+	newIstio := func(updateWorkloads bool) *v1alpha1.Istio {
+		return &v1alpha1.Istio{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: istioName,
+				UID:  istioUID,
+			},
+			Spec: v1alpha1.IstioSpec{
+				Version:   "v1.20.3",
+				Namespace: istioNamespace,
+				UpdateStrategy: &v1alpha1.IstioUpdateStrategy{
+					Type:            v1alpha1.UpdateStrategyTypeRevisionBased,
+					UpdateWorkloads: updateWorkloads,
+				},
+			},
+		}
+	}
+
+	newRevision := func(name string, ready bool) *v1alpha1.IstioRevision {
+		return &v1alpha1.IstioRevision{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:            name,
+				OwnerReferences: []metav1.OwnerReference{ownedByIstio},
+			},
+			Status: v1alpha1.IstioRevisionStatus{
+				Conditions: []v1alpha1.IstioRevisionCondition{
+					{Type: v1alpha1.IstioRevisionConditionTypeReady, Status: toConditionStatus(ready)},
+				},
+			},
+		}
+	}
+
+	newNamespace := func(name, revision string) *corev1.Namespace {
+		return &corev1.Namespace{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:   name,
+				Labels: map[string]string{"istio.io/rev": revision},
+			},
+		}
+	}
+
+	newDeployment := func(ns, name string) *appsv1.Deployment {
+		return &appsv1.Deployment{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      name,
+				Namespace: ns,
+			},
+			Spec: appsv1.DeploymentSpec{
+				Replicas: ptr.Of(int32(1)),
+				Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": name}},
+				Template: corev1.PodTemplateSpec{
+					ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{"app": name}},
+				},
+			},
+		}
+	}
+
+	newPod := func(ns, app, revision string) *corev1.Pod {
+		return &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        app + "-pod",
+				Namespace:   ns,
+				Labels:      map[string]string{"app": app},
+				Annotations: map[string]string{"istio.io/rev": revision},
+			},
+		}
+	}
+
+	restarted := func(deployment *appsv1.Deployment, rolledOut bool) *appsv1.Deployment {
+		deployment.Spec.Template.Annotations = map[string]string{RestartedForRevisionAnnotation: activeRevision}
+		if rolledOut {
+			deployment.Status = appsv1.DeploymentStatus{Replicas: 1, UpdatedReplicas: 1, AvailableReplicas: 1}
+		}
+		return deployment
+	}
+
+	progressDeadlineExceeded := func(deployment *appsv1.Deployment) *appsv1.Deployment {
+		deployment.Status.Conditions = []appsv1.DeploymentCondition{
+			{Type: appsv1.DeploymentProgressing, Status: corev1.ConditionFalse, Reason: "ProgressDeadlineExceeded"},
+		}
+		return deployment
+	}
+
+	testCases := []struct {
+		name                  string
+		istio                 *v1alpha1.Istio
+		objects               []client.Object
+		expectRequeue         bool
+		expectNamespaceLabels map[string]string
+		expectRestarted       []string
+		expectStatus          *v1alpha1.WorkloadMigrationStatus
+	}{
+		{
+			name:  "does nothing when updateWorkloads is false",
+			istio: newIstio(false),
+			objects: []client.Object{
+				newRevision(oldRevision, true),
+				newRevision(activeRevision, true),
+				newNamespace("ns1", oldRevision),
+				newDeployment("ns1", "app1"),
+				newPod("ns1", "app1", oldRevision),
+			},
+			expectNamespaceLabels: map[string]string{"ns1": oldRevision},
+		},
+		{
+			name:  "waits for active revision to be ready",
+			istio: newIstio(true),
+			objects: []client.Object{
+				newRevision(oldRevision, true),
+				newRevision(activeRevision, false),
+				newNamespace("ns1", oldRevision),
+				newDeployment("ns1", "app1"),
+				newPod("ns1", "app1", oldRevision),
+			},
+			expectNamespaceLabels: map[string]string{"ns1": oldRevision},
+			expectStatus: &v1alpha1.WorkloadMigrationStatus{
+				TargetRevision: activeRevision,
+				State:          v1alpha1.WorkloadMigrationStatePending,
+				TotalWorkloads: 1,
+			},
+		},
+		{
+			name:  "relabels namespaces and restarts first batch",
+			istio: newIstio(true),
+			objects: []client.Object{
+				newRevision(oldRevision, true),
+				newRevision(activeRevision, true),
+				newNamespace("ns1", oldRevision),
+				newNamespace("ns2", oldRevision),
+				newNamespace("unrelated", "some-other-revision"),
+				newDeployment("ns1", "app1"),
+				newPod("ns1", "app1", oldRevision),
+				newDeployment("ns2", "app2"),
+				newPod("ns2", "app2", oldRevision),
+				newDeployment("unrelated", "app3"),
+				newPod("unrelated", "app3", "some-other-revision"),
+			},
+			expectRequeue:         true,
+			expectNamespaceLabels: map[string]string{"ns1": activeRevision, "ns2": activeRevision, "unrelated": "some-other-revision"},
+			expectRestarted:       []string{"ns1/app1"},
+			expectStatus: &v1alpha1.WorkloadMigrationStatus{
+				TargetRevision:    activeRevision,
+				State:             v1alpha1.WorkloadMigrationStateInProgress,
+				Namespaces:        2,
+				TotalWorkloads:    2,
+				UpdatingWorkloads: 1,
+			},
+		},
+		{
+			name:  "waits for previous batch to roll out",
+			istio: newIstio(true),
+			objects: []client.Object{
+				newRevision(oldRevision, true),
+				newRevision(activeRevision, true),
+				newNamespace("ns1", activeRevision),
+				restarted(newDeployment("ns1", "app1"), false),
+				newPod("ns1", "app1", oldRevision),
+				newDeployment("ns1", "app2"),
+				newPod("ns1", "app2", oldRevision),
+			},
+			expectRequeue:         true,
+			expectNamespaceLabels: map[string]string{"ns1": activeRevision},
+			expectRestarted:       []string{"ns1/app1"},
+			expectStatus: &v1alpha1.WorkloadMigrationStatus{
+				TargetRevision:    activeRevision,
+				State:             v1alpha1.WorkloadMigrationStateInProgress,
+				Namespaces:        1,
+				TotalWorkloads:    2,
+				UpdatingWorkloads: 1,
+			},
+		},
+		{
+			name:  "restarts next batch after previous batch is ready",
+			istio: newIstio(true),
+			objects: []client.Object{
+				newRevision(oldRevision, true),
+				newRevision(activeRevision, true),
+				newNamespace("ns1", activeRevision),
+				restarted(newDeployment("ns1", "app1"), true),
+				newPod("ns1", "app1", activeRevision),
+				newDeployment("ns1", "app2"),
+				newPod("ns1", "app2", oldRevision),
+			},
+			expectRequeue:         true,
+			expectNamespaceLabels: map[string]string{"ns1": activeRevision},
+			expectRestarted:       []string{"ns1/app1", "ns1/app2"},
+			expectStatus: &v1alpha1.WorkloadMigrationStatus{
+				TargetRevision:    activeRevision,
+				State:             v1alpha1.WorkloadMigrationStateInProgress,
+				Namespaces:        1,
+				TotalWorkloads:    2,
+				UpdatedWorkloads:  1,
+				UpdatingWorkloads: 1,
+			},
+		},
+		{
+			name:  "restarts next batch when previous batch exceeded its progress deadline",
+			istio: newIstio(true),
+			objects: []client.Object{
+				newRevision(oldRevision, true),
+				newRevision(activeRevision, true),
+				newNamespace("ns1", activeRevision),
+				progressDeadlineExceeded(restarted(newDeployment("ns1", "app1"), false)),
+				newPod("ns1", "app1", oldRevision),
+				newDeployment("ns1", "app2"),
+				newPod("ns1", "app2", oldRevision),
+			},
+			expectRequeue:         true,
+			expectNamespaceLabels: map[string]string{"ns1": activeRevision},
+			expectRestarted:       []string{"ns1/app1", "ns1/app2"},
+			expectStatus: &v1alpha1.WorkloadMigrationStatus{
+				TargetRevision:    activeRevision,
+				State:             v1alpha1.WorkloadMigrationStateInProgress,
+				Namespaces:        1,
+				TotalWorkloads:    2,
+				UpdatingWorkloads: 1,
+				FailedWorkloads:   1,
+			},
+		},
+		{
+			name:  "reports failed workloads when all other workloads are rolled out",
+			istio: newIstio(true),
+			objects: []client.Object{
+				newRevision(oldRevision, true),
+				newRevision(activeRevision, true),
+				newNamespace("ns1", activeRevision),
+				progressDeadlineExceeded(restarted(newDeployment("ns1", "app1"), false)),
+				newPod("ns1", "app1", oldRevision),
+				restarted(newDeployment("ns1", "app2"), true),
+				newPod("ns1", "app2", activeRevision),
+			},
+			expectNamespaceLabels: map[string]string{"ns1": activeRevision},
+			expectRestarted:       []string{"ns1/app1", "ns1/app2"},
+			expectStatus: &v1alpha1.WorkloadMigrationStatus{
+				TargetRevision:   activeRevision,
+				State:            v1alpha1.WorkloadMigrationStateFailed,
+				Namespaces:       1,
+				TotalWorkloads:   2,
+				UpdatedWorkloads: 1,
+				FailedWorkloads:  1,
+			},
+		},
+		{
+			name:  "completes when all workloads are rolled out",
+			istio: newIstio(true),
+			objects: []client.Object{
+				newRevision(oldRevision, true),
+				newRevision(activeRevision, true),
+				newNamespace("ns1", activeRevision),
+				restarted(newDeployment("ns1", "app1"), true),
+				newPod("ns1", "app1", activeRevision),
+			},
+			expectNamespaceLabels: map[string]string{"ns1": activeRevision},
+			expectRestarted:       []string{"ns1/app1"},
+			expectStatus: &v1alpha1.WorkloadMigrationStatus{
+				TargetRevision:   activeRevision,
+				State:            v1alpha1.WorkloadMigrationStateCompleted,
+				Namespaces:       1,
+				TotalWorkloads:   1,
+				UpdatedWorkloads: 1,
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			cl := newFakeClientBuilder().
+				WithObjects(append(tc.objects, tc.istio)...).
+				Build()
+			reconciler := NewIstioReconciler(cl, scheme.Scheme, resourceDir, nil, "", nil)
+
+			result, migration, err := reconciler.migrateWorkloads(ctx, tc.istio)
+			if err != nil {
+				t.Fatalf("migrateWorkloads() returned unexpected error: %v", err)
+			}
+			if (result.RequeueAfter > 0) != tc.expectRequeue {
+				t.Errorf("expected requeue to be %v, but got RequeueAfter %v", tc.expectRequeue, result.RequeueAfter)
+			}
+
+			for ns, expectedRev := range tc.expectNamespaceLabels {
+				namespace := corev1.Namespace{}
+				Must(t, cl.Get(ctx, client.ObjectKey{Name: ns}, &namespace))
+				if actual := namespace.Labels["istio.io/rev"]; actual != expectedRev {
+					t.Errorf("expected Namespace %s to reference revision %q, but got %q", ns, expectedRev, actual)
+				}
+			}
+
+			deployments := appsv1.DeploymentList{}
+			Must(t, cl.List(ctx, &deployments))
+			var actualRestarted []string
+			for _, deployment := range deployments.Items {
+				if deployment.Spec.Template.Annotations[RestartedForRevisionAnnotation] == activeRevision {
+					actualRestarted = append(actualRestarted, deployment.Namespace+"/"+deployment.Name)
+				}
+			}
+			if diff := cmp.Diff(tc.expectRestarted, actualRestarted); diff != "" {
+				t.Errorf("unexpected restarted Deployments; diff (-expected, +actual):\n%v", diff)
+			}
+
+			// the returned migration must reflect the changes, so that it matches a migration planned afterwards
+			var actualStatus, plannedStatus *v1alpha1.WorkloadMigrationStatus
+			if migration != nil {
+				actualStatus = migration.status()
+			}
+			if isWorkloadMigrationEnabled(tc.istio) {
+				planned, err := reconciler.planWorkloadMigration(ctx, tc.istio)
+				Must(t, err)
+				if planned != nil {
+					plannedStatus = planned.status()
+				}
+			}
+			if diff := cmp.Diff(tc.expectStatus, actualStatus); diff != "" {
+				t.Errorf("unexpected migration status; diff (-expected, +actual):\n%v", diff)
+			}
+			if diff := cmp.Diff(plannedStatus, actualStatus); diff != "" {
+				t.Errorf("returned migration doesn't match the planned migration; diff (-planned, +returned):\n%v", diff)
+			}
+		})
+	}
+}
+
+func TestGetUpdateWorkloadsBatchSize(t *testing.T) {
+	tests := []struct {
+		name           string
+		updateStrategy *v1alpha1.IstioUpdateStrategy
+		expected       int
+	}{
+		{
+			name:           "nil update strategy",
+			updateStrategy: nil,
+			expected:       v1alpha1.DefaultUpdateWorkloadsBatchSize,
+		},
+		{
+			name:           "nil batch size",
+			updateStrategy: &v1alpha1.IstioUpdateStrategy{},
+			expected:       v1alpha1.DefaultUpdateWorkloadsBatchSize,
+		},
+		{
+			name:           "batch size less than minimum",
+			updateStrategy: &v1alpha1.IstioUpdateStrategy{UpdateWorkloadsBatchSize: ptr.Of(int32(0))},
+			expected:       1,
+		},
+		{
+			name:           "custom batch size",
+			updateStrategy: &v1alpha1.IstioUpdateStrategy{UpdateWorkloadsBatchSize: ptr.Of(int32(5))},
+			expected:       5,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			istio := &v1alpha1.Istio{
+				Spec: v1alpha1.IstioSpec{
+					UpdateStrategy: tt.updateStrategy,
+				},
+			}
+			if got := getUpdateWorkloadsBatchSize(istio); got != tt.expected {
+				t.Errorf("getUpdateWorkloadsBatchSize() = %v, want %v", got, tt.expected)
+			}
+		})
+	}
+}
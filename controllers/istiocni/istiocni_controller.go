@@ -0,0 +1,365 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package istiocni
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"reflect"
+	"slices"
+
+	"github.com/go-logr/logr"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/client-go/rest"
+	"maistra.io/istio-operator/api/v1alpha1"
+	"maistra.io/istio-operator/pkg/common"
+	"maistra.io/istio-operator/pkg/helm"
+	"maistra.io/istio-operator/pkg/istiovalues"
+	"maistra.io/istio-operator/pkg/kube"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	"istio.io/istio/pkg/ptr"
+)
+
+const (
+	cniReleaseNameBase = "istio"
+	cniDaemonSetName   = "istio-cni-node"
+)
+
+// charts to deploy in the operator namespace
+var cniCharts = []string{"cni"}
+
+// IstioCNIReconciler reconciles an IstioCNI object
+type IstioCNIReconciler struct {
+	ResourceDirectory string
+	DefaultProfiles   []string
+	Namespace         string
+	RestClientGetter  genericclioptions.RESTClientGetter
+	client.Client
+	Scheme *runtime.Scheme
+}
+
+func NewIstioCNIReconciler(
+	client client.Client, scheme *runtime.Scheme, restConfig *rest.Config, resourceDir string, defaultProfiles []string, namespace string,
+) *IstioCNIReconciler {
+	return &IstioCNIReconciler{
+		ResourceDirectory: resourceDir,
+		DefaultProfiles:   defaultProfiles,
+		Namespace:         namespace,
+		RestClientGetter:  helm.NewRESTClientGetter(restConfig),
+		Client:            client,
+		Scheme:            scheme,
+	}
+}
+
+// +kubebuilder:rbac:groups=operator.istio.io,resources=istiocnis,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=operator.istio.io,resources=istiocnis/status,verbs=get;update;patch
+// +kubebuilder:rbac:groups=operator.istio.io,resources=istiocnis/finalizers,verbs=update
+
+// Reconcile is part of the main kubernetes reconciliation loop which aims to
+// move the current state of the cluster closer to the desired state.
+func (r *IstioCNIReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	log := logf.FromContext(ctx)
+	var cni v1alpha1.IstioCNI
+	if err := r.Client.Get(ctx, req.NamespacedName, &cni); err != nil {
+		if errors.IsNotFound(err) {
+			log.V(2).Info("IstioCNI not found. Skipping reconciliation")
+			return ctrl.Result{}, nil
+		}
+		return ctrl.Result{}, err
+	}
+
+	if cni.DeletionTimestamp != nil {
+		if err := r.uninstallHelmCharts(ctx); err != nil {
+			return ctrl.Result{}, err
+		}
+
+		if err := kube.RemoveFinalizer(ctx, &cni, r.Client); err != nil {
+			log.Info("failed to remove finalizer")
+			return ctrl.Result{}, err
+		}
+		return ctrl.Result{}, nil
+	}
+
+	if !kube.HasFinalizer(&cni) {
+		err := kube.AddFinalizer(ctx, &cni, r.Client)
+		if err != nil {
+			log.Info("failed to add finalizer")
+			return ctrl.Result{}, err
+		}
+	}
+
+	var warnings []string
+	err := validateIstioCNI(cni)
+	if err == nil {
+		log.Info("Installing components")
+		warnings, err = r.installHelmCharts(ctx, &cni)
+	}
+
+	log.Info("Reconciliation done. Updating status.")
+	err = r.updateStatus(ctx, &cni, warnings, err)
+
+	return ctrl.Result{}, err
+}
+
+func validateIstioCNI(cni v1alpha1.IstioCNI) error {
+	if cni.Name != v1alpha1.IstioCNIName {
+		return fmt.Errorf("metadata.name must be %q", v1alpha1.IstioCNIName)
+	}
+	if cni.Spec.Version == "" {
+		return fmt.Errorf("spec.version not set")
+	}
+	return nil
+}
+
+// installHelmCharts installs or upgrades the CNI chart and returns the fields of the applied profiles that
+// couldn't be converted to helm values
+func (r *IstioCNIReconciler) installHelmCharts(ctx context.Context, cni *v1alpha1.IstioCNI) ([]string, error) {
+	ownerReference := metav1.OwnerReference{
+		APIVersion:         v1alpha1.GroupVersion.String(),
+		Kind:               v1alpha1.IstioCNIKind,
+		Name:               cni.Name,
+		UID:                cni.UID,
+		Controller:         ptr.Of(true),
+		BlockOwnerDeletion: ptr.Of(true),
+	}
+
+	// user-defined profiles are read from the ConfigMaps in the operator namespace
+	profileConfigMaps, err := istiovalues.ListProfileConfigMaps(ctx, r.Client, r.Namespace, cni.Spec.Version)
+	if err != nil {
+		return nil, err
+	}
+
+	values, warnings, err := computeHelmValues(cni, r.DefaultProfiles, r.ResourceDirectory, profileConfigMaps)
+	if err != nil {
+		return nil, err
+	}
+
+	return warnings, helm.UpgradeOrInstallCharts(ctx, r.RestClientGetter, cniCharts, values,
+		cni.Spec.Version, cniReleaseNameBase, r.Namespace, ownerReference, helm.DefaultMaxHistory)
+}
+
+func (r *IstioCNIReconciler) uninstallHelmCharts(ctx context.Context) error {
+	return helm.UninstallCharts(ctx, r.RestClientGetter, cniCharts, cniReleaseNameBase, r.Namespace)
+}
+
+// computeHelmValues returns the values for the CNI chart, along with the fields of the applied profiles
+// that couldn't be converted to helm values
+func computeHelmValues(cni *v1alpha1.IstioCNI, defaultProfiles []string, resourceDir string, profileConfigMaps []corev1.ConfigMap,
+) (helm.HelmValues, []string, error) {
+	// apply image digests from configuration, if not already set by user
+	userValues := applyImageDigests(cni, cni.Spec.Values, common.Config)
+
+	// apply userValues on top of defaultValues from profiles
+	profiles, err := istiovalues.MergeProfiles(getProfilesDir(resourceDir, cni), getProfiles(cni, defaultProfiles), profileConfigMaps)
+	if err != nil {
+		return nil, nil, err
+	}
+	return istiovalues.MergeOverwrite(profiles.Values, userValues.ToHelmValues()), profiles.Warnings, nil
+}
+
+func getProfiles(cni *v1alpha1.IstioCNI, defaultProfiles []string) []string {
+	if cni.Spec.Profile == "" {
+		return defaultProfiles
+	}
+	return append(slices.Clone(defaultProfiles), cni.Spec.Profile)
+}
+
+func getProfilesDir(resourceDir string, cni *v1alpha1.IstioCNI) string {
+	return path.Join(resourceDir, cni.Spec.Version, "profiles")
+}
+
+func applyImageDigests(cni *v1alpha1.IstioCNI, values *v1alpha1.CNIValues, config common.OperatorConfig) *v1alpha1.CNIValues {
+	imageDigests, digestsDefined := config.ImageDigests[cni.Spec.Version]
+	// if we don't have default image digests defined for this version, it's a no-op
+	if !digestsDefined {
+		return values
+	}
+
+	if values == nil {
+		values = &v1alpha1.CNIValues{}
+	} else {
+		values = values.DeepCopy()
+	}
+
+	// set image digest unless it's been configured by the user
+	if values.Cni == nil {
+		values.Cni = &v1alpha1.CNIConfig{}
+	}
+	if values.Cni.Image == "" && values.Cni.Hub == "" && values.Cni.Tag == nil {
+		values.Cni.Image = imageDigests.CNIImage
+	}
+	return values
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *IstioCNIReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		WithOptions(controller.Options{
+			LogConstructor: func(req *reconcile.Request) logr.Logger {
+				log := mgr.GetLogger().WithName("ctrlr").WithName("istiocni")
+				if req != nil {
+					log = log.WithValues("IstioCNI", req.Name)
+				}
+				return log
+			},
+		}).
+		For(&v1alpha1.IstioCNI{}).
+
+		// namespaced resources
+		Owns(&corev1.ConfigMap{}).
+		Owns(&appsv1.DaemonSet{}).
+		Owns(&corev1.ResourceQuota{}).
+		Owns(&corev1.ServiceAccount{}).
+
+		// TODO: only register NetAttachDef if the CRD is installed (may also need to watch for CRD creation)
+		// Owns(&multusv1.NetworkAttachmentDefinition{}).
+
+		// cluster-scoped resources
+		Owns(&rbacv1.ClusterRole{}).
+		Owns(&rbacv1.ClusterRoleBinding{}).
+
+		// the values must be recomputed when a user-defined profile changes
+		Watches(&corev1.ConfigMap{}, handler.EnqueueRequestsFromMapFunc(r.mapProfileConfigMapToReconcileRequests)).
+		Complete(r)
+}
+
+// mapProfileConfigMapToReconcileRequests returns the IstioCNIs that use the user-defined profile in the given ConfigMap
+func (r *IstioCNIReconciler) mapProfileConfigMapToReconcileRequests(ctx context.Context, obj client.Object) []reconcile.Request {
+	profile, isProfile := obj.GetLabels()[istiovalues.ProfileLabel]
+	if !isProfile || obj.GetNamespace() != r.Namespace {
+		return nil
+	}
+
+	log := logf.FromContext(ctx)
+	cniList := v1alpha1.IstioCNIList{}
+	if err := r.Client.List(ctx, &cniList); err != nil {
+		log.Error(err, "Could not list IstioCNIs")
+		return nil
+	}
+
+	var requests []reconcile.Request
+	for _, cni := range cniList.Items {
+		if slices.Contains(getProfiles(&cni, r.DefaultProfiles), profile) {
+			requests = append(requests, reconcile.Request{NamespacedName: types.NamespacedName{Name: cni.Name}})
+		}
+	}
+	return requests
+}
+
+func (r *IstioCNIReconciler) updateStatus(ctx context.Context, cni *v1alpha1.IstioCNI, warnings []string, err error) error {
+	log := logf.FromContext(ctx)
+	reconciledCondition := determineReconciledCondition(err)
+	readyCondition := r.determineReadyCondition(ctx)
+
+	status := cni.Status.DeepCopy()
+	status.ObservedGeneration = cni.Generation
+	status.SetCondition(reconciledCondition)
+	status.SetCondition(readyCondition)
+	status.State = deriveState(reconciledCondition, readyCondition)
+	status.Warnings = warnings
+
+	if reflect.DeepEqual(cni.Status, *status) {
+		return err
+	}
+
+	statusErr := r.Client.Status().Patch(ctx, cni, kube.NewStatusPatch(*status))
+	if statusErr != nil {
+		log.Error(statusErr, "failed to patch status")
+
+		// ensure that we retry the reconcile by returning the status error
+		// (but without overriding the original error)
+		if err == nil {
+			return statusErr
+		}
+	}
+	return err
+}
+
+func deriveState(reconciledCondition, readyCondition v1alpha1.IstioCNICondition) v1alpha1.IstioCNIConditionReason {
+	if reconciledCondition.Status == metav1.ConditionFalse {
+		return reconciledCondition.Reason
+	} else if readyCondition.Status == metav1.ConditionFalse {
+		return readyCondition.Reason
+	}
+
+	return v1alpha1.IstioCNIConditionReasonHealthy
+}
+
+func determineReconciledCondition(err error) v1alpha1.IstioCNICondition {
+	if err == nil {
+		return v1alpha1.IstioCNICondition{
+			Type:   v1alpha1.IstioCNIConditionTypeReconciled,
+			Status: metav1.ConditionTrue,
+		}
+	}
+
+	return v1alpha1.IstioCNICondition{
+		Type:    v1alpha1.IstioCNIConditionTypeReconciled,
+		Status:  metav1.ConditionFalse,
+		Reason:  v1alpha1.IstioCNIConditionReasonReconcileError,
+		Message: fmt.Sprintf("error reconciling resource: %v", err),
+	}
+}
+
+func (r *IstioCNIReconciler) determineReadyCondition(ctx context.Context) v1alpha1.IstioCNICondition {
+	notReady := func(reason v1alpha1.IstioCNIConditionReason, message string) v1alpha1.IstioCNICondition {
+		return v1alpha1.IstioCNICondition{
+			Type:    v1alpha1.IstioCNIConditionTypeReady,
+			Status:  metav1.ConditionFalse,
+			Reason:  reason,
+			Message: message,
+		}
+	}
+
+	ds := appsv1.DaemonSet{}
+	if err := r.Client.Get(ctx, r.cniDaemonSetKey(), &ds); err != nil {
+		if errors.IsNotFound(err) {
+			return notReady(v1alpha1.IstioCNIConditionReasonCNINotReady, "istio-cni-node DaemonSet not found")
+		}
+		return notReady(v1alpha1.IstioCNIConditionReasonReconcileError, fmt.Sprintf("failed to get readiness: %v", err))
+	}
+
+	if ds.Status.CurrentNumberScheduled == 0 {
+		return notReady(v1alpha1.IstioCNIConditionReasonCNINotReady, "no istio-cni-node pods are currently scheduled")
+	} else if ds.Status.NumberReady < ds.Status.CurrentNumberScheduled {
+		return notReady(v1alpha1.IstioCNIConditionReasonCNINotReady, "not all istio-cni-node pods are ready")
+	}
+
+	return v1alpha1.IstioCNICondition{
+		Type:   v1alpha1.IstioCNIConditionTypeReady,
+		Status: metav1.ConditionTrue,
+	}
+}
+
+func (r *IstioCNIReconciler) cniDaemonSetKey() client.ObjectKey {
+	return client.ObjectKey{
+		Namespace: r.Namespace,
+		Name:      cniDaemonSetName,
+	}
+}
@@ -0,0 +1,475 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package istiocni
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	appsv1 "k8s.io/api/apps/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/kubectl/pkg/scheme"
+	"maistra.io/istio-operator/api/v1alpha1"
+	"maistra.io/istio-operator/pkg/common"
+	"maistra.io/istio-operator/pkg/helm"
+	"maistra.io/istio-operator/pkg/test"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+const operatorNamespace = "istio-operator"
+
+func TestValidateIstioCNI(t *testing.T) {
+	testCases := []struct {
+		name      string
+		cni       v1alpha1.IstioCNI
+		expectErr bool
+	}{
+		{
+			name: "valid",
+			cni: v1alpha1.IstioCNI{
+				ObjectMeta: metav1.ObjectMeta{Name: v1alpha1.IstioCNIName},
+				Spec:       v1alpha1.IstioCNISpec{Version: "v1.20.0"},
+			},
+		},
+		{
+			name: "wrong name",
+			cni: v1alpha1.IstioCNI{
+				ObjectMeta: metav1.ObjectMeta{Name: "my-cni"},
+				Spec:       v1alpha1.IstioCNISpec{Version: "v1.20.0"},
+			},
+			expectErr: true,
+		},
+		{
+			name: "no version",
+			cni: v1alpha1.IstioCNI{
+				ObjectMeta: metav1.ObjectMeta{Name: v1alpha1.IstioCNIName},
+			},
+			expectErr: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := validateIstioCNI(tc.cni)
+			if tc.expectErr && err == nil {
+				t.Error("expected an error, but got nil")
+			} else if !tc.expectErr && err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+func TestDeriveState(t *testing.T) {
+	testCases := []struct {
+		name                string
+		reconciledCondition v1alpha1.IstioCNICondition
+		readyCondition      v1alpha1.IstioCNICondition
+		expectedState       v1alpha1.IstioCNIConditionReason
+	}{
+		{
+			name:                "healthy",
+			reconciledCondition: newCondition(v1alpha1.IstioCNIConditionTypeReconciled, true, ""),
+			readyCondition:      newCondition(v1alpha1.IstioCNIConditionTypeReady, true, ""),
+			expectedState:       v1alpha1.IstioCNIConditionReasonHealthy,
+		},
+		{
+			name:                "not reconciled",
+			reconciledCondition: newCondition(v1alpha1.IstioCNIConditionTypeReconciled, false, v1alpha1.IstioCNIConditionReasonReconcileError),
+			readyCondition:      newCondition(v1alpha1.IstioCNIConditionTypeReady, true, ""),
+			expectedState:       v1alpha1.IstioCNIConditionReasonReconcileError,
+		},
+		{
+			name:                "not ready",
+			reconciledCondition: newCondition(v1alpha1.IstioCNIConditionTypeReconciled, true, ""),
+			readyCondition:      newCondition(v1alpha1.IstioCNIConditionTypeReady, false, v1alpha1.IstioCNIConditionReasonCNINotReady),
+			expectedState:       v1alpha1.IstioCNIConditionReasonCNINotReady,
+		},
+		{
+			name:                "not reconciled nor ready",
+			reconciledCondition: newCondition(v1alpha1.IstioCNIConditionTypeReconciled, false, v1alpha1.IstioCNIConditionReasonReconcileError),
+			readyCondition:      newCondition(v1alpha1.IstioCNIConditionTypeReady, false, v1alpha1.IstioCNIConditionReasonCNINotReady),
+			expectedState:       v1alpha1.IstioCNIConditionReasonReconcileError, // reconcile reason takes precedence over ready reason
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			result := deriveState(tc.reconciledCondition, tc.readyCondition)
+			if result != tc.expectedState {
+				t.Errorf("Expected reason %s, but got %s", tc.expectedState, result)
+			}
+		})
+	}
+}
+
+func newCondition(conditionType v1alpha1.IstioCNIConditionType, status bool, reason v1alpha1.IstioCNIConditionReason) v1alpha1.IstioCNICondition {
+	st := metav1.ConditionFalse
+	if status {
+		st = metav1.ConditionTrue
+	}
+	return v1alpha1.IstioCNICondition{
+		Type:   conditionType,
+		Status: st,
+		Reason: reason,
+	}
+}
+
+func TestDetermineReconciledCondition(t *testing.T) {
+	testCases := []struct {
+		name     string
+		err      error
+		expected v1alpha1.IstioCNICondition
+	}{
+		{
+			name: "no error",
+			expected: v1alpha1.IstioCNICondition{
+				Type:   v1alpha1.IstioCNIConditionTypeReconciled,
+				Status: metav1.ConditionTrue,
+			},
+		},
+		{
+			name: "error",
+			err:  errors.New("some error"),
+			expected: v1alpha1.IstioCNICondition{
+				Type:    v1alpha1.IstioCNIConditionTypeReconciled,
+				Status:  metav1.ConditionFalse,
+				Reason:  v1alpha1.IstioCNIConditionReasonReconcileError,
+				Message: "error reconciling resource: some error",
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			result := determineReconciledCondition(tc.err)
+			if diff := cmp.Diff(tc.expected, result); diff != "" {
+				t.Errorf("unexpected condition; diff (-expected, +actual):\n%v", diff)
+			}
+		})
+	}
+}
+
+func TestDetermineReadyCondition(t *testing.T) {
+	test.SetupScheme()
+
+	testCases := []struct {
+		name          string
+		clientObjects []client.Object
+		expected      v1alpha1.IstioCNICondition
+	}{
+		{
+			name: "CNI ready",
+			clientObjects: []client.Object{
+				&appsv1.DaemonSet{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "istio-cni-node",
+						Namespace: operatorNamespace,
+					},
+					Status: appsv1.DaemonSetStatus{
+						CurrentNumberScheduled: 3,
+						NumberReady:            3,
+					},
+				},
+			},
+			expected: v1alpha1.IstioCNICondition{
+				Type:   v1alpha1.IstioCNIConditionTypeReady,
+				Status: metav1.ConditionTrue,
+			},
+		},
+		{
+			name: "CNI not ready",
+			clientObjects: []client.Object{
+				&appsv1.DaemonSet{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "istio-cni-node",
+						Namespace: operatorNamespace,
+					},
+					Status: appsv1.DaemonSetStatus{
+						CurrentNumberScheduled: 3,
+						NumberReady:            2,
+					},
+				},
+			},
+			expected: v1alpha1.IstioCNICondition{
+				Type:    v1alpha1.IstioCNIConditionTypeReady,
+				Status:  metav1.ConditionFalse,
+				Reason:  v1alpha1.IstioCNIConditionReasonCNINotReady,
+				Message: "not all istio-cni-node pods are ready",
+			},
+		},
+		{
+			name: "CNI pods not scheduled",
+			clientObjects: []client.Object{
+				&appsv1.DaemonSet{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "istio-cni-node",
+						Namespace: operatorNamespace,
+					},
+					Status: appsv1.DaemonSetStatus{
+						CurrentNumberScheduled: 0,
+						NumberReady:            0,
+					},
+				},
+			},
+			expected: v1alpha1.IstioCNICondition{
+				Type:    v1alpha1.IstioCNIConditionTypeReady,
+				Status:  metav1.ConditionFalse,
+				Reason:  v1alpha1.IstioCNIConditionReasonCNINotReady,
+				Message: "no istio-cni-node pods are currently scheduled",
+			},
+		},
+		{
+			name:          "CNI not found",
+			clientObjects: []client.Object{},
+			expected: v1alpha1.IstioCNICondition{
+				Type:    v1alpha1.IstioCNIConditionTypeReady,
+				Status:  metav1.ConditionFalse,
+				Reason:  v1alpha1.IstioCNIConditionReasonCNINotReady,
+				Message: "istio-cni-node DaemonSet not found",
+			},
+		},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			cl := fake.NewClientBuilder().WithScheme(scheme.Scheme).WithObjects(tt.clientObjects...).Build()
+			r := NewIstioCNIReconciler(cl, scheme.Scheme, nil, "", nil, operatorNamespace)
+
+			result := r.determineReadyCondition(context.TODO())
+			if diff := cmp.Diff(tt.expected, result); diff != "" {
+				t.Errorf("unexpected condition; diff (-expected, +actual):\n%v", diff)
+			}
+		})
+	}
+}
+
+func TestApplyImageDigests(t *testing.T) {
+	testCases := []struct {
+		name         string
+		config       common.OperatorConfig
+		inputValues  *v1alpha1.CNIValues
+		expectValues *v1alpha1.CNIValues
+	}{
+		{
+			name: "no-config",
+			config: common.OperatorConfig{
+				ImageDigests: map[string]common.IstioImageConfig{},
+			},
+			inputValues: &v1alpha1.CNIValues{
+				Cni: &v1alpha1.CNIConfig{
+					Image: "cni-custom",
+				},
+			},
+			expectValues: &v1alpha1.CNIValues{
+				Cni: &v1alpha1.CNIConfig{
+					Image: "cni-custom",
+				},
+			},
+		},
+		{
+			name: "no-user-values",
+			config: common.OperatorConfig{
+				ImageDigests: map[string]common.IstioImageConfig{
+					"v1.20.0": {
+						CNIImage: "cni-test",
+					},
+				},
+			},
+			inputValues: nil,
+			expectValues: &v1alpha1.CNIValues{
+				Cni: &v1alpha1.CNIConfig{
+					Image: "cni-test",
+				},
+			},
+		},
+		{
+			name: "user-supplied-image",
+			config: common.OperatorConfig{
+				ImageDigests: map[string]common.IstioImageConfig{
+					"v1.20.0": {
+						CNIImage: "cni-test",
+					},
+				},
+			},
+			inputValues: &v1alpha1.CNIValues{
+				Cni: &v1alpha1.CNIConfig{
+					Image: "cni-custom",
+				},
+			},
+			expectValues: &v1alpha1.CNIValues{
+				Cni: &v1alpha1.CNIConfig{
+					Image: "cni-custom",
+				},
+			},
+		},
+		{
+			name: "user-supplied-hub",
+			config: common.OperatorConfig{
+				ImageDigests: map[string]common.IstioImageConfig{
+					"v1.20.0": {
+						CNIImage: "cni-test",
+					},
+				},
+			},
+			inputValues: &v1alpha1.CNIValues{
+				Cni: &v1alpha1.CNIConfig{
+					Hub: "docker.io/istio",
+				},
+			},
+			expectValues: &v1alpha1.CNIValues{
+				Cni: &v1alpha1.CNIConfig{
+					Hub: "docker.io/istio",
+				},
+			},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			cni := &v1alpha1.IstioCNI{
+				Spec: v1alpha1.IstioCNISpec{
+					Version: "v1.20.0",
+				},
+			}
+			result := applyImageDigests(cni, tc.inputValues, tc.config)
+			if diff := cmp.Diff(tc.expectValues, result); diff != "" {
+				t.Errorf("unexpected merge result; diff (-expected, +actual):\n%v", diff)
+			}
+		})
+	}
+}
+
+func TestComputeHelmValues(t *testing.T) {
+	const version = "my-version"
+	resourceDir := t.TempDir()
+	profilesDir := path.Join(resourceDir, version, "profiles")
+	Must(t, os.MkdirAll(profilesDir, 0o755))
+
+	Must(t, os.WriteFile(path.Join(profilesDir, "default.yaml"), []byte((`
+apiVersion: operator.istio.io/v1alpha1
+kind: Istio
+spec:
+  values:
+    cni:
+      cniConfDir: /etc/cni/net.d
+      logLevel: info`)), 0o644))
+
+	Must(t, os.WriteFile(path.Join(profilesDir, "custom.yaml"), []byte((`
+apiVersion: operator.istio.io/v1alpha1
+kind: Istio
+spec:
+  values:
+    cni:
+      logLevel: debug`)), 0o644))
+
+	cni := &v1alpha1.IstioCNI{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: v1alpha1.IstioCNIName,
+		},
+		Spec: v1alpha1.IstioCNISpec{
+			Version: version,
+			Profile: "custom",
+			Values: &v1alpha1.CNIValues{
+				Cni: &v1alpha1.CNIConfig{
+					CniConfDir: "/var/lib/cni",
+				},
+			},
+		},
+	}
+
+	result, _, err := computeHelmValues(cni, []string{"default"}, resourceDir, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := helm.HelmValues{
+		"cni": map[string]any{
+			"cniConfDir": "/var/lib/cni",
+			"logLevel":   "debug",
+		},
+	}
+	if diff := cmp.Diff(expected, result); diff != "" {
+		t.Errorf("unexpected values; diff (-expected, +actual):\n%v", diff)
+	}
+}
+
+func TestGetProfilesDoesNotModifyDefaultProfiles(t *testing.T) {
+	defaultProfiles := make([]string, 1, 2)
+	defaultProfiles[0] = "default"
+
+	first := getProfiles(&v1alpha1.IstioCNI{Spec: v1alpha1.IstioCNISpec{Profile: "first"}}, defaultProfiles)
+	second := getProfiles(&v1alpha1.IstioCNI{Spec: v1alpha1.IstioCNISpec{Profile: "second"}}, defaultProfiles)
+
+	if diff := cmp.Diff([]string{"default", "first"}, first); diff != "" {
+		t.Errorf("unexpected profiles; diff (-expected, +actual):\n%v", diff)
+	}
+	if diff := cmp.Diff([]string{"default", "second"}, second); diff != "" {
+		t.Errorf("unexpected profiles; diff (-expected, +actual):\n%v", diff)
+	}
+}
+
+func Must(t *testing.T, err error) {
+	t.Helper()
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestUpdateStatusWarnings(t *testing.T) {
+	test.SetupScheme()
+
+	testCases := []struct {
+		name             string
+		existingWarnings []string
+		warnings         []string
+	}{
+		{
+			name:     "reports warnings",
+			warnings: []string{"profile custom: spec.components.ingressGateways is not supported"},
+		},
+		{
+			name:             "clears warnings",
+			existingWarnings: []string{"profile custom: spec.components.ingressGateways is not supported"},
+		},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			cni := &v1alpha1.IstioCNI{
+				ObjectMeta: metav1.ObjectMeta{Name: v1alpha1.IstioCNIName},
+				Spec:       v1alpha1.IstioCNISpec{Version: "my-version"},
+				Status:     v1alpha1.IstioCNIStatus{Warnings: tt.existingWarnings},
+			}
+			cl := fake.NewClientBuilder().WithScheme(scheme.Scheme).WithObjects(cni).WithStatusSubresource(cni).Build()
+			r := NewIstioCNIReconciler(cl, scheme.Scheme, nil, "", nil, operatorNamespace)
+
+			if err := r.updateStatus(context.TODO(), cni, tt.warnings, nil); err != nil {
+				t.Fatalf("updateStatus() returned error: %v", err)
+			}
+
+			if err := cl.Get(context.TODO(), client.ObjectKeyFromObject(cni), cni); err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(tt.warnings, cni.Status.Warnings); diff != "" {
+				t.Errorf("unexpected warnings; diff (-expected, +actual):\n%v", diff)
+			}
+		})
+	}
+}
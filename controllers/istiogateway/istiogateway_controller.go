@@ -0,0 +1,374 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package istiogateway
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+
+	"github.com/go-logr/logr"
+	appsv1 "k8s.io/api/apps/v1"
+	autoscalingv2 "k8s.io/api/autoscaling/v2"
+	corev1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/client-go/rest"
+	"maistra.io/istio-operator/api/v1alpha1"
+	"maistra.io/istio-operator/pkg/helm"
+	"maistra.io/istio-operator/pkg/kube"
+	"maistra.io/istio-operator/pkg/revision"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	"istio.io/istio/pkg/ptr"
+)
+
+// charts to deploy in the gateway's namespace
+var gatewayCharts = []string{"gateway"}
+
+// IstioGatewayReconciler reconciles an IstioGateway object
+type IstioGatewayReconciler struct {
+	RestClientGetter genericclioptions.RESTClientGetter
+	client.Client
+	Scheme *runtime.Scheme
+}
+
+func NewIstioGatewayReconciler(client client.Client, scheme *runtime.Scheme, restConfig *rest.Config) *IstioGatewayReconciler {
+	return &IstioGatewayReconciler{
+		RestClientGetter: helm.NewRESTClientGetter(restConfig),
+		Client:           client,
+		Scheme:           scheme,
+	}
+}
+
+// gatewayError is returned when the gateway can't be reconciled because the referenced Istio or
+// IstioRevision doesn't exist. The gateway is reconciled again when the referenced objects change.
+type gatewayError struct {
+	reason  v1alpha1.IstioGatewayConditionReason
+	message string
+}
+
+func (e *gatewayError) Error() string {
+	return e.message
+}
+
+// +kubebuilder:rbac:groups=operator.istio.io,resources=istiogateways,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=operator.istio.io,resources=istiogateways/status,verbs=get;update;patch
+// +kubebuilder:rbac:groups=operator.istio.io,resources=istiogateways/finalizers,verbs=update
+
+// Reconcile installs the gateway chart of the referenced revision's version into the namespace
+// of the IstioGateway.
+func (r *IstioGatewayReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	log := logf.FromContext(ctx)
+	var gw v1alpha1.IstioGateway
+	if err := r.Client.Get(ctx, req.NamespacedName, &gw); err != nil {
+		if errors.IsNotFound(err) {
+			log.V(2).Info("IstioGateway not found. Skipping reconciliation")
+			return ctrl.Result{}, nil
+		}
+		return ctrl.Result{}, err
+	}
+
+	if gw.DeletionTimestamp != nil {
+		if err := r.uninstallHelmCharts(ctx, &gw); err != nil {
+			return ctrl.Result{}, err
+		}
+
+		if err := kube.RemoveFinalizer(ctx, &gw, r.Client); err != nil {
+			log.Info("failed to remove finalizer")
+			return ctrl.Result{}, err
+		}
+		return ctrl.Result{}, nil
+	}
+
+	if !kube.HasFinalizer(&gw) {
+		err := kube.AddFinalizer(ctx, &gw, r.Client)
+		if err != nil {
+			log.Info("failed to add finalizer")
+			return ctrl.Result{}, err
+		}
+	}
+
+	rev, err := r.getTargetRevision(ctx, &gw)
+	if err == nil {
+		log.Info("Installing gateway", "IstioRevision", rev.Name)
+		err = r.installHelmCharts(ctx, &gw, rev)
+	}
+
+	log.Info("Reconciliation done. Updating status.")
+	err = r.updateStatus(ctx, &gw, rev, err)
+
+	if _, ok := err.(*gatewayError); ok {
+		// retrying won't help; the watches trigger a new reconciliation when the referenced objects change
+		return ctrl.Result{}, nil
+	}
+	return ctrl.Result{}, err
+}
+
+// getTargetRevision returns the IstioRevision that the gateway connects to. If the gateway references an Istio,
+// this is the Istio's active revision.
+func (r *IstioGatewayReconciler) getTargetRevision(ctx context.Context, gw *v1alpha1.IstioGateway) (*v1alpha1.IstioRevision, error) {
+	rev, err := revision.GetTargetRevision(ctx, r.Client, gw.Spec.TargetRef)
+	if notFoundErr, ok := err.(*revision.NotFoundError); ok {
+		return nil, &gatewayError{
+			reason:  v1alpha1.IstioGatewayConditionReasonReferenceNotFound,
+			message: notFoundErr.Error(),
+		}
+	}
+	return rev, err
+}
+
+func (r *IstioGatewayReconciler) installHelmCharts(ctx context.Context, gw *v1alpha1.IstioGateway, rev *v1alpha1.IstioRevision) error {
+	ownerReference := metav1.OwnerReference{
+		APIVersion:         v1alpha1.GroupVersion.String(),
+		Kind:               v1alpha1.IstioGatewayKind,
+		Name:               gw.Name,
+		UID:                gw.UID,
+		Controller:         ptr.Of(true),
+		BlockOwnerDeletion: ptr.Of(true),
+	}
+
+	values, err := computeHelmValues(gw, rev)
+	if err != nil {
+		return err
+	}
+
+	return helm.UpgradeOrInstallCharts(ctx, r.RestClientGetter, gatewayCharts, values,
+		rev.Spec.Version, gw.Name, gw.Namespace, ownerReference, helm.DefaultMaxHistory)
+}
+
+func (r *IstioGatewayReconciler) uninstallHelmCharts(ctx context.Context, gw *v1alpha1.IstioGateway) error {
+	return helm.UninstallCharts(ctx, r.RestClientGetter, gatewayCharts, gw.Name, gw.Namespace)
+}
+
+// computeHelmValues returns the user's values along with the values that tie the gateway to the IstioRevision.
+// The gateway's resources are always named after the IstioGateway object.
+func computeHelmValues(gw *v1alpha1.IstioGateway, rev *v1alpha1.IstioRevision) (helm.HelmValues, error) {
+	values := gw.Spec.Values.ToHelmValues()
+	if values == nil {
+		values = helm.HelmValues{}
+	}
+
+	if err := values.Set("name", gw.Name); err != nil {
+		return nil, err
+	}
+	if rev.Spec.Values != nil {
+		if err := values.Set("revision", rev.Spec.Values.Revision); err != nil {
+			return nil, err
+		}
+		if rev.Spec.Values.Profile != "" {
+			if err := values.Set("profile", rev.Spec.Values.Profile); err != nil {
+				return nil, err
+			}
+		}
+		if rev.Spec.Values.CompatibilityVersion != "" {
+			if err := values.Set("compatibilityVersion", rev.Spec.Values.CompatibilityVersion); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return values, nil
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *IstioGatewayReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	// istioHandler and revisionHandler trigger the reconciliation of the gateways that reference the Istio or
+	// IstioRevision, so that the gateways follow the active revision and are reinstalled when its version changes
+	istioHandler := handler.EnqueueRequestsFromMapFunc(r.mapIstioToReconcileRequest)
+	revisionHandler := handler.EnqueueRequestsFromMapFunc(r.mapRevisionToReconcileRequest)
+
+	return ctrl.NewControllerManagedBy(mgr).
+		WithOptions(controller.Options{
+			LogConstructor: func(req *reconcile.Request) logr.Logger {
+				log := mgr.GetLogger().WithName("ctrlr").WithName("istiogw")
+				if req != nil {
+					log = log.WithValues("IstioGateway", req.NamespacedName)
+				}
+				return log
+			},
+		}).
+		For(&v1alpha1.IstioGateway{}).
+		Owns(&appsv1.Deployment{}).
+		Owns(&appsv1.DaemonSet{}).
+		Owns(&corev1.Service{}).
+		Owns(&corev1.ServiceAccount{}).
+		Owns(&rbacv1.Role{}).
+		Owns(&rbacv1.RoleBinding{}).
+		Owns(&autoscalingv2.HorizontalPodAutoscaler{}).
+		Owns(&policyv1.PodDisruptionBudget{}).
+		Watches(&v1alpha1.Istio{}, istioHandler).
+		Watches(&v1alpha1.IstioRevision{}, revisionHandler).
+		Complete(r)
+}
+
+func (r *IstioGatewayReconciler) updateStatus(ctx context.Context, gw *v1alpha1.IstioGateway, rev *v1alpha1.IstioRevision, err error) error {
+	log := logf.FromContext(ctx)
+	reconciledCondition := determineReconciledCondition(err)
+	readyCondition := r.determineReadyCondition(ctx, gw)
+
+	status := gw.Status.DeepCopy()
+	status.ObservedGeneration = gw.Generation
+	status.SetCondition(reconciledCondition)
+	status.SetCondition(readyCondition)
+	status.State = deriveState(reconciledCondition, readyCondition)
+	if err == nil {
+		status.IstioRevision = rev.Name
+	}
+
+	if reflect.DeepEqual(gw.Status, *status) {
+		return err
+	}
+
+	statusErr := r.Client.Status().Patch(ctx, gw, kube.NewStatusPatch(*status))
+	if statusErr != nil {
+		log.Error(statusErr, "failed to patch status")
+
+		// ensure that we retry the reconcile by returning the status error
+		// (but without overriding the original error)
+		if err == nil {
+			return statusErr
+		}
+	}
+	return err
+}
+
+func deriveState(reconciledCondition, readyCondition v1alpha1.IstioGatewayCondition) v1alpha1.IstioGatewayConditionReason {
+	if reconciledCondition.Status == metav1.ConditionFalse {
+		return reconciledCondition.Reason
+	} else if readyCondition.Status == metav1.ConditionFalse {
+		return readyCondition.Reason
+	}
+
+	return v1alpha1.IstioGatewayConditionReasonHealthy
+}
+
+func determineReconciledCondition(err error) v1alpha1.IstioGatewayCondition {
+	c := v1alpha1.IstioGatewayCondition{Type: v1alpha1.IstioGatewayConditionTypeReconciled}
+
+	if err == nil {
+		c.Status = metav1.ConditionTrue
+	} else if gwErr, ok := err.(*gatewayError); ok {
+		c.Status = metav1.ConditionFalse
+		c.Reason = gwErr.reason
+		c.Message = gwErr.message
+	} else {
+		c.Status = metav1.ConditionFalse
+		c.Reason = v1alpha1.IstioGatewayConditionReasonReconcileError
+		c.Message = fmt.Sprintf("error reconciling resource: %v", err)
+	}
+	return c
+}
+
+func (r *IstioGatewayReconciler) determineReadyCondition(ctx context.Context, gw *v1alpha1.IstioGateway) v1alpha1.IstioGatewayCondition {
+	notReady := func(reason v1alpha1.IstioGatewayConditionReason, message string) v1alpha1.IstioGatewayCondition {
+		return v1alpha1.IstioGatewayCondition{
+			Type:    v1alpha1.IstioGatewayConditionTypeReady,
+			Status:  metav1.ConditionFalse,
+			Reason:  reason,
+			Message: message,
+		}
+	}
+
+	key := client.ObjectKeyFromObject(gw)
+	values := gw.Spec.Values
+	if values == nil {
+		values = &v1alpha1.GatewayValues{}
+	}
+
+	if values.Kind == "DaemonSet" {
+		ds := appsv1.DaemonSet{}
+		if err := r.Client.Get(ctx, key, &ds); err != nil {
+			if errors.IsNotFound(err) {
+				return notReady(v1alpha1.IstioGatewayConditionReasonGatewayNotReady, "gateway DaemonSet not found")
+			}
+			return notReady(v1alpha1.IstioGatewayConditionReasonReconcileError, fmt.Sprintf("failed to get readiness: %v", err))
+		}
+		if ds.Status.CurrentNumberScheduled == 0 {
+			return notReady(v1alpha1.IstioGatewayConditionReasonGatewayNotReady, "no gateway pods are currently scheduled")
+		} else if ds.Status.NumberReady < ds.Status.CurrentNumberScheduled {
+			return notReady(v1alpha1.IstioGatewayConditionReasonGatewayNotReady, "not all gateway pods are ready")
+		}
+	} else {
+		deployment := appsv1.Deployment{}
+		if err := r.Client.Get(ctx, key, &deployment); err != nil {
+			if errors.IsNotFound(err) {
+				return notReady(v1alpha1.IstioGatewayConditionReasonGatewayNotReady, "gateway Deployment not found")
+			}
+			return notReady(v1alpha1.IstioGatewayConditionReasonReconcileError, fmt.Sprintf("failed to get readiness: %v", err))
+		}
+		if deployment.Status.Replicas == 0 {
+			return notReady(v1alpha1.IstioGatewayConditionReasonGatewayNotReady, "gateway Deployment is scaled to zero replicas")
+		} else if deployment.Status.ReadyReplicas < deployment.Status.Replicas {
+			return notReady(v1alpha1.IstioGatewayConditionReasonGatewayNotReady, "not all gateway pods are ready")
+		}
+	}
+
+	if values.Service == nil || values.Service.Type != "None" {
+		svc := corev1.Service{}
+		if err := r.Client.Get(ctx, key, &svc); err != nil {
+			if errors.IsNotFound(err) {
+				return notReady(v1alpha1.IstioGatewayConditionReasonServiceNotReady, "gateway Service not found")
+			}
+			return notReady(v1alpha1.IstioGatewayConditionReasonReconcileError, fmt.Sprintf("failed to get readiness: %v", err))
+		}
+		if svc.Spec.Type == corev1.ServiceTypeLoadBalancer && len(svc.Status.LoadBalancer.Ingress) == 0 {
+			return notReady(v1alpha1.IstioGatewayConditionReasonServiceNotReady, "gateway Service has not been assigned a load balancer address")
+		}
+	}
+
+	return v1alpha1.IstioGatewayCondition{
+		Type:   v1alpha1.IstioGatewayConditionTypeReady,
+		Status: metav1.ConditionTrue,
+	}
+}
+
+func (r *IstioGatewayReconciler) mapIstioToReconcileRequest(ctx context.Context, obj client.Object) []reconcile.Request {
+	return r.mapGatewaysToReconcileRequests(ctx, func(gw v1alpha1.IstioGateway) bool {
+		return gw.Spec.TargetRef.Kind == v1alpha1.IstioKind && gw.Spec.TargetRef.Name == obj.GetName()
+	})
+}
+
+func (r *IstioGatewayReconciler) mapRevisionToReconcileRequest(ctx context.Context, obj client.Object) []reconcile.Request {
+	return r.mapGatewaysToReconcileRequests(ctx, func(gw v1alpha1.IstioGateway) bool {
+		return gw.Spec.TargetRef.Kind == v1alpha1.IstioRevisionKind && gw.Spec.TargetRef.Name == obj.GetName() ||
+			gw.Status.IstioRevision == obj.GetName()
+	})
+}
+
+func (r *IstioGatewayReconciler) mapGatewaysToReconcileRequests(ctx context.Context, matches func(v1alpha1.IstioGateway) bool) []reconcile.Request {
+	log := logf.FromContext(ctx)
+	gwList := v1alpha1.IstioGatewayList{}
+	if err := r.Client.List(ctx, &gwList); err != nil {
+		log.Error(err, "Could not list IstioGateways")
+		return nil
+	}
+
+	var requests []reconcile.Request
+	for _, gw := range gwList.Items {
+		if matches(gw) {
+			requests = append(requests, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(&gw)})
+		}
+	}
+	return requests
+}
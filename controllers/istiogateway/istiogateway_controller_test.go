@@ -0,0 +1,407 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package istiogateway
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/kubectl/pkg/scheme"
+	"maistra.io/istio-operator/api/v1alpha1"
+	"maistra.io/istio-operator/pkg/helm"
+	"maistra.io/istio-operator/pkg/test"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	"istio.io/istio/pkg/ptr"
+)
+
+const gwNamespace = "istio-ingress"
+
+var ctx = context.Background()
+
+func newGateway(name, kind, target string) *v1alpha1.IstioGateway {
+	return &v1alpha1.IstioGateway{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: gwNamespace,
+		},
+		Spec: v1alpha1.IstioGatewaySpec{
+			TargetRef: v1alpha1.TargetReference{
+				Kind: kind,
+				Name: target,
+			},
+		},
+	}
+}
+
+func newRevision(name string) *v1alpha1.IstioRevision {
+	return &v1alpha1.IstioRevision{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: name,
+		},
+		Spec: v1alpha1.IstioRevisionSpec{
+			Namespace: "istio-system",
+			Version:   "my-version",
+			Values: &v1alpha1.Values{
+				Revision: name,
+			},
+		},
+	}
+}
+
+func TestGetTargetRevision(t *testing.T) {
+	test.SetupScheme()
+
+	istio := &v1alpha1.Istio{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "my-istio",
+		},
+		Status: v1alpha1.IstioStatus{
+			ActiveRevisionName: "my-istio-1-21-0",
+		},
+	}
+	istioWithoutActiveRevision := &v1alpha1.Istio{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "new-istio",
+		},
+	}
+
+	testCases := []struct {
+		name              string
+		gw                *v1alpha1.IstioGateway
+		expectedRevision  string
+		expectedErrReason v1alpha1.IstioGatewayConditionReason
+	}{
+		{
+			name:             "resolves IstioRevision reference",
+			gw:               newGateway("ingress", v1alpha1.IstioRevisionKind, "my-istio-1-20-0"),
+			expectedRevision: "my-istio-1-20-0",
+		},
+		{
+			name:             "resolves Istio reference to active revision",
+			gw:               newGateway("ingress", v1alpha1.IstioKind, "my-istio"),
+			expectedRevision: "my-istio-1-21-0",
+		},
+		{
+			name:              "reports missing IstioRevision",
+			gw:                newGateway("ingress", v1alpha1.IstioRevisionKind, "missing"),
+			expectedErrReason: v1alpha1.IstioGatewayConditionReasonReferenceNotFound,
+		},
+		{
+			name:              "reports missing Istio",
+			gw:                newGateway("ingress", v1alpha1.IstioKind, "missing"),
+			expectedErrReason: v1alpha1.IstioGatewayConditionReasonReferenceNotFound,
+		},
+		{
+			name:              "reports Istio without active revision",
+			gw:                newGateway("ingress", v1alpha1.IstioKind, "new-istio"),
+			expectedErrReason: v1alpha1.IstioGatewayConditionReasonReferenceNotFound,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			cl := fake.NewClientBuilder().
+				WithScheme(scheme.Scheme).
+				WithObjects(istio, istioWithoutActiveRevision, newRevision("my-istio-1-20-0"), newRevision("my-istio-1-21-0"), tc.gw).
+				Build()
+			r := NewIstioGatewayReconciler(cl, scheme.Scheme, nil)
+
+			rev, err := r.getTargetRevision(ctx, tc.gw)
+			if tc.expectedErrReason != "" {
+				gwErr, ok := err.(*gatewayError)
+				if !ok {
+					t.Fatalf("expected gatewayError, but got: %v", err)
+				}
+				if gwErr.reason != tc.expectedErrReason {
+					t.Errorf("expected reason %s, but got %s", tc.expectedErrReason, gwErr.reason)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if rev.Name != tc.expectedRevision {
+				t.Errorf("expected revision %s, but got %s", tc.expectedRevision, rev.Name)
+			}
+		})
+	}
+}
+
+func TestComputeHelmValues(t *testing.T) {
+	testCases := []struct {
+		name     string
+		values   *v1alpha1.GatewayValues
+		rev      *v1alpha1.IstioRevision
+		expected helm.HelmValues
+	}{
+		{
+			name:   "no user values",
+			values: nil,
+			rev:    newRevision("my-istio-1-21-0"),
+			expected: helm.HelmValues{
+				"name":     "ingress",
+				"revision": "my-istio-1-21-0",
+			},
+		},
+		{
+			name: "user values",
+			values: &v1alpha1.GatewayValues{
+				ReplicaCount: ptr.Of(int32(2)),
+				Service: &v1alpha1.GatewayServiceConfig{
+					Type: "ClusterIP",
+				},
+			},
+			rev: newRevision("my-istio-1-21-0"),
+			expected: helm.HelmValues{
+				"name":         "ingress",
+				"revision":     "my-istio-1-21-0",
+				"replicaCount": float64(2),
+				"service": map[string]any{
+					"type": "ClusterIP",
+				},
+			},
+		},
+		{
+			name:   "default revision with profile",
+			values: nil,
+			rev: &v1alpha1.IstioRevision{
+				ObjectMeta: metav1.ObjectMeta{Name: "default"},
+				Spec: v1alpha1.IstioRevisionSpec{
+					Values: &v1alpha1.Values{
+						Profile: "openshift",
+					},
+				},
+			},
+			expected: helm.HelmValues{
+				"name":     "ingress",
+				"revision": "",
+				"profile":  "openshift",
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			gw := newGateway("ingress", v1alpha1.IstioKind, "my-istio")
+			gw.Spec.Values = tc.values
+
+			result, err := computeHelmValues(gw, tc.rev)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if diff := cmp.Diff(tc.expected, result); diff != "" {
+				t.Errorf("unexpected values; diff (-expected, +actual):\n%v", diff)
+			}
+		})
+	}
+}
+
+func TestDetermineReadyCondition(t *testing.T) {
+	test.SetupScheme()
+
+	readyDeployment := &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Name: "ingress", Namespace: gwNamespace},
+		Status:     appsv1.DeploymentStatus{Replicas: 2, ReadyReplicas: 2},
+	}
+	clusterIPService := &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{Name: "ingress", Namespace: gwNamespace},
+		Spec:       corev1.ServiceSpec{Type: corev1.ServiceTypeClusterIP},
+	}
+
+	testCases := []struct {
+		name          string
+		values        *v1alpha1.GatewayValues
+		clientObjects []client.Object
+		expected      v1alpha1.IstioGatewayCondition
+	}{
+		{
+			name:          "Deployment and Service ready",
+			clientObjects: []client.Object{readyDeployment, clusterIPService},
+			expected: v1alpha1.IstioGatewayCondition{
+				Type:   v1alpha1.IstioGatewayConditionTypeReady,
+				Status: metav1.ConditionTrue,
+			},
+		},
+		{
+			name: "Deployment not ready",
+			clientObjects: []client.Object{
+				&appsv1.Deployment{
+					ObjectMeta: metav1.ObjectMeta{Name: "ingress", Namespace: gwNamespace},
+					Status:     appsv1.DeploymentStatus{Replicas: 2, ReadyReplicas: 1},
+				},
+				clusterIPService,
+			},
+			expected: v1alpha1.IstioGatewayCondition{
+				Type:    v1alpha1.IstioGatewayConditionTypeReady,
+				Status:  metav1.ConditionFalse,
+				Reason:  v1alpha1.IstioGatewayConditionReasonGatewayNotReady,
+				Message: "not all gateway pods are ready",
+			},
+		},
+		{
+			name:          "Deployment not found",
+			clientObjects: []client.Object{clusterIPService},
+			expected: v1alpha1.IstioGatewayCondition{
+				Type:    v1alpha1.IstioGatewayConditionTypeReady,
+				Status:  metav1.ConditionFalse,
+				Reason:  v1alpha1.IstioGatewayConditionReasonGatewayNotReady,
+				Message: "gateway Deployment not found",
+			},
+		},
+		{
+			name:   "DaemonSet ready",
+			values: &v1alpha1.GatewayValues{Kind: "DaemonSet"},
+			clientObjects: []client.Object{
+				&appsv1.DaemonSet{
+					ObjectMeta: metav1.ObjectMeta{Name: "ingress", Namespace: gwNamespace},
+					Status:     appsv1.DaemonSetStatus{CurrentNumberScheduled: 3, NumberReady: 3},
+				},
+				clusterIPService,
+			},
+			expected: v1alpha1.IstioGatewayCondition{
+				Type:   v1alpha1.IstioGatewayConditionTypeReady,
+				Status: metav1.ConditionTrue,
+			},
+		},
+		{
+			name:          "Service not found",
+			clientObjects: []client.Object{readyDeployment},
+			expected: v1alpha1.IstioGatewayCondition{
+				Type:    v1alpha1.IstioGatewayConditionTypeReady,
+				Status:  metav1.ConditionFalse,
+				Reason:  v1alpha1.IstioGatewayConditionReasonServiceNotReady,
+				Message: "gateway Service not found",
+			},
+		},
+		{
+			name:          "Service disabled",
+			values:        &v1alpha1.GatewayValues{Service: &v1alpha1.GatewayServiceConfig{Type: "None"}},
+			clientObjects: []client.Object{readyDeployment},
+			expected: v1alpha1.IstioGatewayCondition{
+				Type:   v1alpha1.IstioGatewayConditionTypeReady,
+				Status: metav1.ConditionTrue,
+			},
+		},
+		{
+			name: "LoadBalancer without address",
+			clientObjects: []client.Object{
+				readyDeployment,
+				&corev1.Service{
+					ObjectMeta: metav1.ObjectMeta{Name: "ingress", Namespace: gwNamespace},
+					Spec:       corev1.ServiceSpec{Type: corev1.ServiceTypeLoadBalancer},
+				},
+			},
+			expected: v1alpha1.IstioGatewayCondition{
+				Type:    v1alpha1.IstioGatewayConditionTypeReady,
+				Status:  metav1.ConditionFalse,
+				Reason:  v1alpha1.IstioGatewayConditionReasonServiceNotReady,
+				Message: "gateway Service has not been assigned a load balancer address",
+			},
+		},
+		{
+			name: "LoadBalancer with address",
+			clientObjects: []client.Object{
+				readyDeployment,
+				&corev1.Service{
+					ObjectMeta: metav1.ObjectMeta{Name: "ingress", Namespace: gwNamespace},
+					Spec:       corev1.ServiceSpec{Type: corev1.ServiceTypeLoadBalancer},
+					Status: corev1.ServiceStatus{
+						LoadBalancer: corev1.LoadBalancerStatus{
+							Ingress: []corev1.LoadBalancerIngress{{IP: "192.0.2.1"}},
+						},
+					},
+				},
+			},
+			expected: v1alpha1.IstioGatewayCondition{
+				Type:   v1alpha1.IstioGatewayConditionTypeReady,
+				Status: metav1.ConditionTrue,
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			cl := fake.NewClientBuilder().WithScheme(scheme.Scheme).WithObjects(tc.clientObjects...).Build()
+			r := NewIstioGatewayReconciler(cl, scheme.Scheme, nil)
+
+			gw := newGateway("ingress", v1alpha1.IstioKind, "my-istio")
+			gw.Spec.Values = tc.values
+
+			result := r.determineReadyCondition(ctx, gw)
+			if diff := cmp.Diff(tc.expected, result); diff != "" {
+				t.Errorf("unexpected condition; diff (-expected, +actual):\n%v", diff)
+			}
+		})
+	}
+}
+
+func TestMapIstioToReconcileRequest(t *testing.T) {
+	test.SetupScheme()
+
+	cl := fake.NewClientBuilder().
+		WithScheme(scheme.Scheme).
+		WithObjects(
+			newGateway("ingress", v1alpha1.IstioKind, "my-istio"),
+			newGateway("egress", v1alpha1.IstioRevisionKind, "my-istio"),
+		).
+		Build()
+	r := NewIstioGatewayReconciler(cl, scheme.Scheme, nil)
+
+	istio := &v1alpha1.Istio{ObjectMeta: metav1.ObjectMeta{Name: "my-istio"}}
+	requests := r.mapIstioToReconcileRequest(ctx, client.Object(istio))
+
+	expected := []reconcile.Request{
+		{NamespacedName: types.NamespacedName{Namespace: gwNamespace, Name: "ingress"}},
+	}
+	if diff := cmp.Diff(expected, requests); diff != "" {
+		t.Errorf("unexpected requests; diff (-expected, +actual):\n%v", diff)
+	}
+}
+
+func TestMapRevisionToReconcileRequest(t *testing.T) {
+	test.SetupScheme()
+
+	// the gateway that references the Istio is currently connected to the revision
+	followsIstio := newGateway("ingress", v1alpha1.IstioKind, "my-istio")
+	followsIstio.Status.IstioRevision = "my-istio-1-20-0"
+
+	cl := fake.NewClientBuilder().
+		WithScheme(scheme.Scheme).
+		WithObjects(
+			followsIstio,
+			newGateway("egress", v1alpha1.IstioRevisionKind, "my-istio-1-20-0"),
+			newGateway("other", v1alpha1.IstioRevisionKind, "my-istio-1-21-0"),
+		).
+		Build()
+	r := NewIstioGatewayReconciler(cl, scheme.Scheme, nil)
+
+	requests := r.mapRevisionToReconcileRequest(ctx, client.Object(newRevision("my-istio-1-20-0")))
+
+	expected := []reconcile.Request{
+		{NamespacedName: types.NamespacedName{Namespace: gwNamespace, Name: "egress"}},
+		{NamespacedName: types.NamespacedName{Namespace: gwNamespace, Name: "ingress"}},
+	}
+	if diff := cmp.Diff(expected, requests); diff != "" {
+		t.Errorf("unexpected requests; diff (-expected, +actual):\n%v", diff)
+	}
+}
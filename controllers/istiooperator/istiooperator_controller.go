@@ -0,0 +1,124 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package istiooperator
+
+import (
+	"context"
+	"strings"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
+	"maistra.io/istio-operator/pkg/iopimport"
+	"maistra.io/istio-operator/pkg/kube"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// reasons of the Events recorded for IstioOperator resources
+const (
+	EventReasonImported          = "Imported"
+	EventReasonImportFailed      = "ImportFailed"
+	EventReasonUnsupportedFields = "UnsupportedFields"
+)
+
+// IstioOperatorReconciler imports IstioOperator resources of the upstream in-cluster Istio operator into
+// Istio resources
+type IstioOperatorReconciler struct {
+	client.Client
+	Scheme        *runtime.Scheme
+	EventRecorder *kube.EventRecorder
+}
+
+func NewIstioOperatorReconciler(client client.Client, scheme *runtime.Scheme, recorder record.EventRecorder) *IstioOperatorReconciler {
+	return &IstioOperatorReconciler{
+		Client:        client,
+		Scheme:        scheme,
+		EventRecorder: kube.NewEventRecorder(recorder),
+	}
+}
+
+// +kubebuilder:rbac:groups=install.istio.io,resources=istiooperators,verbs=get;list;watch
+
+// Reconcile creates or updates the Istio resource that is equivalent to the IstioOperator. The Istio resource
+// isn't owned by the IstioOperator, since deleting the IstioOperator is part of the migration and must not
+// uninstall the control plane. The Istio is only updated when the IstioOperator changes, so that the changes
+// made to it during the migration, e.g. a version upgrade, aren't reverted.
+func (r *IstioOperatorReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	log := logf.FromContext(ctx)
+	iop := &unstructured.Unstructured{}
+	iop.SetGroupVersionKind(iopimport.IstioOperatorGVK)
+	if err := r.Client.Get(ctx, req.NamespacedName, iop); err != nil {
+		if errors.IsNotFound(err) {
+			log.V(2).Info("IstioOperator not found. Skipping reconciliation")
+			return ctrl.Result{}, nil
+		}
+		return ctrl.Result{}, err
+	}
+	if iop.GetDeletionTimestamp() != nil {
+		log.V(2).Info("IstioOperator is being deleted. Skipping reconciliation")
+		return ctrl.Result{}, nil
+	}
+
+	result, err := iopimport.Convert(iop, iopimport.Options{})
+	if err != nil {
+		// retrying won't help; the IstioOperator is reconciled again when it changes
+		r.EventRecorder.Eventf(iop, corev1.EventTypeWarning, EventReasonImportFailed, "failed to convert IstioOperator: %v", err)
+		return ctrl.Result{}, nil
+	}
+	if len(result.Unsupported) > 0 {
+		r.EventRecorder.Eventf(iop, corev1.EventTypeWarning, EventReasonUnsupportedFields,
+			"the following fields have no equivalent in the Istio resource and were ignored: %s", strings.Join(result.Unsupported, ", "))
+	}
+
+	applied, err := iopimport.Apply(ctx, r.Client, result.Istio)
+	if err != nil {
+		r.EventRecorder.Eventf(iop, corev1.EventTypeWarning, EventReasonImportFailed, "failed to import IstioOperator into Istio %s: %v", result.Istio.Name, err)
+		return ctrl.Result{}, err
+	}
+	if !applied {
+		log.V(2).Info("IstioOperator already imported", "Istio", result.Istio.Name)
+		return ctrl.Result{}, nil
+	}
+	log.Info("Imported IstioOperator", "Istio", result.Istio.Name)
+	r.EventRecorder.Eventf(iop, corev1.EventTypeNormal, EventReasonImported, "imported into Istio %s", result.Istio.Name)
+	return ctrl.Result{}, nil
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *IstioOperatorReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	iop := &unstructured.Unstructured{}
+	iop.SetGroupVersionKind(iopimport.IstioOperatorGVK)
+
+	return ctrl.NewControllerManagedBy(mgr).
+		WithOptions(controller.Options{
+			LogConstructor: func(req *reconcile.Request) logr.Logger {
+				log := mgr.GetLogger().WithName("ctrlr").WithName("iop")
+				if req != nil {
+					log = log.WithValues("IstioOperator", req.NamespacedName)
+				}
+				return log
+			},
+		}).
+		Named("istiooperator").
+		For(iop).
+		Complete(r)
+}
@@ -0,0 +1,177 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package istiooperator
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/kubectl/pkg/scheme"
+	"maistra.io/istio-operator/api/v1alpha1"
+	"maistra.io/istio-operator/pkg/iopimport"
+	"maistra.io/istio-operator/pkg/test"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestReconcile(t *testing.T) {
+	test.SetupScheme()
+	ctx := context.Background()
+
+	newIstioOperator := func(spec map[string]any) *unstructured.Unstructured {
+		iop := &unstructured.Unstructured{Object: map[string]any{"spec": spec}}
+		iop.SetGroupVersionKind(iopimport.IstioOperatorGVK)
+		iop.SetName("example")
+		iop.SetNamespace("istio-system")
+		return iop
+	}
+
+	withGeneration := func(iop *unstructured.Unstructured, generation int64) *unstructured.Unstructured {
+		iop.SetGeneration(generation)
+		return iop
+	}
+	importedIstio := func(generation, version string) *v1alpha1.Istio {
+		return &v1alpha1.Istio{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: "default",
+				Annotations: map[string]string{
+					iopimport.ImportedFromAnnotation:       "istio-system/example",
+					iopimport.ImportedGenerationAnnotation: generation,
+				},
+			},
+			Spec: v1alpha1.IstioSpec{Version: version, Namespace: "istio-system"},
+		}
+	}
+
+	tests := []struct {
+		name          string
+		iop           *unstructured.Unstructured
+		existing      []client.Object
+		expectErr     bool
+		expectIstio   string
+		expectVersion string
+		expectEvents  []string
+	}{
+		{
+			name: "IstioOperator not found",
+		},
+		{
+			name:          "creates Istio named after the revision",
+			iop:           newIstioOperator(map[string]any{"revision": "canary", "tag": "1.20.3"}),
+			expectIstio:   "canary",
+			expectVersion: "v1.20.3",
+			expectEvents:  []string{"Normal Imported imported into Istio canary"},
+		},
+		{
+			name:          "reports unsupported fields",
+			iop:           newIstioOperator(map[string]any{"tag": "1.20.3", "installPackagePath": "/tmp/charts"}),
+			expectIstio:   "default",
+			expectVersion: "v1.20.3",
+			expectEvents: []string{
+				"Warning UnsupportedFields the following fields have no equivalent in the Istio resource and were ignored: spec.installPackagePath",
+				"Normal Imported imported into Istio default",
+			},
+		},
+		{
+			name:         "invalid IstioOperator",
+			iop:          newIstioOperator(map[string]any{"revision": []any{"canary"}}),
+			expectEvents: []string{"Warning ImportFailed failed to convert IstioOperator: spec.revision is not a string"},
+		},
+		{
+			name:          "updates imported Istio when IstioOperator changes",
+			iop:           withGeneration(newIstioOperator(map[string]any{"tag": "1.20.3"}), 2),
+			existing:      []client.Object{importedIstio("1", "v1.19.7")},
+			expectIstio:   "default",
+			expectVersion: "v1.20.3",
+			expectEvents:  []string{"Normal Imported imported into Istio default"},
+		},
+		{
+			name:          "keeps changes made to imported Istio",
+			iop:           withGeneration(newIstioOperator(map[string]any{"tag": "1.20.3"}), 2),
+			existing:      []client.Object{importedIstio("2", "v1.21.0")},
+			expectIstio:   "default",
+			expectVersion: "v1.21.0",
+		},
+		{
+			name: "doesn't overwrite Istio created by user",
+			iop:  newIstioOperator(map[string]any{"tag": "1.20.3"}),
+			existing: []client.Object{
+				&v1alpha1.Istio{
+					ObjectMeta: metav1.ObjectMeta{Name: "default"},
+					Spec:       v1alpha1.IstioSpec{Version: "v1.19.7", Namespace: "istio-system"},
+				},
+			},
+			expectErr:     true,
+			expectIstio:   "default",
+			expectVersion: "v1.19.7",
+			expectEvents: []string{
+				"Warning ImportFailed failed to import IstioOperator into Istio default: " +
+					"an Istio named default already exists and wasn't imported from IstioOperator istio-system/example",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			objs := tt.existing
+			if tt.iop != nil {
+				objs = append(objs, tt.iop)
+			}
+			cl := fake.NewClientBuilder().WithScheme(scheme.Scheme).WithObjects(objs...).Build()
+			recorder := record.NewFakeRecorder(10)
+			reconciler := NewIstioOperatorReconciler(cl, scheme.Scheme, recorder)
+
+			_, err := reconciler.Reconcile(ctx, ctrl.Request{NamespacedName: client.ObjectKey{Name: "example", Namespace: "istio-system"}})
+			if (err != nil) != tt.expectErr {
+				t.Fatalf("Reconcile() error = %v, expectErr %v", err, tt.expectErr)
+			}
+
+			istioList := &v1alpha1.IstioList{}
+			if err := cl.List(ctx, istioList); err != nil {
+				t.Fatalf("failed to list Istios: %v", err)
+			}
+			if tt.expectIstio == "" {
+				if len(istioList.Items) > 0 {
+					t.Errorf("expected no Istio to be created, but got %s", istioList.Items[0].Name)
+				}
+			} else {
+				istio := &v1alpha1.Istio{}
+				if err := cl.Get(ctx, client.ObjectKey{Name: tt.expectIstio}, istio); errors.IsNotFound(err) {
+					t.Fatalf("expected Istio %s to be created", tt.expectIstio)
+				} else if err != nil {
+					t.Fatalf("failed to get Istio: %v", err)
+				}
+				if istio.Spec.Version != tt.expectVersion {
+					t.Errorf("expected version %s, but got %s", tt.expectVersion, istio.Spec.Version)
+				}
+			}
+
+			close(recorder.Events)
+			var events []string
+			for event := range recorder.Events {
+				events = append(events, event)
+			}
+			if strings.Join(events, "\n") != strings.Join(tt.expectEvents, "\n") {
+				t.Errorf("expected events %q, but got %q", tt.expectEvents, events)
+			}
+		})
+	}
+}
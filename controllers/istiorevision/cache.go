@@ -0,0 +1,32 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package istiorevision
+
+import (
+	"maistra.io/istio-operator/pkg/kube"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// CacheByObject returns the cache settings for the namespaces and pods watched by the IstioRevision and
+// IstioRevisionTag reconcilers. Since there can be a large number of them, the cache only keeps the metadata
+// these reconcilers read: the labels of both and the istio.io/rev annotation of pods, which records the
+// revision that injected the pod.
+func CacheByObject() map[client.Object]cache.ByObject {
+	return map[client.Object]cache.ByObject{
+		kube.NewMetadata(kube.NamespaceGVK): {Transform: kube.StripMetadata()},
+		kube.NewMetadata(kube.PodGVK):       {Transform: kube.StripMetadata(IstioRevLabel)},
+	}
+}
@@ -0,0 +1,225 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package istiorevision
+
+import (
+	"fmt"
+	"runtime"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	toolscache "k8s.io/client-go/tools/cache"
+	"maistra.io/istio-operator/pkg/kube"
+)
+
+func TestCacheByObject(t *testing.T) {
+	testCases := []struct {
+		name     string
+		gvk      schema.GroupVersionKind
+		obj      metav1.ObjectMeta
+		expected metav1.ObjectMeta
+	}{
+		{
+			name: "pod",
+			gvk:  kube.PodGVK,
+			obj: metav1.ObjectMeta{
+				Name:          "pod",
+				Namespace:     "ns",
+				Labels:        map[string]string{"app": "test", "istio.io/rev": "my-rev"},
+				Annotations:   map[string]string{"istio.io/rev": "my-rev", "sidecar.istio.io/status": "{}"},
+				ManagedFields: []metav1.ManagedFieldsEntry{{Manager: "kubelet"}},
+			},
+			expected: metav1.ObjectMeta{
+				Name:        "pod",
+				Namespace:   "ns",
+				Labels:      map[string]string{"app": "test", "istio.io/rev": "my-rev"},
+				Annotations: map[string]string{"istio.io/rev": "my-rev"},
+			},
+		},
+		{
+			name: "pod that wasn't injected",
+			gvk:  kube.PodGVK,
+			obj: metav1.ObjectMeta{
+				Name:        "pod",
+				Namespace:   "ns",
+				Annotations: map[string]string{"some": "annotation"},
+			},
+			expected: metav1.ObjectMeta{
+				Name:      "pod",
+				Namespace: "ns",
+			},
+		},
+		{
+			name: "namespace",
+			gvk:  kube.NamespaceGVK,
+			obj: metav1.ObjectMeta{
+				Name:          "ns",
+				Labels:        map[string]string{"istio.io/rev": "my-rev"},
+				Annotations:   map[string]string{"istio.io/rev": "my-rev", "kubectl.kubernetes.io/last-applied-configuration": "{}"},
+				ManagedFields: []metav1.ManagedFieldsEntry{{Manager: "kubectl"}},
+			},
+			expected: metav1.ObjectMeta{
+				Name:   "ns",
+				Labels: map[string]string{"istio.io/rev": "my-rev"},
+			},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			result, err := cacheTransform(t, tc.gvk)(&metav1.PartialObjectMetadata{ObjectMeta: tc.obj})
+			if err != nil {
+				t.Fatalf("expected no error, but got: %v", err)
+			}
+			if diff := cmp.Diff(tc.expected, result.(*metav1.PartialObjectMetadata).ObjectMeta); diff != "" {
+				t.Errorf("unexpected metadata; diff (-expected, +actual):\n%v", diff)
+			}
+		})
+	}
+
+	t.Run("tombstone", func(t *testing.T) {
+		tombstone := toolscache.DeletedFinalStateUnknown{Key: "ns/pod"}
+		if result, err := cacheTransform(t, kube.PodGVK)(tombstone); err != nil || result != tombstone {
+			t.Errorf("expected tombstone to be returned unchanged, but got %v, %v", result, err)
+		}
+	})
+}
+
+// BenchmarkPodCache compares the heap retained by an informer store that holds the full pods with the heap
+// retained by one that holds only their stripped metadata, as the cache configured by CacheByObject does.
+// Run it with: go test ./controllers/istiorevision -run=^$ -bench=BenchmarkPodCache
+func BenchmarkPodCache(b *testing.B) {
+	pods := newSyntheticPods(1000)
+	transform := cacheTransform(b, kube.PodGVK)
+
+	b.Run("full pods", func(b *testing.B) {
+		benchmarkStoreMemory(b, pods, func(pod *corev1.Pod) (any, error) {
+			return pod.DeepCopy(), nil
+		})
+	})
+	b.Run("pod metadata", func(b *testing.B) {
+		benchmarkStoreMemory(b, pods, func(pod *corev1.Pod) (any, error) {
+			// this is what the API server returns to a metadata-only informer
+			return transform(&metav1.PartialObjectMetadata{TypeMeta: pod.TypeMeta, ObjectMeta: *pod.ObjectMeta.DeepCopy()})
+		})
+	})
+}
+
+func cacheTransform(tb testing.TB, gvk schema.GroupVersionKind) toolscache.TransformFunc {
+	for obj, byObject := range CacheByObject() {
+		if obj.GetObjectKind().GroupVersionKind() == gvk && byObject.Transform != nil {
+			return byObject.Transform
+		}
+	}
+	tb.Fatalf("no cache transform defined for %s", gvk.Kind)
+	return nil
+}
+
+func benchmarkStoreMemory(b *testing.B, pods []corev1.Pod, toCached func(*corev1.Pod) (any, error)) {
+	var retained int64
+	for i := 0; i < b.N; i++ {
+		var before, after runtime.MemStats
+		runtime.GC()
+		runtime.ReadMemStats(&before)
+
+		store := toolscache.NewIndexer(toolscache.MetaNamespaceKeyFunc, toolscache.Indexers{})
+		for j := range pods {
+			obj, err := toCached(&pods[j])
+			if err != nil {
+				b.Fatal(err)
+			}
+			if err := store.Add(obj); err != nil {
+				b.Fatal(err)
+			}
+		}
+
+		runtime.GC()
+		runtime.ReadMemStats(&after)
+		retained += int64(after.HeapAlloc) - int64(before.HeapAlloc)
+		runtime.KeepAlive(store)
+	}
+	b.ReportMetric(float64(retained)/float64(b.N*len(pods)), "B/pod")
+}
+
+// newSyntheticPods returns injected pods with a spec and status that are typical for an application pod
+func newSyntheticPods(count int) []corev1.Pod {
+	pods := make([]corev1.Pod, count)
+	for i := range pods {
+		name := fmt.Sprintf("app-%d", i)
+		container := func(name, image string) corev1.Container {
+			return corev1.Container{
+				Name:  name,
+				Image: image,
+				Args:  []string{"proxy", "sidecar", "--domain", "$(POD_NAMESPACE).svc.cluster.local", "--log_output_level=default:info"},
+				Env: []corev1.EnvVar{
+					{Name: "POD_NAME", ValueFrom: &corev1.EnvVarSource{FieldRef: &corev1.ObjectFieldSelector{FieldPath: "metadata.name"}}},
+					{Name: "POD_NAMESPACE", ValueFrom: &corev1.EnvVarSource{FieldRef: &corev1.ObjectFieldSelector{FieldPath: "metadata.namespace"}}},
+					{Name: "ISTIO_META_APP_CONTAINERS", Value: "app"},
+					{Name: "ISTIO_META_CLUSTER_ID", Value: "Kubernetes"},
+					{Name: "PROXY_CONFIG", Value: "{}"},
+				},
+				Ports:        []corev1.ContainerPort{{Name: "http", ContainerPort: 8080, Protocol: corev1.ProtocolTCP}},
+				VolumeMounts: []corev1.VolumeMount{{Name: "istio-envoy", MountPath: "/etc/istio/proxy"}, {Name: "istio-token", MountPath: "/var/run/secrets/tokens"}},
+			}
+		}
+		pods[i] = corev1.Pod{
+			TypeMeta: metav1.TypeMeta{APIVersion: "v1", Kind: "Pod"},
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      name,
+				Namespace: fmt.Sprintf("ns-%d", i%20),
+				UID:       "6f1c3a8e-8d0b-4a0f-9c1e-2b7d5e4f3a21",
+				Labels:    map[string]string{"app": name, "version": "v1", "pod-template-hash": "5d9c7b6f8d", "service.istio.io/canonical-name": name},
+				Annotations: map[string]string{
+					IstioRevLabel:                   "my-rev",
+					"sidecar.istio.io/status":       `{"initContainers":["istio-init"],"containers":["istio-proxy"],"volumes":["istio-envoy","istio-token"]}`,
+					"kubectl.kubernetes.io/default": "app",
+				},
+				OwnerReferences: []metav1.OwnerReference{{APIVersion: "apps/v1", Kind: "ReplicaSet", Name: name + "-5d9c7b6f8d", UID: "uid"}},
+				ManagedFields: []metav1.ManagedFieldsEntry{
+					{Manager: "kube-controller-manager", Operation: metav1.ManagedFieldsOperationUpdate, APIVersion: "v1"},
+					{Manager: "kubelet", Operation: metav1.ManagedFieldsOperationUpdate, APIVersion: "v1", Subresource: "status"},
+				},
+			},
+			Spec: corev1.PodSpec{
+				InitContainers: []corev1.Container{container("istio-init", "docker.io/istio/proxyv2:1.20.3")},
+				Containers:     []corev1.Container{container("app", "quay.io/example/app:v1"), container("istio-proxy", "docker.io/istio/proxyv2:1.20.3")},
+				Volumes: []corev1.Volume{
+					{Name: "istio-envoy", VolumeSource: corev1.VolumeSource{EmptyDir: &corev1.EmptyDirVolumeSource{Medium: corev1.StorageMediumMemory}}},
+					{Name: "istio-token", VolumeSource: corev1.VolumeSource{Projected: &corev1.ProjectedVolumeSource{}}},
+				},
+				NodeName:           "worker-0",
+				ServiceAccountName: "default",
+			},
+			Status: corev1.PodStatus{
+				Phase:  corev1.PodRunning,
+				PodIP:  "10.0.0.1",
+				HostIP: "192.168.0.1",
+				Conditions: []corev1.PodCondition{
+					{Type: corev1.PodReady, Status: corev1.ConditionTrue},
+					{Type: corev1.ContainersReady, Status: corev1.ConditionTrue},
+					{Type: corev1.PodInitialized, Status: corev1.ConditionTrue},
+					{Type: corev1.PodScheduled, Status: corev1.ConditionTrue},
+				},
+				ContainerStatuses: []corev1.ContainerStatus{
+					{Name: "app", Ready: true, Image: "quay.io/example/app:v1", ContainerID: "cri-o://0123456789abcdef"},
+					{Name: "istio-proxy", Ready: true, Image: "docker.io/istio/proxyv2:1.20.3", ContainerID: "cri-o://fedcba9876543210"},
+				},
+			},
+		}
+	}
+	return pods
+}
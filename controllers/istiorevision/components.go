@@ -0,0 +1,330 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package istiorevision
+
+import (
+	"context"
+	"fmt"
+
+	admissionv1 "k8s.io/api/admissionregistration/v1"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"maistra.io/istio-operator/api/v1alpha1"
+	"maistra.io/istio-operator/pkg/helm"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+
+	"istio.io/istio/pkg/ptr"
+)
+
+// names of the components reported in status.components
+const (
+	istiodComponentName            = "istiod"
+	injectionWebhookComponentName  = "sidecar-injector-webhook"
+	validationWebhookComponentName = "validation-webhook"
+	cniComponentName               = "cni"
+	ztunnelComponentName           = "ztunnel"
+	gatewayComponentNamePrefix     = "gateway/"
+)
+
+// readinessComponents lists the components that determine the revision's Ready condition, in the order in
+// which they are checked
+var readinessComponents = []string{istiodComponentName, cniComponentName, ztunnelComponentName}
+
+// determineComponentStatuses returns the status of each component of the revision. Components that the
+// revision doesn't deploy, e.g. the CNI node agent when CNI is disabled, are omitted.
+func (r *IstioRevisionReconciler) determineComponentStatuses(ctx context.Context, rev *v1alpha1.IstioRevision) ([]v1alpha1.ComponentStatus, error) {
+	components := []v1alpha1.ComponentStatus{r.determineIstiodStatus(ctx, rev)}
+	components = append(components, r.determineWebhookStatuses(ctx, rev)...)
+	if isCNIEnabled(rev.Spec.Values) {
+		components = append(components, r.determineCNIStatus(ctx))
+	}
+	if isZTunnelEnabled(rev.Spec.Values) {
+		components = append(components, r.determineZTunnelStatus(ctx, rev))
+	}
+
+	gateways, err := r.determineGatewayStatuses(ctx, rev)
+	if err != nil {
+		return nil, err
+	}
+	return append(components, gateways...), nil
+}
+
+func (r *IstioRevisionReconciler) determineIstiodStatus(ctx context.Context, rev *v1alpha1.IstioRevision) v1alpha1.ComponentStatus {
+	status := v1alpha1.ComponentStatus{
+		Name:         istiodComponentName,
+		Kind:         "Deployment",
+		ChartVersion: getChartVersion(ctx, rev.Spec.Version, "istiod"),
+	}
+
+	istiod := appsv1.Deployment{}
+	if err := r.Client.Get(ctx, istiodDeploymentKey(rev), &istiod); err != nil {
+		return componentNotFound(status, err, v1alpha1.IstioRevisionConditionReasonIstiodNotReady, "istiod Deployment not found")
+	}
+	setDeploymentReplicas(&status, &istiod)
+
+	if istiod.Status.Replicas == 0 {
+		return componentNotReady(status, v1alpha1.IstioRevisionConditionReasonIstiodNotReady, "istiod Deployment is scaled to zero replicas")
+	} else if istiod.Status.ReadyReplicas < istiod.Status.Replicas {
+		return componentNotReady(status, v1alpha1.IstioRevisionConditionReasonIstiodNotReady, "not all istiod pods are ready")
+	}
+	status.Ready = metav1.ConditionTrue
+	return status
+}
+
+// determineWebhookStatuses returns the status of the revision's sidecar injector and validation webhooks.
+// A webhook is ready once istiod has injected its CA certificate into the webhook configuration. Webhooks
+// that aren't deployed, e.g. because config validation is disabled, are omitted.
+func (r *IstioRevisionReconciler) determineWebhookStatuses(ctx context.Context, rev *v1alpha1.IstioRevision) []v1alpha1.ComponentStatus {
+	var statuses []v1alpha1.ComponentStatus
+	chartVersion := getChartVersion(ctx, rev.Spec.Version, "istiod")
+
+	injector := admissionv1.MutatingWebhookConfiguration{}
+	if err := r.Client.Get(ctx, types.NamespacedName{Name: injectionWebhookName(rev)}, &injector); err == nil {
+		var caBundles [][]byte
+		for _, webhook := range injector.Webhooks {
+			caBundles = append(caBundles, webhook.ClientConfig.CABundle)
+		}
+		statuses = append(statuses, webhookStatus(injectionWebhookComponentName, "MutatingWebhookConfiguration", chartVersion, caBundles))
+	} else if !errors.IsNotFound(err) {
+		status := v1alpha1.ComponentStatus{Name: injectionWebhookComponentName, Kind: "MutatingWebhookConfiguration", ChartVersion: chartVersion}
+		statuses = append(statuses, componentNotFound(status, err, v1alpha1.IstioRevisionConditionReasonWebhookNotReady, ""))
+	}
+
+	validator := admissionv1.ValidatingWebhookConfiguration{}
+	if err := r.Client.Get(ctx, types.NamespacedName{Name: validationWebhookName(rev)}, &validator); err == nil {
+		var caBundles [][]byte
+		for _, webhook := range validator.Webhooks {
+			caBundles = append(caBundles, webhook.ClientConfig.CABundle)
+		}
+		statuses = append(statuses, webhookStatus(validationWebhookComponentName, "ValidatingWebhookConfiguration", chartVersion, caBundles))
+	} else if !errors.IsNotFound(err) {
+		status := v1alpha1.ComponentStatus{Name: validationWebhookComponentName, Kind: "ValidatingWebhookConfiguration", ChartVersion: chartVersion}
+		statuses = append(statuses, componentNotFound(status, err, v1alpha1.IstioRevisionConditionReasonWebhookNotReady, ""))
+	}
+	return statuses
+}
+
+func webhookStatus(name, kind, chartVersion string, caBundles [][]byte) v1alpha1.ComponentStatus {
+	status := v1alpha1.ComponentStatus{Name: name, Kind: kind, ChartVersion: chartVersion}
+	for _, caBundle := range caBundles {
+		if len(caBundle) == 0 {
+			return componentNotReady(status, v1alpha1.IstioRevisionConditionReasonWebhookNotReady,
+				"istiod has not injected its CA certificate into all the webhooks yet")
+		}
+	}
+	status.Ready = metav1.ConditionTrue
+	return status
+}
+
+// determineCNIStatus returns the status of the CNI node agent. Its readiness is taken from the IstioCNI
+// resource, while the replica counts and the image are taken from the istio-cni-node DaemonSet it deploys.
+func (r *IstioRevisionReconciler) determineCNIStatus(ctx context.Context) v1alpha1.ComponentStatus {
+	status := v1alpha1.ComponentStatus{
+		Name: cniComponentName,
+		Kind: "DaemonSet",
+	}
+
+	cni := v1alpha1.IstioCNI{}
+	if err := r.Client.Get(ctx, types.NamespacedName{Name: v1alpha1.IstioCNIName}, &cni); err != nil {
+		return componentNotFound(status, err, v1alpha1.IstioRevisionConditionReasonCNINotReady, "IstioCNI not found")
+	}
+	status.ChartVersion = getChartVersion(ctx, cni.Spec.Version, "cni")
+
+	// the DaemonSet is deployed in the operator's namespace, so it's looked up through its owner
+	dsList := appsv1.DaemonSetList{}
+	if err := r.Client.List(ctx, &dsList, client.MatchingLabels{"k8s-app": "istio-cni-node"}); err != nil {
+		return componentNotFound(status, err, v1alpha1.IstioRevisionConditionReasonCNINotReady, "")
+	}
+	for i := range dsList.Items {
+		if isOwnedBy(&dsList.Items[i], v1alpha1.IstioCNIKind, cni.Name) {
+			setDaemonSetReplicas(&status, &dsList.Items[i])
+			break
+		}
+	}
+
+	if readyCondition := cni.Status.GetCondition(v1alpha1.IstioCNIConditionTypeReady); readyCondition.Status != metav1.ConditionTrue {
+		message := "IstioCNI is not ready"
+		if readyCondition.Message != "" {
+			message += ": " + readyCondition.Message
+		}
+		return componentNotReady(status, v1alpha1.IstioRevisionConditionReasonCNINotReady, message)
+	}
+	status.Ready = metav1.ConditionTrue
+	return status
+}
+
+func (r *IstioRevisionReconciler) determineZTunnelStatus(ctx context.Context, rev *v1alpha1.IstioRevision) v1alpha1.ComponentStatus {
+	status := v1alpha1.ComponentStatus{
+		Name:         ztunnelComponentName,
+		Kind:         "DaemonSet",
+		ChartVersion: getChartVersion(ctx, rev.Spec.Version, "ztunnel"),
+	}
+
+	owner, preferred, err := r.getZTunnelOwners(ctx)
+	if err != nil {
+		return componentNotFound(status, err, v1alpha1.IstioRevisionConditionReasonZTunnelNotReady, "ztunnel DaemonSet not found")
+	}
+	if preferred != nil && isZTunnelOwner(rev, preferred) && !isZTunnelOwner(rev, owner) {
+		return componentNotReady(status, v1alpha1.IstioRevisionConditionReasonZTunnelHandoverBlocked,
+			fmt.Sprintf("ztunnel is managed by IstioRevision %q in namespace %q and can't be moved to namespace %q without interrupting "+
+				"the traffic of the ambient workloads; it's handed over once %q is deleted or disables ztunnel",
+				owner.Name, owner.Spec.Namespace, rev.Spec.Namespace, owner.Name))
+	}
+	ztunnelNamespace := rev.Spec.Namespace
+	if owner != nil {
+		ztunnelNamespace = owner.Spec.Namespace
+	}
+
+	ztunnel := appsv1.DaemonSet{}
+	if err := r.Client.Get(ctx, ztunnelDaemonSetKey(ztunnelNamespace), &ztunnel); err != nil {
+		return componentNotFound(status, err, v1alpha1.IstioRevisionConditionReasonZTunnelNotReady, "ztunnel DaemonSet not found")
+	}
+	setDaemonSetReplicas(&status, &ztunnel)
+
+	if ztunnel.Status.CurrentNumberScheduled == 0 {
+		return componentNotReady(status, v1alpha1.IstioRevisionConditionReasonZTunnelNotReady, "no ztunnel pods are currently scheduled")
+	} else if ztunnel.Status.NumberReady < ztunnel.Status.CurrentNumberScheduled {
+		return componentNotReady(status, v1alpha1.IstioRevisionConditionReasonZTunnelNotReady, "not all ztunnel pods are ready")
+	}
+	status.Ready = metav1.ConditionTrue
+	return status
+}
+
+// determineGatewayStatuses returns the status of the IstioGateways that are connected to the revision. Their
+// readiness is taken from the IstioGateway resources, while the replica counts and the image are taken from
+// the gateways' Deployments or DaemonSets.
+func (r *IstioRevisionReconciler) determineGatewayStatuses(ctx context.Context, rev *v1alpha1.IstioRevision) ([]v1alpha1.ComponentStatus, error) {
+	gwList := v1alpha1.IstioGatewayList{}
+	if err := r.Client.List(ctx, &gwList); err != nil {
+		return nil, fmt.Errorf("failed to list IstioGateways: %v", err)
+	}
+
+	var statuses []v1alpha1.ComponentStatus
+	for _, gw := range gwList.Items {
+		if gw.Status.IstioRevision != rev.Name {
+			continue
+		}
+
+		status := v1alpha1.ComponentStatus{
+			Name:         gatewayComponentNamePrefix + gw.Namespace + "/" + gw.Name,
+			Kind:         "Deployment",
+			ChartVersion: getChartVersion(ctx, rev.Spec.Version, "gateway"),
+		}
+		if gw.Spec.Values != nil && gw.Spec.Values.Kind == "DaemonSet" {
+			status.Kind = "DaemonSet"
+			ds := appsv1.DaemonSet{}
+			if err := r.Client.Get(ctx, client.ObjectKeyFromObject(&gw), &ds); err == nil {
+				setDaemonSetReplicas(&status, &ds)
+			} else if !errors.IsNotFound(err) {
+				return nil, err
+			}
+		} else {
+			deployment := appsv1.Deployment{}
+			if err := r.Client.Get(ctx, client.ObjectKeyFromObject(&gw), &deployment); err == nil {
+				setDeploymentReplicas(&status, &deployment)
+			} else if !errors.IsNotFound(err) {
+				return nil, err
+			}
+		}
+
+		readyCondition := gw.Status.GetCondition(v1alpha1.IstioGatewayConditionTypeReady)
+		switch readyCondition.Status {
+		case metav1.ConditionTrue:
+			status.Ready = metav1.ConditionTrue
+		case metav1.ConditionFalse:
+			status = componentNotReady(status, v1alpha1.IstioRevisionConditionReasonGatewayNotReady, readyCondition.Message)
+		default:
+			status.Ready = metav1.ConditionUnknown
+		}
+		statuses = append(statuses, status)
+	}
+	return statuses, nil
+}
+
+func componentNotReady(status v1alpha1.ComponentStatus, reason v1alpha1.IstioRevisionConditionReason, message string) v1alpha1.ComponentStatus {
+	status.Ready = metav1.ConditionFalse
+	status.Reason = reason
+	status.Message = message
+	return status
+}
+
+// componentNotFound returns the status of a component whose object couldn't be retrieved
+func componentNotFound(status v1alpha1.ComponentStatus, err error,
+	reason v1alpha1.IstioRevisionConditionReason, notFoundMessage string,
+) v1alpha1.ComponentStatus {
+	if errors.IsNotFound(err) {
+		return componentNotReady(status, reason, notFoundMessage)
+	}
+	return componentNotReady(status, v1alpha1.IstioRevisionConditionReasonReconcileError, fmt.Sprintf("failed to get readiness: %v", err))
+}
+
+func setDeploymentReplicas(status *v1alpha1.ComponentStatus, deployment *appsv1.Deployment) {
+	status.DesiredReplicas = ptr.Of(ptr.OrDefault(deployment.Spec.Replicas, 1))
+	status.ReadyReplicas = ptr.Of(deployment.Status.ReadyReplicas)
+	status.Image = getMainContainerImage(deployment.Spec.Template.Spec)
+}
+
+func setDaemonSetReplicas(status *v1alpha1.ComponentStatus, ds *appsv1.DaemonSet) {
+	status.DesiredReplicas = ptr.Of(ds.Status.DesiredNumberScheduled)
+	status.ReadyReplicas = ptr.Of(ds.Status.NumberReady)
+	status.Image = getMainContainerImage(ds.Spec.Template.Spec)
+}
+
+// getMainContainerImage returns the image of the first container, which is the main container in all the
+// workloads deployed by the Istio charts
+func getMainContainerImage(podSpec corev1.PodSpec) string {
+	if len(podSpec.Containers) == 0 {
+		return ""
+	}
+	return podSpec.Containers[0].Image
+}
+
+func getChartVersion(ctx context.Context, version, chartName string) string {
+	chartVersion, err := helm.GetChartVersion(version, chartName)
+	if err != nil {
+		logf.FromContext(ctx).V(2).Info("Failed to read chart version", "chart", chartName, "version", version, "error", err)
+		return ""
+	}
+	return chartVersion
+}
+
+func isOwnedBy(obj client.Object, kind, name string) bool {
+	for _, ref := range obj.GetOwnerReferences() {
+		if ref.Kind == kind && ref.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+func injectionWebhookName(rev *v1alpha1.IstioRevision) string {
+	name := "istio-sidecar-injector"
+	if rev.Spec.Values != nil && rev.Spec.Values.Revision != "" {
+		name += "-" + rev.Spec.Values.Revision
+	}
+	return name
+}
+
+func validationWebhookName(rev *v1alpha1.IstioRevision) string {
+	name := "istio-validator"
+	if rev.Spec.Values != nil && rev.Spec.Values.Revision != "" {
+		name += "-" + rev.Spec.Values.Revision
+	}
+	return name + "-" + rev.Spec.Namespace
+}
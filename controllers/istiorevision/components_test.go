@@ -0,0 +1,211 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package istiorevision
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	admissionv1 "k8s.io/api/admissionregistration/v1"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/kubectl/pkg/scheme"
+	v1 "maistra.io/istio-operator/api/v1alpha1"
+	"maistra.io/istio-operator/pkg/test"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"istio.io/istio/pkg/ptr"
+)
+
+func TestDetermineComponentStatuses(t *testing.T) {
+	test.SetupScheme()
+
+	rev := &v1.IstioRevision{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "my-rev",
+		},
+		Spec: v1.IstioRevisionSpec{
+			Namespace: "istio-system",
+			Values: &v1.Values{
+				Revision: "my-rev",
+				IstioCni: &v1.CNIConfig{Enabled: true},
+			},
+		},
+	}
+	podSpec := func(image string) corev1.PodTemplateSpec {
+		return corev1.PodTemplateSpec{
+			Spec: corev1.PodSpec{Containers: []corev1.Container{{Name: "main", Image: image}}},
+		}
+	}
+
+	cl := fake.NewClientBuilder().
+		WithScheme(scheme.Scheme).
+		WithObjects(
+			&appsv1.Deployment{
+				ObjectMeta: metav1.ObjectMeta{Name: "istiod-my-rev", Namespace: "istio-system"},
+				Spec:       appsv1.DeploymentSpec{Replicas: ptr.Of(int32(2)), Template: podSpec("istio/pilot:1.20.3")},
+				Status:     appsv1.DeploymentStatus{Replicas: 2, ReadyReplicas: 1},
+			},
+			&admissionv1.MutatingWebhookConfiguration{
+				ObjectMeta: metav1.ObjectMeta{Name: "istio-sidecar-injector-my-rev"},
+				Webhooks: []admissionv1.MutatingWebhook{
+					{Name: "rev.namespace.sidecar-injector.istio.io", ClientConfig: admissionv1.WebhookClientConfig{CABundle: []byte("ca")}},
+				},
+			},
+			&admissionv1.ValidatingWebhookConfiguration{
+				ObjectMeta: metav1.ObjectMeta{Name: "istio-validator-my-rev-istio-system"},
+				Webhooks:   []admissionv1.ValidatingWebhook{{Name: "rev.validation.istio.io"}},
+			},
+			&v1.IstioCNI{
+				ObjectMeta: metav1.ObjectMeta{Name: v1.IstioCNIName},
+				Status: v1.IstioCNIStatus{
+					Conditions: []v1.IstioCNICondition{{Type: v1.IstioCNIConditionTypeReady, Status: metav1.ConditionTrue}},
+				},
+			},
+			&appsv1.DaemonSet{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:            "istio-cni-node",
+					Namespace:       "sail-operator",
+					Labels:          map[string]string{"k8s-app": "istio-cni-node"},
+					OwnerReferences: []metav1.OwnerReference{{Kind: v1.IstioCNIKind, Name: v1.IstioCNIName}},
+				},
+				Spec:   appsv1.DaemonSetSpec{Template: podSpec("istio/install-cni:1.20.3")},
+				Status: appsv1.DaemonSetStatus{DesiredNumberScheduled: 3, NumberReady: 3},
+			},
+			&v1.IstioGateway{
+				ObjectMeta: metav1.ObjectMeta{Name: "ingress", Namespace: "gateways"},
+				Status: v1.IstioGatewayStatus{
+					IstioRevision: "my-rev",
+					Conditions: []v1.IstioGatewayCondition{
+						{
+							Type:    v1.IstioGatewayConditionTypeReady,
+							Status:  metav1.ConditionFalse,
+							Reason:  v1.IstioGatewayConditionReasonServiceNotReady,
+							Message: "gateway Service not found",
+						},
+					},
+				},
+			},
+			&appsv1.Deployment{
+				ObjectMeta: metav1.ObjectMeta{Name: "ingress", Namespace: "gateways"},
+				Spec:       appsv1.DeploymentSpec{Template: podSpec("auto")},
+				Status:     appsv1.DeploymentStatus{Replicas: 1, ReadyReplicas: 1},
+			},
+			&v1.IstioGateway{
+				ObjectMeta: metav1.ObjectMeta{Name: "other", Namespace: "gateways"},
+				Status:     v1.IstioGatewayStatus{IstioRevision: "other-rev"},
+			},
+		).
+		Build()
+	r := NewIstioRevisionReconciler(cl, scheme.Scheme, nil, nil)
+
+	components, err := r.determineComponentStatuses(context.TODO(), rev)
+	if err != nil {
+		t.Fatalf("Expected no error, but got: %v", err)
+	}
+
+	expected := []v1.ComponentStatus{
+		{
+			Name:            "istiod",
+			Kind:            "Deployment",
+			DesiredReplicas: ptr.Of(int32(2)),
+			ReadyReplicas:   ptr.Of(int32(1)),
+			Image:           "istio/pilot:1.20.3",
+			Ready:           metav1.ConditionFalse,
+			Reason:          v1.IstioRevisionConditionReasonIstiodNotReady,
+			Message:         "not all istiod pods are ready",
+		},
+		{
+			Name:  "sidecar-injector-webhook",
+			Kind:  "MutatingWebhookConfiguration",
+			Ready: metav1.ConditionTrue,
+		},
+		{
+			Name:    "validation-webhook",
+			Kind:    "ValidatingWebhookConfiguration",
+			Ready:   metav1.ConditionFalse,
+			Reason:  v1.IstioRevisionConditionReasonWebhookNotReady,
+			Message: "istiod has not injected its CA certificate into all the webhooks yet",
+		},
+		{
+			Name:            "cni",
+			Kind:            "DaemonSet",
+			DesiredReplicas: ptr.Of(int32(3)),
+			ReadyReplicas:   ptr.Of(int32(3)),
+			Image:           "istio/install-cni:1.20.3",
+			Ready:           metav1.ConditionTrue,
+		},
+		{
+			Name:            "gateway/gateways/ingress",
+			Kind:            "Deployment",
+			DesiredReplicas: ptr.Of(int32(1)),
+			ReadyReplicas:   ptr.Of(int32(1)),
+			Image:           "auto",
+			Ready:           metav1.ConditionFalse,
+			Reason:          v1.IstioRevisionConditionReasonGatewayNotReady,
+			Message:         "gateway Service not found",
+		},
+	}
+	if diff := cmp.Diff(expected, components); diff != "" {
+		t.Errorf("unexpected components; diff (-expected, +actual):\n%v", diff)
+	}
+
+	t.Run("ready condition reports first component that isn't ready", func(t *testing.T) {
+		readyCondition := determineReadyCondition(components)
+		if readyCondition.Status != metav1.ConditionFalse || readyCondition.Reason != v1.IstioRevisionConditionReasonIstiodNotReady {
+			t.Errorf("expected Ready condition to report istiod, but got: %+v", readyCondition)
+		}
+	})
+
+	t.Run("webhooks and gateways don't affect the ready condition", func(t *testing.T) {
+		readyCondition := determineReadyCondition(components[1:])
+		if readyCondition.Status != metav1.ConditionTrue {
+			t.Errorf("expected Ready condition to be True, but got: %+v", readyCondition)
+		}
+	})
+}
+
+func TestDetermineZTunnelStatusWithBlockedHandover(t *testing.T) {
+	test.SetupScheme()
+	oldRev := newZTunnelRevision("old", time.Now().Add(-time.Hour), true)
+	newRev := inNamespace(newZTunnelRevision("new", time.Now(), true), "other-namespace")
+	ds := &appsv1.DaemonSet{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "ztunnel",
+			Namespace: oldRev.Spec.Namespace,
+			OwnerReferences: []metav1.OwnerReference{{
+				APIVersion: v1.GroupVersion.String(),
+				Kind:       v1.IstioRevisionKind,
+				Name:       oldRev.Name,
+				UID:        oldRev.UID,
+				Controller: ptr.Of(true),
+			}},
+		},
+		Status: appsv1.DaemonSetStatus{DesiredNumberScheduled: 2, CurrentNumberScheduled: 2, NumberReady: 2},
+	}
+	cl := fake.NewClientBuilder().WithScheme(scheme.Scheme).WithObjects(&oldRev, &newRev, ds).Build()
+	r := &IstioRevisionReconciler{Client: cl, Scheme: scheme.Scheme}
+
+	if status := r.determineZTunnelStatus(context.TODO(), &oldRev); status.Ready != metav1.ConditionTrue {
+		t.Errorf("expected ztunnel to be ready for the revision that manages it, but got: %+v", status)
+	}
+	status := r.determineZTunnelStatus(context.TODO(), &newRev)
+	if status.Ready != metav1.ConditionFalse || status.Reason != v1.IstioRevisionConditionReasonZTunnelHandoverBlocked {
+		t.Errorf("expected ztunnel handover to be reported as blocked, but got: %+v", status)
+	}
+}
@@ -0,0 +1,189 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package istiorevision
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"maistra.io/istio-operator/api/v1alpha1"
+	"maistra.io/istio-operator/pkg/helm"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// maxReportedDrifts is the maximum number of drifted objects listed in the Drifted condition
+const maxReportedDrifts = 10
+
+// builtinIgnoredFields lists the fields that istiod itself modifies: it injects its CA certificate into the
+// webhook configurations and switches the failure policy of the validating webhook once the webhook is ready.
+var builtinIgnoredFields = []v1alpha1.DriftIgnoreRule{
+	{
+		Kind:  "MutatingWebhookConfiguration",
+		Paths: []string{"webhooks[*].clientConfig.caBundle"},
+	},
+	{
+		Kind:  "ValidatingWebhookConfiguration",
+		Paths: []string{"webhooks[*].clientConfig.caBundle", "webhooks[*].failurePolicy"},
+	},
+}
+
+// reconcileHelmCharts installs or upgrades the charts when the rendered manifests differ from the installed
+// releases. It also detects the changes made to the installed objects outside of the operator and, depending on
+// the revision's drift policy, reverts them by upgrading the charts. It returns the detected changes and whether
+// the charts were upgraded, which also reverts all the changes.
+func (r *IstioRevisionReconciler) reconcileHelmCharts(ctx context.Context, rev *v1alpha1.IstioRevision,
+	ownsZTunnel bool,
+) ([]helm.Drift, bool, error) {
+	log := logf.FromContext(ctx)
+
+	ignore, err := newDriftIgnoreFunc(rev)
+	if err != nil {
+		return nil, false, err
+	}
+
+	manifests, err := r.renderManifests(rev, ownsZTunnel)
+	if err != nil {
+		return nil, false, err
+	}
+	previousManifests, deployed, err := r.getReleaseManifests(ctx, rev, ownsZTunnel)
+	if err != nil {
+		return nil, false, err
+	}
+	upToDate, err := helm.ManifestsEqual(previousManifests, manifests)
+	if err != nil {
+		return nil, false, err
+	}
+	drifts, err := helm.DetectDrift(ctx, r.Client, previousManifests, rev.Spec.Namespace, ignore)
+	if err != nil {
+		return nil, false, err
+	}
+
+	if deployed && upToDate && (len(drifts) == 0 || getDriftAction(rev) == v1alpha1.DriftActionReport) {
+		if len(drifts) > 0 {
+			log.Info("Components were changed outside of the operator; leaving the changes in place", "changes", len(drifts))
+		}
+		return drifts, false, nil
+	}
+
+	if len(drifts) > 0 {
+		log.Info("Reverting changes made to components outside of the operator", "changes", len(drifts))
+	}
+	if err := r.installHelmCharts(ctx, rev, ownsZTunnel); err != nil {
+		if deployed {
+			r.EventRecorder.Eventf(rev, corev1.EventTypeWarning, EventReasonHelmUpgradeFailed, "failed to upgrade the Helm releases: %v", err)
+		} else {
+			r.EventRecorder.Eventf(rev, corev1.EventTypeWarning, EventReasonHelmInstallFailed, "failed to install the Helm releases: %v", err)
+		}
+		return drifts, true, err
+	}
+	if deployed {
+		r.EventRecorder.TransitionEventf(rev, corev1.EventTypeNormal, EventReasonHelmUpgradeSucceeded, "upgraded the Helm releases to version %s", rev.Spec.Version)
+	} else {
+		r.EventRecorder.TransitionEventf(rev, corev1.EventTypeNormal, EventReasonHelmInstallSucceeded, "installed the Helm releases of version %s", rev.Spec.Version)
+	}
+	return drifts, true, nil
+}
+
+func getDriftAction(rev *v1alpha1.IstioRevision) v1alpha1.DriftAction {
+	if rev.Spec.DriftPolicy == nil || rev.Spec.DriftPolicy.Action == "" {
+		return v1alpha1.DriftActionRevert
+	}
+	return rev.Spec.DriftPolicy.Action
+}
+
+// newDriftIgnoreFunc returns the function that determines which changes are ignored, based on the built-in
+// rules and the rules in the revision's drift policy
+func newDriftIgnoreFunc(rev *v1alpha1.IstioRevision) (helm.IgnoreFunc, error) {
+	rules := builtinIgnoredFields
+	if rev.Spec.DriftPolicy != nil {
+		rules = append(append([]v1alpha1.DriftIgnoreRule{}, rules...), rev.Spec.DriftPolicy.IgnoredFields...)
+	}
+
+	type compiledRule struct {
+		kind     string
+		name     string
+		patterns []helm.FieldPath
+	}
+	compiledRules := make([]compiledRule, 0, len(rules))
+	for _, rule := range rules {
+		compiled := compiledRule{kind: rule.Kind, name: rule.Name}
+		for _, p := range rule.Paths {
+			pattern, err := helm.ParseFieldPath(p)
+			if err != nil {
+				return nil, fmt.Errorf("invalid spec.driftPolicy.ignoredFields: %v", err)
+			}
+			compiled.patterns = append(compiled.patterns, pattern)
+		}
+		compiledRules = append(compiledRules, compiled)
+	}
+
+	return func(obj *unstructured.Unstructured, path helm.FieldPath) bool {
+		for _, rule := range compiledRules {
+			if (rule.kind != "" && rule.kind != obj.GetKind()) || (rule.name != "" && rule.name != obj.GetName()) {
+				continue
+			}
+			for _, pattern := range rule.patterns {
+				if path.Matches(pattern) {
+					return true
+				}
+			}
+		}
+		return false
+	}, nil
+}
+
+func determineDriftedCondition(rev *v1alpha1.IstioRevision, drifts []helm.Drift, upgraded bool) v1alpha1.IstioRevisionCondition {
+	if len(drifts) == 0 {
+		// keep reporting the most recently reverted changes
+		if previous := rev.Status.GetCondition(v1alpha1.IstioRevisionConditionTypeDrifted); previous.Reason == v1alpha1.IstioRevisionConditionReasonDriftReverted {
+			return previous
+		}
+		return v1alpha1.IstioRevisionCondition{
+			Type:   v1alpha1.IstioRevisionConditionTypeDrifted,
+			Status: metav1.ConditionFalse,
+		}
+	}
+
+	if upgraded {
+		return v1alpha1.IstioRevisionCondition{
+			Type:    v1alpha1.IstioRevisionConditionTypeDrifted,
+			Status:  metav1.ConditionFalse,
+			Reason:  v1alpha1.IstioRevisionConditionReasonDriftReverted,
+			Message: "reverted changes made outside of the operator: " + summarizeDrifts(drifts),
+		}
+	}
+	return v1alpha1.IstioRevisionCondition{
+		Type:    v1alpha1.IstioRevisionConditionTypeDrifted,
+		Status:  metav1.ConditionTrue,
+		Reason:  v1alpha1.IstioRevisionConditionReasonDriftDetected,
+		Message: "objects were changed outside of the operator: " + summarizeDrifts(drifts),
+	}
+}
+
+func summarizeDrifts(drifts []helm.Drift) string {
+	var descriptions []string
+	for i, drift := range drifts {
+		if i == maxReportedDrifts {
+			descriptions = append(descriptions, fmt.Sprintf("and %d more", len(drifts)-maxReportedDrifts))
+			break
+		}
+		descriptions = append(descriptions, drift.String())
+	}
+	return strings.Join(descriptions, "; ")
+}
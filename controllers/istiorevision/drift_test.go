@@ -0,0 +1,180 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package istiorevision
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	v1 "maistra.io/istio-operator/api/v1alpha1"
+	"maistra.io/istio-operator/pkg/helm"
+)
+
+func TestNewDriftIgnoreFunc(t *testing.T) {
+	rev := &v1.IstioRevision{
+		Spec: v1.IstioRevisionSpec{
+			DriftPolicy: &v1.DriftPolicy{
+				IgnoredFields: []v1.DriftIgnoreRule{
+					{Kind: "Deployment", Name: "istiod", Paths: []string{"spec.replicas"}},
+					{Paths: []string{"metadata.annotations[example.com/owner]"}},
+				},
+			},
+		},
+	}
+	ignore, err := newDriftIgnoreFunc(rev)
+	if err != nil {
+		t.Fatalf("Expected no error, but got: %v", err)
+	}
+
+	testCases := []struct {
+		kind         string
+		name         string
+		path         string
+		expectIgnore bool
+	}{
+		{kind: "Deployment", name: "istiod", path: "spec.replicas", expectIgnore: true},
+		{kind: "Deployment", name: "istiod", path: "spec.template.spec.containers[0].image", expectIgnore: false},
+		{kind: "Deployment", name: "other", path: "spec.replicas", expectIgnore: false},
+		{kind: "Service", name: "istiod", path: "spec.replicas", expectIgnore: false},
+		{kind: "Service", name: "istiod", path: "metadata.annotations[example.com/owner]", expectIgnore: true},
+		{kind: "ValidatingWebhookConfiguration", name: "istiod", path: "webhooks[1].failurePolicy", expectIgnore: true},
+		{kind: "ValidatingWebhookConfiguration", name: "istiod", path: "webhooks[1].clientConfig.caBundle", expectIgnore: true},
+		{kind: "MutatingWebhookConfiguration", name: "istiod", path: "webhooks[0].clientConfig.caBundle", expectIgnore: true},
+		{kind: "MutatingWebhookConfiguration", name: "istiod", path: "webhooks[0].failurePolicy", expectIgnore: false},
+	}
+	for _, tc := range testCases {
+		t.Run(fmt.Sprintf("%s %s %s", tc.kind, tc.name, tc.path), func(t *testing.T) {
+			obj := &unstructured.Unstructured{}
+			obj.SetKind(tc.kind)
+			obj.SetName(tc.name)
+			path, err := helm.ParseFieldPath(tc.path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if ignored := ignore(obj, path); ignored != tc.expectIgnore {
+				t.Errorf("expected ignored to be %v, but got %v", tc.expectIgnore, ignored)
+			}
+		})
+	}
+
+	t.Run("invalid path", func(t *testing.T) {
+		rev := &v1.IstioRevision{
+			Spec: v1.IstioRevisionSpec{
+				DriftPolicy: &v1.DriftPolicy{
+					IgnoredFields: []v1.DriftIgnoreRule{{Paths: []string{"spec..replicas"}}},
+				},
+			},
+		}
+		if _, err := newDriftIgnoreFunc(rev); err == nil {
+			t.Errorf("Expected error, but got none")
+		}
+	})
+}
+
+func TestDetermineDriftedCondition(t *testing.T) {
+	drifts := []helm.Drift{
+		{Kind: "Deployment", Namespace: "istio-system", Name: "istiod", Paths: []helm.FieldPath{{"spec", "replicas"}}},
+		{Kind: "Service", Namespace: "istio-system", Name: "istiod", Deleted: true},
+	}
+	reverted := v1.IstioRevisionCondition{
+		Type:    v1.IstioRevisionConditionTypeDrifted,
+		Status:  metav1.ConditionFalse,
+		Reason:  v1.IstioRevisionConditionReasonDriftReverted,
+		Message: "reverted changes made outside of the operator: Deployment istio-system/istiod changed at spec.replicas; Service istio-system/istiod deleted",
+	}
+
+	testCases := []struct {
+		name       string
+		conditions []v1.IstioRevisionCondition
+		drifts     []helm.Drift
+		upgraded   bool
+		expected   v1.IstioRevisionCondition
+	}{
+		{
+			name: "no drift",
+			expected: v1.IstioRevisionCondition{
+				Type:   v1.IstioRevisionConditionTypeDrifted,
+				Status: metav1.ConditionFalse,
+			},
+		},
+		{
+			name:     "drift reported",
+			drifts:   drifts,
+			upgraded: false,
+			expected: v1.IstioRevisionCondition{
+				Type:   v1.IstioRevisionConditionTypeDrifted,
+				Status: metav1.ConditionTrue,
+				Reason: v1.IstioRevisionConditionReasonDriftDetected,
+				Message: "objects were changed outside of the operator: " +
+					"Deployment istio-system/istiod changed at spec.replicas; Service istio-system/istiod deleted",
+			},
+		},
+		{
+			name:     "drift reverted",
+			drifts:   drifts,
+			upgraded: true,
+			expected: reverted,
+		},
+		{
+			name:       "keeps reporting reverted drift",
+			conditions: []v1.IstioRevisionCondition{reverted},
+			expected:   reverted,
+		},
+		{
+			name: "clears reported drift",
+			conditions: []v1.IstioRevisionCondition{
+				{
+					Type:   v1.IstioRevisionConditionTypeDrifted,
+					Status: metav1.ConditionTrue,
+					Reason: v1.IstioRevisionConditionReasonDriftDetected,
+				},
+			},
+			expected: v1.IstioRevisionCondition{
+				Type:   v1.IstioRevisionConditionTypeDrifted,
+				Status: metav1.ConditionFalse,
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			rev := &v1.IstioRevision{
+				Status: v1.IstioRevisionStatus{Conditions: tc.conditions},
+			}
+			result := determineDriftedCondition(rev, tc.drifts, tc.upgraded)
+			if diff := cmp.Diff(tc.expected, result); diff != "" {
+				t.Errorf("unexpected condition; diff (-expected, +actual):\n%v", diff)
+			}
+		})
+	}
+}
+
+func TestSummarizeDriftsIsBounded(t *testing.T) {
+	var drifts []helm.Drift
+	for i := 0; i < maxReportedDrifts+5; i++ {
+		drifts = append(drifts, helm.Drift{Kind: "ConfigMap", Namespace: "istio-system", Name: fmt.Sprintf("cm-%d", i), Deleted: true})
+	}
+	summary := summarizeDrifts(drifts)
+	if count := strings.Count(summary, " deleted"); count != maxReportedDrifts {
+		t.Errorf("expected %d objects to be listed, but got %d", maxReportedDrifts, count)
+	}
+	if !strings.HasSuffix(summary, "; and 5 more") {
+		t.Errorf("expected summary to end with the number of objects not listed, but got %q", summary)
+	}
+}
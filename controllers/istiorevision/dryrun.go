@@ -0,0 +1,106 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package istiorevision
+
+import (
+	"context"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"maistra.io/istio-operator/api/v1alpha1"
+	"maistra.io/istio-operator/pkg/helm"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+const (
+	// DryRunManifestsKey is the key under which the dry-run ConfigMap holds the rendered manifests
+	DryRunManifestsKey = "manifests.yaml"
+
+	// DryRunDiffKey is the key under which the dry-run ConfigMap holds the diff between the rendered manifests
+	// and the live objects in the cluster
+	DryRunDiffKey = "diff"
+)
+
+// renderHelmCharts renders the charts that installHelmCharts would install or upgrade and writes the resulting
+// manifests, together with their diff against the live objects, to the dry-run ConfigMap. Nothing else in the
+// cluster is modified.
+func (r *IstioRevisionReconciler) renderHelmCharts(ctx context.Context, rev *v1alpha1.IstioRevision) error {
+	log := logf.FromContext(ctx)
+
+	ztunnelOwner, err := r.getZTunnelOwner(ctx)
+	if err != nil {
+		return err
+	}
+	ownsZTunnel := isZTunnelOwner(rev, ztunnelOwner)
+	manifests, err := r.renderManifests(rev, ownsZTunnel)
+	if err != nil {
+		return err
+	}
+
+	// the ztunnel release is only rendered by the revision that manages it
+	previousManifests, _, err := r.getReleaseManifests(ctx, rev, ownsZTunnel)
+	if err != nil {
+		return err
+	}
+
+	diff, err := helm.DiffManifests(ctx, r.Client, previousManifests, manifests, rev.Spec.Namespace)
+	if err != nil {
+		return err
+	}
+
+	key := dryRunConfigMapKey(rev)
+	cm := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: key.Name, Namespace: key.Namespace}}
+	result, err := controllerutil.CreateOrUpdate(ctx, r.Client, cm, func() error {
+		cm.Data = map[string]string{
+			DryRunManifestsKey: manifests,
+			DryRunDiffKey:      diff,
+		}
+		return controllerutil.SetControllerReference(rev, cm, r.Scheme)
+	})
+	if err != nil {
+		return err
+	}
+	log.V(2).Info("Wrote dry-run ConfigMap", "ConfigMap", key, "result", result)
+	return nil
+}
+
+// deleteDryRunConfigMap deletes the ConfigMap written in dry-run mode, so that the outdated manifests
+// don't linger after the dry-run mode is disabled
+func (r *IstioRevisionReconciler) deleteDryRunConfigMap(ctx context.Context, rev *v1alpha1.IstioRevision) error {
+	cm := &corev1.ConfigMap{}
+	if err := r.Client.Get(ctx, dryRunConfigMapKey(rev), cm); err != nil {
+		if errors.IsNotFound(err) {
+			return nil
+		}
+		return err
+	}
+	if !metav1.IsControlledBy(cm, rev) {
+		return nil
+	}
+	if err := r.Client.Delete(ctx, cm); err != nil && !errors.IsNotFound(err) {
+		return err
+	}
+	return nil
+}
+
+func dryRunConfigMapKey(rev *v1alpha1.IstioRevision) types.NamespacedName {
+	return types.NamespacedName{
+		Name:      rev.Name + "-dry-run",
+		Namespace: rev.Spec.Namespace,
+	}
+}
@@ -0,0 +1,93 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package istiorevision
+
+import (
+	"context"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/kubectl/pkg/scheme"
+	v1 "maistra.io/istio-operator/api/v1alpha1"
+	"maistra.io/istio-operator/pkg/test"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestDeleteDryRunConfigMap(t *testing.T) {
+	test.SetupScheme()
+
+	rev := &v1.IstioRevision{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "my-rev",
+			UID:  "my-uid",
+		},
+		Spec: v1.IstioRevisionSpec{
+			Namespace: "istio-system",
+		},
+	}
+	key := dryRunConfigMapKey(rev)
+
+	testCases := []struct {
+		name         string
+		ownerRefs    []metav1.OwnerReference
+		expectDelete bool
+	}{
+		{
+			name:         "owned by revision",
+			ownerRefs:    []metav1.OwnerReference{newOwnerReference(rev)},
+			expectDelete: true,
+		},
+		{
+			name:         "not owned by revision",
+			expectDelete: false,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			cm := &corev1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:            key.Name,
+					Namespace:       key.Namespace,
+					OwnerReferences: tc.ownerRefs,
+				},
+			}
+			cl := fake.NewClientBuilder().WithScheme(scheme.Scheme).WithObjects(cm).Build()
+			r := &IstioRevisionReconciler{Client: cl, Scheme: scheme.Scheme}
+
+			if err := r.deleteDryRunConfigMap(context.TODO(), rev); err != nil {
+				t.Fatalf("Expected no error, but got: %v", err)
+			}
+
+			err := cl.Get(context.TODO(), client.ObjectKeyFromObject(cm), cm)
+			if tc.expectDelete && !errors.IsNotFound(err) {
+				t.Errorf("Expected ConfigMap to be deleted, but got: %v", err)
+			} else if !tc.expectDelete && err != nil {
+				t.Errorf("Expected ConfigMap to be kept, but got: %v", err)
+			}
+		})
+	}
+
+	t.Run("ConfigMap doesn't exist", func(t *testing.T) {
+		cl := fake.NewClientBuilder().WithScheme(scheme.Scheme).Build()
+		r := &IstioRevisionReconciler{Client: cl, Scheme: scheme.Scheme}
+		if err := r.deleteDryRunConfigMap(context.TODO(), rev); err != nil {
+			t.Errorf("Expected no error, but got: %v", err)
+		}
+	})
+}
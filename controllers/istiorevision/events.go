@@ -0,0 +1,57 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package istiorevision
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"maistra.io/istio-operator/api/v1alpha1"
+)
+
+// reasons of the Events recorded for IstioRevisions
+const (
+	EventReasonHelmInstallSucceeded    = "HelmInstallSucceeded"
+	EventReasonHelmInstallFailed       = "HelmInstallFailed"
+	EventReasonHelmUpgradeSucceeded    = "HelmUpgradeSucceeded"
+	EventReasonHelmUpgradeFailed       = "HelmUpgradeFailed"
+	EventReasonHelmUninstallSucceeded  = "HelmUninstallSucceeded"
+	EventReasonHelmUninstallFailed     = "HelmUninstallFailed"
+	EventReasonHelmReleaseAdopted      = "HelmReleaseAdopted"
+	EventReasonCNIOwnershipSkipped     = "CNIOwnershipSkipped"
+	EventReasonZTunnelOwnershipSkipped = "ZTunnelOwnershipSkipped"
+	EventReasonZTunnelTransferred      = "ZTunnelTransferred"
+	EventReasonReady                   = "Ready"
+	EventReasonNotReady                = "NotReady"
+)
+
+// isNewGeneration returns whether the revision's spec changed since its status was last updated. Events that
+// describe the spec rather than a change of state are only recorded then, so that they aren't repeated on
+// every reconcile.
+func isNewGeneration(rev *v1alpha1.IstioRevision) bool {
+	return rev.Generation != rev.Status.ObservedGeneration
+}
+
+// recordReadyTransition records an Event when the revision's Ready condition changes between True and False
+func (r *IstioRevisionReconciler) recordReadyTransition(rev *v1alpha1.IstioRevision, readyCondition v1alpha1.IstioRevisionCondition) {
+	if rev.Status.GetCondition(v1alpha1.IstioRevisionConditionTypeReady).Status == readyCondition.Status {
+		return
+	}
+	switch readyCondition.Status {
+	case metav1.ConditionTrue:
+		r.EventRecorder.TransitionEvent(rev, corev1.EventTypeNormal, EventReasonReady, "all components are ready")
+	case metav1.ConditionFalse:
+		r.EventRecorder.TransitionEventf(rev, corev1.EventTypeWarning, EventReasonNotReady, "components are not ready: %s", readyCondition.Message)
+	}
+}
@@ -0,0 +1,86 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package istiorevision
+
+import (
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/kubectl/pkg/scheme"
+	v1 "maistra.io/istio-operator/api/v1alpha1"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestRecordReadyTransition(t *testing.T) {
+	testCases := []struct {
+		name          string
+		currentStatus metav1.ConditionStatus
+		newCondition  v1.IstioRevisionCondition
+		expectedEvent string
+	}{
+		{
+			name:          "becomes ready",
+			currentStatus: metav1.ConditionFalse,
+			newCondition:  v1.IstioRevisionCondition{Type: v1.IstioRevisionConditionTypeReady, Status: metav1.ConditionTrue},
+			expectedEvent: "Normal Ready all components are ready",
+		},
+		{
+			name:          "becomes not ready",
+			currentStatus: metav1.ConditionTrue,
+			newCondition: v1.IstioRevisionCondition{
+				Type:    v1.IstioRevisionConditionTypeReady,
+				Status:  metav1.ConditionFalse,
+				Message: "not all istiod pods are ready",
+			},
+			expectedEvent: "Warning NotReady components are not ready: not all istiod pods are ready",
+		},
+		{
+			name:          "stays ready",
+			currentStatus: metav1.ConditionTrue,
+			newCondition:  v1.IstioRevisionCondition{Type: v1.IstioRevisionConditionTypeReady, Status: metav1.ConditionTrue},
+		},
+		{
+			name:          "becomes unknown",
+			currentStatus: metav1.ConditionTrue,
+			newCondition:  v1.IstioRevisionCondition{Type: v1.IstioRevisionConditionTypeReady, Status: metav1.ConditionUnknown},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			recorder := record.NewFakeRecorder(10)
+			cl := fake.NewClientBuilder().WithScheme(scheme.Scheme).Build()
+			r := NewIstioRevisionReconciler(cl, scheme.Scheme, nil, recorder)
+
+			rev := &v1.IstioRevision{
+				Status: v1.IstioRevisionStatus{
+					Conditions: []v1.IstioRevisionCondition{{Type: v1.IstioRevisionConditionTypeReady, Status: tc.currentStatus}},
+				},
+			}
+			r.recordReadyTransition(rev, tc.newCondition)
+
+			select {
+			case event := <-recorder.Events:
+				if event != tc.expectedEvent {
+					t.Errorf("expected event %q, but got %q", tc.expectedEvent, event)
+				}
+			default:
+				if tc.expectedEvent != "" {
+					t.Errorf("expected event %q, but no event was recorded", tc.expectedEvent)
+				}
+			}
+		})
+	}
+}
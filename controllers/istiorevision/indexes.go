@@ -0,0 +1,88 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package istiorevision
+
+import (
+	"context"
+
+	"maistra.io/istio-operator/pkg/kube"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// names of the field indexes that allow looking up the namespaces and pods that reference a revision or
+// IstioRevisionTag, without listing all namespaces and pods in the cluster
+const (
+	// NamespaceRevisionIndex indexes Namespaces by the revision or tag referenced by their labels
+	NamespaceRevisionIndex = "istioRevision"
+
+	// PodInjectedRevisionIndex indexes Pods by the revision that injected them, as recorded in their
+	// istio.io/rev annotation
+	PodInjectedRevisionIndex = "injectedIstioRevision"
+
+	// PodLabelRevisionIndex indexes Pods by the revision or tag referenced by their own labels. The labels of
+	// the pod's namespace aren't taken into account.
+	PodLabelRevisionIndex = "labeledIstioRevision"
+)
+
+// FieldIndex describes a field index that must be registered in the manager's cache
+type FieldIndex struct {
+	Object  client.Object
+	Field   string
+	Extract client.IndexerFunc
+}
+
+// FieldIndexes are the field indexes used by the IstioRevision and IstioRevisionTag reconcilers. They're defined
+// on the metadata-only informers, since the reconcilers only read the metadata of namespaces and pods.
+var FieldIndexes = []FieldIndex{
+	{
+		Object: kube.NewMetadata(kube.NamespaceGVK),
+		Field:  NamespaceRevisionIndex,
+		Extract: func(obj client.Object) []string {
+			return indexValue(GetReferencedRevisionFromNamespace(obj.GetLabels()))
+		},
+	},
+	{
+		Object: kube.NewMetadata(kube.PodGVK),
+		Field:  PodInjectedRevisionIndex,
+		Extract: func(obj client.Object) []string {
+			return indexValue(obj.GetAnnotations()[IstioRevLabel])
+		},
+	},
+	{
+		Object: kube.NewMetadata(kube.PodGVK),
+		Field:  PodLabelRevisionIndex,
+		Extract: func(obj client.Object) []string {
+			return indexValue(getPodLabelReference(obj.GetLabels()))
+		},
+	},
+}
+
+// SetupFieldIndexes registers the FieldIndexes with the given indexer. It must be called before the manager
+// is started.
+func SetupFieldIndexes(ctx context.Context, indexer client.FieldIndexer) error {
+	for _, index := range FieldIndexes {
+		if err := indexer.IndexField(ctx, index.Object, index.Field, index.Extract); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func indexValue(value string) []string {
+	if value == "" {
+		return nil
+	}
+	return []string{value}
+}
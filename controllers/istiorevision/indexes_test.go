@@ -0,0 +1,106 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package istiorevision
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/kubectl/pkg/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+// newFakeClientBuilder returns a builder of fake clients that support the lookups through the FieldIndexes
+func newFakeClientBuilder() *fake.ClientBuilder {
+	builder := fake.NewClientBuilder().WithScheme(scheme.Scheme)
+	for _, index := range FieldIndexes {
+		builder = builder.WithIndex(index.Object, index.Field, index.Extract)
+	}
+	return builder
+}
+
+func TestFieldIndexes(t *testing.T) {
+	extract := func(field string, obj *metav1.ObjectMeta) []string {
+		for _, index := range FieldIndexes {
+			if index.Field != field {
+				continue
+			}
+			return index.Extract(&metav1.PartialObjectMetadata{ObjectMeta: *obj})
+		}
+		t.Fatalf("index %s not found", field)
+		return nil
+	}
+
+	testCases := []struct {
+		name     string
+		field    string
+		obj      metav1.ObjectMeta
+		expected []string
+	}{
+		{
+			name:     "namespace with istio-injection label",
+			field:    NamespaceRevisionIndex,
+			obj:      metav1.ObjectMeta{Labels: map[string]string{"istio-injection": "enabled"}},
+			expected: []string{"default"},
+		},
+		{
+			name:     "namespace with istio.io/rev label",
+			field:    NamespaceRevisionIndex,
+			obj:      metav1.ObjectMeta{Labels: map[string]string{"istio.io/rev": "my-rev"}},
+			expected: []string{"my-rev"},
+		},
+		{
+			name:  "namespace without labels",
+			field: NamespaceRevisionIndex,
+			obj:   metav1.ObjectMeta{},
+		},
+		{
+			name:     "injected pod",
+			field:    PodInjectedRevisionIndex,
+			obj:      metav1.ObjectMeta{Annotations: map[string]string{"istio.io/rev": "my-rev"}},
+			expected: []string{"my-rev"},
+		},
+		{
+			name:  "pod that wasn't injected",
+			field: PodInjectedRevisionIndex,
+			obj:   metav1.ObjectMeta{Labels: map[string]string{"istio.io/rev": "my-rev"}},
+		},
+		{
+			name:     "pod with istio.io/rev label",
+			field:    PodLabelRevisionIndex,
+			obj:      metav1.ObjectMeta{Labels: map[string]string{"istio.io/rev": "my-rev"}},
+			expected: []string{"my-rev"},
+		},
+		{
+			name:     "pod with sidecar.istio.io/inject label",
+			field:    PodLabelRevisionIndex,
+			obj:      metav1.ObjectMeta{Labels: map[string]string{"sidecar.istio.io/inject": "true"}},
+			expected: []string{"default"},
+		},
+		{
+			name:  "pod that opted out of injection",
+			field: PodLabelRevisionIndex,
+			obj:   metav1.ObjectMeta{Labels: map[string]string{"istio.io/rev": "my-rev", "sidecar.istio.io/inject": "false"}},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if diff := cmp.Diff(tc.expected, extract(tc.field, &tc.obj)); diff != "" {
+				t.Errorf("unexpected index values; diff (-expected, +actual):\n%v", diff)
+			}
+		})
+	}
+}
@@ -19,7 +19,6 @@ import (
 	"fmt"
 	"reflect"
 	"regexp"
-	"strings"
 
 	"github.com/go-logr/logr"
 	admissionv1 "k8s.io/api/admissionregistration/v1"
@@ -35,9 +34,12 @@ import (
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/cli-runtime/pkg/genericclioptions"
 	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/record"
 	"maistra.io/istio-operator/api/v1alpha1"
+	"maistra.io/istio-operator/pkg/common"
 	"maistra.io/istio-operator/pkg/helm"
 	"maistra.io/istio-operator/pkg/kube"
+	"maistra.io/istio-operator/pkg/metrics"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/builder"
 	"sigs.k8s.io/controller-runtime/pkg/client"
@@ -50,10 +52,10 @@ import (
 
 	networkingv1alpha3 "istio.io/client-go/pkg/apis/networking/v1alpha3"
 	"istio.io/istio/pkg/ptr"
+	"istio.io/istio/pkg/util/sets"
 )
 
 const (
-	cniReleaseNameBase         = "istio"
 	IstioInjectionLabel        = "istio-injection"
 	IstioInjectionEnabledValue = "enabled"
 	IstioRevLabel              = "istio.io/rev"
@@ -62,27 +64,39 @@ const (
 
 // IstioRevisionReconciler reconciles an IstioRevision object
 type IstioRevisionReconciler struct {
-	CNINamespace     string
 	RestClientGetter genericclioptions.RESTClientGetter
 	client.Client
-	Scheme *runtime.Scheme
+	Scheme        *runtime.Scheme
+	EventRecorder *kube.EventRecorder
 }
 
-func NewIstioRevisionReconciler(client client.Client, scheme *runtime.Scheme, restConfig *rest.Config, cniNamespace string) *IstioRevisionReconciler {
+func NewIstioRevisionReconciler(client client.Client, scheme *runtime.Scheme, restConfig *rest.Config,
+	recorder record.EventRecorder,
+) *IstioRevisionReconciler {
 	return &IstioRevisionReconciler{
-		CNINamespace:     cniNamespace,
 		RestClientGetter: helm.NewRESTClientGetter(restConfig),
 		Client:           client,
 		Scheme:           scheme,
+		EventRecorder:    kube.NewEventRecorder(recorder),
 	}
 }
 
+// the chart whose existing releases can be adopted; see common.AdoptHelmReleaseKey
+const istiodChart = "istiod"
+
 // charts to deploy in the istio namespace
-var userCharts = []string{"istiod"}
+var userCharts = []string{istiodChart}
+
+// charts to deploy in the istio namespace when ambient mode is enabled
+var ambientCharts = []string{"ztunnel"}
+
+// namespaces that the sidecar injector webhook never injects by default, even when enableNamespacesByDefault is set
+var systemNamespaces = sets.New("kube-system", "kube-public", "kube-node-lease", "local-path-storage")
 
 // +kubebuilder:rbac:groups=operator.istio.io,resources=istiorevisions,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=operator.istio.io,resources=istiorevisions/status,verbs=get;update;patch
 // +kubebuilder:rbac:groups=operator.istio.io,resources=istiorevisions/finalizers,verbs=update
+// +kubebuilder:rbac:groups="",resources=events,verbs=create;patch
 // +kubebuilder:rbac:groups="",resources="*",verbs="*"
 // +kubebuilder:rbac:groups="networking.k8s.io",resources="networkpolicies",verbs="*"
 // +kubebuilder:rbac:groups="policy",resources="poddisruptionbudgets",verbs="*"
@@ -106,6 +120,7 @@ func (r *IstioRevisionReconciler) Reconcile(ctx context.Context, req ctrl.Reques
 	if err := r.Client.Get(ctx, req.NamespacedName, &rev); err != nil {
 		if errors.IsNotFound(err) {
 			log.V(2).Info("IstioRevision not found. Skipping reconciliation")
+			metrics.ForgetRevision(req.Name)
 			return ctrl.Result{}, nil
 		}
 		log.Error(err, "failed to get IstioRevision from cluster")
@@ -131,20 +146,76 @@ func (r *IstioRevisionReconciler) Reconcile(ctx context.Context, req ctrl.Reques
 		}
 	}
 
-	if err := validateIstioRevision(rev); err != nil {
+	if err := ValidateIstioRevision(rev); err != nil {
 		return ctrl.Result{}, err
 	}
 
-	log.Info("Installing components")
-	err := r.installHelmCharts(ctx, &rev)
+	// the parts of the status that are determined while installing the components are recorded in status
+	status := rev.Status.DeepCopy()
+	var result ctrl.Result
+	var err error
+	if kube.IsPaused(&rev) {
+		// the components are left as they are, so that they can be modified manually (e.g. during an incident)
+		log.Info("Reconciliation paused. Skipping installation of components")
+	} else if kube.IsDryRun(&rev) {
+		log.Info("Dry run enabled. Rendering components without installing them")
+		err = r.renderHelmCharts(ctx, &rev)
+	} else {
+		log.Info("Installing components")
+		result, err = r.installComponents(ctx, &rev, status)
+	}
 
 	log.Info("Reconciliation done. Updating status.")
-	err = r.updateStatus(ctx, &rev, err)
+	err = r.updateStatus(ctx, &rev, status, err)
 
-	return ctrl.Result{}, err
+	return result, err
 }
 
-func validateIstioRevision(rev v1alpha1.IstioRevision) error {
+// installComponents installs or upgrades the components and records the detected drift in the given status.
+// When the rollback policy is enabled, it also verifies that istiod becomes ready after an upgrade and rolls
+// the upgrade back otherwise.
+func (r *IstioRevisionReconciler) installComponents(ctx context.Context, rev *v1alpha1.IstioRevision,
+	status *v1alpha1.IstioRevisionStatus,
+) (ctrl.Result, error) {
+	if !isRollbackEnabled(rev) {
+		status.Rollback = nil
+	} else if err := checkRolledBack(rev, status); err != nil {
+		return ctrl.Result{}, err
+	}
+
+	// the Event is only recorded for a new generation of the spec, since it would otherwise be repeated on every reconcile
+	if isCNIEnabled(rev.Spec.Values) && isNewGeneration(rev) {
+		r.EventRecorder.Eventf(rev, corev1.EventTypeNormal, EventReasonCNIOwnershipSkipped,
+			"CNI is enabled, but the CNI node agent is not installed with the revision; it is managed by the IstioCNI resource named %q",
+			v1alpha1.IstioCNIName)
+	}
+
+	ztunnelOwner, err := r.getZTunnelOwner(ctx)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	drifts, upgraded, err := r.reconcileHelmCharts(ctx, rev, isZTunnelOwner(rev, ztunnelOwner))
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	if err := r.reconcileZTunnelOwnership(ctx, rev, ztunnelOwner); err != nil {
+		return ctrl.Result{}, err
+	}
+	status.SetCondition(determineDriftedCondition(rev, drifts, upgraded))
+	if err := r.deleteDryRunConfigMap(ctx, rev); err != nil {
+		return ctrl.Result{}, err
+	}
+
+	if isRollbackEnabled(rev) {
+		return r.verifyUpgrade(ctx, rev, status, upgraded)
+	}
+	return ctrl.Result{}, nil
+}
+
+// ValidateIstioRevision checks that the IstioRevision spec is complete and that the revision name and
+// istioNamespace in spec.values are consistent with the object. It is used by both the controller and
+// the validating webhook.
+func ValidateIstioRevision(rev v1alpha1.IstioRevision) error {
 	if rev.Spec.Version == "" {
 		return fmt.Errorf("spec.version not set")
 	}
@@ -167,67 +238,159 @@ func validateIstioRevision(rev v1alpha1.IstioRevision) error {
 	return nil
 }
 
-func (r *IstioRevisionReconciler) installHelmCharts(ctx context.Context, rev *v1alpha1.IstioRevision) error {
-	ownerReference := metav1.OwnerReference{
-		APIVersion:         v1alpha1.GroupVersion.String(),
-		Kind:               v1alpha1.IstioRevisionKind,
-		Name:               rev.Name,
-		UID:                rev.UID,
-		Controller:         ptr.Of(true),
-		BlockOwnerDeletion: ptr.Of(true),
-	}
+// installHelmCharts installs or upgrades the revision's charts. The ztunnel chart is only installed by the
+// revision that manages the cluster's single ztunnel release.
+func (r *IstioRevisionReconciler) installHelmCharts(ctx context.Context, rev *v1alpha1.IstioRevision, ownsZTunnel bool) error {
+	ownerReference := newOwnerReference(rev)
 
 	values := rev.Spec.Values.ToHelmValues()
 
-	if isCNIEnabled(rev.Spec.Values) {
-		if shouldInstallCNI, err := r.isOldestRevisionWithCNI(ctx, rev); shouldInstallCNI {
-			if err := helm.UpgradeOrInstallCharts(ctx, r.RestClientGetter, []string{"cni"}, values,
-				rev.Spec.Version, cniReleaseNameBase, r.CNINamespace, ownerReference); err != nil {
-				return err
-			}
-		} else if err != nil {
-			return err
-		} else {
-			log := logf.FromContext(ctx)
-			log.Info("Skipping istio-cni-node installation because CNI is already installed and owned by another IstioRevision")
-		}
+	if err := r.adoptHelmRelease(ctx, rev); err != nil {
+		return err
 	}
-
 	if err := helm.UpgradeOrInstallCharts(ctx, r.RestClientGetter, userCharts, values,
-		rev.Spec.Version, rev.Name, rev.Spec.Namespace, ownerReference); err != nil {
+		rev.Spec.Version, rev.Name, rev.Spec.Namespace, ownerReference, getMaxHistory(rev)); err != nil {
 		return err
 	}
+
+	if !ownsZTunnel {
+		return nil
+	}
+	return r.installZTunnel(ctx, rev)
+}
+
+// adoptHelmRelease transfers the resources of the existing istiod release named in the common.AdoptHelmReleaseKey
+// annotation to the revision's release, so that installing the chart takes them over instead of creating a
+// second control plane
+func (r *IstioRevisionReconciler) adoptHelmRelease(ctx context.Context, rev *v1alpha1.IstioRevision) error {
+	releaseName := rev.Annotations[common.AdoptHelmReleaseKey]
+	if releaseName == "" {
+		return nil
+	}
+	adopted, err := helm.AdoptRelease(ctx, r.RestClientGetter, releaseName, istiodChart, rev.Spec.Values.Revision, rev.Name, rev.Spec.Namespace)
+	if err != nil {
+		return fmt.Errorf("failed to adopt helm release %s: %w", releaseName, err)
+	}
+	if adopted {
+		r.EventRecorder.TransitionEventf(rev, corev1.EventTypeNormal, EventReasonHelmReleaseAdopted, "adopted the resources of helm release %s", releaseName)
+	}
 	return nil
 }
 
-func (r *IstioRevisionReconciler) uninstallHelmCharts(ctx context.Context, rev *v1alpha1.IstioRevision) error {
-	if err := helm.UninstallCharts(ctx, r.RestClientGetter, []string{"cni"}, cniReleaseNameBase, r.CNINamespace); err != nil {
-		return err
+// renderManifests renders the charts that installHelmCharts installs, the same way it installs them
+func (r *IstioRevisionReconciler) renderManifests(rev *v1alpha1.IstioRevision, ownsZTunnel bool) (string, error) {
+	ownerReference := newOwnerReference(rev)
+	kubeVersion, err := helm.GetKubeVersion(r.RestClientGetter)
+	if err != nil {
+		return "", err
+	}
+
+	values := rev.Spec.Values.ToHelmValues()
+	manifests, err := helm.RenderCharts(userCharts, values, rev.Spec.Version, rev.Name, rev.Spec.Namespace, ownerReference, kubeVersion)
+	if err != nil {
+		return "", err
 	}
 
-	if err := helm.UninstallCharts(ctx, r.RestClientGetter, userCharts, rev.Name, rev.Spec.Namespace); err != nil {
+	if ownsZTunnel {
+		ztunnelValues, err := computeZTunnelValues(values)
+		if err != nil {
+			return "", err
+		}
+		ztunnelManifests, err := helm.RenderCharts(ambientCharts, ztunnelValues,
+			rev.Spec.Version, ztunnelReleaseNameBase, rev.Spec.Namespace, ownerReference, kubeVersion)
+		if err != nil {
+			return "", err
+		}
+		manifests += ztunnelManifests
+	}
+	return manifests, nil
+}
+
+// getReleaseManifests returns the manifests of the releases that renderManifests renders and whether
+// they're all deployed
+func (r *IstioRevisionReconciler) getReleaseManifests(ctx context.Context, rev *v1alpha1.IstioRevision, ownsZTunnel bool) (string, bool, error) {
+	manifests, deployed, err := helm.GetReleaseManifests(ctx, r.RestClientGetter, userCharts, rev.Name, rev.Spec.Namespace)
+	if err != nil || !ownsZTunnel {
+		return manifests, deployed, err
+	}
+	ztunnelManifests, ztunnelDeployed, err := helm.GetReleaseManifests(ctx, r.RestClientGetter, ambientCharts, ztunnelReleaseNameBase, rev.Spec.Namespace)
+	if err != nil {
+		return "", false, err
+	}
+	return manifests + ztunnelManifests, deployed && ztunnelDeployed, nil
+}
+
+func newOwnerReference(rev *v1alpha1.IstioRevision) metav1.OwnerReference {
+	return metav1.OwnerReference{
+		APIVersion:         v1alpha1.GroupVersion.String(),
+		Kind:               v1alpha1.IstioRevisionKind,
+		Name:               rev.Name,
+		UID:                rev.UID,
+		Controller:         ptr.Of(true),
+		BlockOwnerDeletion: ptr.Of(true),
+	}
+}
+
+// uninstallHelmCharts uninstalls the revision's charts. If the revision manages ztunnel, the ztunnel release is
+// transferred to the revision that takes over, or uninstalled if there's none.
+func (r *IstioRevisionReconciler) uninstallHelmCharts(ctx context.Context, rev *v1alpha1.IstioRevision) error {
+	ztunnelOwner, err := r.getZTunnelOwner(ctx)
+	if err == nil {
+		err = r.reconcileZTunnelOwnership(ctx, rev, ztunnelOwner)
+	}
+	if err == nil {
+		err = helm.UninstallCharts(ctx, r.RestClientGetter, userCharts, rev.Name, rev.Spec.Namespace)
+	}
+	if err != nil {
+		r.EventRecorder.Eventf(rev, corev1.EventTypeWarning, EventReasonHelmUninstallFailed, "failed to uninstall the Helm releases: %v", err)
 		return err
 	}
+	r.EventRecorder.TransitionEvent(rev, corev1.EventTypeNormal, EventReasonHelmUninstallSucceeded, "uninstalled the Helm releases")
 	return nil
 }
 
-func (r *IstioRevisionReconciler) isOldestRevisionWithCNI(ctx context.Context, rev *v1alpha1.IstioRevision) (bool, error) {
-	revList := v1alpha1.IstioRevisionList{}
-	if err := r.Client.List(ctx, &revList); err != nil {
-		return false, err
+// computeZTunnelValues returns the values for the ztunnel chart. Unlike the istiod chart, the
+// ztunnel chart expects its values at the top level, so the ztunnel section is lifted out of the
+// revision's values and the few global settings the chart uses are copied next to it.
+func computeZTunnelValues(values helm.HelmValues) (helm.HelmValues, error) {
+	ztunnelValues, _, err := values.GetMap("ztunnel")
+	if err != nil {
+		return nil, err
+	}
+	result := helm.HelmValues(ztunnelValues)
+	if result == nil {
+		result = helm.HelmValues{}
+	}
+	delete(result, "enabled")
+
+	for src, dst := range map[string]string{
+		"revision":                        "revision",
+		"profile":                         "profile",
+		"compatibilityVersion":            "compatibilityVersion",
+		"global.istioNamespace":           "istioNamespace",
+		"global.multiCluster.clusterName": "multiCluster.clusterName",
+	} {
+		val, found, err := values.GetString(src)
+		if err != nil {
+			return nil, err
+		}
+		if found && val != "" {
+			if err := result.Set(dst, val); err != nil {
+				return nil, err
+			}
+		}
 	}
 
-	oldestRevision := *rev
-	for _, item := range revList.Items {
-		if isCNIEnabled(item.Spec.Values) &&
-			(item.CreationTimestamp.Before(&oldestRevision.CreationTimestamp) ||
-				item.CreationTimestamp.Equal(&oldestRevision.CreationTimestamp) &&
-					strings.Compare(item.Name, oldestRevision.Name) < 0) {
-
-			oldestRevision = item
+	defaultConfig, found, err := values.GetMap("meshConfig.defaultConfig")
+	if err != nil {
+		return nil, err
+	}
+	if found {
+		if err := result.Set("meshConfig.defaultConfig", defaultConfig); err != nil {
+			return nil, err
 		}
 	}
-	return oldestRevision.UID == rev.UID, nil
+	return result, nil
 }
 
 func isCNIEnabled(values *v1alpha1.Values) bool {
@@ -239,9 +402,30 @@ func isCNIEnabled(values *v1alpha1.Values) bool {
 		return true
 	}
 
+	// ambient mode requires the CNI node agent
+	if isZTunnelEnabled(values) {
+		return true
+	}
+
 	return values.IstioCni != nil && values.IstioCni.Enabled
 }
 
+func isZTunnelEnabled(values *v1alpha1.Values) bool {
+	if values == nil {
+		return false
+	}
+	// newer charts select the ambient profile through the profile value, while
+	// the profiles of older versions enable ambient redirection in CNI instead
+	if values.Profile == "ambient" || values.Profile == "openshift-ambient" {
+		return true
+	}
+	if values.Cni != nil && values.Cni.Ambient != nil && values.Cni.Ambient.Enabled {
+		return true
+	}
+
+	return values.ZTunnel != nil && values.ZTunnel.Enabled
+}
+
 // SetupWithManager sets up the controller with the Manager.
 func (r *IstioRevisionReconciler) SetupWithManager(mgr ctrl.Manager) error {
 	// ownedResourceHandler handles resources that are owned by the IstioRevision CR
@@ -251,10 +435,27 @@ func (r *IstioRevisionReconciler) SetupWithManager(mgr ctrl.Manager) error {
 	// The handler triggers the reconciliation of the referenced IstioRevision CR so that its InUse condition is updated.
 	nsHandler := handler.EnqueueRequestsFromMapFunc(r.mapNamespaceToReconcileRequest)
 
-	// podHandler handles pods that reference the IstioRevision CR via the istio.io/rev or sidecar.istio.io/inject labels.
+	// podHandler handles pods that were injected by the IstioRevision CR or reference it via the istio.io/rev or sidecar.istio.io/inject labels.
 	// The handler triggers the reconciliation of the referenced IstioRevision CR so that its InUse condition is updated.
 	podHandler := handler.EnqueueRequestsFromMapFunc(r.mapPodToReconcileRequest)
 
+	// cniHandler handles the IstioCNI CR. The handler triggers the reconciliation of all IstioRevision CRs
+	// that have CNI enabled, so that their Ready condition reflects the readiness of the IstioCNI.
+	cniHandler := handler.EnqueueRequestsFromMapFunc(r.mapIstioCNIToReconcileRequest)
+
+	// revisionTagHandler handles IstioRevisionTags that point to the IstioRevision CR. Namespaces and pods may reference
+	// the revision through a tag, so the InUse condition must be updated whenever a tag is created, retargeted or deleted.
+	revisionTagHandler := handler.EnqueueRequestsFromMapFunc(r.mapRevisionTagToReconcileRequest)
+
+	// gatewayHandler handles IstioGateways connected to the IstioRevision CR, so that the gateways are reported in
+	// status.components. Both the old and the new revision are reconciled when a gateway moves to another revision.
+	gatewayHandler := handler.EnqueueRequestsFromMapFunc(r.mapGatewayToReconcileRequest)
+
+	// ztunnelHandler handles IstioRevisions that enable ztunnel. When a revision is created, deleted or its spec
+	// changes, all revisions that enable ztunnel are reconciled, so that the revision that becomes its owner takes
+	// it over. Status updates are ignored, since they don't affect the ownership.
+	ztunnelHandler := handler.EnqueueRequestsFromMapFunc(r.mapRevisionToZTunnelReconcileRequests)
+
 	return ctrl.NewControllerManagedBy(mgr).
 		WithOptions(controller.Options{
 			LogConstructor: func(req *reconcile.Request) logr.Logger {
@@ -285,8 +486,9 @@ func (r *IstioRevisionReconciler) SetupWithManager(mgr ctrl.Manager) error {
 		// TODO: only register NetAttachDef if the CRD is installed (may also need to watch for CRD creation)
 		// Owns(&multusv1.NetworkAttachmentDefinition{}).
 
-		Watches(&corev1.Namespace{}, nsHandler).
-		Watches(&corev1.Pod{}, podHandler).
+		// only the metadata of namespaces and pods is cached, since their spec and status aren't needed
+		WatchesMetadata(kube.NewMetadata(kube.NamespaceGVK), nsHandler).
+		WatchesMetadata(kube.NewMetadata(kube.PodGVK), podHandler).
 
 		// cluster-scoped resources
 		Watches(&rbacv1.ClusterRole{}, ownedResourceHandler).
@@ -295,26 +497,43 @@ func (r *IstioRevisionReconciler) SetupWithManager(mgr ctrl.Manager) error {
 		Watches(&admissionv1.ValidatingWebhookConfiguration{},
 			ownedResourceHandler,
 			builder.WithPredicates(validatingWebhookConfigPredicate{})).
+		Watches(&v1alpha1.IstioRevisionTag{}, revisionTagHandler).
+		Watches(&v1alpha1.IstioCNI{}, cniHandler).
+		Watches(&v1alpha1.IstioGateway{}, gatewayHandler).
+		Watches(&v1alpha1.IstioRevision{}, ztunnelHandler, builder.WithPredicates(ztunnelOwnershipPredicate{})).
 
 		// +lint-watches:ignore: CustomResourceDefinition (prevents `make lint-watches` from bugging us about CRDs)
 		Complete(r)
 }
 
-func (r *IstioRevisionReconciler) updateStatus(ctx context.Context, rev *v1alpha1.IstioRevision, err error) error {
+// updateStatus completes the given status, which already contains the parts determined while installing the
+// components, and writes it to the IstioRevision
+func (r *IstioRevisionReconciler) updateStatus(ctx context.Context, rev *v1alpha1.IstioRevision,
+	status *v1alpha1.IstioRevisionStatus, err error,
+) error {
 	log := logf.FromContext(ctx)
-	reconciledCondition := r.determineReconciledCondition(err)
-	readyCondition := r.determineReadyCondition(ctx, rev)
-	inUseCondition, err := r.determineInUseCondition(ctx, rev)
+	reconciledCondition := r.determineReconciledCondition(rev, err)
+	components, err := r.determineComponentStatuses(ctx, rev)
 	if err != nil {
 		return err
 	}
+	readyCondition := determineReadyCondition(components)
+	r.recordReadyTransition(rev, readyCondition)
+	r.recordTimeToReady(ctx, rev, readyCondition)
+	workloads, err := r.getWorkloadInventory(ctx, rev)
+	if err != nil {
+		return err
+	}
+	metrics.IstioRevisionPods.WithLabelValues(rev.Name).Set(float64(workloads.Pods))
+	inUseCondition := determineInUseCondition(rev, workloads)
 
-	status := rev.Status.DeepCopy()
 	status.ObservedGeneration = rev.Generation
 	status.SetCondition(reconciledCondition)
 	status.SetCondition(readyCondition)
 	status.SetCondition(inUseCondition)
 	status.State = deriveState(reconciledCondition, readyCondition)
+	status.Components = components
+	status.Workloads = workloads
 
 	if reflect.DeepEqual(rev.Status, *status) {
 		return nil
@@ -333,6 +552,26 @@ func (r *IstioRevisionReconciler) updateStatus(ctx context.Context, rev *v1alpha
 	return err
 }
 
+// recordTimeToReady measures the time it takes the revision to become Ready after its spec changed. The revision
+// only counts as Ready once istiod has been rolled out, since the old istiod pods remain ready during the upgrade.
+func (r *IstioRevisionReconciler) recordTimeToReady(ctx context.Context, rev *v1alpha1.IstioRevision, readyCondition v1alpha1.IstioRevisionCondition) {
+	if kube.IsPaused(rev) || kube.IsDryRun(rev) {
+		return
+	}
+	if rev.Status.ObservedGeneration != rev.Generation {
+		// the cache may not contain the changes made by the Helm upgrade yet, so the readiness is only
+		// checked in the following reconciliations
+		metrics.RevisionSpecChanged(rev.Name, rev.Generation)
+		return
+	}
+	if readyCondition.Status != metav1.ConditionTrue {
+		return
+	}
+	if rolledOut, err := r.isIstiodRolledOut(ctx, rev); err == nil && rolledOut {
+		metrics.RevisionReady(rev.Name, rev.Generation)
+	}
+}
+
 func deriveState(reconciledCondition, readyCondition v1alpha1.IstioRevisionCondition) v1alpha1.IstioRevisionConditionReason {
 	if reconciledCondition.Status == metav1.ConditionFalse {
 		return reconciledCondition.Reason
@@ -343,58 +582,61 @@ func deriveState(reconciledCondition, readyCondition v1alpha1.IstioRevisionCondi
 	return v1alpha1.IstioRevisionConditionReasonHealthy
 }
 
-func (r *IstioRevisionReconciler) determineReconciledCondition(err error) v1alpha1.IstioRevisionCondition {
-	if err == nil {
+func (r *IstioRevisionReconciler) determineReconciledCondition(rev *v1alpha1.IstioRevision, err error) v1alpha1.IstioRevisionCondition {
+	if rolledBackErr, ok := err.(*rolledBackError); ok {
 		return v1alpha1.IstioRevisionCondition{
-			Type:   v1alpha1.IstioRevisionConditionTypeReconciled,
-			Status: metav1.ConditionTrue,
+			Type:    v1alpha1.IstioRevisionConditionTypeReconciled,
+			Status:  metav1.ConditionFalse,
+			Reason:  v1alpha1.IstioRevisionConditionReasonRolledBack,
+			Message: rolledBackErr.message,
+		}
+	} else if err != nil {
+		return v1alpha1.IstioRevisionCondition{
+			Type:    v1alpha1.IstioRevisionConditionTypeReconciled,
+			Status:  metav1.ConditionFalse,
+			Reason:  v1alpha1.IstioRevisionConditionReasonReconcileError,
+			Message: fmt.Sprintf("error reconciling resource: %v", err),
 		}
 	}
 
-	return v1alpha1.IstioRevisionCondition{
-		Type:    v1alpha1.IstioRevisionConditionTypeReconciled,
-		Status:  metav1.ConditionFalse,
-		Reason:  v1alpha1.IstioRevisionConditionReasonReconcileError,
-		Message: fmt.Sprintf("error reconciling resource: %v", err),
-	}
-}
-
-func (r *IstioRevisionReconciler) determineReadyCondition(ctx context.Context, rev *v1alpha1.IstioRevision) v1alpha1.IstioRevisionCondition {
-	notReady := func(reason v1alpha1.IstioRevisionConditionReason, message string) v1alpha1.IstioRevisionCondition {
+	if kube.IsPaused(rev) {
 		return v1alpha1.IstioRevisionCondition{
-			Type:    v1alpha1.IstioRevisionConditionTypeReady,
+			Type:    v1alpha1.IstioRevisionConditionTypeReconciled,
 			Status:  metav1.ConditionFalse,
-			Reason:  reason,
-			Message: message,
+			Reason:  v1alpha1.IstioRevisionConditionReasonPaused,
+			Message: fmt.Sprintf("reconciliation is paused by the %s annotation", common.PausedKey),
 		}
 	}
 
-	istiod := appsv1.Deployment{}
-	if err := r.Client.Get(ctx, istiodDeploymentKey(rev), &istiod); err != nil {
-		if errors.IsNotFound(err) {
-			return notReady(v1alpha1.IstioRevisionConditionReasonIstiodNotReady, "istiod Deployment not found")
+	if kube.IsDryRun(rev) {
+		return v1alpha1.IstioRevisionCondition{
+			Type:   v1alpha1.IstioRevisionConditionTypeReconciled,
+			Status: metav1.ConditionFalse,
+			Reason: v1alpha1.IstioRevisionConditionReasonDryRun,
+			Message: fmt.Sprintf("dry run enabled by the %s annotation; the rendered manifests and their diff against the cluster are in ConfigMap %s",
+				common.DryRunKey, dryRunConfigMapKey(rev)),
 		}
-		return notReady(v1alpha1.IstioRevisionConditionReasonReconcileError, fmt.Sprintf("failed to get readiness: %v", err))
 	}
-	if istiod.Status.Replicas == 0 {
-		return notReady(v1alpha1.IstioRevisionConditionReasonIstiodNotReady, "istiod Deployment is scaled to zero replicas")
-	} else if istiod.Status.ReadyReplicas < istiod.Status.Replicas {
-		return notReady(v1alpha1.IstioRevisionConditionReasonIstiodNotReady, "not all istiod pods are ready")
+
+	return v1alpha1.IstioRevisionCondition{
+		Type:   v1alpha1.IstioRevisionConditionTypeReconciled,
+		Status: metav1.ConditionTrue,
 	}
+}
 
-	if isCNIEnabled(rev.Spec.Values) {
-		cni := appsv1.DaemonSet{}
-		if err := r.Client.Get(ctx, r.cniDaemonSetKey(), &cni); err != nil {
-			if errors.IsNotFound(err) {
-				return notReady(v1alpha1.IstioRevisionConditionReasonCNINotReady, "istio-cni-node DaemonSet not found")
+// determineReadyCondition derives the Ready condition from the status of the components. Only the first
+// component that isn't ready is reported; status.components lists all of them.
+func determineReadyCondition(components []v1alpha1.ComponentStatus) v1alpha1.IstioRevisionCondition {
+	for _, name := range readinessComponents {
+		for _, component := range components {
+			if component.Name == name && component.Ready != metav1.ConditionTrue {
+				return v1alpha1.IstioRevisionCondition{
+					Type:    v1alpha1.IstioRevisionConditionTypeReady,
+					Status:  metav1.ConditionFalse,
+					Reason:  component.Reason,
+					Message: component.Message,
+				}
 			}
-			return notReady(v1alpha1.IstioRevisionConditionReasonReconcileError, fmt.Sprintf("failed to get readiness: %v", err))
-		}
-
-		if cni.Status.CurrentNumberScheduled == 0 {
-			return notReady(v1alpha1.IstioRevisionConditionReasonCNINotReady, "no istio-cni-node pods are currently scheduled")
-		} else if cni.Status.NumberReady < cni.Status.CurrentNumberScheduled {
-			return notReady(v1alpha1.IstioRevisionConditionReasonCNINotReady, "not all istio-cni-node pods are ready")
 		}
 	}
 
@@ -404,113 +646,115 @@ func (r *IstioRevisionReconciler) determineReadyCondition(ctx context.Context, r
 	}
 }
 
-func (r *IstioRevisionReconciler) determineInUseCondition(ctx context.Context, rev *v1alpha1.IstioRevision) (v1alpha1.IstioRevisionCondition, error) {
-	isReferenced, err := r.isRevisionReferencedByWorkloads(ctx, rev)
-	if err != nil {
-		return v1alpha1.IstioRevisionCondition{}, err
-	}
-
-	if isReferenced {
+// determineInUseCondition returns the InUse condition of the revision, based on the workloads that reference it
+func determineInUseCondition(rev *v1alpha1.IstioRevision, workloads *v1alpha1.WorkloadInventory) v1alpha1.IstioRevisionCondition {
+	if workloads.Namespaces > 0 || workloads.Pods > 0 {
 		return v1alpha1.IstioRevisionCondition{
 			Type:    v1alpha1.IstioRevisionConditionTypeInUse,
 			Status:  metav1.ConditionTrue,
 			Reason:  v1alpha1.IstioRevisionConditionReasonReferencedByWorkloads,
 			Message: "Referenced by at least one pod or namespace",
-		}, nil
+		}
 	}
 	return v1alpha1.IstioRevisionCondition{
 		Type:    v1alpha1.IstioRevisionConditionTypeInUse,
 		Status:  metav1.ConditionFalse,
 		Reason:  v1alpha1.IstioRevisionConditionReasonNotReferenced,
 		Message: "Not referenced by any pod or namespace",
-	}, nil
-}
-
-func (r *IstioRevisionReconciler) isRevisionReferencedByWorkloads(ctx context.Context, rev *v1alpha1.IstioRevision) (bool, error) {
-	log := logf.FromContext(ctx)
-	nsList := corev1.NamespaceList{}
-	nsMap := map[string]corev1.Namespace{}
-	if err := r.Client.List(ctx, &nsList); err != nil { // TODO: can we optimize this by specifying a label selector
-		return false, err
-	}
-	for _, ns := range nsList.Items {
-		if namespaceReferencesRevision(ns, rev) {
-			log.V(2).Info("Revision is referenced by Namespace", "Namespace", ns.Name)
-			return true, nil
-		}
-		nsMap[ns.Name] = ns
-	}
-
-	podList := corev1.PodList{}
-	if err := r.Client.List(ctx, &podList); err != nil { // TODO: can we optimize this by specifying a label selector
-		return false, err
-	}
-	for _, pod := range podList.Items {
-		if ns, found := nsMap[pod.Namespace]; found && podReferencesRevision(pod, ns, rev) {
-			log.V(2).Info("Revision is referenced by Pod", "Pod", client.ObjectKeyFromObject(&pod))
-			return true, nil
-		}
 	}
+}
 
-	if rev.Name == v1alpha1.DefaultRevision && rev.Spec.Values != nil &&
-		rev.Spec.Values.SidecarInjectorWebhook != nil &&
-		rev.Spec.Values.SidecarInjectorWebhook.EnableNamespacesByDefault {
-		return true, nil
-	}
+// EnablesNamespacesByDefault returns true if the given values set sidecarInjectorWebhook.enableNamespacesByDefault.
+// When they're the values of the default revision, or of the revision that the "default" IstioRevisionTag points
+// to, the sidecar is injected into the pods of all namespaces for which IsNamespaceInjectedByDefault returns true.
+func EnablesNamespacesByDefault(values *v1alpha1.Values) bool {
+	return values != nil && values.SidecarInjectorWebhook != nil && values.SidecarInjectorWebhook.EnableNamespacesByDefault
+}
 
-	log.V(2).Info("Revision is not referenced by any Pod or Namespace")
-	return false, nil
+// IsNamespaceInjectedByDefault returns true if the namespace with the given name and labels references the
+// default revision implicitly when the default revision enables namespaces by default, i.e. if the namespace
+// doesn't reference any revision via its labels, doesn't opt out of the injection, and isn't a system namespace.
+func IsNamespaceInjectedByDefault(name string, labels map[string]string) bool {
+	_, hasInjectionLabel := labels[IstioInjectionLabel]
+	_, hasRevLabel := labels[IstioRevLabel]
+	return !hasInjectionLabel && !hasRevLabel && !systemNamespaces.Contains(name)
 }
 
-func namespaceReferencesRevision(ns corev1.Namespace, rev *v1alpha1.IstioRevision) bool {
-	return rev.Name == getReferencedRevisionFromNamespace(ns.Labels)
+// isPodInjectedByDefault returns true if the pod with the given labels is injected by the default revision when
+// its namespace references the default revision only implicitly. A pod that has the istio.io/rev or
+// sidecar.istio.io/inject label is injected according to that label instead.
+func isPodInjectedByDefault(podLabels map[string]string) bool {
+	_, hasInjectLabel := podLabels[IstioSidecarInjectLabel]
+	_, hasRevLabel := podLabels[IstioRevLabel]
+	return !hasInjectLabel && !hasRevLabel
 }
 
-func podReferencesRevision(pod corev1.Pod, ns corev1.Namespace, rev *v1alpha1.IstioRevision) bool {
-	return rev.Name == getReferencedRevisionFromPod(pod.GetLabels(), pod.GetAnnotations(), ns.GetLabels())
+// getNamesReferringToRevision returns the name of the revision and the names of all IstioRevisionTags that point to it
+func (r *IstioRevisionReconciler) getNamesReferringToRevision(ctx context.Context, rev *v1alpha1.IstioRevision) (sets.String, error) {
+	names := sets.New(rev.Name)
+	tagList := v1alpha1.IstioRevisionTagList{}
+	if err := r.Client.List(ctx, &tagList); err != nil {
+		return nil, err
+	}
+	for _, tag := range tagList.Items {
+		if tag.Status.IstioRevision == rev.Name {
+			names.Insert(tag.Name)
+		}
+	}
+	return names, nil
 }
 
-func getReferencedRevisionFromNamespace(labels map[string]string) string {
+// GetReferencedRevisionFromNamespace returns the name of the revision or IstioRevisionTag that
+// the namespace with the given labels references. Only the references made via the namespace's labels
+// are considered; see IsNamespaceInjectedByDefault for the namespaces that reference the default
+// revision implicitly.
+func GetReferencedRevisionFromNamespace(labels map[string]string) string {
 	if labels[IstioInjectionLabel] == IstioInjectionEnabledValue {
 		return v1alpha1.DefaultRevision
 	}
-	revision := labels[IstioRevLabel]
-	if revision != "" {
-		return revision
-	}
-	// TODO: if .Values.sidecarInjectorWebhook.enableNamespacesByDefault is true, then all namespaces except system namespaces should use the "default" revision
+	return labels[IstioRevLabel]
+}
 
-	return ""
+// GetReferencedRevisionFromPod returns the name of the revision or IstioRevisionTag that the pod
+// with the given labels and annotations references
+func GetReferencedRevisionFromPod(podLabels, podAnnotations, nsLabels map[string]string) string {
+	revision, _ := getPodReference(podLabels, podAnnotations, nsLabels)
+	return revision
 }
 
-func getReferencedRevisionFromPod(podLabels, podAnnotations, nsLabels map[string]string) string {
+// getPodReference returns the name of the revision or IstioRevisionTag that the pod with the given labels
+// and annotations references, and how the pod references it
+func getPodReference(podLabels, podAnnotations, nsLabels map[string]string) (string, v1alpha1.WorkloadReferenceType) {
 	// if pod was already injected, the revision that did the injection is specified in the istio.io/rev annotation
 	revision := podAnnotations[IstioRevLabel]
 	if revision != "" {
-		return revision
+		return revision, v1alpha1.WorkloadReferenceTypeInjected
 	}
 
 	// pod is marked for injection by a specific revision, but wasn't injected (e.g. because it was created before the revision was applied)
-	revisionFromNamespace := getReferencedRevisionFromNamespace(nsLabels)
 	if podLabels[IstioSidecarInjectLabel] != "false" {
-		if revisionFromNamespace != "" {
-			return revisionFromNamespace
+		if revisionFromNamespace := GetReferencedRevisionFromNamespace(nsLabels); revisionFromNamespace != "" {
+			return revisionFromNamespace, v1alpha1.WorkloadReferenceTypePendingInjection
 		}
-		revisionFromPod := podLabels[IstioRevLabel]
-		if revisionFromPod != "" {
-			return revisionFromPod
-		} else if podLabels[IstioSidecarInjectLabel] == "true" {
-			return v1alpha1.DefaultRevision
+		if revisionFromPod := getPodLabelReference(podLabels); revisionFromPod != "" {
+			return revisionFromPod, v1alpha1.WorkloadReferenceTypePodLabel
 		}
 	}
-	return ""
+	return "", ""
 }
 
-func (r *IstioRevisionReconciler) cniDaemonSetKey() client.ObjectKey {
-	return client.ObjectKey{
-		Namespace: r.CNINamespace,
-		Name:      "istio-cni-node",
+// getPodLabelReference returns the name of the revision or IstioRevisionTag that the pod's own labels reference.
+// The pod only references it if its namespace doesn't reference any revision.
+func getPodLabelReference(podLabels map[string]string) string {
+	if podLabels[IstioSidecarInjectLabel] == "false" {
+		return ""
 	}
+	if revision := podLabels[IstioRevLabel]; revision != "" {
+		return revision
+	} else if podLabels[IstioSidecarInjectLabel] == "true" {
+		return v1alpha1.DefaultRevision
+	}
+	return ""
 }
 
 func istiodDeploymentKey(rev *v1alpha1.IstioRevision) client.ObjectKey {
@@ -525,6 +769,13 @@ func istiodDeploymentKey(rev *v1alpha1.IstioRevision) client.ObjectKey {
 	}
 }
 
+func ztunnelDaemonSetKey(namespace string) client.ObjectKey {
+	return client.ObjectKey{
+		Namespace: namespace,
+		Name:      "ztunnel",
+	}
+}
+
 func (r *IstioRevisionReconciler) mapOwnerToReconcileRequest(ctx context.Context, obj client.Object) []reconcile.Request {
 	log := logf.FromContext(ctx)
 	ownerKind := v1alpha1.IstioRevisionKind
@@ -550,34 +801,37 @@ func (r *IstioRevisionReconciler) mapOwnerToReconcileRequest(ctx context.Context
 		requests = append(requests, reconcile.Request{NamespacedName: *namespacedName})
 	}
 
-	// HACK: because CNI components are shared between multiple IstioRevisions, we need to trigger a reconcile
-	// of all IstioRevisions that have CNI enabled whenever a CNI component changes so that their status is
-	// updated (e.g. readiness).
-	if obj.GetNamespace() == r.CNINamespace &&
-		annotations != nil && annotations["meta.helm.sh/release-name"] == cniReleaseNameBase+"-cni" {
-
-		revList := v1alpha1.IstioRevisionList{}
-		if err := r.Client.List(ctx, &revList); err != nil {
-			log.Error(err, "Could not list IstioRevisions")
-		} else {
-			for _, item := range revList.Items {
-				if isCNIEnabled(item.Spec.Values) {
-					requests = append(requests, reconcile.Request{
-						NamespacedName: types.NamespacedName{
-							Name:      item.Name,
-							Namespace: item.Spec.Namespace,
-						},
-					})
-				}
-			}
-		}
+	return requests
+}
+
+func (r *IstioRevisionReconciler) mapIstioCNIToReconcileRequest(ctx context.Context, _ client.Object) []reconcile.Request {
+	log := logf.FromContext(ctx)
+	revList := v1alpha1.IstioRevisionList{}
+	if err := r.Client.List(ctx, &revList); err != nil {
+		log.Error(err, "Could not list IstioRevisions")
+		return nil
 	}
 
+	var requests []reconcile.Request
+	for _, item := range revList.Items {
+		if isCNIEnabled(item.Spec.Values) {
+			requests = append(requests, reconcile.Request{NamespacedName: types.NamespacedName{Name: item.Name}})
+		}
+	}
 	return requests
 }
 
 func (r *IstioRevisionReconciler) mapNamespaceToReconcileRequest(ctx context.Context, ns client.Object) []reconcile.Request {
-	revision := getReferencedRevisionFromNamespace(ns.GetLabels())
+	revision := r.resolveRevisionTag(ctx, GetReferencedRevisionFromNamespace(ns.GetLabels()))
+	if revision == "" && IsNamespaceInjectedByDefault(ns.GetName(), ns.GetLabels()) {
+		// the namespace references the default revision only if it enables namespaces by default; checking this
+		// here prevents reconciling the default revision whenever any namespace changes
+		revision = r.resolveRevisionTag(ctx, v1alpha1.DefaultRevision)
+		rev := v1alpha1.IstioRevision{}
+		if err := r.Client.Get(ctx, types.NamespacedName{Name: revision}, &rev); err != nil || !EnablesNamespacesByDefault(rev.Spec.Values) {
+			return nil
+		}
+	}
 	if revision != "" {
 		return []reconcile.Request{{NamespacedName: types.NamespacedName{Name: revision}}}
 	}
@@ -585,20 +839,50 @@ func (r *IstioRevisionReconciler) mapNamespaceToReconcileRequest(ctx context.Con
 }
 
 func (r *IstioRevisionReconciler) mapPodToReconcileRequest(ctx context.Context, pod client.Object) []reconcile.Request {
-	// TODO: rewrite getReferencedRevisionFromPod to use lazy loading to avoid loading the namespace if the pod references a revision directly
-	ns := corev1.Namespace{}
-	err := r.Client.Get(ctx, types.NamespacedName{Name: pod.GetNamespace()}, &ns)
-	if err != nil {
-		return nil
+	// The pod's namespace isn't looked up, so that pod events don't require a Namespace lookup each. A pod that
+	// references a revision only through its namespace doesn't affect the revision's InUse condition, since the
+	// namespace references the revision too; the revision's workload inventory is updated in its next reconciliation.
+	reference := pod.GetAnnotations()[IstioRevLabel]
+	if reference == "" {
+		reference = getPodLabelReference(pod.GetLabels())
 	}
 
-	revision := getReferencedRevisionFromPod(pod.GetLabels(), pod.GetAnnotations(), ns.GetLabels())
+	revision := r.resolveRevisionTag(ctx, reference)
 	if revision != "" {
 		return []reconcile.Request{{NamespacedName: types.NamespacedName{Name: revision}}}
 	}
 	return nil
 }
 
+func (r *IstioRevisionReconciler) mapRevisionTagToReconcileRequest(ctx context.Context, obj client.Object) []reconcile.Request {
+	tag, ok := obj.(*v1alpha1.IstioRevisionTag)
+	if ok && tag.Status.IstioRevision != "" {
+		return []reconcile.Request{{NamespacedName: types.NamespacedName{Name: tag.Status.IstioRevision}}}
+	}
+	return nil
+}
+
+func (r *IstioRevisionReconciler) mapGatewayToReconcileRequest(ctx context.Context, obj client.Object) []reconcile.Request {
+	gw, ok := obj.(*v1alpha1.IstioGateway)
+	if ok && gw.Status.IstioRevision != "" {
+		return []reconcile.Request{{NamespacedName: types.NamespacedName{Name: gw.Status.IstioRevision}}}
+	}
+	return nil
+}
+
+// resolveRevisionTag returns the name of the IstioRevision that the given IstioRevisionTag points to. If no
+// IstioRevisionTag with the given name exists, the name is returned unchanged, as it refers to a revision directly.
+func (r *IstioRevisionReconciler) resolveRevisionTag(ctx context.Context, name string) string {
+	if name == "" {
+		return ""
+	}
+	tag := v1alpha1.IstioRevisionTag{}
+	if err := r.Client.Get(ctx, types.NamespacedName{Name: name}, &tag); err == nil && tag.Status.IstioRevision != "" {
+		return tag.Status.IstioRevision
+	}
+	return name
+}
+
 type validatingWebhookConfigPredicate struct {
 	predicate.Funcs
 }
@@ -618,6 +902,20 @@ func (v validatingWebhookConfigPredicate) Update(e event.UpdateEvent) bool {
 	return true
 }
 
+// ztunnelOwnershipPredicate filters the IstioRevision updates that can change which revision manages ztunnel, i.e.
+// changes to the spec and the start of the deletion
+type ztunnelOwnershipPredicate struct {
+	predicate.Funcs
+}
+
+func (ztunnelOwnershipPredicate) Update(e event.UpdateEvent) bool {
+	if e.ObjectOld == nil || e.ObjectNew == nil {
+		return false
+	}
+	return e.ObjectOld.GetGeneration() != e.ObjectNew.GetGeneration() ||
+		(e.ObjectOld.GetDeletionTimestamp() == nil) != (e.ObjectNew.GetDeletionTimestamp() == nil)
+}
+
 func clearIgnoredFields(obj client.Object) {
 	obj.SetResourceVersion("")
 	obj.SetGeneration(0)
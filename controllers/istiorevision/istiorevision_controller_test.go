@@ -16,21 +16,26 @@ package istiorevision
 
 import (
 	"context"
+	"errors"
 	"strings"
 	"testing"
 
+	"github.com/google/go-cmp/cmp"
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/kubectl/pkg/scheme"
 	v1 "maistra.io/istio-operator/api/v1alpha1"
+	"maistra.io/istio-operator/pkg/common"
+	"maistra.io/istio-operator/pkg/helm"
 	"maistra.io/istio-operator/pkg/test"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 )
 
-const operatorNamespace = "istio-operator"
-
 func TestDeriveState(t *testing.T) {
 	testCases := []struct {
 		name                string
@@ -86,7 +91,150 @@ func newCondition(conditionType v1.IstioRevisionConditionType, status bool, reas
 	}
 }
 
+func TestDetermineReconciledCondition(t *testing.T) {
+	testCases := []struct {
+		name        string
+		annotations map[string]string
+		err         error
+		expected    v1.IstioRevisionCondition
+	}{
+		{
+			name: "no error",
+			expected: v1.IstioRevisionCondition{
+				Type:   v1.IstioRevisionConditionTypeReconciled,
+				Status: metav1.ConditionTrue,
+			},
+		},
+		{
+			name: "error",
+			err:  errors.New("some error"),
+			expected: v1.IstioRevisionCondition{
+				Type:    v1.IstioRevisionConditionTypeReconciled,
+				Status:  metav1.ConditionFalse,
+				Reason:  v1.IstioRevisionConditionReasonReconcileError,
+				Message: "error reconciling resource: some error",
+			},
+		},
+		{
+			name: "rolled back",
+			err:  &rolledBackError{message: "rolled back"},
+			expected: v1.IstioRevisionCondition{
+				Type:    v1.IstioRevisionConditionTypeReconciled,
+				Status:  metav1.ConditionFalse,
+				Reason:  v1.IstioRevisionConditionReasonRolledBack,
+				Message: "rolled back",
+			},
+		},
+		{
+			name:        "paused",
+			annotations: map[string]string{common.PausedKey: "true"},
+			expected: v1.IstioRevisionCondition{
+				Type:    v1.IstioRevisionConditionTypeReconciled,
+				Status:  metav1.ConditionFalse,
+				Reason:  v1.IstioRevisionConditionReasonPaused,
+				Message: "reconciliation is paused by the operator.istio.io/paused annotation",
+			},
+		},
+		{
+			name:        "dry run",
+			annotations: map[string]string{common.DryRunKey: "true"},
+			expected: v1.IstioRevisionCondition{
+				Type:   v1.IstioRevisionConditionTypeReconciled,
+				Status: metav1.ConditionFalse,
+				Reason: v1.IstioRevisionConditionReasonDryRun,
+				Message: "dry run enabled by the operator.istio.io/dry-run annotation; " +
+					"the rendered manifests and their diff against the cluster are in ConfigMap istio-system/my-rev-dry-run",
+			},
+		},
+		{
+			name:        "paused annotation not set to true",
+			annotations: map[string]string{common.PausedKey: "false"},
+			expected: v1.IstioRevisionCondition{
+				Type:   v1.IstioRevisionConditionTypeReconciled,
+				Status: metav1.ConditionTrue,
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			r := &IstioRevisionReconciler{}
+			rev := &v1.IstioRevision{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:        "my-rev",
+					Annotations: tc.annotations,
+				},
+				Spec: v1.IstioRevisionSpec{
+					Namespace: "istio-system",
+				},
+			}
+			result := r.determineReconciledCondition(rev, tc.err)
+			if diff := cmp.Diff(tc.expected, result); diff != "" {
+				t.Errorf("unexpected condition; diff (-expected, +actual):\n%v", diff)
+			}
+		})
+	}
+}
+
+func TestReconcilePaused(t *testing.T) {
+	test.SetupScheme()
+
+	rev := &v1.IstioRevision{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "my-rev",
+			Annotations: map[string]string{common.PausedKey: "true"},
+		},
+		Spec: v1.IstioRevisionSpec{
+			Version:   "my-version",
+			Namespace: "istio-system",
+			Values: &v1.Values{
+				Revision: "my-rev",
+				Global: &v1.GlobalConfig{
+					IstioNamespace: "istio-system",
+				},
+			},
+		},
+	}
+
+	cl := newFakeClientBuilder().
+		WithStatusSubresource(&v1.IstioRevision{}).
+		WithObjects(rev).
+		Build()
+	// RestClientGetter is left nil, since no Helm charts may be installed while the revision is paused
+	r := &IstioRevisionReconciler{Client: cl, Scheme: scheme.Scheme}
+
+	if _, err := r.Reconcile(context.TODO(), reconcile.Request{NamespacedName: client.ObjectKeyFromObject(rev)}); err != nil {
+		t.Fatalf("Expected no error, but got: %v", err)
+	}
+
+	if err := cl.Get(context.TODO(), client.ObjectKeyFromObject(rev), rev); err != nil {
+		t.Fatal(err)
+	}
+	if rev.Status.State != v1.IstioRevisionConditionReasonPaused {
+		t.Errorf("Expected state %s, but got %s", v1.IstioRevisionConditionReasonPaused, rev.Status.State)
+	}
+	if cond := rev.Status.GetCondition(v1.IstioRevisionConditionTypeReconciled); cond.Reason != v1.IstioRevisionConditionReasonPaused {
+		t.Errorf("Expected Reconciled condition reason %s, but got %s", v1.IstioRevisionConditionReasonPaused, cond.Reason)
+	}
+}
+
 func TestDetermineReadyCondition(t *testing.T) {
+	test.SetupScheme()
+
+	readyCNI := &v1.IstioCNI{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: v1.IstioCNIName,
+		},
+		Status: v1.IstioCNIStatus{
+			Conditions: []v1.IstioCNICondition{
+				{
+					Type:   v1.IstioCNIConditionTypeReady,
+					Status: metav1.ConditionTrue,
+				},
+			},
+		},
+	}
+
 	testCases := []struct {
 		name          string
 		cniEnabled    bool
@@ -191,10 +339,149 @@ func TestDetermineReadyCondition(t *testing.T) {
 						AvailableReplicas: 2,
 					},
 				},
+				&v1.IstioCNI{
+					ObjectMeta: metav1.ObjectMeta{
+						Name: v1.IstioCNIName,
+					},
+					Status: v1.IstioCNIStatus{
+						Conditions: []v1.IstioCNICondition{
+							{
+								Type:   v1.IstioCNIConditionTypeReady,
+								Status: metav1.ConditionTrue,
+							},
+						},
+					},
+				},
+			},
+			expected: v1.IstioRevisionCondition{
+				Type:   v1.IstioRevisionConditionTypeReady,
+				Status: metav1.ConditionTrue,
+			},
+		},
+		{
+			name: "CNI not ready",
+			values: &v1.Values{
+				IstioCni: &v1.CNIConfig{
+					Enabled: true,
+				},
+			},
+			clientObjects: []client.Object{
+				&appsv1.Deployment{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "istiod",
+						Namespace: "istio-system",
+					},
+					Status: appsv1.DeploymentStatus{
+						Replicas:          2,
+						ReadyReplicas:     2,
+						AvailableReplicas: 2,
+					},
+				},
+				&v1.IstioCNI{
+					ObjectMeta: metav1.ObjectMeta{
+						Name: v1.IstioCNIName,
+					},
+					Status: v1.IstioCNIStatus{
+						Conditions: []v1.IstioCNICondition{
+							{
+								Type:    v1.IstioCNIConditionTypeReady,
+								Status:  metav1.ConditionFalse,
+								Message: "not all istio-cni-node pods are ready",
+							},
+						},
+					},
+				},
+			},
+			expected: v1.IstioRevisionCondition{
+				Type:    v1.IstioRevisionConditionTypeReady,
+				Status:  metav1.ConditionFalse,
+				Reason:  v1.IstioRevisionConditionReasonCNINotReady,
+				Message: "IstioCNI is not ready: not all istio-cni-node pods are ready",
+			},
+		},
+		{
+			name: "CNI readiness unknown",
+			values: &v1.Values{
+				IstioCni: &v1.CNIConfig{
+					Enabled: true,
+				},
+			},
+			clientObjects: []client.Object{
+				&appsv1.Deployment{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "istiod",
+						Namespace: "istio-system",
+					},
+					Status: appsv1.DeploymentStatus{
+						Replicas:          2,
+						ReadyReplicas:     2,
+						AvailableReplicas: 2,
+					},
+				},
+				&v1.IstioCNI{
+					ObjectMeta: metav1.ObjectMeta{
+						Name: v1.IstioCNIName,
+					},
+				},
+			},
+			expected: v1.IstioRevisionCondition{
+				Type:    v1.IstioRevisionConditionTypeReady,
+				Status:  metav1.ConditionFalse,
+				Reason:  v1.IstioRevisionConditionReasonCNINotReady,
+				Message: "IstioCNI is not ready",
+			},
+		},
+		{
+			name: "CNI not found",
+			values: &v1.Values{
+				IstioCni: &v1.CNIConfig{
+					Enabled: true,
+				},
+			},
+			clientObjects: []client.Object{
+				&appsv1.Deployment{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "istiod",
+						Namespace: "istio-system",
+					},
+					Status: appsv1.DeploymentStatus{
+						Replicas:          2,
+						ReadyReplicas:     2,
+						AvailableReplicas: 2,
+					},
+				},
+			},
+			expected: v1.IstioRevisionCondition{
+				Type:    v1.IstioRevisionConditionTypeReady,
+				Status:  metav1.ConditionFalse,
+				Reason:  v1.IstioRevisionConditionReasonCNINotReady,
+				Message: "IstioCNI not found",
+			},
+		},
+		{
+			name: "ZTunnel ready",
+			values: &v1.Values{
+				ZTunnel: &v1.ZTunnelConfig{
+					Enabled: true,
+				},
+			},
+			clientObjects: []client.Object{
+				&appsv1.Deployment{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "istiod",
+						Namespace: "istio-system",
+					},
+					Status: appsv1.DeploymentStatus{
+						Replicas:          2,
+						ReadyReplicas:     2,
+						AvailableReplicas: 2,
+					},
+				},
+				readyCNI,
 				&appsv1.DaemonSet{
 					ObjectMeta: metav1.ObjectMeta{
-						Name:      "istio-cni-node",
-						Namespace: operatorNamespace,
+						Name:      "ztunnel",
+						Namespace: "istio-system",
 					},
 					Status: appsv1.DaemonSetStatus{
 						CurrentNumberScheduled: 3,
@@ -208,9 +495,9 @@ func TestDetermineReadyCondition(t *testing.T) {
 			},
 		},
 		{
-			name: "CNI not ready",
+			name: "ZTunnel not ready",
 			values: &v1.Values{
-				IstioCni: &v1.CNIConfig{
+				ZTunnel: &v1.ZTunnelConfig{
 					Enabled: true,
 				},
 			},
@@ -226,28 +513,29 @@ func TestDetermineReadyCondition(t *testing.T) {
 						AvailableReplicas: 2,
 					},
 				},
+				readyCNI,
 				&appsv1.DaemonSet{
 					ObjectMeta: metav1.ObjectMeta{
-						Name:      "istio-cni-node",
-						Namespace: operatorNamespace,
+						Name:      "ztunnel",
+						Namespace: "istio-system",
 					},
 					Status: appsv1.DaemonSetStatus{
-						CurrentNumberScheduled: 1,
-						NumberReady:            0,
+						CurrentNumberScheduled: 3,
+						NumberReady:            2,
 					},
 				},
 			},
 			expected: v1.IstioRevisionCondition{
 				Type:    v1.IstioRevisionConditionTypeReady,
 				Status:  metav1.ConditionFalse,
-				Reason:  v1.IstioRevisionConditionReasonCNINotReady,
-				Message: "not all istio-cni-node pods are ready",
+				Reason:  v1.IstioRevisionConditionReasonZTunnelNotReady,
+				Message: "not all ztunnel pods are ready",
 			},
 		},
 		{
-			name: "CNI pods not scheduled",
+			name: "ZTunnel pods not scheduled",
 			values: &v1.Values{
-				IstioCni: &v1.CNIConfig{
+				ZTunnel: &v1.ZTunnelConfig{
 					Enabled: true,
 				},
 			},
@@ -263,10 +551,11 @@ func TestDetermineReadyCondition(t *testing.T) {
 						AvailableReplicas: 2,
 					},
 				},
+				readyCNI,
 				&appsv1.DaemonSet{
 					ObjectMeta: metav1.ObjectMeta{
-						Name:      "istio-cni-node",
-						Namespace: operatorNamespace,
+						Name:      "ztunnel",
+						Namespace: "istio-system",
 					},
 					Status: appsv1.DaemonSetStatus{
 						CurrentNumberScheduled: 0,
@@ -277,14 +566,14 @@ func TestDetermineReadyCondition(t *testing.T) {
 			expected: v1.IstioRevisionCondition{
 				Type:    v1.IstioRevisionConditionTypeReady,
 				Status:  metav1.ConditionFalse,
-				Reason:  v1.IstioRevisionConditionReasonCNINotReady,
-				Message: "no istio-cni-node pods are currently scheduled",
+				Reason:  v1.IstioRevisionConditionReasonZTunnelNotReady,
+				Message: "no ztunnel pods are currently scheduled",
 			},
 		},
 		{
-			name: "CNI not found",
+			name: "ZTunnel not found",
 			values: &v1.Values{
-				IstioCni: &v1.CNIConfig{
+				ZTunnel: &v1.ZTunnelConfig{
 					Enabled: true,
 				},
 			},
@@ -300,12 +589,13 @@ func TestDetermineReadyCondition(t *testing.T) {
 						AvailableReplicas: 2,
 					},
 				},
+				readyCNI,
 			},
 			expected: v1.IstioRevisionCondition{
 				Type:    v1.IstioRevisionConditionTypeReady,
 				Status:  metav1.ConditionFalse,
-				Reason:  v1.IstioRevisionConditionReasonCNINotReady,
-				Message: "istio-cni-node DaemonSet not found",
+				Reason:  v1.IstioRevisionConditionReasonZTunnelNotReady,
+				Message: "ztunnel DaemonSet not found",
 			},
 		},
 		{
@@ -337,7 +627,7 @@ func TestDetermineReadyCondition(t *testing.T) {
 		t.Run(tt.name, func(t *testing.T) {
 			cl := fake.NewClientBuilder().WithScheme(scheme.Scheme).WithObjects(tt.clientObjects...).Build()
 
-			r := NewIstioRevisionReconciler(cl, scheme.Scheme, nil, operatorNamespace)
+			r := NewIstioRevisionReconciler(cl, scheme.Scheme, nil, nil)
 
 			rev := &v1.IstioRevision{
 				ObjectMeta: metav1.ObjectMeta{
@@ -349,7 +639,11 @@ func TestDetermineReadyCondition(t *testing.T) {
 				},
 			}
 
-			result := r.determineReadyCondition(context.TODO(), rev)
+			components, err := r.determineComponentStatuses(context.TODO(), rev)
+			if err != nil {
+				t.Fatalf("Expected no error, but got: %v", err)
+			}
+			result := determineReadyCondition(components)
 			if result.Type != tt.expected.Type || result.Status != tt.expected.Status ||
 				result.Reason != tt.expected.Reason || result.Message != tt.expected.Message {
 				t.Errorf("Unexpected result.\nGot:\n    %+v\nexpected:\n    %+v", result, tt.expected)
@@ -531,17 +825,17 @@ func TestDetermineInUseCondition(t *testing.T) {
 					},
 				}
 
-				cl := fake.NewClientBuilder().
-					WithScheme(scheme.Scheme).
+				cl := newFakeClientBuilder().
 					WithObjects(rev, ns, pod).
 					Build()
 
-				r := NewIstioRevisionReconciler(cl, scheme.Scheme, nil, operatorNamespace)
+				r := NewIstioRevisionReconciler(cl, scheme.Scheme, nil, nil)
 
-				result, err := r.determineInUseCondition(context.TODO(), rev)
+				workloads, err := r.getWorkloadInventory(context.TODO(), rev)
 				if err != nil {
 					t.Fatalf("Unexpected error: %v", err)
 				}
+				result := determineInUseCondition(rev, workloads)
 				if result.Type != v1.IstioRevisionConditionTypeInUse {
 					t.Errorf("unexpected condition type: %v", result.Type)
 				}
@@ -561,9 +855,362 @@ func TestDetermineInUseCondition(t *testing.T) {
 	}
 }
 
+func TestDetermineInUseConditionWithRevisionTag(t *testing.T) {
+	test.SetupScheme()
+
+	testCases := []struct {
+		name           string
+		nsLabels       map[string]string
+		podLabels      map[string]string
+		tagRevision    string
+		expectedStatus metav1.ConditionStatus
+	}{
+		{
+			name:           "namespace references tag pointing to revision",
+			nsLabels:       map[string]string{"istio.io/rev": "prod"},
+			tagRevision:    "my-rev",
+			expectedStatus: metav1.ConditionTrue,
+		},
+		{
+			name:           "pod references tag pointing to revision",
+			podLabels:      map[string]string{"istio.io/rev": "prod"},
+			tagRevision:    "my-rev",
+			expectedStatus: metav1.ConditionTrue,
+		},
+		{
+			name:           "namespace references tag pointing to other revision",
+			nsLabels:       map[string]string{"istio.io/rev": "prod"},
+			tagRevision:    "other-rev",
+			expectedStatus: metav1.ConditionFalse,
+		},
+		{
+			name:           "namespace references unknown tag",
+			nsLabels:       map[string]string{"istio.io/rev": "canary"},
+			tagRevision:    "my-rev",
+			expectedStatus: metav1.ConditionFalse,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			rev := &v1.IstioRevision{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: "my-rev",
+				},
+			}
+			tag := &v1.IstioRevisionTag{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: "prod",
+				},
+				Spec: v1.IstioRevisionTagSpec{
+					TargetRef: v1.TargetReference{
+						Kind: v1.IstioRevisionKind,
+						Name: tc.tagRevision,
+					},
+				},
+				Status: v1.IstioRevisionTagStatus{
+					IstioRevision: tc.tagRevision,
+				},
+			}
+			ns := &corev1.Namespace{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:   "bookinfo",
+					Labels: tc.nsLabels,
+				},
+			}
+			pod := &corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "some-pod",
+					Namespace: "bookinfo",
+					Labels:    tc.podLabels,
+				},
+			}
+
+			cl := newFakeClientBuilder().
+				WithObjects(rev, tag, ns, pod).
+				Build()
+
+			r := NewIstioRevisionReconciler(cl, scheme.Scheme, nil, nil)
+
+			workloads, err := r.getWorkloadInventory(context.TODO(), rev)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			result := determineInUseCondition(rev, workloads)
+			if result.Status != tc.expectedStatus {
+				t.Errorf("Expected InUse status %s, but got %s", tc.expectedStatus, result.Status)
+			}
+		})
+	}
+}
+
+func TestIsZTunnelEnabled(t *testing.T) {
+	testCases := []struct {
+		name     string
+		values   *v1.Values
+		expected bool
+	}{
+		{
+			name:     "nil values",
+			values:   nil,
+			expected: false,
+		},
+		{
+			name:     "default profile",
+			values:   &v1.Values{},
+			expected: false,
+		},
+		{
+			name:     "ambient profile",
+			values:   &v1.Values{Profile: "ambient"},
+			expected: true,
+		},
+		{
+			name:     "openshift-ambient profile",
+			values:   &v1.Values{Profile: "openshift-ambient"},
+			expected: true,
+		},
+		{
+			name: "CNI ambient redirection enabled",
+			values: &v1.Values{
+				Cni: &v1.CNIConfig{
+					Ambient: &v1.CNIAmbientConfig{Enabled: true},
+				},
+			},
+			expected: true,
+		},
+		{
+			name: "ztunnel enabled explicitly",
+			values: &v1.Values{
+				ZTunnel: &v1.ZTunnelConfig{Enabled: true},
+			},
+			expected: true,
+		},
+		{
+			name: "only ztunnel image set",
+			values: &v1.Values{
+				ZTunnel: &v1.ZTunnelConfig{Image: "ztunnel-test"},
+			},
+			expected: false,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if result := isZTunnelEnabled(tc.values); result != tc.expected {
+				t.Errorf("Expected %v, but got %v", tc.expected, result)
+			}
+			if tc.expected && !isCNIEnabled(tc.values) {
+				t.Errorf("Expected CNI to be enabled when ztunnel is enabled")
+			}
+		})
+	}
+}
+
+func TestComputeZTunnelValues(t *testing.T) {
+	values := &v1.Values{
+		Revision: "my-rev",
+		Profile:  "ambient",
+		Global: &v1.GlobalConfig{
+			IstioNamespace: "istio-system",
+			MultiCluster: &v1.MultiClusterConfig{
+				ClusterName: "cluster1",
+			},
+		},
+		Pilot: &v1.PilotConfig{
+			Image: "istiod-test",
+		},
+		ZTunnel: &v1.ZTunnelConfig{
+			Enabled: true,
+			Image:   "ztunnel-test",
+			Variant: "distroless",
+		},
+	}
+
+	result, err := computeZTunnelValues(values.ToHelmValues())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := helm.HelmValues{
+		"image":          "ztunnel-test",
+		"variant":        "distroless",
+		"revision":       "my-rev",
+		"profile":        "ambient",
+		"istioNamespace": "istio-system",
+		"multiCluster": map[string]any{
+			"clusterName": "cluster1",
+		},
+	}
+	if diff := cmp.Diff(expected, result); diff != "" {
+		t.Errorf("unexpected values; diff (-expected, +actual):\n%v", diff)
+	}
+}
+
 func Must(t *testing.T, err error) {
 	t.Helper()
 	if err != nil {
 		t.Fatal(err)
 	}
 }
+
+func TestMapPodToReconcileRequest(t *testing.T) {
+	test.SetupScheme()
+
+	// the pods' namespace intentionally doesn't exist, since the pods are mapped without looking it up
+	cl := newFakeClientBuilder().
+		WithObjects(&v1.IstioRevisionTag{
+			ObjectMeta: metav1.ObjectMeta{Name: "prod"},
+			Status:     v1.IstioRevisionTagStatus{IstioRevision: "my-rev"},
+		}).
+		Build()
+	r := NewIstioRevisionReconciler(cl, scheme.Scheme, nil, nil)
+
+	testCases := []struct {
+		name        string
+		labels      map[string]string
+		annotations map[string]string
+		expected    []reconcile.Request
+	}{
+		{
+			name:        "injected pod",
+			labels:      map[string]string{"istio.io/rev": "other-rev"},
+			annotations: map[string]string{"istio.io/rev": "my-rev"},
+			expected:    []reconcile.Request{{NamespacedName: types.NamespacedName{Name: "my-rev"}}},
+		},
+		{
+			name:     "pod labeled with tag",
+			labels:   map[string]string{"istio.io/rev": "prod"},
+			expected: []reconcile.Request{{NamespacedName: types.NamespacedName{Name: "my-rev"}}},
+		},
+		{
+			name:     "pod with sidecar.istio.io/inject label",
+			labels:   map[string]string{"sidecar.istio.io/inject": "true"},
+			expected: []reconcile.Request{{NamespacedName: types.NamespacedName{Name: "default"}}},
+		},
+		{
+			name: "pod without labels",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			pod := &corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{Name: "pod", Namespace: "bookinfo", Labels: tc.labels, Annotations: tc.annotations},
+			}
+			if diff := cmp.Diff(tc.expected, r.mapPodToReconcileRequest(context.TODO(), pod)); diff != "" {
+				t.Errorf("unexpected requests; diff (-expected, +actual):\n%v", diff)
+			}
+		})
+	}
+}
+
+func TestMapNamespaceToReconcileRequest(t *testing.T) {
+	test.SetupScheme()
+
+	testCases := []struct {
+		name                      string
+		enableNamespacesByDefault bool
+		nsName                    string
+		labels                    map[string]string
+		expected                  []reconcile.Request
+	}{
+		{
+			name:     "namespace labeled with tag",
+			labels:   map[string]string{"istio.io/rev": "prod"},
+			expected: []reconcile.Request{{NamespacedName: types.NamespacedName{Name: "my-rev"}}},
+		},
+		{
+			name:     "namespace with istio-injection label",
+			labels:   map[string]string{"istio-injection": "enabled"},
+			expected: []reconcile.Request{{NamespacedName: types.NamespacedName{Name: "default"}}},
+		},
+		{
+			name: "namespace without labels",
+		},
+		{
+			name:                      "namespace without labels and namespaces enabled by default",
+			enableNamespacesByDefault: true,
+			expected:                  []reconcile.Request{{NamespacedName: types.NamespacedName{Name: "default"}}},
+		},
+		{
+			name:                      "opted out namespace and namespaces enabled by default",
+			enableNamespacesByDefault: true,
+			labels:                    map[string]string{"istio-injection": "disabled"},
+		},
+		{
+			name:                      "system namespace and namespaces enabled by default",
+			enableNamespacesByDefault: true,
+			nsName:                    "kube-system",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			rev := &v1.IstioRevision{ObjectMeta: metav1.ObjectMeta{Name: "default"}}
+			if tc.enableNamespacesByDefault {
+				rev.Spec.Values = &v1.Values{SidecarInjectorWebhook: &v1.SidecarInjectorConfig{EnableNamespacesByDefault: true}}
+			}
+			cl := newFakeClientBuilder().
+				WithObjects(rev, &v1.IstioRevisionTag{
+					ObjectMeta: metav1.ObjectMeta{Name: "prod"},
+					Status:     v1.IstioRevisionTagStatus{IstioRevision: "my-rev"},
+				}).
+				Build()
+			r := NewIstioRevisionReconciler(cl, scheme.Scheme, nil, nil)
+
+			name := "bookinfo"
+			if tc.nsName != "" {
+				name = tc.nsName
+			}
+			ns := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: name, Labels: tc.labels}}
+			if diff := cmp.Diff(tc.expected, r.mapNamespaceToReconcileRequest(context.TODO(), ns)); diff != "" {
+				t.Errorf("unexpected requests; diff (-expected, +actual):\n%v", diff)
+			}
+		})
+	}
+}
+
+func TestZTunnelOwnershipPredicate(t *testing.T) {
+	rev := func(generation int64, deleting bool, state v1.IstioRevisionConditionReason) *v1.IstioRevision {
+		rev := &v1.IstioRevision{
+			ObjectMeta: metav1.ObjectMeta{Name: "default", Generation: generation},
+			Status:     v1.IstioRevisionStatus{State: state},
+		}
+		if deleting {
+			rev.DeletionTimestamp = &metav1.Time{}
+		}
+		return rev
+	}
+
+	testCases := []struct {
+		name     string
+		old      *v1.IstioRevision
+		new      *v1.IstioRevision
+		expected bool
+	}{
+		{
+			name:     "spec changed",
+			old:      rev(1, false, v1.IstioRevisionConditionReasonHealthy),
+			new:      rev(2, false, v1.IstioRevisionConditionReasonHealthy),
+			expected: true,
+		},
+		{
+			name:     "deletion started",
+			old:      rev(1, false, v1.IstioRevisionConditionReasonHealthy),
+			new:      rev(1, true, v1.IstioRevisionConditionReasonHealthy),
+			expected: true,
+		},
+		{
+			name:     "status changed",
+			old:      rev(1, false, v1.IstioRevisionConditionReasonHealthy),
+			new:      rev(1, false, v1.IstioRevisionConditionReasonIstiodNotReady),
+			expected: false,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if actual := (ztunnelOwnershipPredicate{}).Update(event.UpdateEvent{ObjectOld: tc.old, ObjectNew: tc.new}); actual != tc.expected {
+				t.Errorf("expected %v, but got %v", tc.expected, actual)
+			}
+		})
+	}
+}
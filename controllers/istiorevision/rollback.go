@@ -0,0 +1,204 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package istiorevision
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"maistra.io/istio-operator/api/v1alpha1"
+	"maistra.io/istio-operator/pkg/helm"
+	ctrl "sigs.k8s.io/controller-runtime"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+
+	"istio.io/istio/pkg/ptr"
+)
+
+const (
+	defaultProgressDeadlineSeconds = 300
+	defaultRollbackMaxHistory      = 3
+)
+
+// rolledBackError is returned when the components aren't upgraded, because an upgrade to the same version and
+// values was rolled back. Retrying won't help; the components are upgraded again when the spec changes.
+type rolledBackError struct {
+	message string
+}
+
+func (e *rolledBackError) Error() string {
+	return e.message
+}
+
+func isRollbackEnabled(rev *v1alpha1.IstioRevision) bool {
+	return rev.Spec.RollbackPolicy != nil && rev.Spec.RollbackPolicy.Enabled
+}
+
+// getMaxHistory returns the number of revisions to keep for each Helm release. Only the most recent revision is
+// kept, unless the rollback policy is enabled.
+func getMaxHistory(rev *v1alpha1.IstioRevision) int {
+	if !isRollbackEnabled(rev) {
+		return helm.DefaultMaxHistory
+	}
+	if rev.Spec.RollbackPolicy.MaxHistory == nil || *rev.Spec.RollbackPolicy.MaxHistory < 2 {
+		return defaultRollbackMaxHistory
+	}
+	return int(*rev.Spec.RollbackPolicy.MaxHistory)
+}
+
+func getProgressDeadline(rev *v1alpha1.IstioRevision) time.Duration {
+	seconds := int32(defaultProgressDeadlineSeconds)
+	if rev.Spec.RollbackPolicy != nil && rev.Spec.RollbackPolicy.ProgressDeadlineSeconds != nil {
+		seconds = max(*rev.Spec.RollbackPolicy.ProgressDeadlineSeconds, 30)
+	}
+	return time.Duration(seconds) * time.Second
+}
+
+// computeValuesHash returns the hash of everything that determines the content of the Helm releases
+func computeValuesHash(rev *v1alpha1.IstioRevision) (string, error) {
+	data, err := json.Marshal(struct {
+		Version string           `json:"version"`
+		Values  *v1alpha1.Values `json:"values"`
+	}{rev.Spec.Version, rev.Spec.Values})
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%x", sha256.Sum256(data)), nil
+}
+
+// checkRolledBack returns a rolledBackError if the revision's version and values are the ones whose upgrade
+// was rolled back
+func checkRolledBack(rev *v1alpha1.IstioRevision, status *v1alpha1.IstioRevisionStatus) error {
+	if status.Rollback == nil || status.Rollback.FailedValuesHash == "" {
+		return nil
+	}
+	hash, err := computeValuesHash(rev)
+	if err != nil {
+		return err
+	}
+	if hash == status.Rollback.FailedValuesHash {
+		return &rolledBackError{
+			message: "the components were rolled back, because istiod didn't become ready after the upgrade to the current " +
+				"version and values; change the spec to upgrade the components again",
+		}
+	}
+	return nil
+}
+
+// verifyUpgrade tracks the upgrades of the components in the status. After every upgrade, it waits for istiod
+// to become ready. When istiod is ready, the releases are recorded as the last good ones. If istiod doesn't
+// become ready within the progress deadline, the releases are rolled back to the last good ones. When the
+// policy is enabled, the currently installed releases are verified the same way.
+//
+// Upgrades that reapply the last good values (e.g. to revert drift) are verified too, since they add revisions
+// to the release history, which may push the recorded revisions out of it.
+func (r *IstioRevisionReconciler) verifyUpgrade(ctx context.Context, rev *v1alpha1.IstioRevision,
+	status *v1alpha1.IstioRevisionStatus, upgraded bool,
+) (ctrl.Result, error) {
+	log := logf.FromContext(ctx)
+
+	hash, err := computeValuesHash(rev)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	if status.Rollback == nil {
+		status.Rollback = &v1alpha1.RollbackStatus{}
+	}
+	rollback := status.Rollback
+	// when the policy was just enabled, the installed releases haven't been verified yet
+	notVerified := rollback.LastGoodValuesHash == "" && rollback.PendingSince == nil
+	alreadyPending := rollback.PendingSince != nil && rollback.PendingValuesHash == hash
+	if (upgraded || notVerified) && !alreadyPending {
+		rollback.PendingValuesHash = hash
+		rollback.PendingSince = ptr.Of(metav1.Now())
+	}
+	if rollback.PendingSince == nil {
+		return ctrl.Result{}, nil
+	}
+
+	rolledOut, err := r.isIstiodRolledOut(ctx, rev)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	if rolledOut {
+		releases, err := helm.GetReleaseRevisions(ctx, r.RestClientGetter, userCharts, rev.Name, rev.Spec.Namespace)
+		if err != nil {
+			return ctrl.Result{}, err
+		}
+		log.Info("Upgrade verified; istiod is ready")
+		rollback.LastGoodReleases = releases
+		rollback.LastGoodValuesHash = rollback.PendingValuesHash
+		rollback.PendingValuesHash = ""
+		rollback.PendingSince = nil
+		rollback.FailedValuesHash = ""
+		return ctrl.Result{}, nil
+	}
+
+	remaining := time.Until(rollback.PendingSince.Add(getProgressDeadline(rev)))
+	if remaining > 0 {
+		log.V(2).Info("Waiting for istiod to become ready after upgrade", "remaining", remaining)
+		return ctrl.Result{RequeueAfter: remaining}, nil
+	}
+
+	failedHash := rollback.PendingValuesHash
+	rollback.PendingValuesHash = ""
+	rollback.PendingSince = nil
+	if failedHash == rollback.LastGoodValuesHash {
+		log.Info("istiod didn't become ready after the last good values were reapplied; there's no other release to roll back to")
+		return ctrl.Result{}, nil
+	}
+	if len(rollback.LastGoodReleases) == 0 {
+		log.Info("istiod didn't become ready after upgrade, but there's no healthy release to roll back to")
+		return ctrl.Result{}, nil
+	}
+
+	log.Info("istiod didn't become ready after upgrade; rolling back", "releases", rollback.LastGoodReleases)
+	if err := helm.RollbackCharts(ctx, r.RestClientGetter, userCharts, rev.Name, rev.Spec.Namespace,
+		rollback.LastGoodReleases, getMaxHistory(rev)); err != nil {
+		return ctrl.Result{}, err
+	}
+	// the rollback creates new revisions of the releases
+	releases, err := helm.GetReleaseRevisions(ctx, r.RestClientGetter, userCharts, rev.Name, rev.Spec.Namespace)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	rollback.LastGoodReleases = releases
+	rollback.FailedValuesHash = failedHash
+	return ctrl.Result{}, &rolledBackError{
+		message: fmt.Sprintf("the components were rolled back, because istiod didn't become ready within %s after the upgrade "+
+			"to the current version and values; change the spec to upgrade the components again", getProgressDeadline(rev)),
+	}
+}
+
+// isIstiodRolledOut returns true if all the replicas of the istiod Deployment have been updated and are available
+func (r *IstioRevisionReconciler) isIstiodRolledOut(ctx context.Context, rev *v1alpha1.IstioRevision) (bool, error) {
+	istiod := appsv1.Deployment{}
+	if err := r.Client.Get(ctx, istiodDeploymentKey(rev), &istiod); err != nil {
+		if errors.IsNotFound(err) {
+			return false, nil
+		}
+		return false, err
+	}
+	replicas := ptr.OrDefault(istiod.Spec.Replicas, 1)
+	return istiod.Status.ObservedGeneration >= istiod.Generation &&
+		istiod.Status.UpdatedReplicas == replicas &&
+		istiod.Status.Replicas == replicas &&
+		istiod.Status.AvailableReplicas == replicas, nil
+}
@@ -0,0 +1,253 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package istiorevision
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/kubectl/pkg/scheme"
+	v1 "maistra.io/istio-operator/api/v1alpha1"
+	"maistra.io/istio-operator/pkg/helm"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"istio.io/istio/pkg/ptr"
+)
+
+func TestGetMaxHistory(t *testing.T) {
+	testCases := []struct {
+		name     string
+		policy   *v1.RollbackPolicy
+		expected int
+	}{
+		{name: "no policy", expected: helm.DefaultMaxHistory},
+		{name: "disabled", policy: &v1.RollbackPolicy{MaxHistory: ptr.Of(int32(5))}, expected: helm.DefaultMaxHistory},
+		{name: "enabled with default", policy: &v1.RollbackPolicy{Enabled: true}, expected: defaultRollbackMaxHistory},
+		{name: "enabled with custom", policy: &v1.RollbackPolicy{Enabled: true, MaxHistory: ptr.Of(int32(5))}, expected: 5},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			rev := &v1.IstioRevision{Spec: v1.IstioRevisionSpec{RollbackPolicy: tc.policy}}
+			if result := getMaxHistory(rev); result != tc.expected {
+				t.Errorf("expected %d, but got %d", tc.expected, result)
+			}
+		})
+	}
+}
+
+func TestCheckRolledBack(t *testing.T) {
+	rev := &v1.IstioRevision{
+		Spec: v1.IstioRevisionSpec{
+			Version: "v1.20.0",
+			Values:  &v1.Values{Revision: "my-rev"},
+		},
+	}
+	hash, err := computeValuesHash(rev)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("failed values", func(t *testing.T) {
+		status := &v1.IstioRevisionStatus{Rollback: &v1.RollbackStatus{FailedValuesHash: hash}}
+		if err := checkRolledBack(rev, status); err == nil {
+			t.Errorf("expected rolledBackError, but got no error")
+		} else if _, ok := err.(*rolledBackError); !ok {
+			t.Errorf("expected rolledBackError, but got: %v", err)
+		}
+	})
+
+	t.Run("changed spec", func(t *testing.T) {
+		changed := rev.DeepCopy()
+		changed.Spec.Version = "v1.21.0"
+		status := &v1.IstioRevisionStatus{Rollback: &v1.RollbackStatus{FailedValuesHash: hash}}
+		if err := checkRolledBack(changed, status); err != nil {
+			t.Errorf("expected no error, but got: %v", err)
+		}
+	})
+
+	t.Run("no rollback status", func(t *testing.T) {
+		if err := checkRolledBack(rev, &v1.IstioRevisionStatus{}); err != nil {
+			t.Errorf("expected no error, but got: %v", err)
+		}
+	})
+}
+
+func TestIsIstiodRolledOut(t *testing.T) {
+	testCases := []struct {
+		name     string
+		status   *appsv1.DeploymentStatus
+		expected bool
+	}{
+		{
+			name:     "not found",
+			expected: false,
+		},
+		{
+			name:     "rolled out",
+			status:   &appsv1.DeploymentStatus{ObservedGeneration: 2, Replicas: 2, UpdatedReplicas: 2, AvailableReplicas: 2},
+			expected: true,
+		},
+		{
+			name:     "not observed",
+			status:   &appsv1.DeploymentStatus{ObservedGeneration: 1, Replicas: 2, UpdatedReplicas: 2, AvailableReplicas: 2},
+			expected: false,
+		},
+		{
+			name:     "old replicas remaining",
+			status:   &appsv1.DeploymentStatus{ObservedGeneration: 2, Replicas: 3, UpdatedReplicas: 2, AvailableReplicas: 3},
+			expected: false,
+		},
+		{
+			name:     "replicas unavailable",
+			status:   &appsv1.DeploymentStatus{ObservedGeneration: 2, Replicas: 2, UpdatedReplicas: 2, AvailableReplicas: 1},
+			expected: false,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			rev := &v1.IstioRevision{Spec: v1.IstioRevisionSpec{Namespace: "istio-system"}}
+			var objs []client.Object
+			if tc.status != nil {
+				objs = append(objs, newIstiodDeployment(rev, *tc.status))
+			}
+			cl := fake.NewClientBuilder().WithScheme(scheme.Scheme).WithObjects(objs...).Build()
+			r := NewIstioRevisionReconciler(cl, scheme.Scheme, nil, nil)
+
+			result, err := r.isIstiodRolledOut(context.TODO(), rev)
+			if err != nil {
+				t.Fatalf("expected no error, but got: %v", err)
+			}
+			if result != tc.expected {
+				t.Errorf("expected %v, but got %v", tc.expected, result)
+			}
+		})
+	}
+}
+
+// TestVerifyUpgradeWithoutRollback covers the cases that don't need to read or roll back the Helm releases
+func TestVerifyUpgradeWithoutRollback(t *testing.T) {
+	rev := &v1.IstioRevision{
+		Spec: v1.IstioRevisionSpec{
+			Namespace:      "istio-system",
+			Version:        "v1.20.0",
+			RollbackPolicy: &v1.RollbackPolicy{Enabled: true, ProgressDeadlineSeconds: ptr.Of(int32(60))},
+		},
+	}
+	hash, err := computeValuesHash(rev)
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := metav1.Now()
+	expired := metav1.NewTime(now.Add(-2 * time.Minute))
+
+	testCases := []struct {
+		name            string
+		rollback        *v1.RollbackStatus
+		upgraded        bool
+		expectRequeue   bool
+		expectedPending *v1.RollbackStatus
+	}{
+		{
+			name:     "already verified",
+			rollback: &v1.RollbackStatus{LastGoodValuesHash: hash},
+			upgraded: false,
+		},
+		{
+			name:            "upgraded to last good values",
+			rollback:        &v1.RollbackStatus{LastGoodValuesHash: hash},
+			upgraded:        true,
+			expectRequeue:   true,
+			expectedPending: &v1.RollbackStatus{PendingValuesHash: hash},
+		},
+		{
+			name:            "upgraded again while pending keeps the deadline",
+			rollback:        &v1.RollbackStatus{LastGoodValuesHash: "old", PendingValuesHash: hash, PendingSince: &expired},
+			upgraded:        true,
+			expectedPending: nil,
+		},
+		{
+			name:            "upgraded to new values",
+			rollback:        &v1.RollbackStatus{LastGoodValuesHash: "old"},
+			upgraded:        true,
+			expectRequeue:   true,
+			expectedPending: &v1.RollbackStatus{PendingValuesHash: hash},
+		},
+		{
+			name:            "policy just enabled",
+			upgraded:        false,
+			expectRequeue:   true,
+			expectedPending: &v1.RollbackStatus{PendingValuesHash: hash},
+		},
+		{
+			name:     "deadline passed without healthy release",
+			rollback: &v1.RollbackStatus{PendingValuesHash: hash, PendingSince: &expired},
+			upgraded: false,
+		},
+		{
+			name: "deadline passed after reapplying last good values",
+			rollback: &v1.RollbackStatus{
+				LastGoodReleases:   map[string]int{"istiod": 1},
+				LastGoodValuesHash: hash,
+				PendingValuesHash:  hash,
+				PendingSince:       &expired,
+			},
+			upgraded: false,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			cl := fake.NewClientBuilder().WithScheme(scheme.Scheme).Build()
+			r := NewIstioRevisionReconciler(cl, scheme.Scheme, nil, nil)
+			status := &v1.IstioRevisionStatus{Rollback: tc.rollback.DeepCopy()}
+
+			result, err := r.verifyUpgrade(context.TODO(), rev, status, tc.upgraded)
+			if err != nil {
+				t.Fatalf("expected no error, but got: %v", err)
+			}
+			if requeue := result.RequeueAfter > 0; requeue != tc.expectRequeue {
+				t.Errorf("expected requeue to be %v, but got result %v", tc.expectRequeue, result)
+			}
+			if tc.expectedPending == nil {
+				if status.Rollback.PendingSince != nil || status.Rollback.PendingValuesHash != "" {
+					t.Errorf("expected no pending upgrade, but got %+v", status.Rollback)
+				}
+			} else if status.Rollback.PendingSince == nil || status.Rollback.PendingValuesHash != tc.expectedPending.PendingValuesHash {
+				t.Errorf("expected pending upgrade to %s, but got %+v", tc.expectedPending.PendingValuesHash, status.Rollback)
+			}
+			if status.Rollback.FailedValuesHash != "" {
+				t.Errorf("expected no failed values, but got %s", status.Rollback.FailedValuesHash)
+			}
+		})
+	}
+}
+
+func newIstiodDeployment(rev *v1.IstioRevision, status appsv1.DeploymentStatus) *appsv1.Deployment {
+	key := istiodDeploymentKey(rev)
+	return &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:       key.Name,
+			Namespace:  key.Namespace,
+			Generation: 2,
+		},
+		Spec: appsv1.DeploymentSpec{
+			Replicas: ptr.Of(int32(2)),
+		},
+		Status: status,
+	}
+}
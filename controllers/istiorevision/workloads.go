@@ -0,0 +1,175 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package istiorevision
+
+import (
+	"cmp"
+	"context"
+	"slices"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"maistra.io/istio-operator/api/v1alpha1"
+	"maistra.io/istio-operator/pkg/kube"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+
+	"istio.io/istio/pkg/util/sets"
+)
+
+// getWorkloadInventory counts the namespaces and pods that reference the revision, either directly or through
+// any of the tags that point to it, and lists the first v1alpha1.MaxListedWorkloads of each. Instead of listing
+// all namespaces and pods in the cluster, only the ones found through the FieldIndexes are inspected, unless the
+// revision injects the sidecar into all namespaces by default.
+func (r *IstioRevisionReconciler) getWorkloadInventory(ctx context.Context, rev *v1alpha1.IstioRevision) (*v1alpha1.WorkloadInventory, error) {
+	log := logf.FromContext(ctx)
+
+	names, err := r.getNamesReferringToRevision(ctx, rev)
+	if err != nil {
+		return nil, err
+	}
+
+	inventory := &v1alpha1.WorkloadInventory{}
+	nsMap := map[string]*metav1.PartialObjectMetadata{}
+	var candidatePods []metav1.PartialObjectMetadata
+	addNamespace := func(ns *metav1.PartialObjectMetadata) error {
+		log.V(2).Info("Revision is referenced by Namespace", "Namespace", ns.Name)
+		inventory.Namespaces++
+		inventory.NamespaceNames = append(inventory.NamespaceNames, ns.Name)
+		nsMap[ns.Name] = ns
+
+		// the pods in the namespace reference the revision unless they were injected by another revision
+		// or opted out of the injection
+		podList := kube.NewMetadataList(kube.PodGVK)
+		if err := r.Client.List(ctx, podList, client.InNamespace(ns.Name)); err != nil {
+			return err
+		}
+		candidatePods = append(candidatePods, podList.Items...)
+		return nil
+	}
+
+	// with enableNamespacesByDefault, the default revision is also referenced by all namespaces that don't
+	// reference any revision explicitly
+	enabledByDefault := names.Contains(v1alpha1.DefaultRevision) && EnablesNamespacesByDefault(rev.Spec.Values)
+	if enabledByDefault {
+		nsList := kube.NewMetadataList(kube.NamespaceGVK)
+		if err := r.Client.List(ctx, nsList); err != nil {
+			return nil, err
+		}
+		for i, ns := range nsList.Items {
+			if IsNamespaceInjectedByDefault(ns.Name, ns.Labels) {
+				if err := addNamespace(&nsList.Items[i]); err != nil {
+					return nil, err
+				}
+			}
+		}
+	}
+
+	for _, name := range sets.SortedList(names) {
+		nsList := kube.NewMetadataList(kube.NamespaceGVK)
+		if err := r.Client.List(ctx, nsList, client.MatchingFields{NamespaceRevisionIndex: name}); err != nil {
+			return nil, err
+		}
+		for i := range nsList.Items {
+			if err := addNamespace(&nsList.Items[i]); err != nil {
+				return nil, err
+			}
+		}
+
+		for _, index := range []string{PodInjectedRevisionIndex, PodLabelRevisionIndex} {
+			podList := kube.NewMetadataList(kube.PodGVK)
+			if err := r.Client.List(ctx, podList, client.MatchingFields{index: name}); err != nil {
+				return nil, err
+			}
+			candidatePods = append(candidatePods, podList.Items...)
+		}
+	}
+
+	seen := sets.New[types.NamespacedName]()
+	for _, pod := range candidatePods {
+		key := client.ObjectKeyFromObject(&pod)
+		if seen.InsertContains(key) {
+			continue
+		}
+		ns, err := r.getNamespace(ctx, nsMap, pod.Namespace)
+		if err != nil {
+			return nil, err
+		} else if ns == nil {
+			continue
+		}
+		revision, referenceType := getPodReference(pod.Labels, pod.Annotations, ns.Labels)
+		if revision == "" && enabledByDefault && IsNamespaceInjectedByDefault(ns.Name, ns.Labels) && isPodInjectedByDefault(pod.Labels) {
+			revision, referenceType = v1alpha1.DefaultRevision, v1alpha1.WorkloadReferenceTypePendingInjection
+		}
+		if !names.Contains(revision) {
+			continue
+		}
+		log.V(2).Info("Revision is referenced by Pod", "Pod", key, "Type", referenceType)
+		inventory.Pods++
+		switch referenceType {
+		case v1alpha1.WorkloadReferenceTypeInjected:
+			inventory.InjectedPods++
+		case v1alpha1.WorkloadReferenceTypePendingInjection:
+			inventory.PendingInjectionPods++
+		case v1alpha1.WorkloadReferenceTypePodLabel:
+			inventory.PodLabelPods++
+		}
+		inventory.PodReferences = append(inventory.PodReferences, v1alpha1.PodReference{
+			Namespace: pod.Namespace,
+			Name:      pod.Name,
+			Type:      referenceType,
+		})
+	}
+
+	// sort the lists, so that the status doesn't change when the order of the listed objects does
+	slices.Sort(inventory.NamespaceNames)
+	slices.SortFunc(inventory.PodReferences, func(a, b v1alpha1.PodReference) int {
+		if a.Namespace != b.Namespace {
+			return cmp.Compare(a.Namespace, b.Namespace)
+		}
+		return cmp.Compare(a.Name, b.Name)
+	})
+	if len(inventory.NamespaceNames) > v1alpha1.MaxListedWorkloads {
+		inventory.NamespaceNames = inventory.NamespaceNames[:v1alpha1.MaxListedWorkloads]
+	}
+	if len(inventory.PodReferences) > v1alpha1.MaxListedWorkloads {
+		inventory.PodReferences = inventory.PodReferences[:v1alpha1.MaxListedWorkloads]
+	}
+
+	if inventory.Namespaces == 0 && inventory.Pods == 0 {
+		log.V(2).Info("Revision is not referenced by any Pod or Namespace")
+	}
+	return inventory, nil
+}
+
+// getNamespace returns the namespace with the given name, looking it up only if it isn't in the given map
+// yet. Returns nil if the namespace doesn't exist.
+func (r *IstioRevisionReconciler) getNamespace(ctx context.Context, nsMap map[string]*metav1.PartialObjectMetadata, name string,
+) (*metav1.PartialObjectMetadata, error) {
+	if ns, found := nsMap[name]; found {
+		return ns, nil
+	}
+	ns := kube.NewMetadata(kube.NamespaceGVK)
+	if err := r.Client.Get(ctx, types.NamespacedName{Name: name}, ns); err != nil {
+		if errors.IsNotFound(err) {
+			ns = nil
+		} else {
+			return nil, err
+		}
+	}
+	nsMap[name] = ns
+	return ns, nil
+}
@@ -0,0 +1,163 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package istiorevision
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/kubectl/pkg/scheme"
+	v1 "maistra.io/istio-operator/api/v1alpha1"
+	"maistra.io/istio-operator/pkg/test"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func TestGetWorkloadInventory(t *testing.T) {
+	test.SetupScheme()
+
+	rev := &v1.IstioRevision{ObjectMeta: metav1.ObjectMeta{Name: "my-rev"}}
+	newPod := func(name, namespace string, labels, annotations map[string]string) *corev1.Pod {
+		return &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace, Labels: labels, Annotations: annotations}}
+	}
+
+	cl := newFakeClientBuilder().
+		WithObjects(
+			rev,
+			&v1.IstioRevisionTag{
+				ObjectMeta: metav1.ObjectMeta{Name: "prod"},
+				Status:     v1.IstioRevisionTagStatus{IstioRevision: "my-rev"},
+			},
+			&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "labeled", Labels: map[string]string{"istio.io/rev": "prod"}}},
+			&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "migrated", Labels: map[string]string{"istio.io/rev": "new-rev"}}},
+			&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "other"}},
+			newPod("pending", "labeled", nil, nil),
+			newPod("injected", "labeled", nil, map[string]string{"istio.io/rev": "my-rev"}),
+			newPod("not-restarted", "migrated", nil, map[string]string{"istio.io/rev": "my-rev"}),
+			newPod("restarted", "migrated", nil, map[string]string{"istio.io/rev": "new-rev"}),
+			newPod("pod-label", "other", map[string]string{"istio.io/rev": "my-rev"}, nil),
+			newPod("not-injected", "other", nil, nil),
+		).
+		Build()
+	r := NewIstioRevisionReconciler(cl, scheme.Scheme, nil, nil)
+
+	workloads, err := r.getWorkloadInventory(context.TODO(), rev)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	expected := &v1.WorkloadInventory{
+		Namespaces:           1,
+		Pods:                 4,
+		InjectedPods:         2,
+		PendingInjectionPods: 1,
+		PodLabelPods:         1,
+		NamespaceNames:       []string{"labeled"},
+		PodReferences: []v1.PodReference{
+			{Namespace: "labeled", Name: "injected", Type: v1.WorkloadReferenceTypeInjected},
+			{Namespace: "labeled", Name: "pending", Type: v1.WorkloadReferenceTypePendingInjection},
+			{Namespace: "migrated", Name: "not-restarted", Type: v1.WorkloadReferenceTypeInjected},
+			{Namespace: "other", Name: "pod-label", Type: v1.WorkloadReferenceTypePodLabel},
+		},
+	}
+	if diff := cmp.Diff(expected, workloads); diff != "" {
+		t.Errorf("unexpected workload inventory; diff (-expected, +actual):\n%v", diff)
+	}
+}
+
+func TestGetWorkloadInventoryWithNamespacesEnabledByDefault(t *testing.T) {
+	test.SetupScheme()
+
+	rev := &v1.IstioRevision{
+		ObjectMeta: metav1.ObjectMeta{Name: "default"},
+		Spec: v1.IstioRevisionSpec{
+			Values: &v1.Values{SidecarInjectorWebhook: &v1.SidecarInjectorConfig{EnableNamespacesByDefault: true}},
+		},
+	}
+	newPod := func(name, namespace string, labels map[string]string) *corev1.Pod {
+		return &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace, Labels: labels}}
+	}
+
+	cl := newFakeClientBuilder().
+		WithObjects(
+			rev,
+			&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "unlabeled"}},
+			&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "disabled", Labels: map[string]string{"istio-injection": "disabled"}}},
+			&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "other-rev", Labels: map[string]string{"istio.io/rev": "other-rev"}}},
+			&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "kube-system"}},
+			newPod("pending", "unlabeled", nil),
+			newPod("opted-out", "unlabeled", map[string]string{"sidecar.istio.io/inject": "false"}),
+			newPod("other-rev-label", "unlabeled", map[string]string{"istio.io/rev": "other-rev"}),
+			newPod("inject-label", "unlabeled", map[string]string{"sidecar.istio.io/inject": "true"}),
+			newPod("in-disabled", "disabled", nil),
+			newPod("in-other-rev", "other-rev", nil),
+			newPod("in-system", "kube-system", nil),
+		).
+		Build()
+	r := NewIstioRevisionReconciler(cl, scheme.Scheme, nil, nil)
+
+	workloads, err := r.getWorkloadInventory(context.TODO(), rev)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	expected := &v1.WorkloadInventory{
+		Namespaces:           1,
+		Pods:                 2,
+		PendingInjectionPods: 1,
+		PodLabelPods:         1,
+		NamespaceNames:       []string{"unlabeled"},
+		PodReferences: []v1.PodReference{
+			{Namespace: "unlabeled", Name: "inject-label", Type: v1.WorkloadReferenceTypePodLabel},
+			{Namespace: "unlabeled", Name: "pending", Type: v1.WorkloadReferenceTypePendingInjection},
+		},
+	}
+	if diff := cmp.Diff(expected, workloads); diff != "" {
+		t.Errorf("unexpected workload inventory; diff (-expected, +actual):\n%v", diff)
+	}
+}
+
+func TestGetWorkloadInventoryListsLimitedNumberOfWorkloads(t *testing.T) {
+	test.SetupScheme()
+
+	rev := &v1.IstioRevision{ObjectMeta: metav1.ObjectMeta{Name: "my-rev"}}
+	objs := []client.Object{rev}
+	total := v1.MaxListedWorkloads + 5
+	for i := 0; i < total; i++ {
+		ns := fmt.Sprintf("ns-%02d", i)
+		objs = append(objs,
+			&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: ns, Labels: map[string]string{"istio.io/rev": "my-rev"}}},
+			&corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "pod", Namespace: ns}},
+		)
+	}
+	cl := newFakeClientBuilder().WithObjects(objs...).Build()
+	r := NewIstioRevisionReconciler(cl, scheme.Scheme, nil, nil)
+
+	workloads, err := r.getWorkloadInventory(context.TODO(), rev)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if workloads.Namespaces != int32(total) || workloads.Pods != int32(total) {
+		t.Errorf("expected %d namespaces and pods, but got %d namespaces and %d pods", total, workloads.Namespaces, workloads.Pods)
+	}
+	if len(workloads.NamespaceNames) != v1.MaxListedWorkloads || len(workloads.PodReferences) != v1.MaxListedWorkloads {
+		t.Errorf("expected %d namespaces and pods to be listed, but got %d namespaces and %d pods",
+			v1.MaxListedWorkloads, len(workloads.NamespaceNames), len(workloads.PodReferences))
+	}
+	if workloads.NamespaceNames[0] != "ns-00" || workloads.PodReferences[0].Namespace != "ns-00" {
+		t.Errorf("expected the lists to be sorted, but got %v and %v", workloads.NamespaceNames, workloads.PodReferences)
+	}
+}
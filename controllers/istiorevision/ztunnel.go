@@ -0,0 +1,225 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package istiorevision
+
+import (
+	"context"
+	"sort"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"maistra.io/istio-operator/api/v1alpha1"
+	"maistra.io/istio-operator/pkg/helm"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// ztunnel is a node agent whose resources have fixed names, so only one instance can run in the cluster.
+// It's installed in a single release named after this base, which is managed by one of the revisions
+// that enable ambient mode.
+const ztunnelReleaseNameBase = "istio"
+
+// ztunnelAction is what a revision does with the ztunnel release when it isn't the one that installs it
+type ztunnelAction int
+
+const (
+	// ztunnelActionNone means that the revision has nothing to do with the ztunnel release
+	ztunnelActionNone ztunnelAction = iota
+	// ztunnelActionSkip means that the revision enables ztunnel, but another revision manages it
+	ztunnelActionSkip
+	// ztunnelActionTransfer means that the revision manages ztunnel, but ownership must pass to another revision
+	ztunnelActionTransfer
+	// ztunnelActionUninstall means that the revision manages ztunnel, but no revision enables it anymore
+	ztunnelActionUninstall
+)
+
+// determineZTunnelOwner returns the revision that manages the ztunnel release, which is the newest revision
+// that enables ztunnel and isn't being deleted, or nil if there's no such revision. Choosing the newest revision
+// upgrades ztunnel together with the control plane during a revision-based upgrade.
+// ztunnel's cluster-scoped resources can't belong to two releases, so it can't be moved to another namespace
+// without interrupting the traffic of the ambient workloads. As long as the holder, i.e. the revision that controls
+// the ztunnel DaemonSet, enables ztunnel, only the revisions in the holder's namespace can take it over.
+func determineZTunnelOwner(revs []v1alpha1.IstioRevision, holder *v1alpha1.IstioRevision) *v1alpha1.IstioRevision {
+	var candidates []*v1alpha1.IstioRevision
+	for i := range revs {
+		if revs[i].DeletionTimestamp == nil && isZTunnelEnabled(revs[i].Spec.Values) {
+			candidates = append(candidates, &revs[i])
+		}
+	}
+	if holder != nil && holder.DeletionTimestamp == nil && isZTunnelEnabled(holder.Spec.Values) {
+		sameNamespace := candidates[:0:0]
+		for _, candidate := range candidates {
+			if candidate.Spec.Namespace == holder.Spec.Namespace {
+				sameNamespace = append(sameNamespace, candidate)
+			}
+		}
+		candidates = sameNamespace
+	}
+	if len(candidates) == 0 {
+		return nil
+	}
+	sort.Slice(candidates, func(i, j int) bool {
+		if !candidates[i].CreationTimestamp.Equal(&candidates[j].CreationTimestamp) {
+			return candidates[j].CreationTimestamp.Before(&candidates[i].CreationTimestamp)
+		}
+		return candidates[i].Name < candidates[j].Name
+	})
+	return candidates[0]
+}
+
+// determineZTunnelAction returns what the given revision must do with the ztunnel release, given the
+// revision that should manage it and whether the revision currently owns the ztunnel DaemonSet
+func determineZTunnelAction(rev, owner *v1alpha1.IstioRevision, ownsDaemonSet bool) ztunnelAction {
+	if isZTunnelOwner(rev, owner) {
+		return ztunnelActionNone
+	}
+	if ownsDaemonSet {
+		if owner == nil {
+			return ztunnelActionUninstall
+		}
+		return ztunnelActionTransfer
+	}
+	if rev.DeletionTimestamp == nil && isZTunnelEnabled(rev.Spec.Values) {
+		return ztunnelActionSkip
+	}
+	return ztunnelActionNone
+}
+
+func isZTunnelOwner(rev, owner *v1alpha1.IstioRevision) bool {
+	return owner != nil && owner.UID == rev.UID
+}
+
+// getZTunnelOwner returns the revision that manages the ztunnel release, or nil if no revision enables ztunnel
+func (r *IstioRevisionReconciler) getZTunnelOwner(ctx context.Context) (*v1alpha1.IstioRevision, error) {
+	owner, _, err := r.getZTunnelOwners(ctx)
+	return owner, err
+}
+
+// getZTunnelOwners returns the revision that manages the ztunnel release and the revision that would manage it
+// if ztunnel could be moved to any namespace. They differ when the newest revision is in another namespace than
+// the ztunnel DaemonSet.
+func (r *IstioRevisionReconciler) getZTunnelOwners(ctx context.Context) (owner, preferred *v1alpha1.IstioRevision, err error) {
+	revList := v1alpha1.IstioRevisionList{}
+	if err := r.Client.List(ctx, &revList); err != nil {
+		return nil, nil, err
+	}
+	holder, err := r.getZTunnelHolder(ctx, revList.Items)
+	if err != nil {
+		return nil, nil, err
+	}
+	return determineZTunnelOwner(revList.Items, holder), determineZTunnelOwner(revList.Items, nil), nil
+}
+
+// getZTunnelHolder returns the revision that controls the ztunnel DaemonSet, or nil if there's none
+func (r *IstioRevisionReconciler) getZTunnelHolder(ctx context.Context, revs []v1alpha1.IstioRevision) (*v1alpha1.IstioRevision, error) {
+	for i := range revs {
+		owns, err := r.ownsZTunnelDaemonSet(ctx, &revs[i])
+		if err != nil {
+			return nil, err
+		} else if owns {
+			return &revs[i], nil
+		}
+	}
+	return nil, nil
+}
+
+// ownsZTunnelDaemonSet returns whether the ztunnel DaemonSet in the revision's namespace is controlled by the revision
+func (r *IstioRevisionReconciler) ownsZTunnelDaemonSet(ctx context.Context, rev *v1alpha1.IstioRevision) (bool, error) {
+	ds := appsv1.DaemonSet{}
+	if err := r.Client.Get(ctx, ztunnelDaemonSetKey(rev.Spec.Namespace), &ds); err != nil {
+		if errors.IsNotFound(err) {
+			return false, nil
+		}
+		return false, err
+	}
+	controller := metav1.GetControllerOf(&ds)
+	return controller != nil && controller.UID == rev.UID, nil
+}
+
+// reconcileZTunnelOwnership hands the ztunnel release over when the revision installed it, but should no longer
+// manage it. If another revision takes over, the release is upgraded with that revision's values and owner
+// reference, so that ztunnel keeps running; otherwise it's uninstalled. It's also called when the revision is
+// deleted, since ztunnel would otherwise be garbage-collected together with the revision.
+func (r *IstioRevisionReconciler) reconcileZTunnelOwnership(ctx context.Context, rev, owner *v1alpha1.IstioRevision) error {
+	log := logf.FromContext(ctx)
+	ownsDaemonSet, err := r.ownsZTunnelDaemonSet(ctx, rev)
+	if err != nil {
+		return err
+	}
+
+	switch determineZTunnelAction(rev, owner, ownsDaemonSet) {
+	case ztunnelActionSkip:
+		// only recorded for a new generation of the spec, since it would otherwise be repeated on every reconcile;
+		// a change of ownership is recorded by the revision that hands ztunnel over
+		if isNewGeneration(rev) {
+			r.EventRecorder.Eventf(rev, corev1.EventTypeNormal, EventReasonZTunnelOwnershipSkipped,
+				"ztunnel is enabled, but it is not installed with this revision; it is managed by IstioRevision %q", owner.Name)
+		}
+	case ztunnelActionTransfer:
+		log.Info("Transferring ztunnel to another IstioRevision", "IstioRevision", owner.Name)
+		r.EventRecorder.TransitionEventf(rev, corev1.EventTypeNormal, EventReasonZTunnelTransferred,
+			"handing ztunnel over to IstioRevision %q", owner.Name)
+		// ztunnel's cluster-scoped resources can't belong to two releases, so a release in another namespace
+		// can only be installed after this one is removed. determineZTunnelOwner only chooses a revision in
+		// another namespace when this revision is deleted or no longer enables ztunnel.
+		if owner.Spec.Namespace != rev.Spec.Namespace {
+			if err := r.uninstallZTunnel(ctx, rev.Spec.Namespace); err != nil {
+				return err
+			}
+		}
+		return r.installZTunnel(ctx, owner)
+	case ztunnelActionUninstall:
+		log.Info("Uninstalling ztunnel, since no IstioRevision enables it")
+		return r.uninstallZTunnel(ctx, rev.Spec.Namespace)
+	}
+	return nil
+}
+
+// installZTunnel installs or upgrades the ztunnel release with the values and owner reference of the given revision
+func (r *IstioRevisionReconciler) installZTunnel(ctx context.Context, rev *v1alpha1.IstioRevision) error {
+	ztunnelValues, err := computeZTunnelValues(rev.Spec.Values.ToHelmValues())
+	if err != nil {
+		return err
+	}
+	return helm.UpgradeOrInstallCharts(ctx, r.RestClientGetter, ambientCharts, ztunnelValues,
+		rev.Spec.Version, ztunnelReleaseNameBase, rev.Spec.Namespace, newOwnerReference(rev), getMaxHistory(rev))
+}
+
+func (r *IstioRevisionReconciler) uninstallZTunnel(ctx context.Context, namespace string) error {
+	return helm.UninstallCharts(ctx, r.RestClientGetter, ambientCharts, ztunnelReleaseNameBase, namespace)
+}
+
+// mapRevisionToZTunnelReconcileRequests triggers the reconciliation of all IstioRevisions that enable ztunnel
+// when any IstioRevision changes, so that the revision that becomes the ztunnel owner takes it over
+func (r *IstioRevisionReconciler) mapRevisionToZTunnelReconcileRequests(ctx context.Context, _ client.Object) []reconcile.Request {
+	log := logf.FromContext(ctx)
+	revList := v1alpha1.IstioRevisionList{}
+	if err := r.Client.List(ctx, &revList); err != nil {
+		log.Error(err, "Could not list IstioRevisions")
+		return nil
+	}
+
+	var requests []reconcile.Request
+	for _, item := range revList.Items {
+		if isZTunnelEnabled(item.Spec.Values) {
+			requests = append(requests, reconcile.Request{NamespacedName: types.NamespacedName{Name: item.Name}})
+		}
+	}
+	return requests
+}
@@ -0,0 +1,316 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package istiorevision
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/kubectl/pkg/scheme"
+	v1 "maistra.io/istio-operator/api/v1alpha1"
+	"maistra.io/istio-operator/pkg/test"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"istio.io/istio/pkg/ptr"
+)
+
+func newZTunnelRevision(name string, created time.Time, ztunnel bool) v1.IstioRevision {
+	return v1.IstioRevision{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:              name,
+			UID:               types.UID(name),
+			CreationTimestamp: metav1.NewTime(created),
+		},
+		Spec: v1.IstioRevisionSpec{
+			Namespace: "istio-system",
+			Values:    &v1.Values{ZTunnel: &v1.ZTunnelConfig{Enabled: ztunnel}},
+		},
+	}
+}
+
+func inNamespace(rev v1.IstioRevision, namespace string) v1.IstioRevision {
+	rev.Spec.Namespace = namespace
+	return rev
+}
+
+func deleting(rev v1.IstioRevision) v1.IstioRevision {
+	rev.DeletionTimestamp = &metav1.Time{Time: time.Now()}
+	return rev
+}
+
+func TestDetermineZTunnelOwner(t *testing.T) {
+	now := time.Now()
+
+	testCases := []struct {
+		name          string
+		revs          []v1.IstioRevision
+		holder        int
+		expectedOwner string
+	}{
+		{
+			name:   "no revisions",
+			holder: -1,
+		},
+		{
+			name:   "ztunnel disabled",
+			revs:   []v1.IstioRevision{newZTunnelRevision("default", now, false)},
+			holder: -1,
+		},
+		{
+			name:          "single revision",
+			revs:          []v1.IstioRevision{newZTunnelRevision("default", now, true)},
+			holder:        -1,
+			expectedOwner: "default",
+		},
+		{
+			name: "newer revision takes over ztunnel during upgrade",
+			revs: []v1.IstioRevision{
+				newZTunnelRevision("new", now, true),
+				newZTunnelRevision("old", now.Add(-time.Hour), true),
+			},
+			holder:        1,
+			expectedOwner: "new",
+		},
+		{
+			name: "older revision takes over when newer is deleted",
+			revs: []v1.IstioRevision{
+				deleting(newZTunnelRevision("new", now, true)),
+				newZTunnelRevision("old", now.Add(-time.Hour), true),
+			},
+			holder:        0,
+			expectedOwner: "old",
+		},
+		{
+			name: "older revision takes over when newer disables ztunnel",
+			revs: []v1.IstioRevision{
+				newZTunnelRevision("new", now, false),
+				newZTunnelRevision("old", now.Add(-time.Hour), true),
+			},
+			holder:        0,
+			expectedOwner: "old",
+		},
+		{
+			name: "revisions created at the same time",
+			revs: []v1.IstioRevision{
+				newZTunnelRevision("b", now, true),
+				newZTunnelRevision("a", now, true),
+			},
+			holder:        -1,
+			expectedOwner: "a",
+		},
+		{
+			name: "newer revision in another namespace doesn't take over",
+			revs: []v1.IstioRevision{
+				inNamespace(newZTunnelRevision("new", now, true), "other-namespace"),
+				newZTunnelRevision("old", now.Add(-time.Hour), true),
+			},
+			holder:        1,
+			expectedOwner: "old",
+		},
+		{
+			name: "newer revision in another namespace takes over when holder is deleted",
+			revs: []v1.IstioRevision{
+				inNamespace(newZTunnelRevision("new", now, true), "other-namespace"),
+				deleting(newZTunnelRevision("old", now.Add(-time.Hour), true)),
+			},
+			holder:        1,
+			expectedOwner: "new",
+		},
+		{
+			name: "newer revision in another namespace takes over when ztunnel isn't installed",
+			revs: []v1.IstioRevision{
+				inNamespace(newZTunnelRevision("new", now, true), "other-namespace"),
+				newZTunnelRevision("old", now.Add(-time.Hour), true),
+			},
+			holder:        -1,
+			expectedOwner: "new",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var holder *v1.IstioRevision
+			if tc.holder >= 0 {
+				holder = &tc.revs[tc.holder]
+			}
+			owner := determineZTunnelOwner(tc.revs, holder)
+			var ownerName string
+			if owner != nil {
+				ownerName = owner.Name
+			}
+			if ownerName != tc.expectedOwner {
+				t.Errorf("expected owner %q, but got %q", tc.expectedOwner, ownerName)
+			}
+		})
+	}
+}
+
+func TestDetermineZTunnelActionWithTwoRevisions(t *testing.T) {
+	now := time.Now()
+	oldRev := newZTunnelRevision("old", now.Add(-time.Hour), true)
+	newRev := newZTunnelRevision("new", now, true)
+	disabledOldRev := newZTunnelRevision("old", now.Add(-time.Hour), false)
+	disabledNewRev := newZTunnelRevision("new", now, false)
+	newRevInOtherNamespace := inNamespace(newRev, "other-namespace")
+
+	testCases := []struct {
+		name              string
+		revs              []v1.IstioRevision
+		oldOwnsDaemonSet  bool
+		expectedOldAction ztunnelAction
+		expectedNewAction ztunnelAction
+	}{
+		{
+			name:              "new revision takes over ztunnel",
+			revs:              []v1.IstioRevision{oldRev, newRev},
+			oldOwnsDaemonSet:  true,
+			expectedOldAction: ztunnelActionTransfer,
+			expectedNewAction: ztunnelActionNone,
+		},
+		{
+			name:              "ztunnel was transferred to new revision",
+			revs:              []v1.IstioRevision{oldRev, newRev},
+			expectedOldAction: ztunnelActionSkip,
+			expectedNewAction: ztunnelActionNone,
+		},
+		{
+			name:              "new revision is deleted",
+			revs:              []v1.IstioRevision{oldRev, deleting(newRev)},
+			expectedOldAction: ztunnelActionNone,
+			expectedNewAction: ztunnelActionTransfer,
+		},
+		{
+			name:              "new revision disables ztunnel",
+			revs:              []v1.IstioRevision{oldRev, disabledNewRev},
+			expectedOldAction: ztunnelActionNone,
+			expectedNewAction: ztunnelActionTransfer,
+		},
+		{
+			name:              "both revisions disable ztunnel",
+			revs:              []v1.IstioRevision{disabledOldRev, disabledNewRev},
+			expectedOldAction: ztunnelActionNone,
+			expectedNewAction: ztunnelActionUninstall,
+		},
+		{
+			name:              "old revision is deleted and new revision disables ztunnel",
+			revs:              []v1.IstioRevision{deleting(oldRev), disabledNewRev},
+			oldOwnsDaemonSet:  true,
+			expectedOldAction: ztunnelActionUninstall,
+			expectedNewAction: ztunnelActionNone,
+		},
+		{
+			name:              "new revision in another namespace doesn't take over ztunnel",
+			revs:              []v1.IstioRevision{oldRev, newRevInOtherNamespace},
+			oldOwnsDaemonSet:  true,
+			expectedOldAction: ztunnelActionNone,
+			expectedNewAction: ztunnelActionSkip,
+		},
+		{
+			name:              "new revision in another namespace takes over ztunnel when old revision is deleted",
+			revs:              []v1.IstioRevision{deleting(oldRev), newRevInOtherNamespace},
+			oldOwnsDaemonSet:  true,
+			expectedOldAction: ztunnelActionTransfer,
+			expectedNewAction: ztunnelActionNone,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// the DaemonSet is controlled by the old revision or, if it doesn't own it, by the new revision
+			holder := &tc.revs[1]
+			if tc.oldOwnsDaemonSet {
+				holder = &tc.revs[0]
+			}
+			owner := determineZTunnelOwner(tc.revs, holder)
+			if action := determineZTunnelAction(&tc.revs[0], owner, tc.oldOwnsDaemonSet); action != tc.expectedOldAction {
+				t.Errorf("expected action %d for old revision, but got %d", tc.expectedOldAction, action)
+			}
+			if action := determineZTunnelAction(&tc.revs[1], owner, !tc.oldOwnsDaemonSet); action != tc.expectedNewAction {
+				t.Errorf("expected action %d for new revision, but got %d", tc.expectedNewAction, action)
+			}
+		})
+	}
+}
+
+func TestOwnsZTunnelDaemonSet(t *testing.T) {
+	test.SetupScheme()
+	oldRev := newZTunnelRevision("old", time.Now().Add(-time.Hour), true)
+	newRev := newZTunnelRevision("new", time.Now(), true)
+	ds := &appsv1.DaemonSet{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "ztunnel",
+			Namespace: "istio-system",
+			OwnerReferences: []metav1.OwnerReference{{
+				APIVersion: v1.GroupVersion.String(),
+				Kind:       v1.IstioRevisionKind,
+				Name:       oldRev.Name,
+				UID:        oldRev.UID,
+				Controller: ptr.Of(true),
+			}},
+		},
+	}
+	cl := fake.NewClientBuilder().WithScheme(scheme.Scheme).WithObjects(ds).Build()
+	r := &IstioRevisionReconciler{Client: cl, Scheme: scheme.Scheme}
+
+	for _, tc := range []struct {
+		rev      *v1.IstioRevision
+		expected bool
+	}{
+		{rev: &oldRev, expected: true},
+		{rev: &newRev, expected: false},
+	} {
+		owns, err := r.ownsZTunnelDaemonSet(context.TODO(), tc.rev)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if owns != tc.expected {
+			t.Errorf("expected ownsZTunnelDaemonSet for %s to be %v, but got %v", tc.rev.Name, tc.expected, owns)
+		}
+	}
+}
+
+func TestZTunnelOwnershipSkippedRecordedForNewGeneration(t *testing.T) {
+	test.SetupScheme()
+	owner := newZTunnelRevision("new", time.Now(), true)
+
+	for _, tc := range []struct {
+		name               string
+		observedGeneration int64
+		expectEvent        bool
+	}{
+		{name: "new generation", observedGeneration: 1, expectEvent: true},
+		{name: "observed generation", observedGeneration: 2, expectEvent: false},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			rev := newZTunnelRevision("old", time.Now().Add(-time.Hour), true)
+			rev.Generation = 2
+			rev.Status.ObservedGeneration = tc.observedGeneration
+
+			recorder := record.NewFakeRecorder(10)
+			cl := fake.NewClientBuilder().WithScheme(scheme.Scheme).Build()
+			r := NewIstioRevisionReconciler(cl, scheme.Scheme, nil, recorder)
+			if err := r.reconcileZTunnelOwnership(context.TODO(), &rev, &owner); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if recorded := len(recorder.Events) > 0; recorded != tc.expectEvent {
+				t.Errorf("expected event to be recorded: %v, but got: %v", tc.expectEvent, recorded)
+			}
+		})
+	}
+}
@@ -0,0 +1,434 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package istiorevisiontag
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+
+	"github.com/go-logr/logr"
+	admissionv1 "k8s.io/api/admissionregistration/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/client-go/rest"
+	"maistra.io/istio-operator/api/v1alpha1"
+	"maistra.io/istio-operator/controllers/istiorevision"
+	"maistra.io/istio-operator/pkg/helm"
+	"maistra.io/istio-operator/pkg/kube"
+	"maistra.io/istio-operator/pkg/revision"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	"istio.io/istio/pkg/ptr"
+)
+
+const (
+	// the revision tag webhook is rendered from the istiod chart
+	revisionTagChart       = "istiod"
+	revisionTagReleaseName = "revision-tag"
+)
+
+// templates of the istiod chart that are deployed for each tag; zzz_profile.yaml is required to
+// apply the chart's default values
+var revisionTagTemplates = []string{"revision-tags.yaml", "zzz_profile.yaml"}
+
+// IstioRevisionTagReconciler reconciles an IstioRevisionTag object
+type IstioRevisionTagReconciler struct {
+	RestClientGetter genericclioptions.RESTClientGetter
+	client.Client
+	Scheme *runtime.Scheme
+}
+
+func NewIstioRevisionTagReconciler(client client.Client, scheme *runtime.Scheme, restConfig *rest.Config) *IstioRevisionTagReconciler {
+	return &IstioRevisionTagReconciler{
+		RestClientGetter: helm.NewRESTClientGetter(restConfig),
+		Client:           client,
+		Scheme:           scheme,
+	}
+}
+
+// tagError is returned when the tag can't be reconciled because of a problem that retrying won't fix, such
+// as a missing target. The tag is reconciled again when the referenced objects change.
+type tagError struct {
+	reason  v1alpha1.IstioRevisionTagConditionReason
+	message string
+}
+
+func (e *tagError) Error() string {
+	return e.message
+}
+
+// +kubebuilder:rbac:groups=operator.istio.io,resources=istiorevisiontags,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=operator.istio.io,resources=istiorevisiontags/status,verbs=get;update;patch
+// +kubebuilder:rbac:groups=operator.istio.io,resources=istiorevisiontags/finalizers,verbs=update
+
+// Reconcile installs the sidecar injector webhook for the IstioRevisionTag and points it to the
+// istiod instance of the referenced revision.
+func (r *IstioRevisionTagReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	log := logf.FromContext(ctx)
+	var tag v1alpha1.IstioRevisionTag
+	if err := r.Client.Get(ctx, req.NamespacedName, &tag); err != nil {
+		if errors.IsNotFound(err) {
+			log.V(2).Info("IstioRevisionTag not found. Skipping reconciliation")
+			return ctrl.Result{}, nil
+		}
+		return ctrl.Result{}, err
+	}
+
+	if tag.DeletionTimestamp != nil {
+		if err := r.uninstallHelmChart(ctx, &tag, tag.Status.IstiodNamespace); err != nil {
+			return ctrl.Result{}, err
+		}
+
+		if err := kube.RemoveFinalizer(ctx, &tag, r.Client); err != nil {
+			log.Info("failed to remove finalizer")
+			return ctrl.Result{}, err
+		}
+		return ctrl.Result{}, nil
+	}
+
+	if !kube.HasFinalizer(&tag) {
+		err := kube.AddFinalizer(ctx, &tag, r.Client)
+		if err != nil {
+			log.Info("failed to add finalizer")
+			return ctrl.Result{}, err
+		}
+	}
+
+	rev, err := r.getTargetRevision(ctx, &tag)
+	if err == nil {
+		err = r.validateTagName(ctx, &tag)
+	}
+	if err == nil {
+		log.Info("Installing revision tag", "IstioRevision", rev.Name)
+		err = r.installHelmChart(ctx, &tag, rev)
+	}
+
+	log.Info("Reconciliation done. Updating status.")
+	err = r.updateStatus(ctx, &tag, rev, err)
+
+	if _, ok := err.(*tagError); ok {
+		// retrying won't help; the watches trigger a new reconciliation when the referenced objects change
+		return ctrl.Result{}, nil
+	}
+	return ctrl.Result{}, err
+}
+
+// getTargetRevision returns the IstioRevision that the tag points to. If the tag references an Istio, this is
+// the Istio's active revision.
+func (r *IstioRevisionTagReconciler) getTargetRevision(ctx context.Context, tag *v1alpha1.IstioRevisionTag) (*v1alpha1.IstioRevision, error) {
+	rev, err := revision.GetTargetRevision(ctx, r.Client, tag.Spec.TargetRef)
+	if notFoundErr, ok := err.(*revision.NotFoundError); ok {
+		return nil, &tagError{
+			reason:  v1alpha1.IstioRevisionTagConditionReasonReferenceNotFound,
+			message: notFoundErr.Error(),
+		}
+	}
+	return rev, err
+}
+
+// validateTagName ensures that the tag doesn't shadow an IstioRevision with the same name, as both would
+// install a webhook that matches the same istio.io/rev label value
+func (r *IstioRevisionTagReconciler) validateTagName(ctx context.Context, tag *v1alpha1.IstioRevisionTag) error {
+	rev := v1alpha1.IstioRevision{}
+	if err := r.Client.Get(ctx, types.NamespacedName{Name: tag.Name}, &rev); err == nil {
+		return &tagError{
+			reason:  v1alpha1.IstioRevisionTagConditionReasonNameAlreadyExists,
+			message: fmt.Sprintf("IstioRevision %q already exists; tag names must not match revision names", tag.Name),
+		}
+	} else if !errors.IsNotFound(err) {
+		return err
+	}
+	return nil
+}
+
+func (r *IstioRevisionTagReconciler) installHelmChart(ctx context.Context, tag *v1alpha1.IstioRevisionTag, rev *v1alpha1.IstioRevision) error {
+	if rev.Spec.Values == nil {
+		return fmt.Errorf("spec.values not set in IstioRevision %q", rev.Name)
+	}
+
+	// if the tag previously pointed to a revision in another namespace, the old webhook must be removed first
+	if tag.Status.IstiodNamespace != "" && tag.Status.IstiodNamespace != rev.Spec.Namespace {
+		if err := r.uninstallHelmChart(ctx, tag, tag.Status.IstiodNamespace); err != nil {
+			return err
+		}
+	}
+
+	ownerReference := metav1.OwnerReference{
+		APIVersion:         v1alpha1.GroupVersion.String(),
+		Kind:               v1alpha1.IstioRevisionTagKind,
+		Name:               tag.Name,
+		UID:                tag.UID,
+		Controller:         ptr.Of(true),
+		BlockOwnerDeletion: ptr.Of(true),
+	}
+
+	values := rev.Spec.Values.DeepCopy()
+	values.RevisionTags = []string{tag.Name}
+
+	return helm.UpgradeOrInstallChartTemplates(ctx, r.RestClientGetter, revisionTagChart, revisionTagTemplates,
+		values.ToHelmValues(), rev.Spec.Version, getReleaseName(tag), rev.Spec.Namespace, ownerReference)
+}
+
+func (r *IstioRevisionTagReconciler) uninstallHelmChart(ctx context.Context, tag *v1alpha1.IstioRevisionTag, ns string) error {
+	if ns == "" {
+		return nil
+	}
+	return helm.UninstallRelease(ctx, r.RestClientGetter, getReleaseName(tag), ns)
+}
+
+func getReleaseName(tag *v1alpha1.IstioRevisionTag) string {
+	return tag.Name + "-" + revisionTagReleaseName
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *IstioRevisionTagReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	// istioHandler and revisionHandler trigger the reconciliation of the tags that reference the Istio or
+	// IstioRevision, so that the tag's webhook follows the referenced revision
+	istioHandler := handler.EnqueueRequestsFromMapFunc(r.mapIstioToReconcileRequest)
+	revisionHandler := handler.EnqueueRequestsFromMapFunc(r.mapRevisionToReconcileRequest)
+
+	// nsHandler and podHandler trigger the reconciliation of the referenced tag so that its InUse condition is updated
+	nsHandler := handler.EnqueueRequestsFromMapFunc(r.mapNamespaceToReconcileRequest)
+	podHandler := handler.EnqueueRequestsFromMapFunc(r.mapPodToReconcileRequest)
+
+	return ctrl.NewControllerManagedBy(mgr).
+		WithOptions(controller.Options{
+			LogConstructor: func(req *reconcile.Request) logr.Logger {
+				log := mgr.GetLogger().WithName("ctrlr").WithName("revtag")
+				if req != nil {
+					log = log.WithValues("IstioRevisionTag", req.Name)
+				}
+				return log
+			},
+		}).
+		For(&v1alpha1.IstioRevisionTag{}).
+		Owns(&admissionv1.MutatingWebhookConfiguration{}).
+		Watches(&v1alpha1.Istio{}, istioHandler).
+		Watches(&v1alpha1.IstioRevision{}, revisionHandler).
+		WatchesMetadata(kube.NewMetadata(kube.NamespaceGVK), nsHandler).
+		WatchesMetadata(kube.NewMetadata(kube.PodGVK), podHandler).
+		Complete(r)
+}
+
+func (r *IstioRevisionTagReconciler) updateStatus(ctx context.Context, tag *v1alpha1.IstioRevisionTag, rev *v1alpha1.IstioRevision, err error) error {
+	log := logf.FromContext(ctx)
+	reconciledCondition := determineReconciledCondition(err)
+	inUseCondition, inUseErr := r.determineInUseCondition(ctx, tag, rev)
+	if inUseErr != nil {
+		return inUseErr
+	}
+
+	status := tag.Status.DeepCopy()
+	status.ObservedGeneration = tag.Generation
+	status.SetCondition(reconciledCondition)
+	status.SetCondition(inUseCondition)
+	status.State = reconciledCondition.Reason
+	if err == nil {
+		status.IstioRevision = rev.Name
+		status.IstiodNamespace = rev.Spec.Namespace
+	}
+
+	if reflect.DeepEqual(tag.Status, *status) {
+		return err
+	}
+
+	statusErr := r.Client.Status().Patch(ctx, tag, kube.NewStatusPatch(*status))
+	if statusErr != nil {
+		log.Error(statusErr, "failed to patch status")
+
+		// ensure that we retry the reconcile by returning the status error
+		// (but without overriding the original error)
+		if err == nil {
+			return statusErr
+		}
+	}
+	return err
+}
+
+func determineReconciledCondition(err error) v1alpha1.IstioRevisionTagCondition {
+	c := v1alpha1.IstioRevisionTagCondition{Type: v1alpha1.IstioRevisionTagConditionTypeReconciled}
+
+	if err == nil {
+		c.Status = metav1.ConditionTrue
+		c.Reason = v1alpha1.IstioRevisionTagConditionReasonHealthy
+	} else if tagErr, ok := err.(*tagError); ok {
+		c.Status = metav1.ConditionFalse
+		c.Reason = tagErr.reason
+		c.Message = tagErr.message
+	} else {
+		c.Status = metav1.ConditionFalse
+		c.Reason = v1alpha1.IstioRevisionTagConditionReasonReconcileError
+		c.Message = fmt.Sprintf("error reconciling resource: %v", err)
+	}
+	return c
+}
+
+// determineInUseCondition returns the InUse condition of the tag. The given revision is the one the tag points
+// to; it's nil if it couldn't be determined.
+func (r *IstioRevisionTagReconciler) determineInUseCondition(ctx context.Context, tag *v1alpha1.IstioRevisionTag, rev *v1alpha1.IstioRevision,
+) (v1alpha1.IstioRevisionTagCondition, error) {
+	isReferenced, err := r.isTagReferencedByWorkloads(ctx, tag, rev)
+	if err != nil {
+		return v1alpha1.IstioRevisionTagCondition{}, err
+	}
+
+	if isReferenced {
+		return v1alpha1.IstioRevisionTagCondition{
+			Type:    v1alpha1.IstioRevisionTagConditionTypeInUse,
+			Status:  metav1.ConditionTrue,
+			Reason:  v1alpha1.IstioRevisionTagConditionReasonReferencedByWorkloads,
+			Message: "Referenced by at least one pod or namespace",
+		}, nil
+	}
+	return v1alpha1.IstioRevisionTagCondition{
+		Type:    v1alpha1.IstioRevisionTagConditionTypeInUse,
+		Status:  metav1.ConditionFalse,
+		Reason:  v1alpha1.IstioRevisionTagConditionReasonNotReferenced,
+		Message: "Not referenced by any pod or namespace",
+	}, nil
+}
+
+func (r *IstioRevisionTagReconciler) isTagReferencedByWorkloads(ctx context.Context, tag *v1alpha1.IstioRevisionTag, rev *v1alpha1.IstioRevision,
+) (bool, error) {
+	log := logf.FromContext(ctx)
+
+	// the "default" tag is referenced by all namespaces that don't reference any revision explicitly if the
+	// revision it points to enables namespaces by default
+	if tag.Name == v1alpha1.DefaultRevision && rev != nil && istiorevision.EnablesNamespacesByDefault(rev.Spec.Values) {
+		nsList := kube.NewMetadataList(kube.NamespaceGVK)
+		if err := r.Client.List(ctx, nsList); err != nil {
+			return false, err
+		}
+		for _, ns := range nsList.Items {
+			if istiorevision.IsNamespaceInjectedByDefault(ns.Name, ns.Labels) {
+				log.V(2).Info("Tag is referenced by Namespace", "Namespace", ns.Name)
+				return true, nil
+			}
+		}
+	}
+
+	nsList := kube.NewMetadataList(kube.NamespaceGVK)
+	if err := r.Client.List(ctx, nsList, client.MatchingFields{istiorevision.NamespaceRevisionIndex: tag.Name}); err != nil {
+		return false, err
+	}
+	if len(nsList.Items) > 0 {
+		log.V(2).Info("Tag is referenced by Namespace", "Namespace", nsList.Items[0].Name)
+		return true, nil
+	}
+
+	// the pods in the namespaces that reference the tag were covered above, so only the pods that reference
+	// the tag via their own labels need to be checked
+	podList := kube.NewMetadataList(kube.PodGVK)
+	if err := r.Client.List(ctx, podList, client.MatchingFields{istiorevision.PodLabelRevisionIndex: tag.Name}); err != nil {
+		return false, err
+	}
+	for _, pod := range podList.Items {
+		ns := kube.NewMetadata(kube.NamespaceGVK)
+		if err := r.Client.Get(ctx, types.NamespacedName{Name: pod.Namespace}, ns); err != nil {
+			if errors.IsNotFound(err) {
+				continue
+			}
+			return false, err
+		}
+		if podReferencesTag(&pod, ns, tag) {
+			log.V(2).Info("Tag is referenced by Pod", "Pod", client.ObjectKeyFromObject(&pod))
+			return true, nil
+		}
+	}
+
+	log.V(2).Info("Tag is not referenced by any Pod or Namespace")
+	return false, nil
+}
+
+func podReferencesTag(pod, ns client.Object, tag *v1alpha1.IstioRevisionTag) bool {
+	// the istio.io/rev annotation of an injected pod contains the name of the revision rather than the
+	// tag, so only the labels are considered
+	return istiorevision.GetReferencedRevisionFromPod(pod.GetLabels(), nil, ns.GetLabels()) == tag.Name
+}
+
+func (r *IstioRevisionTagReconciler) mapIstioToReconcileRequest(ctx context.Context, obj client.Object) []reconcile.Request {
+	return r.mapTagsToReconcileRequests(ctx, func(tag v1alpha1.IstioRevisionTag) bool {
+		return tag.Spec.TargetRef.Kind == v1alpha1.IstioKind && tag.Spec.TargetRef.Name == obj.GetName()
+	})
+}
+
+func (r *IstioRevisionTagReconciler) mapRevisionToReconcileRequest(ctx context.Context, obj client.Object) []reconcile.Request {
+	return r.mapTagsToReconcileRequests(ctx, func(tag v1alpha1.IstioRevisionTag) bool {
+		return tag.Spec.TargetRef.Kind == v1alpha1.IstioRevisionKind && tag.Spec.TargetRef.Name == obj.GetName() ||
+			tag.Status.IstioRevision == obj.GetName() ||
+			tag.Name == obj.GetName()
+	})
+}
+
+func (r *IstioRevisionTagReconciler) mapTagsToReconcileRequests(ctx context.Context, matches func(v1alpha1.IstioRevisionTag) bool) []reconcile.Request {
+	log := logf.FromContext(ctx)
+	tagList := v1alpha1.IstioRevisionTagList{}
+	if err := r.Client.List(ctx, &tagList); err != nil {
+		log.Error(err, "Could not list IstioRevisionTags")
+		return nil
+	}
+
+	var requests []reconcile.Request
+	for _, tag := range tagList.Items {
+		if matches(tag) {
+			requests = append(requests, reconcile.Request{NamespacedName: types.NamespacedName{Name: tag.Name}})
+		}
+	}
+	return requests
+}
+
+func (r *IstioRevisionTagReconciler) mapNamespaceToReconcileRequest(ctx context.Context, ns client.Object) []reconcile.Request {
+	tagName := istiorevision.GetReferencedRevisionFromNamespace(ns.GetLabels())
+	if tagName == "" && istiorevision.IsNamespaceInjectedByDefault(ns.GetName(), ns.GetLabels()) && r.isDefaultTagEnabledForAllNamespaces(ctx) {
+		tagName = v1alpha1.DefaultRevision
+	}
+	if tagName != "" {
+		return []reconcile.Request{{NamespacedName: types.NamespacedName{Name: tagName}}}
+	}
+	return nil
+}
+
+func (r *IstioRevisionTagReconciler) mapPodToReconcileRequest(ctx context.Context, pod client.Object) []reconcile.Request {
+	// a pod that references the tag only through its namespace doesn't affect the tag's InUse condition, since
+	// the namespace references the tag too, so the pod's namespace doesn't need to be looked up
+	tagName := istiorevision.GetReferencedRevisionFromPod(pod.GetLabels(), nil, nil)
+	if tagName != "" {
+		return []reconcile.Request{{NamespacedName: types.NamespacedName{Name: tagName}}}
+	}
+	return nil
+}
+
+// isDefaultTagEnabledForAllNamespaces returns true if the "default" tag exists and the revision it points to enables
+// namespaces by default. Checking this prevents reconciling the tag whenever any namespace changes.
+func (r *IstioRevisionTagReconciler) isDefaultTagEnabledForAllNamespaces(ctx context.Context) bool {
+	tag := v1alpha1.IstioRevisionTag{}
+	if err := r.Client.Get(ctx, types.NamespacedName{Name: v1alpha1.DefaultRevision}, &tag); err != nil || tag.Status.IstioRevision == "" {
+		return false
+	}
+	rev := v1alpha1.IstioRevision{}
+	if err := r.Client.Get(ctx, types.NamespacedName{Name: tag.Status.IstioRevision}, &rev); err != nil {
+		return false
+	}
+	return istiorevision.EnablesNamespacesByDefault(rev.Spec.Values)
+}
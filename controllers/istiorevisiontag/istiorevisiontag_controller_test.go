@@ -0,0 +1,396 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package istiorevisiontag
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/kubectl/pkg/scheme"
+	"maistra.io/istio-operator/api/v1alpha1"
+	"maistra.io/istio-operator/controllers/istiorevision"
+	"maistra.io/istio-operator/pkg/test"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+var ctx = context.Background()
+
+// newFakeClientBuilder returns a builder of fake clients that support the lookups through the field indexes
+func newFakeClientBuilder() *fake.ClientBuilder {
+	builder := fake.NewClientBuilder().WithScheme(scheme.Scheme)
+	for _, index := range istiorevision.FieldIndexes {
+		builder = builder.WithIndex(index.Object, index.Field, index.Extract)
+	}
+	return builder
+}
+
+func newTag(name, kind, target string) *v1alpha1.IstioRevisionTag {
+	return &v1alpha1.IstioRevisionTag{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: name,
+		},
+		Spec: v1alpha1.IstioRevisionTagSpec{
+			TargetRef: v1alpha1.TargetReference{
+				Kind: kind,
+				Name: target,
+			},
+		},
+	}
+}
+
+func newRevision(name string) *v1alpha1.IstioRevision {
+	return &v1alpha1.IstioRevision{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: name,
+		},
+		Spec: v1alpha1.IstioRevisionSpec{
+			Namespace: "istio-system",
+			Version:   "my-version",
+		},
+	}
+}
+
+func TestGetTargetRevision(t *testing.T) {
+	test.SetupScheme()
+
+	istio := &v1alpha1.Istio{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "my-istio",
+		},
+		Status: v1alpha1.IstioStatus{
+			ActiveRevisionName: "my-istio-1-21-0",
+		},
+	}
+	istioWithoutActiveRevision := &v1alpha1.Istio{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "new-istio",
+		},
+	}
+
+	testCases := []struct {
+		name              string
+		tag               *v1alpha1.IstioRevisionTag
+		expectedRevision  string
+		expectedErrReason v1alpha1.IstioRevisionTagConditionReason
+	}{
+		{
+			name:             "resolves IstioRevision reference",
+			tag:              newTag("prod", v1alpha1.IstioRevisionKind, "my-istio-1-20-0"),
+			expectedRevision: "my-istio-1-20-0",
+		},
+		{
+			name:             "resolves Istio reference to active revision",
+			tag:              newTag("prod", v1alpha1.IstioKind, "my-istio"),
+			expectedRevision: "my-istio-1-21-0",
+		},
+		{
+			name:              "reports missing IstioRevision",
+			tag:               newTag("prod", v1alpha1.IstioRevisionKind, "missing"),
+			expectedErrReason: v1alpha1.IstioRevisionTagConditionReasonReferenceNotFound,
+		},
+		{
+			name:              "reports missing Istio",
+			tag:               newTag("prod", v1alpha1.IstioKind, "missing"),
+			expectedErrReason: v1alpha1.IstioRevisionTagConditionReasonReferenceNotFound,
+		},
+		{
+			name:              "reports Istio without active revision",
+			tag:               newTag("prod", v1alpha1.IstioKind, "new-istio"),
+			expectedErrReason: v1alpha1.IstioRevisionTagConditionReasonReferenceNotFound,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			cl := newFakeClientBuilder().
+				WithObjects(istio, istioWithoutActiveRevision, newRevision("my-istio-1-20-0"), newRevision("my-istio-1-21-0"), tc.tag).
+				Build()
+			r := NewIstioRevisionTagReconciler(cl, scheme.Scheme, nil)
+
+			rev, err := r.getTargetRevision(ctx, tc.tag)
+			if tc.expectedErrReason != "" {
+				tagErr, ok := err.(*tagError)
+				if !ok {
+					t.Fatalf("expected tagError, but got: %v", err)
+				}
+				if tagErr.reason != tc.expectedErrReason {
+					t.Errorf("expected reason %s, but got %s", tc.expectedErrReason, tagErr.reason)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if rev.Name != tc.expectedRevision {
+				t.Errorf("expected revision %s, but got %s", tc.expectedRevision, rev.Name)
+			}
+		})
+	}
+}
+
+func TestValidateTagName(t *testing.T) {
+	test.SetupScheme()
+
+	cl := newFakeClientBuilder().
+		WithObjects(newRevision("my-rev")).
+		Build()
+	r := NewIstioRevisionTagReconciler(cl, scheme.Scheme, nil)
+
+	if err := r.validateTagName(ctx, newTag("prod", v1alpha1.IstioRevisionKind, "my-rev")); err != nil {
+		t.Errorf("expected no error, but got: %v", err)
+	}
+
+	err := r.validateTagName(ctx, newTag("my-rev", v1alpha1.IstioRevisionKind, "my-rev"))
+	tagErr, ok := err.(*tagError)
+	if !ok {
+		t.Fatalf("expected tagError, but got: %v", err)
+	}
+	if tagErr.reason != v1alpha1.IstioRevisionTagConditionReasonNameAlreadyExists {
+		t.Errorf("expected reason %s, but got %s", v1alpha1.IstioRevisionTagConditionReasonNameAlreadyExists, tagErr.reason)
+	}
+}
+
+func TestDetermineReconciledCondition(t *testing.T) {
+	testCases := []struct {
+		name     string
+		err      error
+		expected v1alpha1.IstioRevisionTagCondition
+	}{
+		{
+			name: "no error",
+			expected: v1alpha1.IstioRevisionTagCondition{
+				Type:   v1alpha1.IstioRevisionTagConditionTypeReconciled,
+				Status: metav1.ConditionTrue,
+				Reason: v1alpha1.IstioRevisionTagConditionReasonHealthy,
+			},
+		},
+		{
+			name: "tag error",
+			err: &tagError{
+				reason:  v1alpha1.IstioRevisionTagConditionReasonReferenceNotFound,
+				message: "not found",
+			},
+			expected: v1alpha1.IstioRevisionTagCondition{
+				Type:    v1alpha1.IstioRevisionTagConditionTypeReconciled,
+				Status:  metav1.ConditionFalse,
+				Reason:  v1alpha1.IstioRevisionTagConditionReasonReferenceNotFound,
+				Message: "not found",
+			},
+		},
+		{
+			name: "other error",
+			err:  fmt.Errorf("some error"),
+			expected: v1alpha1.IstioRevisionTagCondition{
+				Type:    v1alpha1.IstioRevisionTagConditionTypeReconciled,
+				Status:  metav1.ConditionFalse,
+				Reason:  v1alpha1.IstioRevisionTagConditionReasonReconcileError,
+				Message: "error reconciling resource: some error",
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if diff := cmp.Diff(tc.expected, determineReconciledCondition(tc.err)); diff != "" {
+				t.Errorf("unexpected condition; diff (-expected, +actual):\n%v", diff)
+			}
+		})
+	}
+}
+
+func TestDetermineInUseCondition(t *testing.T) {
+	test.SetupScheme()
+
+	testCases := []struct {
+		name                      string
+		tagName                   string
+		enableNamespacesByDefault bool
+		nsName                    string
+		nsLabels                  map[string]string
+		podLabels                 map[string]string
+		podAnnotations            map[string]string
+		expectedStatus            metav1.ConditionStatus
+	}{
+		{
+			name:           "no labels",
+			expectedStatus: metav1.ConditionFalse,
+		},
+		{
+			name:                      "default tag with namespaces enabled by default",
+			tagName:                   "default",
+			enableNamespacesByDefault: true,
+			expectedStatus:            metav1.ConditionTrue,
+		},
+		{
+			name:           "default tag without namespaces enabled by default",
+			tagName:        "default",
+			expectedStatus: metav1.ConditionFalse,
+		},
+		{
+			name:                      "default tag with namespaces enabled by default and opted out namespace",
+			tagName:                   "default",
+			enableNamespacesByDefault: true,
+			nsLabels:                  map[string]string{"istio-injection": "disabled"},
+			expectedStatus:            metav1.ConditionFalse,
+		},
+		{
+			name:                      "default tag with namespaces enabled by default and system namespace",
+			tagName:                   "default",
+			enableNamespacesByDefault: true,
+			nsName:                    "kube-system",
+			expectedStatus:            metav1.ConditionFalse,
+		},
+		{
+			name:                      "prod tag with namespaces enabled by default",
+			enableNamespacesByDefault: true,
+			expectedStatus:            metav1.ConditionFalse,
+		},
+		{
+			name:           "namespace references tag",
+			nsLabels:       map[string]string{"istio.io/rev": "prod"},
+			expectedStatus: metav1.ConditionTrue,
+		},
+		{
+			name:           "namespace references other revision",
+			nsLabels:       map[string]string{"istio.io/rev": "my-rev"},
+			expectedStatus: metav1.ConditionFalse,
+		},
+		{
+			name:           "pod references tag",
+			podLabels:      map[string]string{"istio.io/rev": "prod"},
+			expectedStatus: metav1.ConditionTrue,
+		},
+		{
+			name:           "injected pod references tag",
+			podLabels:      map[string]string{"istio.io/rev": "prod"},
+			podAnnotations: map[string]string{"istio.io/rev": "my-rev"},
+			expectedStatus: metav1.ConditionTrue,
+		},
+		{
+			name:           "pod opted out of injection",
+			nsLabels:       map[string]string{"istio.io/rev": "my-rev"},
+			podLabels:      map[string]string{"istio.io/rev": "prod", "sidecar.istio.io/inject": "false"},
+			expectedStatus: metav1.ConditionFalse,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			nsName := "bookinfo"
+			if tc.nsName != "" {
+				nsName = tc.nsName
+			}
+			ns := &corev1.Namespace{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:   nsName,
+					Labels: tc.nsLabels,
+				},
+			}
+			pod := &corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:        "some-pod",
+					Namespace:   nsName,
+					Labels:      tc.podLabels,
+					Annotations: tc.podAnnotations,
+				},
+			}
+			tagName := "prod"
+			if tc.tagName != "" {
+				tagName = tc.tagName
+			}
+			tag := newTag(tagName, v1alpha1.IstioRevisionKind, "my-rev")
+			rev := newRevision("my-rev")
+			if tc.enableNamespacesByDefault {
+				rev.Spec.Values = &v1alpha1.Values{
+					SidecarInjectorWebhook: &v1alpha1.SidecarInjectorConfig{EnableNamespacesByDefault: true},
+				}
+			}
+
+			cl := newFakeClientBuilder().
+				WithObjects(tag, ns, pod).
+				Build()
+			r := NewIstioRevisionTagReconciler(cl, scheme.Scheme, nil)
+
+			result, err := r.determineInUseCondition(ctx, tag, rev)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if result.Type != v1alpha1.IstioRevisionTagConditionTypeInUse {
+				t.Errorf("unexpected condition type: %v", result.Type)
+			}
+			if result.Status != tc.expectedStatus {
+				t.Errorf("expected status %s, but got %s", tc.expectedStatus, result.Status)
+			}
+		})
+	}
+}
+
+func TestMapRevisionToReconcileRequest(t *testing.T) {
+	test.SetupScheme()
+
+	retargetedTag := newTag("retargeted", v1alpha1.IstioRevisionKind, "other-rev")
+	retargetedTag.Status.IstioRevision = "my-rev"
+
+	cl := newFakeClientBuilder().
+		WithObjects(
+			newTag("prod", v1alpha1.IstioRevisionKind, "my-rev"),
+			newTag("canary", v1alpha1.IstioRevisionKind, "other-rev"),
+			newTag("my-rev", v1alpha1.IstioRevisionKind, "other-rev"),
+			newTag("from-istio", v1alpha1.IstioKind, "my-rev"),
+			retargetedTag,
+		).
+		Build()
+	r := NewIstioRevisionTagReconciler(cl, scheme.Scheme, nil)
+
+	requests := r.mapRevisionToReconcileRequest(ctx, newRevision("my-rev"))
+
+	expected := []reconcile.Request{
+		{NamespacedName: types.NamespacedName{Name: "my-rev"}},
+		{NamespacedName: types.NamespacedName{Name: "prod"}},
+		{NamespacedName: types.NamespacedName{Name: "retargeted"}},
+	}
+	if diff := cmp.Diff(expected, requests); diff != "" {
+		t.Errorf("unexpected requests; diff (-expected, +actual):\n%v", diff)
+	}
+}
+
+func TestMapIstioToReconcileRequest(t *testing.T) {
+	test.SetupScheme()
+
+	cl := newFakeClientBuilder().
+		WithObjects(
+			newTag("prod", v1alpha1.IstioKind, "my-istio"),
+			newTag("canary", v1alpha1.IstioRevisionKind, "my-istio"),
+		).
+		Build()
+	r := NewIstioRevisionTagReconciler(cl, scheme.Scheme, nil)
+
+	istio := &v1alpha1.Istio{ObjectMeta: metav1.ObjectMeta{Name: "my-istio"}}
+	requests := r.mapIstioToReconcileRequest(ctx, client.Object(istio))
+
+	expected := []reconcile.Request{
+		{NamespacedName: types.NamespacedName{Name: "prod"}},
+	}
+	if diff := cmp.Diff(expected, requests); diff != "" {
+		t.Errorf("unexpected requests; diff (-expected, +actual):\n%v", diff)
+	}
+}
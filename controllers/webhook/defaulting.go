@@ -0,0 +1,66 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package webhook
+
+import (
+	"context"
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/runtime"
+	"maistra.io/istio-operator/api/v1alpha1"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	"istio.io/istio/pkg/ptr"
+)
+
+// IstioDefaulter writes the defaults that the Istio controller would otherwise
+// apply implicitly into the Istio object, so that the stored object reflects
+// the behavior the user actually gets.
+type IstioDefaulter struct {
+	// DefaultVersion is the version set in spec.version when the user doesn't specify one.
+	// If empty, spec.version is left untouched.
+	DefaultVersion string
+}
+
+var _ admission.CustomDefaulter = &IstioDefaulter{}
+
+func (d *IstioDefaulter) Default(_ context.Context, obj runtime.Object) error {
+	istio, ok := obj.(*v1alpha1.Istio)
+	if !ok {
+		return fmt.Errorf("expected an Istio object but got %T", obj)
+	}
+	applyIstioDefaults(istio, d.DefaultVersion)
+	return nil
+}
+
+func applyIstioDefaults(istio *v1alpha1.Istio, defaultVersion string) {
+	if istio.Spec.Version == "" {
+		istio.Spec.Version = defaultVersion
+	}
+
+	if istio.Spec.UpdateStrategy == nil {
+		istio.Spec.UpdateStrategy = &v1alpha1.IstioUpdateStrategy{}
+	}
+	strategy := istio.Spec.UpdateStrategy
+	if strategy.Type == "" {
+		strategy.Type = v1alpha1.UpdateStrategyTypeInPlace
+	}
+	if strategy.InactiveRevisionDeletionGracePeriodSeconds == nil {
+		strategy.InactiveRevisionDeletionGracePeriodSeconds = ptr.Of(int64(v1alpha1.DefaultRevisionDeletionGracePeriodSeconds))
+	}
+	if strategy.UpdateWorkloads && strategy.UpdateWorkloadsBatchSize == nil {
+		strategy.UpdateWorkloadsBatchSize = ptr.Of(int32(v1alpha1.DefaultUpdateWorkloadsBatchSize))
+	}
+}
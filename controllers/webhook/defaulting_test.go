@@ -0,0 +1,120 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package webhook
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"maistra.io/istio-operator/api/v1alpha1"
+
+	"istio.io/istio/pkg/ptr"
+)
+
+func TestDefaultIstio(t *testing.T) {
+	const defaultVersion = "v1.20.3"
+
+	tests := []struct {
+		name           string
+		defaultVersion string
+		spec           v1alpha1.IstioSpec
+		expectedSpec   v1alpha1.IstioSpec
+	}{
+		{
+			name:           "empty spec",
+			defaultVersion: defaultVersion,
+			spec:           v1alpha1.IstioSpec{Namespace: "istio-system"},
+			expectedSpec: v1alpha1.IstioSpec{
+				Version:   defaultVersion,
+				Namespace: "istio-system",
+				UpdateStrategy: &v1alpha1.IstioUpdateStrategy{
+					Type: v1alpha1.UpdateStrategyTypeInPlace,
+					InactiveRevisionDeletionGracePeriodSeconds: ptr.Of(int64(30)),
+				},
+			},
+		},
+		{
+			name: "no default version",
+			spec: v1alpha1.IstioSpec{Namespace: "istio-system"},
+			expectedSpec: v1alpha1.IstioSpec{
+				Namespace: "istio-system",
+				UpdateStrategy: &v1alpha1.IstioUpdateStrategy{
+					Type: v1alpha1.UpdateStrategyTypeInPlace,
+					InactiveRevisionDeletionGracePeriodSeconds: ptr.Of(int64(30)),
+				},
+			},
+		},
+		{
+			name:           "user-specified values are preserved",
+			defaultVersion: defaultVersion,
+			spec: v1alpha1.IstioSpec{
+				Version:   "v1.19.7",
+				Namespace: "istio-system",
+				UpdateStrategy: &v1alpha1.IstioUpdateStrategy{
+					Type: v1alpha1.UpdateStrategyTypeRevisionBased,
+					InactiveRevisionDeletionGracePeriodSeconds: ptr.Of(int64(60)),
+				},
+			},
+			expectedSpec: v1alpha1.IstioSpec{
+				Version:   "v1.19.7",
+				Namespace: "istio-system",
+				UpdateStrategy: &v1alpha1.IstioUpdateStrategy{
+					Type: v1alpha1.UpdateStrategyTypeRevisionBased,
+					InactiveRevisionDeletionGracePeriodSeconds: ptr.Of(int64(60)),
+				},
+			},
+		},
+		{
+			name:           "batch size defaulted when updateWorkloads is enabled",
+			defaultVersion: defaultVersion,
+			spec: v1alpha1.IstioSpec{
+				Version:   "v1.19.7",
+				Namespace: "istio-system",
+				UpdateStrategy: &v1alpha1.IstioUpdateStrategy{
+					Type:            v1alpha1.UpdateStrategyTypeRevisionBased,
+					UpdateWorkloads: true,
+				},
+			},
+			expectedSpec: v1alpha1.IstioSpec{
+				Version:   "v1.19.7",
+				Namespace: "istio-system",
+				UpdateStrategy: &v1alpha1.IstioUpdateStrategy{
+					Type: v1alpha1.UpdateStrategyTypeRevisionBased,
+					InactiveRevisionDeletionGracePeriodSeconds: ptr.Of(int64(30)),
+					UpdateWorkloads:          true,
+					UpdateWorkloadsBatchSize: ptr.Of(int32(1)),
+				},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := &IstioDefaulter{DefaultVersion: tt.defaultVersion}
+			istio := &v1alpha1.Istio{
+				ObjectMeta: metav1.ObjectMeta{Name: "default"},
+				Spec:       tt.spec,
+			}
+			if err := d.Default(context.TODO(), istio); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if diff := cmp.Diff(tt.expectedSpec, istio.Spec); diff != "" {
+				t.Errorf("unexpected spec; diff (-expected, +actual):\n%v", diff)
+			}
+		})
+	}
+}
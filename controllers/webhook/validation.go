@@ -0,0 +1,229 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package webhook
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path"
+
+	"k8s.io/apimachinery/pkg/runtime"
+	"maistra.io/istio-operator/api/v1alpha1"
+	"maistra.io/istio-operator/controllers/istiorevision"
+	"maistra.io/istio-operator/pkg/helm"
+	"maistra.io/istio-operator/pkg/istiovalues"
+	"maistra.io/istio-operator/pkg/istioversion"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+// IstioValidator rejects Istio objects that reference a version that isn't available in the operator's
+// resource directory, or a profile that is neither built in nor defined in a ConfigMap in the operator's
+// namespace. User-defined profiles are only looked up if Client is set.
+type IstioValidator struct {
+	ResourceDirectory string
+	Client            client.Reader
+	Namespace         string
+}
+
+// IstioRevisionValidator rejects IstioRevision objects that reference an unknown
+// version or whose values are inconsistent with the object.
+type IstioRevisionValidator struct {
+	ResourceDirectory string
+}
+
+var (
+	_ admission.CustomValidator = &IstioValidator{}
+	_ admission.CustomValidator = &IstioRevisionValidator{}
+)
+
+// SetupWithManager registers the defaulting webhook for Istio and the validating webhooks for Istio and
+// IstioRevision with the manager's webhook server.
+func SetupWithManager(mgr ctrl.Manager, resourceDir string, defaultVersion string, operatorNamespace string) error {
+	err := ctrl.NewWebhookManagedBy(mgr).
+		For(&v1alpha1.Istio{}).
+		WithDefaulter(&IstioDefaulter{DefaultVersion: defaultVersion}).
+		WithValidator(&IstioValidator{ResourceDirectory: resourceDir, Client: mgr.GetClient(), Namespace: operatorNamespace}).
+		Complete()
+	if err != nil {
+		return err
+	}
+	return ctrl.NewWebhookManagedBy(mgr).
+		For(&v1alpha1.IstioRevision{}).
+		WithValidator(&IstioRevisionValidator{ResourceDirectory: resourceDir}).
+		Complete()
+}
+
+func (v *IstioValidator) ValidateCreate(ctx context.Context, obj runtime.Object) (admission.Warnings, error) {
+	istio, ok := obj.(*v1alpha1.Istio)
+	if !ok {
+		return nil, fmt.Errorf("expected an Istio object but got %T", obj)
+	}
+	return nil, v.validate(ctx, istio)
+}
+
+func (v *IstioValidator) ValidateUpdate(ctx context.Context, oldObj, newObj runtime.Object) (admission.Warnings, error) {
+	oldIstio, ok := oldObj.(*v1alpha1.Istio)
+	if !ok {
+		return nil, fmt.Errorf("expected an Istio object but got %T", oldObj)
+	}
+	istio, ok := newObj.(*v1alpha1.Istio)
+	if !ok {
+		return nil, fmt.Errorf("expected an Istio object but got %T", newObj)
+	}
+	if istio.Spec.Namespace != oldIstio.Spec.Namespace {
+		return nil, fmt.Errorf("spec.namespace is immutable")
+	}
+	if !specChanged(oldIstio, istio) {
+		return nil, nil
+	}
+	return nil, v.validate(ctx, istio)
+}
+
+func (v *IstioValidator) ValidateDelete(_ context.Context, _ runtime.Object) (admission.Warnings, error) {
+	return nil, nil
+}
+
+func (v *IstioValidator) validate(ctx context.Context, istio *v1alpha1.Istio) error {
+	if istio.Spec.Namespace == "" {
+		return fmt.Errorf("spec.namespace not set")
+	}
+	if istio.Spec.Version == "" {
+		return fmt.Errorf("spec.version not set")
+	}
+	// spec.version may also be an alias such as "v1.20" or "stable"
+	version, err := istioversion.Resolve(v.ResourceDirectory, istio.Spec.Version)
+	if err != nil {
+		return err
+	}
+	if err := validateDriftPolicy(istio.Spec.DriftPolicy); err != nil {
+		return err
+	}
+	if istio.Spec.Profile != "" {
+		if err := v.validateProfile(ctx, version, istio.Spec.Profile); err != nil {
+			return err
+		}
+	}
+	for _, profile := range istio.Spec.Profiles {
+		if err := v.validateProfile(ctx, version, string(profile)); err != nil {
+			return fmt.Errorf("invalid spec.profiles: %w", err)
+		}
+	}
+	return nil
+}
+
+func (v *IstioRevisionValidator) ValidateCreate(_ context.Context, obj runtime.Object) (admission.Warnings, error) {
+	rev, ok := obj.(*v1alpha1.IstioRevision)
+	if !ok {
+		return nil, fmt.Errorf("expected an IstioRevision object but got %T", obj)
+	}
+	return nil, v.validate(rev)
+}
+
+func (v *IstioRevisionValidator) ValidateUpdate(_ context.Context, oldObj, newObj runtime.Object) (admission.Warnings, error) {
+	oldRev, ok := oldObj.(*v1alpha1.IstioRevision)
+	if !ok {
+		return nil, fmt.Errorf("expected an IstioRevision object but got %T", oldObj)
+	}
+	rev, ok := newObj.(*v1alpha1.IstioRevision)
+	if !ok {
+		return nil, fmt.Errorf("expected an IstioRevision object but got %T", newObj)
+	}
+	if rev.Spec.Namespace != oldRev.Spec.Namespace {
+		return nil, fmt.Errorf("spec.namespace is immutable")
+	}
+	if !specChanged(oldRev, rev) {
+		return nil, nil
+	}
+	return nil, v.validate(rev)
+}
+
+func (v *IstioRevisionValidator) ValidateDelete(_ context.Context, _ runtime.Object) (admission.Warnings, error) {
+	return nil, nil
+}
+
+func (v *IstioRevisionValidator) validate(rev *v1alpha1.IstioRevision) error {
+	if err := istiorevision.ValidateIstioRevision(*rev); err != nil {
+		return err
+	}
+	if err := validateDriftPolicy(rev.Spec.DriftPolicy); err != nil {
+		return err
+	}
+	return validateVersion(v.ResourceDirectory, rev.Spec.Version)
+}
+
+// specChanged returns true if the spec of the object was modified. Updates that only touch the
+// metadata (e.g. removing finalizers) must be allowed even when the existing spec is no longer
+// valid, for example because the operator no longer supports the object's version.
+func specChanged(oldObj, newObj client.Object) bool {
+	return oldObj.GetGeneration() != newObj.GetGeneration()
+}
+
+// validateVersion checks that the resource directory contains charts for the given version.
+func validateVersion(resourceDir, version string) error {
+	if version == "" {
+		return fmt.Errorf("spec.version not set")
+	}
+	dir := path.Join(resourceDir, version)
+	// prevent path traversal attacks
+	if path.Dir(dir) != path.Clean(resourceDir) {
+		return fmt.Errorf("invalid version %s", version)
+	}
+	if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+		return fmt.Errorf("unsupported version %s", version)
+	}
+	return nil
+}
+
+// validateProfile checks that the given profile exists for the given version, either as a built-in profile
+// or as a user-defined profile.
+func (v *IstioValidator) validateProfile(ctx context.Context, version, profile string) error {
+	profilesDir := path.Join(v.ResourceDirectory, version, "profiles")
+	file := path.Join(profilesDir, profile+".yaml")
+	// prevent path traversal attacks
+	if path.Dir(file) != profilesDir {
+		return fmt.Errorf("invalid profile name %s", profile)
+	}
+	if istiovalues.ProfileExists(profilesDir, profile) {
+		return nil
+	}
+	if v.Client != nil {
+		configMaps, err := istiovalues.ListProfileConfigMaps(ctx, v.Client, v.Namespace, version)
+		if err != nil {
+			return err
+		}
+		if cm, err := istiovalues.FindProfileConfigMap(configMaps, profile); err != nil || cm != nil {
+			return err
+		}
+	}
+	return fmt.Errorf("unknown profile %s for version %s", profile, version)
+}
+
+// validateDriftPolicy checks that the paths of the ignored fields can be parsed.
+func validateDriftPolicy(policy *v1alpha1.DriftPolicy) error {
+	if policy == nil {
+		return nil
+	}
+	for _, rule := range policy.IgnoredFields {
+		for _, p := range rule.Paths {
+			if _, err := helm.ParseFieldPath(p); err != nil {
+				return fmt.Errorf("invalid spec.driftPolicy.ignoredFields: %v", err)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,367 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package webhook
+
+import (
+	"context"
+	"os"
+	"path"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"maistra.io/istio-operator/api/v1alpha1"
+	"maistra.io/istio-operator/pkg/istiovalues"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+const version = "my-version"
+
+func newResourceDir(t *testing.T) string {
+	resourceDir := t.TempDir()
+	profilesDir := path.Join(resourceDir, version, "profiles")
+	Must(t, os.MkdirAll(profilesDir, 0o755))
+	Must(t, os.WriteFile(path.Join(profilesDir, "default.yaml"), []byte{}, 0o644))
+	Must(t, os.MkdirAll(path.Join(resourceDir, "v1.20.3", "profiles"), 0o755))
+	Must(t, os.WriteFile(path.Join(resourceDir, "v1.20.3", "profiles", "demo.yaml"), []byte{}, 0o644))
+	Must(t, os.WriteFile(path.Join(resourceDir, "secret.yaml"), []byte{}, 0o644))
+	return resourceDir
+}
+
+func TestValidateIstio(t *testing.T) {
+	resourceDir := newResourceDir(t)
+
+	tests := []struct {
+		name      string
+		spec      v1alpha1.IstioSpec
+		expectErr bool
+	}{
+		{
+			name: "valid",
+			spec: v1alpha1.IstioSpec{Version: version, Namespace: "istio-system"},
+		},
+		{
+			name: "valid profile",
+			spec: v1alpha1.IstioSpec{Version: version, Namespace: "istio-system", Profile: "default"},
+		},
+		{
+			name: "version alias",
+			spec: v1alpha1.IstioSpec{Version: "v1.20", Namespace: "istio-system", Profile: "demo"},
+		},
+		{
+			name:      "unknown version alias",
+			spec:      v1alpha1.IstioSpec{Version: "v1.21", Namespace: "istio-system"},
+			expectErr: true,
+		},
+		{
+			name:      "profile not available in resolved version",
+			spec:      v1alpha1.IstioSpec{Version: "stable", Namespace: "istio-system", Profile: "default"},
+			expectErr: true,
+		},
+		{
+			name:      "no version",
+			spec:      v1alpha1.IstioSpec{Namespace: "istio-system"},
+			expectErr: true,
+		},
+		{
+			name:      "no namespace",
+			spec:      v1alpha1.IstioSpec{Version: version},
+			expectErr: true,
+		},
+		{
+			name:      "unknown version",
+			spec:      v1alpha1.IstioSpec{Version: "v0.0.1", Namespace: "istio-system"},
+			expectErr: true,
+		},
+		{
+			name:      "version path traversal",
+			spec:      v1alpha1.IstioSpec{Version: "../" + path.Base(resourceDir), Namespace: "istio-system"},
+			expectErr: true,
+		},
+		{
+			name:      "unknown profile",
+			spec:      v1alpha1.IstioSpec{Version: version, Namespace: "istio-system", Profile: "nonexistent"},
+			expectErr: true,
+		},
+		{
+			name:      "profile path traversal",
+			spec:      v1alpha1.IstioSpec{Version: version, Namespace: "istio-system", Profile: "../../secret"},
+			expectErr: true,
+		},
+		{
+			name: "valid profiles",
+			spec: v1alpha1.IstioSpec{Version: version, Namespace: "istio-system", Profile: "default", Profiles: []v1alpha1.ProfileName{"default"}},
+		},
+		{
+			name:      "unknown profile in profiles",
+			spec:      v1alpha1.IstioSpec{Version: version, Namespace: "istio-system", Profiles: []v1alpha1.ProfileName{"default", "nonexistent"}},
+			expectErr: true,
+		},
+		{
+			name:      "empty profile in profiles",
+			spec:      v1alpha1.IstioSpec{Version: version, Namespace: "istio-system", Profiles: []v1alpha1.ProfileName{""}},
+			expectErr: true,
+		},
+		{
+			name: "valid drift policy",
+			spec: v1alpha1.IstioSpec{
+				Version:   version,
+				Namespace: "istio-system",
+				DriftPolicy: &v1alpha1.DriftPolicy{
+					Action:        v1alpha1.DriftActionReport,
+					IgnoredFields: []v1alpha1.DriftIgnoreRule{{Kind: "Deployment", Paths: []string{"spec.template.spec.containers[*].image"}}},
+				},
+			},
+		},
+		{
+			name: "invalid ignored field path",
+			spec: v1alpha1.IstioSpec{
+				Version:   version,
+				Namespace: "istio-system",
+				DriftPolicy: &v1alpha1.DriftPolicy{
+					IgnoredFields: []v1alpha1.DriftIgnoreRule{{Paths: []string{"spec..replicas"}}},
+				},
+			},
+			expectErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := &IstioValidator{ResourceDirectory: resourceDir}
+			istio := &v1alpha1.Istio{
+				ObjectMeta: metav1.ObjectMeta{Name: "default"},
+				Spec:       tt.spec,
+			}
+			_, err := v.ValidateCreate(context.TODO(), istio)
+			if tt.expectErr && err == nil {
+				t.Errorf("expected error, but got none")
+			} else if !tt.expectErr && err != nil {
+				t.Errorf("expected no error, but got: %v", err)
+			}
+		})
+	}
+}
+
+func TestValidateIstioUpdate(t *testing.T) {
+	resourceDir := newResourceDir(t)
+	v := &IstioValidator{ResourceDirectory: resourceDir}
+
+	oldIstio := &v1alpha1.Istio{
+		ObjectMeta: metav1.ObjectMeta{Name: "default", Generation: 1},
+		Spec:       v1alpha1.IstioSpec{Version: version, Namespace: "istio-system"},
+	}
+
+	istio := oldIstio.DeepCopy()
+	istio.Generation++
+	istio.Spec.Profile = "default"
+	if _, err := v.ValidateUpdate(context.TODO(), oldIstio, istio); err != nil {
+		t.Errorf("expected no error, but got: %v", err)
+	}
+
+	istio = oldIstio.DeepCopy()
+	istio.Generation++
+	istio.Spec.Profile = "nonexistent"
+	if _, err := v.ValidateUpdate(context.TODO(), oldIstio, istio); err == nil {
+		t.Errorf("expected error when setting unknown profile, but got none")
+	}
+
+	istio = oldIstio.DeepCopy()
+	istio.Generation++
+	istio.Spec.Namespace = "other-namespace"
+	if _, err := v.ValidateUpdate(context.TODO(), oldIstio, istio); err == nil {
+		t.Errorf("expected error when changing spec.namespace, but got none")
+	}
+
+	// metadata-only updates must be allowed even if the version is no longer supported
+	unsupported := oldIstio.DeepCopy()
+	unsupported.Spec.Version = "v0.0.1"
+	istio = unsupported.DeepCopy()
+	istio.Finalizers = nil
+	if _, err := v.ValidateUpdate(context.TODO(), unsupported, istio); err != nil {
+		t.Errorf("expected no error for metadata-only update, but got: %v", err)
+	}
+}
+
+func TestValidateIstioWithProfileConfigMaps(t *testing.T) {
+	resourceDir := newResourceDir(t)
+	newConfigMap := func(name string, labels map[string]string) *corev1.ConfigMap {
+		return &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "operator", Labels: labels}}
+	}
+	cl := fake.NewClientBuilder().
+		WithObjects(
+			newConfigMap("hardened", map[string]string{istiovalues.ProfileLabel: "hardened"}),
+			newConfigMap("high-scale", map[string]string{istiovalues.ProfileLabel: "high-scale", istiovalues.ProfileVersionLabel: "other-version"}),
+		).
+		Build()
+	v := &IstioValidator{ResourceDirectory: resourceDir, Client: cl, Namespace: "operator"}
+
+	tests := []struct {
+		profile   string
+		expectErr bool
+	}{
+		{profile: "default"},
+		{profile: "hardened"},
+		{profile: "high-scale", expectErr: true},
+		{profile: "nonexistent", expectErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.profile, func(t *testing.T) {
+			istio := &v1alpha1.Istio{
+				ObjectMeta: metav1.ObjectMeta{Name: "default"},
+				Spec:       v1alpha1.IstioSpec{Version: version, Namespace: "istio-system", Profile: tt.profile},
+			}
+			_, err := v.ValidateCreate(context.TODO(), istio)
+			if tt.expectErr && err == nil {
+				t.Errorf("expected error, but got none")
+			} else if !tt.expectErr && err != nil {
+				t.Errorf("expected no error, but got: %v", err)
+			}
+		})
+	}
+}
+
+func TestValidateIstioRevision(t *testing.T) {
+	resourceDir := newResourceDir(t)
+
+	tests := []struct {
+		name      string
+		revName   string
+		spec      v1alpha1.IstioRevisionSpec
+		expectErr bool
+	}{
+		{
+			name:    "valid default revision",
+			revName: v1alpha1.DefaultRevision,
+			spec: v1alpha1.IstioRevisionSpec{
+				Version:   version,
+				Namespace: "istio-system",
+				Values: &v1alpha1.Values{
+					Global: &v1alpha1.GlobalConfig{IstioNamespace: "istio-system"},
+				},
+			},
+		},
+		{
+			name:    "valid named revision",
+			revName: "my-rev",
+			spec: v1alpha1.IstioRevisionSpec{
+				Version:   version,
+				Namespace: "istio-system",
+				Values: &v1alpha1.Values{
+					Revision: "my-rev",
+					Global:   &v1alpha1.GlobalConfig{IstioNamespace: "istio-system"},
+				},
+			},
+		},
+		{
+			name:    "unknown version",
+			revName: v1alpha1.DefaultRevision,
+			spec: v1alpha1.IstioRevisionSpec{
+				Version:   "v0.0.1",
+				Namespace: "istio-system",
+				Values: &v1alpha1.Values{
+					Global: &v1alpha1.GlobalConfig{IstioNamespace: "istio-system"},
+				},
+			},
+			expectErr: true,
+		},
+		{
+			name:    "revision mismatch",
+			revName: "my-rev",
+			spec: v1alpha1.IstioRevisionSpec{
+				Version:   version,
+				Namespace: "istio-system",
+				Values: &v1alpha1.Values{
+					Revision: "other-rev",
+					Global:   &v1alpha1.GlobalConfig{IstioNamespace: "istio-system"},
+				},
+			},
+			expectErr: true,
+		},
+		{
+			name:    "invalid ignored field path",
+			revName: v1alpha1.DefaultRevision,
+			spec: v1alpha1.IstioRevisionSpec{
+				Version:   version,
+				Namespace: "istio-system",
+				Values: &v1alpha1.Values{
+					Global: &v1alpha1.GlobalConfig{IstioNamespace: "istio-system"},
+				},
+				DriftPolicy: &v1alpha1.DriftPolicy{
+					IgnoredFields: []v1alpha1.DriftIgnoreRule{{Paths: []string{"webhooks[*"}}},
+				},
+			},
+			expectErr: true,
+		},
+		{
+			name:    "istioNamespace mismatch",
+			revName: v1alpha1.DefaultRevision,
+			spec: v1alpha1.IstioRevisionSpec{
+				Version:   version,
+				Namespace: "istio-system",
+				Values: &v1alpha1.Values{
+					Global: &v1alpha1.GlobalConfig{IstioNamespace: "other-namespace"},
+				},
+			},
+			expectErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := &IstioRevisionValidator{ResourceDirectory: resourceDir}
+			rev := &v1alpha1.IstioRevision{
+				ObjectMeta: metav1.ObjectMeta{Name: tt.revName},
+				Spec:       tt.spec,
+			}
+			_, err := v.ValidateCreate(context.TODO(), rev)
+			if tt.expectErr && err == nil {
+				t.Errorf("expected error, but got none")
+			} else if !tt.expectErr && err != nil {
+				t.Errorf("expected no error, but got: %v", err)
+			}
+		})
+	}
+}
+
+func TestValidateIstioRevisionUpdate(t *testing.T) {
+	resourceDir := newResourceDir(t)
+	v := &IstioRevisionValidator{ResourceDirectory: resourceDir}
+
+	oldRev := &v1alpha1.IstioRevision{
+		ObjectMeta: metav1.ObjectMeta{Name: v1alpha1.DefaultRevision, Generation: 1},
+		Spec: v1alpha1.IstioRevisionSpec{
+			Version:   version,
+			Namespace: "istio-system",
+			Values: &v1alpha1.Values{
+				Global: &v1alpha1.GlobalConfig{IstioNamespace: "istio-system"},
+			},
+		},
+	}
+
+	rev := oldRev.DeepCopy()
+	rev.Generation++
+	rev.Spec.Namespace = "other-namespace"
+	rev.Spec.Values.Global.IstioNamespace = "other-namespace"
+	if _, err := v.ValidateUpdate(context.TODO(), oldRev, rev); err == nil {
+		t.Errorf("expected error when changing spec.namespace, but got none")
+	}
+}
+
+func Must(t *testing.T, err error) {
+	if err != nil {
+		t.Fatal(err)
+	}
+}
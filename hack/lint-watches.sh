@@ -21,12 +21,12 @@ check_watches() {
     read -r -a chartKinds <<< "$(grep -rEo "^kind: ([A-Za-z0-9]+)" --no-filename ./resources/*/charts | sed -e 's/^kind: //g' | sort | uniq | tr '\n' ' ')"
     echo "Kinds in charts: ${chartKinds[*]}"
 
-    # Find watched kinds in istiorevision_controller.go
-    read -r -a watchedKinds <<< "$(grep -Eo "(Owns|Watches)\\((.*)" ./controllers/istiorevision/istiorevision_controller.go | sed 's/.*&[^.]*\.\([^{}]*\).*/\1/' | sort | uniq | tr '\n' ' ')"
+    # Find watched kinds in all controllers
+    read -r -a watchedKinds <<< "$(grep -Eoh "(Owns|Watches)\\((.*)" ./controllers/*/*_controller.go | sed 's/.*&[^.]*\.\([^{}]*\).*/\1/' | sort | uniq | tr '\n' ' ')"
     echo "Watched kinds: ${watchedKinds[*]}"
 
-    # Find ignored kinds in istiorevision_controller.go
-    read -r -a ignoredKinds <<< "$(sed -n 's/.*\+lint-watches:ignore:\s*\(\w*\).*/\1/p' ./controllers/istiorevision/istiorevision_controller.go | sort | uniq | tr '\n' ' ')"
+    # Find ignored kinds in all controllers
+    read -r -a ignoredKinds <<< "$(sed -n 's/.*\+lint-watches:ignore:\s*\(\w*\).*/\1/p' ./controllers/*/*_controller.go | sort | uniq | tr '\n' ' ')"
     echo "Ignored kinds: ${ignoredKinds[*]}"
 
     # Check for missing lines
@@ -40,7 +40,7 @@ check_watches() {
 
     # Print missing lines, if any
     if [[ ${#missing_kinds[@]} -gt 0 ]]; then
-        printf "The following kinds aren't watched in istiorevision_controller.go:\n"
+        printf "The following kinds aren't watched by any controller:\n"
         for line in "${missing_kinds[@]}"; do
             printf "  - %s\n" "$line"
         done
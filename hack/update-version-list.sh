@@ -14,16 +14,32 @@
 # See the License for the specific language governing permissions and
 # limitations under the License.
 
-function updateVersionsInIstioTypeComment() {
-    selectValues=$(yq '.versions[].name | ", \"urn:alm:descriptor:com.tectonic.ui:select:" + . + "\""' versions.yaml | tr -d '\n')
-    versionsEnum=$(yq '.versions[].name' versions.yaml | tr '\n' ';' | sed 's/;$//g')
-    versions=$(yq '.versions[].name' versions.yaml | tr '\n' ',' | sed -e 's/,/, /g' -e 's/, $//g')
+function updateVersionsInTypeComment() {
+    file=$1
+    shift
+    versionList=("$@")
+
+    selectValues=$(printf ', "urn:alm:descriptor:com.tectonic.ui:select:%s"' "${versionList[@]}")
+    versionsEnum=$(printf '%s;' "${versionList[@]}" | sed 's/;$//g')
+    versions=$(printf '%s, ' "${versionList[@]}" | sed 's/, $//g')
 
     sed -i -E \
       -e "/\+sail:version/,/Version string/ s/(\/\/ \+operator-sdk:csv:customresourcedefinitions:type=spec,order=1,displayName=\"Istio Version\",xDescriptors=\{.*fieldGroup:General\")[^}]*(})/\1$selectValues}/g" \
       -e "/\+sail:version/,/Version string/ s/(\/\/ \+kubebuilder:validation:Enum=)(.*)/\1$versionsEnum/g" \
       -e "/\+sail:version/,/Version string/ s/(\/\/ \Must be one of:)(.*)/\1 $versions./g" \
-      api/v1alpha1/istio_types.go api/v1alpha1/istiorevision_types.go
+      "$file"
+}
+
+function updateVersionsInIstioTypeComment() {
+    mapfile -t versionNames < <(yq '.versions[].name' versions.yaml)
+
+    # the Istio resource also accepts minor version aliases (e.g. v1.20) and the "stable" alias
+    mapfile -t aliases < <(printf '%s\n' "${versionNames[@]}" | grep -E '^v[0-9]+\.[0-9]+\.[0-9]+$' | sed -E 's/^(v[0-9]+\.[0-9]+)\..*/\1/' | awk '!seen[$0]++')
+    aliases+=("stable")
+
+    updateVersionsInTypeComment api/v1alpha1/istio_types.go "${versionNames[@]}" "${aliases[@]}"
+    updateVersionsInTypeComment api/v1alpha1/istiorevision_types.go "${versionNames[@]}"
+    updateVersionsInTypeComment api/v1alpha1/istiocni_types.go "${versionNames[@]}"
 }
 
 function updateVersionsInCSVDescription() {
@@ -62,5 +78,11 @@ function updateVersionsInCSVDescription() {
     rm "$tmpFile"
 }
 
+function updateDefaultVersion() {
+    defaultVersion=$(yq '.versions[0].name' versions.yaml)
+    sed -i -E "s/^(defaultVersion:).*/\1 $defaultVersion/g" chart/values.yaml
+}
+
 updateVersionsInIstioTypeComment
 updateVersionsInCSVDescription
+updateDefaultVersion
@@ -15,6 +15,7 @@
 package common
 
 import (
+	"fmt"
 	"path/filepath"
 	"runtime"
 	"strings"
@@ -32,7 +33,19 @@ var (
 )
 
 type OperatorConfig struct {
-	ImageDigests map[string]IstioImageConfig `properties:"images"`
+	ImageDigests  map[string]IstioImageConfig `properties:"images"`
+	UpgradePolicy UpgradePolicy               `properties:"upgradePolicy"`
+}
+
+// UpgradePolicy defines which changes to Istio.spec.version the operator accepts.
+type UpgradePolicy struct {
+	// MaxMinorVersionSkips is the number of minor versions that an upgrade may skip
+	// (e.g. 1 allows upgrading from 1.19 directly to 1.21).
+	MaxMinorVersionSkips int `properties:"maxMinorVersionSkips,default=1"`
+
+	// AllowInPlaceMinorUpgrades allows the InPlace update strategy to move the control plane
+	// to a different minor version. By default, this requires the RevisionBased strategy.
+	AllowInPlaceMinorUpgrades bool `properties:"allowInPlaceMinorUpgrades,default=false"`
 }
 
 type IstioImageConfig struct {
@@ -56,6 +69,9 @@ func ReadConfig(configFile string) error {
 	if err != nil {
 		return err
 	}
+	if Config.UpgradePolicy.MaxMinorVersionSkips < 0 {
+		return fmt.Errorf("upgradePolicy.maxMinorVersionSkips must not be negative, but is %d", Config.UpgradePolicy.MaxMinorVersionSkips)
+	}
 	// replace "_" in versions with "." (e.g. v1_20_0 => v1.20.0)
 	newImageDigests := make(map[string]IstioImageConfig, len(Config.ImageDigests))
 	for k, v := range Config.ImageDigests {
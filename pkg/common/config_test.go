@@ -28,6 +28,10 @@ var testImages = IstioImageConfig{
 	ZTunnelImage: "ztunnel-test",
 }
 
+var defaultUpgradePolicy = UpgradePolicy{
+	MaxMinorVersionSkips: 1,
+}
+
 func TestReadConfig(t *testing.T) {
 	testCases := []struct {
 		name           string
@@ -47,6 +51,7 @@ images.v1_20_0.ztunnel=ztunnel-test
 				ImageDigests: map[string]IstioImageConfig{
 					"v1.20.0": testImages,
 				},
+				UpgradePolicy: defaultUpgradePolicy,
 			},
 			success: true,
 		},
@@ -72,6 +77,22 @@ images.latest.ztunnel=ztunnel-test
 					"v1.20.1": testImages,
 					"latest":  testImages,
 				},
+				UpgradePolicy: defaultUpgradePolicy,
+			},
+			success: true,
+		},
+		{
+			name: "upgrade-policy",
+			configFile: `
+upgradePolicy.maxMinorVersionSkips=0
+upgradePolicy.allowInPlaceMinorUpgrades=true
+`,
+			expectedConfig: OperatorConfig{
+				ImageDigests: map[string]IstioImageConfig{},
+				UpgradePolicy: UpgradePolicy{
+					MaxMinorVersionSkips:      0,
+					AllowInPlaceMinorUpgrades: true,
+				},
 			},
 			success: true,
 		},
@@ -81,6 +102,13 @@ images.latest.ztunnel=ztunnel-test
 images.v1_20_0.istiod=istiod-test
 images.v1_20_0.cni=cni-test
 images.v1_20_0.ztunnel=ztunnel-test
+`,
+			success: false,
+		},
+		{
+			name: "negative-max-minor-version-skips",
+			configFile: `
+upgradePolicy.maxMinorVersionSkips=-1
 `,
 			success: false,
 		},
@@ -102,7 +130,7 @@ images.v1_20_0.ztunnel=ztunnel-test
 		err = ReadConfig(file.Name())
 		if !tc.success {
 			if err != nil {
-				return
+				continue
 			}
 			t.Fatal("expected error but got:", err)
 		} else if err != nil {
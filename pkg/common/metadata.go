@@ -43,6 +43,20 @@ const (
 	// InternalKey is used to identify the resource as being internal to the mesh itself (i.e. should not be applied to members)
 	InternalKey = MetadataNamespace + "/internal"
 
+	// PausedKey is used in annotations to pause the reconciliation of an Istio or IstioRevision resource.
+	// While set to "true", the operator doesn't install, upgrade, or prune any of the resource's components.
+	PausedKey = MetadataNamespace + "/paused"
+
+	// DryRunKey is used in annotations to enable the dry-run mode of an Istio or IstioRevision resource.
+	// While set to "true", the operator only renders the resource's components and writes the manifests and
+	// their diff against the live objects to a ConfigMap instead of applying them.
+	DryRunKey = MetadataNamespace + "/dry-run"
+
+	// AdoptHelmReleaseKey is used in annotations of an Istio or IstioRevision resource to name an existing Helm
+	// release of the istiod chart, e.g. one installed with "helm install", that the operator takes over instead
+	// of installing a second control plane next to it.
+	AdoptHelmReleaseKey = MetadataNamespace + "/adopt-helm-release"
+
 	// FinalizerName is the finalizer name the controllers add to any resources that need to be finalized during deletion
 	FinalizerName = MetadataNamespace + "/istio-operator"
 
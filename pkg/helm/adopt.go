@@ -0,0 +1,136 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package helm
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+
+	"helm.sh/helm/v3/pkg/action"
+	"helm.sh/helm/v3/pkg/release"
+	"helm.sh/helm/v3/pkg/storage/driver"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/cli-runtime/pkg/resource"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// annotations with which Helm tracks the release that a resource belongs to
+const (
+	helmReleaseNameAnnotation      = "meta.helm.sh/release-name"
+	helmReleaseNamespaceAnnotation = "meta.helm.sh/release-namespace"
+)
+
+// AdoptRelease transfers the resources of an existing release of the given chart, e.g. one installed with
+// "helm install", to the release that the operator manages for the chart, named "<releaseNameBase>-<chartName>".
+// The Helm ownership annotations of the resources are updated to point to the operator's release, and the
+// records of the existing release are deleted without uninstalling it, so the resources keep running. The next
+// installation of the chart then takes over the resources and adds the owner reference to them.
+//
+// The value of the revision key in the release's values must match the given revision, so that the chart
+// renders the same resources. Returns false if there's no release to adopt, e.g. because it was already adopted.
+func AdoptRelease(ctx context.Context, restClientGetter genericclioptions.RESTClientGetter,
+	releaseName, chartName, revision, releaseNameBase, ns string,
+) (bool, error) {
+	actionConfig, err := newActionConfig(ctx, restClientGetter, ns)
+	if err != nil {
+		return false, err
+	}
+	return adoptRelease(ctx, actionConfig, releaseName, chartName, revision, fmt.Sprintf("%s-%s", releaseNameBase, chartName), ns)
+}
+
+func adoptRelease(ctx context.Context, cfg *action.Configuration, releaseName, chartName, revision, newReleaseName, ns string) (bool, error) {
+	log := logf.FromContext(ctx)
+	if releaseName == newReleaseName {
+		// the release is already managed by the operator
+		return false, nil
+	}
+
+	rel, err := action.NewGet(cfg).Run(releaseName)
+	if errors.Is(err, driver.ErrReleaseNotFound) {
+		return false, nil
+	} else if err != nil {
+		return false, fmt.Errorf("failed to get helm release %s: %v", releaseName, err)
+	}
+	if rel.Chart == nil || rel.Chart.Metadata == nil || rel.Chart.Metadata.Name != chartName {
+		return false, fmt.Errorf("helm release %s wasn't installed from the %s chart", releaseName, chartName)
+	}
+	if rel.Info == nil || rel.Info.Status != release.StatusDeployed {
+		return false, fmt.Errorf("helm release %s isn't in the %s state", releaseName, release.StatusDeployed)
+	}
+	releaseRevision, _, err := HelmValues(rel.Config).GetString("revision")
+	if err != nil {
+		return false, err
+	}
+	if releaseRevision != revision {
+		return false, fmt.Errorf("helm release %s is for revision %q, but the chart would be installed for revision %q", releaseName, releaseRevision, revision)
+	}
+
+	log.Info("Adopting helm release", "release", releaseName, "newRelease", newReleaseName)
+	current, err := cfg.KubeClient.Build(bytes.NewBufferString(rel.Manifest), false)
+	if err != nil {
+		return false, fmt.Errorf("failed to build the resources of helm release %s: %v", releaseName, err)
+	}
+	target, err := cfg.KubeClient.Build(bytes.NewBufferString(rel.Manifest), false)
+	if err != nil {
+		return false, fmt.Errorf("failed to build the resources of helm release %s: %v", releaseName, err)
+	}
+	err = target.Visit(func(info *resource.Info, err error) error {
+		if err != nil {
+			return err
+		}
+		accessor, err := meta.Accessor(info.Object)
+		if err != nil {
+			return err
+		}
+		annotations := accessor.GetAnnotations()
+		if annotations == nil {
+			annotations = map[string]string{}
+		}
+		annotations[helmReleaseNameAnnotation] = newReleaseName
+		annotations[helmReleaseNamespaceAnnotation] = ns
+		accessor.SetAnnotations(annotations)
+		return nil
+	})
+	if err != nil {
+		return false, err
+	}
+	if _, err := cfg.KubeClient.Update(current, target, false); err != nil {
+		return false, fmt.Errorf("failed to update the resources of helm release %s: %v", releaseName, err)
+	}
+
+	// the resources are only removed from the release once they point to the new release, so that adopting them
+	// can be retried if it fails
+	history, err := cfg.Releases.History(releaseName)
+	if err != nil {
+		return false, fmt.Errorf("failed to get the history of helm release %s: %v", releaseName, err)
+	}
+	// the deployed record is deleted last, so that the release can still be adopted on retry if deleting
+	// one of the records fails
+	for _, r := range history {
+		if r.Version == rel.Version {
+			continue
+		}
+		if _, err := cfg.Releases.Delete(r.Name, r.Version); err != nil {
+			return false, fmt.Errorf("failed to delete revision %d of helm release %s: %v", r.Version, releaseName, err)
+		}
+	}
+	if _, err := cfg.Releases.Delete(rel.Name, rel.Version); err != nil {
+		return false, fmt.Errorf("failed to delete revision %d of helm release %s: %v", rel.Version, releaseName, err)
+	}
+	return true, nil
+}
@@ -0,0 +1,264 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package helm
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"helm.sh/helm/v3/pkg/action"
+	"helm.sh/helm/v3/pkg/chart"
+	"helm.sh/helm/v3/pkg/kube"
+	kubefake "helm.sh/helm/v3/pkg/kube/fake"
+	"helm.sh/helm/v3/pkg/release"
+	"helm.sh/helm/v3/pkg/storage"
+	"helm.sh/helm/v3/pkg/storage/driver"
+	"k8s.io/cli-runtime/pkg/resource"
+)
+
+const adoptedManifest = `apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: istio
+  namespace: istio-system
+  annotations:
+    meta.helm.sh/release-name: istiod
+    meta.helm.sh/release-namespace: istio-system
+---
+apiVersion: v1
+kind: ServiceAccount
+metadata:
+  name: istiod
+  namespace: istio-system
+`
+
+// updateRecordingKubeClient builds the resources of a manifest and records the resources they're updated to
+type updateRecordingKubeClient struct {
+	kubefake.PrintingKubeClient
+	updated kube.ResourceList
+}
+
+func (c *updateRecordingKubeClient) Build(reader io.Reader, _ bool) (kube.ResourceList, error) {
+	manifest, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, err
+	}
+	objs, err := parseManifests(string(manifest))
+	if err != nil {
+		return nil, err
+	}
+	var resources kube.ResourceList
+	for _, obj := range objs {
+		resources = append(resources, &resource.Info{Name: obj.GetName(), Namespace: obj.GetNamespace(), Object: obj})
+	}
+	return resources, nil
+}
+
+func (c *updateRecordingKubeClient) Update(_, target kube.ResourceList, _ bool) (*kube.Result, error) {
+	c.updated = target
+	return &kube.Result{Updated: target}, nil
+}
+
+// failingDeleteDriver fails the given call to Delete. Like the Secret driver, which lists the records ordered
+// by name (so that v10 comes before v2), it doesn't return the records ordered by version.
+type failingDeleteDriver struct {
+	*driver.Memory
+	failCall int
+	calls    int
+}
+
+func (d *failingDeleteDriver) Query(labels map[string]string) ([]*release.Release, error) {
+	releases, err := d.Memory.Query(labels)
+	for i, j := 0, len(releases)-1; i < j; i, j = i+1, j-1 {
+		releases[i], releases[j] = releases[j], releases[i]
+	}
+	return releases, err
+}
+
+func (d *failingDeleteDriver) Delete(key string) (*release.Release, error) {
+	d.calls++
+	if d.calls == d.failCall {
+		return nil, errors.New("simulated failure")
+	}
+	return d.Memory.Delete(key)
+}
+
+func newAdoptedRelease(name, chartName string, version int, status release.Status, config map[string]any) *release.Release {
+	return &release.Release{
+		Name:      name,
+		Namespace: "istio-system",
+		Version:   version,
+		Chart:     &chart.Chart{Metadata: &chart.Metadata{Name: chartName}},
+		Config:    config,
+		Info:      &release.Info{Status: status},
+		Manifest:  adoptedManifest,
+	}
+}
+
+func TestAdoptRelease(t *testing.T) {
+	tests := []struct {
+		name              string
+		releases          []*release.Release
+		releaseName       string
+		revision          string
+		expectAdopted     bool
+		expectErr         bool
+		expectReleases    []string
+		expectAnnotations map[string]string
+	}{
+		{
+			name: "adopts release",
+			releases: []*release.Release{
+				newAdoptedRelease("istiod", "istiod", 1, release.StatusSuperseded, nil),
+				newAdoptedRelease("istiod", "istiod", 2, release.StatusDeployed, nil),
+			},
+			releaseName:   "istiod",
+			expectAdopted: true,
+			expectAnnotations: map[string]string{
+				"meta.helm.sh/release-name":      "default-istiod",
+				"meta.helm.sh/release-namespace": "istio-system",
+			},
+		},
+		{
+			name:          "adopts release of revision",
+			releases:      []*release.Release{newAdoptedRelease("istiod-canary", "istiod", 1, release.StatusDeployed, map[string]any{"revision": "canary"})},
+			releaseName:   "istiod-canary",
+			revision:      "canary",
+			expectAdopted: true,
+			expectAnnotations: map[string]string{
+				"meta.helm.sh/release-name":      "default-istiod",
+				"meta.helm.sh/release-namespace": "istio-system",
+			},
+		},
+		{
+			name:        "release not found",
+			releaseName: "istiod",
+		},
+		{
+			name:           "release already managed by the operator",
+			releases:       []*release.Release{newAdoptedRelease("default-istiod", "istiod", 1, release.StatusDeployed, nil)},
+			releaseName:    "default-istiod",
+			expectReleases: []string{"default-istiod"},
+		},
+		{
+			name:           "release of another chart",
+			releases:       []*release.Release{newAdoptedRelease("istiod", "base", 1, release.StatusDeployed, nil)},
+			releaseName:    "istiod",
+			expectErr:      true,
+			expectReleases: []string{"istiod"},
+		},
+		{
+			name:           "release not deployed",
+			releases:       []*release.Release{newAdoptedRelease("istiod", "istiod", 1, release.StatusFailed, nil)},
+			releaseName:    "istiod",
+			expectErr:      true,
+			expectReleases: []string{"istiod"},
+		},
+		{
+			name:           "release of another revision",
+			releases:       []*release.Release{newAdoptedRelease("istiod", "istiod", 1, release.StatusDeployed, map[string]any{"revision": "canary"})},
+			releaseName:    "istiod",
+			expectErr:      true,
+			expectReleases: []string{"istiod"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			kubeClient := &updateRecordingKubeClient{PrintingKubeClient: kubefake.PrintingKubeClient{Out: io.Discard}}
+			cfg := &action.Configuration{
+				Releases:   storage.Init(driver.NewMemory()),
+				KubeClient: kubeClient,
+				Log:        func(string, ...any) {},
+			}
+			for _, rel := range tt.releases {
+				Must(t, cfg.Releases.Create(rel))
+			}
+
+			adopted, err := adoptRelease(context.Background(), cfg, tt.releaseName, "istiod", tt.revision, "default-istiod", "istio-system")
+			if (err != nil) != tt.expectErr {
+				t.Fatalf("adoptRelease() error = %v, expectErr %v", err, tt.expectErr)
+			}
+			if adopted != tt.expectAdopted {
+				t.Errorf("expected adopted to be %v, but got %v", tt.expectAdopted, adopted)
+			}
+
+			releases, err := cfg.Releases.ListReleases()
+			Must(t, err)
+			var releaseNames []string
+			for _, rel := range releases {
+				releaseNames = append(releaseNames, rel.Name)
+			}
+			if diff := cmp.Diff(tt.expectReleases, releaseNames); diff != "" {
+				t.Errorf("unexpected releases; diff (-expected, +actual):\n%v", diff)
+			}
+
+			if tt.expectAnnotations == nil {
+				if kubeClient.updated != nil {
+					t.Errorf("expected no resources to be updated, but got %v", kubeClient.updated)
+				}
+				return
+			}
+			if len(kubeClient.updated) != 2 {
+				t.Fatalf("expected the 2 resources of the release to be updated, but got %d", len(kubeClient.updated))
+			}
+			for _, info := range kubeClient.updated {
+				annotations := info.Object.(interface{ GetAnnotations() map[string]string }).GetAnnotations()
+				if diff := cmp.Diff(tt.expectAnnotations, annotations); diff != "" {
+					t.Errorf("unexpected annotations of %s; diff (-expected, +actual):\n%v", info.Name, diff)
+				}
+			}
+		})
+	}
+}
+
+func TestAdoptReleaseRetriedAfterFailedDelete(t *testing.T) {
+	releases := &failingDeleteDriver{Memory: driver.NewMemory(), failCall: 2}
+	cfg := &action.Configuration{
+		Releases:   storage.Init(releases),
+		KubeClient: &updateRecordingKubeClient{PrintingKubeClient: kubefake.PrintingKubeClient{Out: io.Discard}},
+		Log:        func(string, ...any) {},
+	}
+	for _, rel := range []*release.Release{
+		newAdoptedRelease("istiod", "istiod", 1, release.StatusSuperseded, nil),
+		newAdoptedRelease("istiod", "istiod", 2, release.StatusSuperseded, nil),
+		newAdoptedRelease("istiod", "istiod", 3, release.StatusDeployed, nil),
+	} {
+		Must(t, cfg.Releases.Create(rel))
+	}
+
+	_, err := adoptRelease(context.Background(), cfg, "istiod", "istiod", "", "default-istiod", "istio-system")
+	if err == nil || !strings.Contains(err.Error(), "simulated failure") {
+		t.Fatalf("expected the simulated failure, but got %v", err)
+	}
+
+	// the deployed record is kept until the other records are deleted, so adopting the release can be retried
+	adopted, err := adoptRelease(context.Background(), cfg, "istiod", "istiod", "", "default-istiod", "istio-system")
+	if err != nil {
+		t.Fatalf("expected retry to succeed, but got %v", err)
+	}
+	if !adopted {
+		t.Error("expected release to be adopted on retry")
+	}
+	remaining, err := cfg.Releases.ListReleases()
+	Must(t, err)
+	if len(remaining) != 0 {
+		t.Errorf("expected all records of the release to be deleted, but got %d", len(remaining))
+	}
+}
@@ -16,16 +16,24 @@ package helm
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"os"
 	"path"
 	"path/filepath"
+	"slices"
+	"strings"
+	"time"
 
 	"helm.sh/helm/v3/pkg/action"
+	"helm.sh/helm/v3/pkg/chart"
 	chartLoader "helm.sh/helm/v3/pkg/chart/loader"
+	"helm.sh/helm/v3/pkg/chartutil"
 	"helm.sh/helm/v3/pkg/release"
+	"helm.sh/helm/v3/pkg/storage/driver"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"maistra.io/istio-operator/pkg/metrics"
 	logf "sigs.k8s.io/controller-runtime/pkg/log"
 )
 
@@ -46,9 +54,24 @@ func UninstallCharts(ctx context.Context, restClientGetter genericclioptions.RES
 	return nil
 }
 
+// UninstallRelease uninstalls the helm release with the specified name, if it exists
+func UninstallRelease(ctx context.Context, restClientGetter genericclioptions.RESTClientGetter, releaseName, ns string) error {
+	actionConfig, err := newActionConfig(ctx, restClientGetter, ns)
+	if err != nil {
+		return err
+	}
+	_, err = uninstallChart(actionConfig, ns, releaseName)
+	return err
+}
+
+// DefaultMaxHistory is the number of revisions kept for each release, unless more are needed to roll back
+const DefaultMaxHistory = 1
+
+// UpgradeOrInstallCharts installs or upgrades the releases of the given charts. When upgrading, at most
+// maxHistory revisions of each release are kept.
 func UpgradeOrInstallCharts(ctx context.Context, restClientGetter genericclioptions.RESTClientGetter,
 	charts []string, values HelmValues,
-	chartVersion, releaseNameBase, ns string, ownerReference metav1.OwnerReference,
+	chartVersion, releaseNameBase, ns string, ownerReference metav1.OwnerReference, maxHistory int,
 ) error {
 	actionConfig, err := newActionConfig(ctx, restClientGetter, ns)
 	if err != nil {
@@ -56,7 +79,11 @@ func UpgradeOrInstallCharts(ctx context.Context, restClientGetter genericcliopti
 	}
 	for _, chartName := range charts {
 		releaseName := fmt.Sprintf("%s-%s", releaseNameBase, chartName)
-		_, err = upgradeOrInstallChart(ctx, actionConfig, chartName, chartVersion, ns, releaseName, ownerReference, values)
+		chart, err := loadChart(chartVersion, chartName)
+		if err != nil {
+			return err
+		}
+		_, err = upgradeOrInstallChart(ctx, actionConfig, chart, ns, releaseName, ownerReference, values, maxHistory)
 		if err != nil {
 			return err
 		}
@@ -64,6 +91,112 @@ func UpgradeOrInstallCharts(ctx context.Context, restClientGetter genericcliopti
 	return nil
 }
 
+// GetReleaseRevisions returns the current revisions of the installed releases of the given charts, by chart name
+func GetReleaseRevisions(ctx context.Context, restClientGetter genericclioptions.RESTClientGetter,
+	charts []string, releaseNameBase, ns string,
+) (map[string]int, error) {
+	actionConfig, err := newActionConfig(ctx, restClientGetter, ns)
+	if err != nil {
+		return nil, err
+	}
+	return getReleaseRevisions(actionConfig, charts, releaseNameBase)
+}
+
+func getReleaseRevisions(actionConfig *action.Configuration, charts []string, releaseNameBase string) (map[string]int, error) {
+	revisions := map[string]int{}
+	for _, chartName := range charts {
+		releaseName := fmt.Sprintf("%s-%s", releaseNameBase, chartName)
+		rel, err := action.NewGet(actionConfig).Run(releaseName)
+		if errors.Is(err, driver.ErrReleaseNotFound) {
+			continue
+		} else if err != nil {
+			return nil, fmt.Errorf("failed to get helm release %s: %v", releaseName, err)
+		}
+		revisions[chartName] = rel.Version
+	}
+	return revisions, nil
+}
+
+// RollbackCharts rolls the releases of the given charts back to the given revisions. The releases of charts
+// without a revision are uninstalled, since they didn't exist at the time the revisions were recorded.
+func RollbackCharts(ctx context.Context, restClientGetter genericclioptions.RESTClientGetter,
+	charts []string, releaseNameBase, ns string, revisions map[string]int, maxHistory int,
+) error {
+	actionConfig, err := newActionConfig(ctx, restClientGetter, ns)
+	if err != nil {
+		return err
+	}
+	return rollbackCharts(ctx, actionConfig, charts, releaseNameBase, ns, revisions, maxHistory)
+}
+
+func rollbackCharts(ctx context.Context, actionConfig *action.Configuration,
+	charts []string, releaseNameBase, ns string, revisions map[string]int, maxHistory int,
+) error {
+	log := logf.FromContext(ctx)
+	for _, chartName := range charts {
+		releaseName := fmt.Sprintf("%s-%s", releaseNameBase, chartName)
+		revision, found := revisions[chartName]
+		if !found {
+			if _, err := uninstallChart(actionConfig, ns, releaseName); err != nil {
+				return err
+			}
+			continue
+		}
+
+		log.V(2).Info("Performing helm rollback", "release", releaseName, "revision", revision)
+		rollbackAction := action.NewRollback(actionConfig)
+		rollbackAction.Version = revision
+		rollbackAction.MaxHistory = maxHistory
+		if err := rollbackAction.Run(releaseName); err != nil {
+			return fmt.Errorf("failed to roll back helm release %s to revision %d: %v", releaseName, revision, err)
+		}
+	}
+	return nil
+}
+
+// UpgradeOrInstallChartTemplates installs or upgrades a release that contains only the specified templates
+// of the given chart. The chart's helper templates (those whose name starts with an underscore) are always
+// included. This allows a subset of a chart's resources to be managed in a separate release.
+func UpgradeOrInstallChartTemplates(ctx context.Context, restClientGetter genericclioptions.RESTClientGetter,
+	chartName string, templates []string, values HelmValues,
+	chartVersion, releaseName, ns string, ownerReference metav1.OwnerReference,
+) error {
+	actionConfig, err := newActionConfig(ctx, restClientGetter, ns)
+	if err != nil {
+		return err
+	}
+	ch, err := loadChart(chartVersion, chartName)
+	if err != nil {
+		return err
+	}
+
+	var filteredTemplates []*chart.File
+	for _, template := range ch.Templates {
+		name := path.Base(template.Name)
+		if strings.HasPrefix(name, "_") || slices.Contains(templates, name) {
+			filteredTemplates = append(filteredTemplates, template)
+		}
+	}
+	ch.Templates = filteredTemplates
+
+	_, err = upgradeOrInstallChart(ctx, actionConfig, ch, ns, releaseName, ownerReference, values, DefaultMaxHistory)
+	return err
+}
+
+func loadChart(chartVersion, chartName string) (*chart.Chart, error) {
+	return chartLoader.Load(path.Join(ResourceDirectory, chartVersion, "charts", chartName))
+}
+
+// GetChartVersion returns the version of the given chart that ships with the given Istio version. Only the
+// chart's metadata is read.
+func GetChartVersion(chartVersion, chartName string) (string, error) {
+	metadata, err := chartutil.LoadChartfile(path.Join(ResourceDirectory, chartVersion, "charts", chartName, chartutil.ChartfileName))
+	if err != nil {
+		return "", err
+	}
+	return metadata.Version, nil
+}
+
 // newActionConfig Create a new Helm action config from in-cluster service account
 func newActionConfig(ctx context.Context, restClientGetter genericclioptions.RESTClientGetter, namespace string) (*action.Configuration, error) {
 	actionConfig := new(action.Configuration)
@@ -82,8 +215,8 @@ func newActionConfig(ctx context.Context, restClientGetter genericclioptions.RES
 
 // upgradeOrInstallChart upgrades a chart in cluster or installs it new if it does not already exist
 func upgradeOrInstallChart(ctx context.Context, cfg *action.Configuration,
-	chartName, chartVersion, namespace, releaseName string,
-	ownerReference metav1.OwnerReference, values HelmValues,
+	chart *chart.Chart, namespace, releaseName string,
+	ownerReference metav1.OwnerReference, values HelmValues, maxHistory int,
 ) (*release.Release, error) {
 	log := logf.FromContext(ctx)
 
@@ -101,18 +234,16 @@ func upgradeOrInstallChart(ctx context.Context, cfg *action.Configuration,
 		}
 	}
 
-	chart, err := chartLoader.Load(path.Join(ResourceDirectory, chartVersion, "charts", chartName))
-	if err != nil {
-		return nil, err
-	}
 	var rel *release.Release
 	if toUpgrade {
 		log.V(2).Info("Performing helm upgrade", "chartName", chart.Name())
 		updateAction := action.NewUpgrade(cfg)
 		updateAction.PostRenderer = NewOwnerReferencePostRenderer(ownerReference, "")
-		updateAction.MaxHistory = 1
+		updateAction.MaxHistory = maxHistory
 		updateAction.SkipCRDs = true
+		start := time.Now()
 		rel, err = updateAction.RunWithContext(ctx, releaseName, chart, values)
+		metrics.ObserveHelmOperation(chart.Name(), chart.Metadata.Version, metrics.HelmOperationUpgrade, start, err)
 		if err != nil {
 			return nil, fmt.Errorf("failed to update helm chart %s: %v", chart.Name(), err)
 		}
@@ -124,7 +255,9 @@ func upgradeOrInstallChart(ctx context.Context, cfg *action.Configuration,
 		installAction.Namespace = namespace
 		installAction.ReleaseName = releaseName
 		installAction.SkipCRDs = true
+		start := time.Now()
 		rel, err = installAction.RunWithContext(ctx, chart, values)
+		metrics.ObserveHelmOperation(chart.Name(), chart.Metadata.Version, metrics.HelmOperationInstall, start, err)
 		if err != nil {
 			return nil, fmt.Errorf("failed to install helm chart %s: %v", chart.Name(), err)
 		}
@@ -141,18 +274,22 @@ func uninstallChart(cfg *action.Configuration, namespace, releaseName string) (*
 		return nil, err
 	}
 
-	found := false
+	var installed *release.Release
 	for _, release := range releases {
 		if release.Name == releaseName && release.Namespace == namespace {
-			found = true
+			installed = release
 		}
 	}
-	if !found {
+	if installed == nil {
 		return nil, nil
 	}
 
 	uninstallAction := action.NewUninstall(cfg)
+	start := time.Now()
 	response, err := uninstallAction.Run(releaseName)
+	if installed.Chart != nil && installed.Chart.Metadata != nil {
+		metrics.ObserveHelmOperation(installed.Chart.Metadata.Name, installed.Chart.Metadata.Version, metrics.HelmOperationUninstall, start, err)
+	}
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,123 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package helm
+
+import (
+	"context"
+	"io"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"helm.sh/helm/v3/pkg/action"
+	"helm.sh/helm/v3/pkg/chart"
+	"helm.sh/helm/v3/pkg/chartutil"
+	kubefake "helm.sh/helm/v3/pkg/kube/fake"
+	"helm.sh/helm/v3/pkg/release"
+	"helm.sh/helm/v3/pkg/storage"
+	"helm.sh/helm/v3/pkg/storage/driver"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestRollbackCharts(t *testing.T) {
+	ctx := context.Background()
+	const maxHistory = 3
+	testChart := &chart.Chart{
+		Metadata: &chart.Metadata{APIVersion: chart.APIVersionV2, Name: "istiod", Version: "1.0.0"},
+		Templates: []*chart.File{{
+			Name: "templates/configmap.yaml",
+			Data: []byte("apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: istio\ndata:\n  image: {{ .Values.image }}\n"),
+		}},
+	}
+
+	newConfig := func() *action.Configuration {
+		return &action.Configuration{
+			Releases:     storage.Init(driver.NewMemory()),
+			KubeClient:   &kubefake.PrintingKubeClient{Out: io.Discard},
+			Capabilities: chartutil.DefaultCapabilities,
+			Log:          func(string, ...any) {},
+		}
+	}
+	upgrade := func(t *testing.T, cfg *action.Configuration, image string) {
+		t.Helper()
+		_, err := upgradeOrInstallChart(ctx, cfg, testChart, "istio-system", "default-istiod",
+			metav1.OwnerReference{}, HelmValues{"image": image}, maxHistory)
+		Must(t, err)
+	}
+	deployedImage := func(t *testing.T, cfg *action.Configuration) string {
+		t.Helper()
+		rel, err := cfg.Releases.Deployed("default-istiod")
+		Must(t, err)
+		if rel.Info.Status != release.StatusDeployed {
+			t.Fatalf("expected release to be deployed, but got status %s", rel.Info.Status)
+		}
+		return rel.Config["image"].(string)
+	}
+
+	t.Run("rolls back to recorded revision", func(t *testing.T) {
+		cfg := newConfig()
+		upgrade(t, cfg, "good")
+		revisions, err := getReleaseRevisions(cfg, []string{"istiod"}, "default")
+		Must(t, err)
+		if diff := cmp.Diff(map[string]int{"istiod": 1}, revisions); diff != "" {
+			t.Fatalf("unexpected revisions; diff (-expected, +actual):\n%v", diff)
+		}
+
+		upgrade(t, cfg, "bad")
+		Must(t, rollbackCharts(ctx, cfg, []string{"istiod"}, "default", "istio-system", revisions, maxHistory))
+		if image := deployedImage(t, cfg); image != "good" {
+			t.Errorf("expected rolled back release to have image good, but got %s", image)
+		}
+	})
+
+	t.Run("recorded revision pruned from history", func(t *testing.T) {
+		cfg := newConfig()
+		upgrade(t, cfg, "good")
+		revisions, err := getReleaseRevisions(cfg, []string{"istiod"}, "default")
+		Must(t, err)
+
+		// e.g. upgrades that revert drift without changing the values, followed by a failed upgrade
+		upgrade(t, cfg, "good")
+		upgrade(t, cfg, "good")
+		upgrade(t, cfg, "bad")
+		if err := rollbackCharts(ctx, cfg, []string{"istiod"}, "default", "istio-system", revisions, maxHistory); err == nil {
+			t.Fatal("expected rollback to pruned revision to fail")
+		}
+	})
+
+	t.Run("rolls back to refreshed revision", func(t *testing.T) {
+		cfg := newConfig()
+		upgrade(t, cfg, "good")
+		upgrade(t, cfg, "good")
+		upgrade(t, cfg, "good")
+		// the revisions are recorded again after every verified upgrade
+		revisions, err := getReleaseRevisions(cfg, []string{"istiod"}, "default")
+		Must(t, err)
+
+		upgrade(t, cfg, "bad")
+		Must(t, rollbackCharts(ctx, cfg, []string{"istiod"}, "default", "istio-system", revisions, maxHistory))
+		if image := deployedImage(t, cfg); image != "good" {
+			t.Errorf("expected rolled back release to have image good, but got %s", image)
+		}
+	})
+
+	t.Run("uninstalls release without recorded revision", func(t *testing.T) {
+		cfg := newConfig()
+		upgrade(t, cfg, "bad")
+		Must(t, rollbackCharts(ctx, cfg, []string{"istiod"}, "default", "istio-system", map[string]int{}, maxHistory))
+		if _, err := cfg.Releases.Deployed("default-istiod"); err == nil {
+			t.Errorf("expected release to be uninstalled")
+		}
+	})
+}
@@ -0,0 +1,195 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package helm
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/pmezard/go-difflib/difflib"
+	"gopkg.in/yaml.v3"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// DiffManifests compares the objects in the given manifests with the live objects in the cluster and returns
+// a unified diff for each object that would be created or changed. Only the fields that are set in the manifests
+// are compared, since the live objects also contain defaulted fields and the status. Objects that are part of
+// the previous manifests (i.e. the currently installed release), but not of the new ones, would be deleted and
+// are also included. Objects without a namespace are looked up in the given namespace.
+func DiffManifests(ctx context.Context, cl client.Reader, previousManifests, manifests, namespace string) (string, error) {
+	objects, err := parseManifests(manifests)
+	if err != nil {
+		return "", err
+	}
+	previousObjects, err := parseManifests(previousManifests)
+	if err != nil {
+		return "", err
+	}
+
+	var diff strings.Builder
+	rendered := map[string]bool{}
+	for _, obj := range objects {
+		key := objectKey(obj, namespace)
+		rendered[key] = true
+
+		live, err := getLiveObject(ctx, cl, obj, namespace)
+		if err != nil {
+			return "", err
+		}
+		var liveYAML string
+		if live != nil {
+			if liveYAML, err = toYAML(pruneToShape(live.Object, obj.Object)); err != nil {
+				return "", err
+			}
+		}
+		renderedYAML, err := toYAML(obj.Object)
+		if err != nil {
+			return "", err
+		}
+		if err := writeDiff(&diff, key, liveYAML, renderedYAML); err != nil {
+			return "", err
+		}
+	}
+
+	for _, obj := range previousObjects {
+		key := objectKey(obj, namespace)
+		if rendered[key] {
+			continue
+		}
+		live, err := getLiveObject(ctx, cl, obj, namespace)
+		if err != nil {
+			return "", err
+		} else if live == nil {
+			continue
+		}
+		liveYAML, err := toYAML(pruneToShape(live.Object, obj.Object))
+		if err != nil {
+			return "", err
+		}
+		if err := writeDiff(&diff, key, liveYAML, ""); err != nil {
+			return "", err
+		}
+	}
+	return diff.String(), nil
+}
+
+func parseManifests(manifests string) ([]*unstructured.Unstructured, error) {
+	var objects []*unstructured.Unstructured
+	decoder := yaml.NewDecoder(strings.NewReader(manifests))
+	for {
+		manifest := map[string]any{}
+		if err := decoder.Decode(&manifest); err != nil {
+			if err == io.EOF {
+				break
+			}
+			return nil, fmt.Errorf("failed to parse manifests: %v", err)
+		}
+		if len(manifest) == 0 {
+			continue
+		}
+		objects = append(objects, &unstructured.Unstructured{Object: manifest})
+	}
+	return objects, nil
+}
+
+func getLiveObject(ctx context.Context, cl client.Reader, obj *unstructured.Unstructured, namespace string) (*unstructured.Unstructured, error) {
+	live := &unstructured.Unstructured{}
+	live.SetGroupVersionKind(obj.GroupVersionKind())
+	key := client.ObjectKey{Namespace: obj.GetNamespace(), Name: obj.GetName()}
+	if key.Namespace == "" {
+		key.Namespace = namespace
+	}
+	if err := cl.Get(ctx, key, live); err != nil {
+		if errors.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get %s: %v", objectKey(obj, namespace), err)
+	}
+	return live, nil
+}
+
+func objectKey(obj *unstructured.Unstructured, namespace string) string {
+	ns := obj.GetNamespace()
+	if ns == "" {
+		ns = namespace
+	}
+	return fmt.Sprintf("%s %s %s/%s", obj.GetAPIVersion(), obj.GetKind(), ns, obj.GetName())
+}
+
+// pruneToShape removes all the fields from the live object that aren't present in the desired object
+func pruneToShape(live, desired any) any {
+	switch desired := desired.(type) {
+	case map[string]any:
+		liveMap, ok := live.(map[string]any)
+		if !ok {
+			return live
+		}
+		result := map[string]any{}
+		for k, v := range desired {
+			if liveValue, found := liveMap[k]; found {
+				result[k] = pruneToShape(liveValue, v)
+			}
+		}
+		return result
+	case []any:
+		liveSlice, ok := live.([]any)
+		if !ok || len(liveSlice) != len(desired) {
+			return live
+		}
+		result := make([]any, len(liveSlice))
+		for i := range liveSlice {
+			result[i] = pruneToShape(liveSlice[i], desired[i])
+		}
+		return result
+	default:
+		return live
+	}
+}
+
+func toYAML(obj any) (string, error) {
+	var sb strings.Builder
+	encoder := yaml.NewEncoder(&sb)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(obj); err != nil {
+		return "", err
+	}
+	return sb.String(), nil
+}
+
+func writeDiff(w io.Writer, key, live, rendered string) error {
+	if live == rendered {
+		return nil
+	}
+	return difflib.WriteUnifiedDiff(w, difflib.UnifiedDiff{
+		A:        splitLines(live),
+		B:        splitLines(rendered),
+		FromFile: "live: " + key,
+		ToFile:   "rendered: " + key,
+		Context:  3,
+	})
+}
+
+// splitLines splits the string into lines, keeping the line endings that difflib expects
+func splitLines(s string) []string {
+	lines := strings.SplitAfter(s, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
@@ -0,0 +1,136 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package helm
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestDiffManifests(t *testing.T) {
+	cl := fake.NewClientBuilder().
+		WithObjects(
+			&corev1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "unchanged",
+					Namespace: "istio-system",
+					Labels:    map[string]string{"app": "istiod"},
+				},
+				Data: map[string]string{"key": "value"},
+			},
+			&corev1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "changed",
+					Namespace: "istio-system",
+				},
+				Data: map[string]string{"key": "old-value"},
+			},
+			&corev1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "removed",
+					Namespace: "istio-system",
+				},
+				Data: map[string]string{"key": "value"},
+			},
+		).
+		Build()
+
+	previousManifests := `apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: unchanged
+  namespace: istio-system
+---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: changed
+  namespace: istio-system
+---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: removed
+  namespace: istio-system
+`
+
+	manifests := `---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: unchanged
+  namespace: istio-system
+data:
+  key: value
+---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: changed
+data:
+  key: new-value
+---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: added
+  namespace: istio-system
+data:
+  key: value
+`
+
+	expected := `--- live: v1 ConfigMap istio-system/changed
++++ rendered: v1 ConfigMap istio-system/changed
+@@ -1,6 +1,6 @@
+ apiVersion: v1
+ data:
+-  key: old-value
++  key: new-value
+ kind: ConfigMap
+ metadata:
+   name: changed
+--- live: v1 ConfigMap istio-system/added
++++ rendered: v1 ConfigMap istio-system/added
+@@ -0,0 +1,7 @@
++apiVersion: v1
++data:
++  key: value
++kind: ConfigMap
++metadata:
++  name: added
++  namespace: istio-system
+--- live: v1 ConfigMap istio-system/removed
++++ rendered: v1 ConfigMap istio-system/removed
+@@ -1,5 +0,0 @@
+-apiVersion: v1
+-kind: ConfigMap
+-metadata:
+-  name: removed
+-  namespace: istio-system
+`
+
+	diff, err := DiffManifests(context.TODO(), cl, previousManifests, manifests, "istio-system")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if d := cmp.Diff(expected, diff); d != "" {
+		t.Errorf("unexpected diff; diff (-expected, +actual):\n%v", d)
+	}
+}
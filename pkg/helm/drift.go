@@ -0,0 +1,316 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package helm
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// Drift describes how a live object differs from the manifest it was last applied from
+type Drift struct {
+	Kind      string
+	Namespace string
+	Name      string
+
+	// Deleted is true if the object no longer exists
+	Deleted bool
+
+	// Paths lists the fields whose live values differ from the manifest
+	Paths []FieldPath
+}
+
+func (d Drift) String() string {
+	name := d.Name
+	if d.Namespace != "" {
+		name = d.Namespace + "/" + name
+	}
+	if d.Deleted {
+		return fmt.Sprintf("%s %s deleted", d.Kind, name)
+	}
+	paths := make([]string, len(d.Paths))
+	for i, p := range d.Paths {
+		paths[i] = p.String()
+	}
+	return fmt.Sprintf("%s %s changed at %s", d.Kind, name, strings.Join(paths, ", "))
+}
+
+// IgnoreFunc reports whether changes to the field at the given path of the given object should be ignored
+type IgnoreFunc func(obj *unstructured.Unstructured, path FieldPath) bool
+
+// DetectDrift compares the objects in the given manifests, i.e. the manifests of the currently installed release,
+// with the live objects in the cluster and returns the objects that were changed or deleted since. Like in
+// DiffManifests, only the fields set in the manifests are compared. Objects without a namespace are looked up
+// in the given namespace.
+func DetectDrift(ctx context.Context, cl client.Client, manifests, namespace string, ignore IgnoreFunc) ([]Drift, error) {
+	objects, err := parseManifests(manifests)
+	if err != nil {
+		return nil, err
+	}
+
+	var drifts []Drift
+	for _, obj := range objects {
+		live, err := getLiveObjectTyped(ctx, cl, obj, namespace)
+		if err != nil {
+			return nil, err
+		}
+		drift := Drift{Kind: obj.GetKind(), Namespace: obj.GetNamespace(), Name: obj.GetName()}
+		if live == nil {
+			drift.Deleted = true
+			drifts = append(drifts, drift)
+			continue
+		}
+
+		// the live object returned by the cache doesn't include the type information
+		desired := map[string]any{}
+		for k, v := range obj.Object {
+			if k != "apiVersion" && k != "kind" {
+				desired[k] = v
+			}
+		}
+		compareFields(live.Object, desired, nil, func(path FieldPath) {
+			if ignore == nil || !ignore(obj, path) {
+				drift.Paths = append(drift.Paths, path)
+			}
+		})
+		if len(drift.Paths) > 0 {
+			drifts = append(drifts, drift)
+		}
+	}
+	return drifts, nil
+}
+
+// ManifestsEqual reports whether the two manifests define the same objects with the same content,
+// regardless of their order
+func ManifestsEqual(a, b string) (bool, error) {
+	objectsA, err := parseManifests(a)
+	if err != nil {
+		return false, err
+	}
+	objectsB, err := parseManifests(b)
+	if err != nil {
+		return false, err
+	}
+	if len(objectsA) != len(objectsB) {
+		return false, nil
+	}
+	byKey := map[string]*unstructured.Unstructured{}
+	for _, obj := range objectsA {
+		byKey[objectKey(obj, "")] = obj
+	}
+	for _, obj := range objectsB {
+		other, found := byKey[objectKey(obj, "")]
+		if !found || !reflect.DeepEqual(obj.Object, other.Object) {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// getLiveObjectTyped gets the live object through the typed client when the scheme knows its type, so that
+// the request is served from the cache of the already watched resources. The object is returned in its
+// unstructured form. Returns nil if the object doesn't exist.
+func getLiveObjectTyped(ctx context.Context, cl client.Client, obj *unstructured.Unstructured, namespace string) (*unstructured.Unstructured, error) {
+	typed, err := cl.Scheme().New(obj.GroupVersionKind())
+	if err != nil {
+		return getLiveObject(ctx, cl, obj, namespace)
+	}
+	typedObj, ok := typed.(client.Object)
+	if !ok {
+		return getLiveObject(ctx, cl, obj, namespace)
+	}
+
+	key := client.ObjectKey{Namespace: obj.GetNamespace(), Name: obj.GetName()}
+	if key.Namespace == "" {
+		key.Namespace = namespace
+	}
+	if err := cl.Get(ctx, key, typedObj); err != nil {
+		if errors.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get %s: %v", objectKey(obj, namespace), err)
+	}
+	content, err := runtime.DefaultUnstructuredConverter.ToUnstructured(typedObj)
+	if err != nil {
+		return nil, err
+	}
+	return &unstructured.Unstructured{Object: content}, nil
+}
+
+// compareFields calls report for every field that is set in desired, but has a different value in live. When
+// a map or list differs as a whole (e.g. a list has a different number of elements), only its path is reported.
+func compareFields(live, desired any, path FieldPath, report func(FieldPath)) {
+	switch desired := desired.(type) {
+	case map[string]any:
+		liveMap, ok := live.(map[string]any)
+		if !ok && live != nil {
+			report(path)
+			return
+		}
+		keys := make([]string, 0, len(desired))
+		for k := range desired {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			compareFields(liveMap[k], desired[k], path.Child(k), report)
+		}
+	case []any:
+		liveSlice, ok := live.([]any)
+		if live == nil && len(desired) == 0 {
+			return
+		} else if !ok || len(liveSlice) != len(desired) {
+			report(path)
+			return
+		}
+		for i := range desired {
+			compareFields(liveSlice[i], desired[i], path.Index(i), report)
+		}
+	default:
+		if !scalarsEqual(live, desired) {
+			report(path)
+		}
+	}
+}
+
+// scalarsEqual compares the values of two fields, taking into account that the live object has been through
+// the API server: zero values are omitted, numbers may have a different type, and quantities are canonicalized.
+func scalarsEqual(live, desired any) bool {
+	if desired == nil {
+		return true
+	} else if live == nil {
+		return reflect.ValueOf(desired).IsZero()
+	}
+	liveStr, desiredStr := fmt.Sprint(live), fmt.Sprint(desired)
+	if liveStr == desiredStr {
+		return true
+	}
+	liveQuantity, err := resource.ParseQuantity(liveStr)
+	if err != nil {
+		return false
+	}
+	desiredQuantity, err := resource.ParseQuantity(desiredStr)
+	if err != nil {
+		return false
+	}
+	return liveQuantity.Cmp(desiredQuantity) == 0
+}
+
+// FieldPath is the path of a field within an object. Map keys are stored as they are, while list indexes
+// are stored in brackets, e.g. "[0]".
+type FieldPath []string
+
+// ParseFieldPath parses a path such as "spec.template.spec.containers[0].image". Map keys that contain dots
+// are written in brackets, e.g. "metadata.labels[istio.io/rev]". The index "[*]" matches any list element.
+func ParseFieldPath(s string) (FieldPath, error) {
+	var path FieldPath
+	for i := 0; i < len(s); {
+		switch s[i] {
+		case '[':
+			end := strings.IndexByte(s[i:], ']')
+			if end < 2 {
+				return nil, fmt.Errorf("invalid field path %q: unterminated or empty brackets", s)
+			}
+			segment := s[i+1 : i+end]
+			if isIndex(segment) {
+				segment = "[" + segment + "]"
+			}
+			path = append(path, segment)
+			i += end + 1
+		case '.':
+			if i == 0 || i == len(s)-1 || s[i+1] == '.' || s[i+1] == '[' {
+				return nil, fmt.Errorf("invalid field path %q: empty field name", s)
+			}
+			i++
+		default:
+			if i > 0 && s[i-1] == ']' {
+				return nil, fmt.Errorf("invalid field path %q: missing dot after brackets", s)
+			}
+			end := strings.IndexAny(s[i:], ".[")
+			if end == -1 {
+				end = len(s) - i
+			}
+			path = append(path, s[i:i+end])
+			i += end
+		}
+	}
+	if len(path) == 0 {
+		return nil, fmt.Errorf("invalid field path %q: path is empty", s)
+	}
+	return path, nil
+}
+
+func isIndex(s string) bool {
+	if s == "*" {
+		return true
+	}
+	_, err := strconv.ParseUint(s, 10, 32)
+	return err == nil
+}
+
+func isIndexSegment(s string) bool {
+	return strings.HasPrefix(s, "[") && strings.HasSuffix(s, "]")
+}
+
+// Child returns the path of the given key in the map at this path
+func (p FieldPath) Child(key string) FieldPath {
+	return append(p[:len(p):len(p)], key)
+}
+
+// Index returns the path of the given element in the list at this path
+func (p FieldPath) Index(i int) FieldPath {
+	return append(p[:len(p):len(p)], "["+strconv.Itoa(i)+"]")
+}
+
+// Matches reports whether the path equals the given pattern or lies below it
+func (p FieldPath) Matches(pattern FieldPath) bool {
+	if len(pattern) > len(p) {
+		return false
+	}
+	for i, segment := range pattern {
+		if segment != p[i] && !(segment == "[*]" && isIndexSegment(p[i])) {
+			return false
+		}
+	}
+	return true
+}
+
+func (p FieldPath) String() string {
+	var sb strings.Builder
+	for i, segment := range p {
+		switch {
+		case isIndexSegment(segment):
+			sb.WriteString(segment)
+		case strings.ContainsAny(segment, ".[]"):
+			sb.WriteString("[" + segment + "]")
+		default:
+			if i > 0 {
+				sb.WriteString(".")
+			}
+			sb.WriteString(segment)
+		}
+	}
+	return sb.String()
+}
@@ -0,0 +1,312 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package helm
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"istio.io/istio/pkg/ptr"
+)
+
+const driftManifests = `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: istiod
+  namespace: istio-system
+  labels:
+    app: istiod
+spec:
+  replicas: 1
+  template:
+    spec:
+      containers:
+      - name: discovery
+        image: istio/pilot:1.20.3
+        resources:
+          requests:
+            cpu: 500m
+            memory: 2048Mi
+---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: unchanged
+  namespace: istio-system
+data:
+  key: value
+  empty: ""
+---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: deleted
+  namespace: istio-system
+`
+
+func TestDetectDrift(t *testing.T) {
+	cl := fake.NewClientBuilder().
+		WithObjects(
+			&appsv1.Deployment{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:        "istiod",
+					Namespace:   "istio-system",
+					Labels:      map[string]string{"app": "istiod", "added": "label"},
+					Annotations: map[string]string{"deployment.kubernetes.io/revision": "2"},
+				},
+				Spec: appsv1.DeploymentSpec{
+					Replicas: ptr.Of(int32(3)),
+					Template: corev1.PodTemplateSpec{
+						Spec: corev1.PodSpec{
+							Containers: []corev1.Container{
+								{
+									Name:  "discovery",
+									Image: "istio/pilot:debug",
+									Resources: corev1.ResourceRequirements{
+										Requests: corev1.ResourceList{
+											corev1.ResourceCPU:    resource.MustParse("500m"),
+											corev1.ResourceMemory: resource.MustParse("2Gi"),
+										},
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+			&corev1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "unchanged",
+					Namespace: "istio-system",
+				},
+				Data: map[string]string{"key": "value"},
+			},
+		).
+		Build()
+
+	t.Run("reports changed and deleted objects", func(t *testing.T) {
+		drifts, err := DetectDrift(context.TODO(), cl, driftManifests, "istio-system", nil)
+		if err != nil {
+			t.Fatalf("expected no error, but got: %v", err)
+		}
+		expected := []Drift{
+			{
+				Kind:      "Deployment",
+				Namespace: "istio-system",
+				Name:      "istiod",
+				Paths: []FieldPath{
+					{"spec", "replicas"},
+					{"spec", "template", "spec", "containers", "[0]", "image"},
+				},
+			},
+			{
+				Kind:      "ConfigMap",
+				Namespace: "istio-system",
+				Name:      "deleted",
+				Deleted:   true,
+			},
+		}
+		if diff := cmp.Diff(expected, drifts); diff != "" {
+			t.Errorf("unexpected drifts; diff (-expected, +actual):\n%v", diff)
+		}
+	})
+
+	t.Run("skips ignored fields", func(t *testing.T) {
+		pattern, err := ParseFieldPath("spec.template.spec.containers[*].image")
+		if err != nil {
+			t.Fatal(err)
+		}
+		ignore := func(obj *unstructured.Unstructured, path FieldPath) bool {
+			return obj.GetKind() == "Deployment" && path.Matches(pattern)
+		}
+		drifts, err := DetectDrift(context.TODO(), cl, driftManifests, "istio-system", ignore)
+		if err != nil {
+			t.Fatalf("expected no error, but got: %v", err)
+		}
+		if len(drifts) != 2 || len(drifts[0].Paths) != 1 || drifts[0].Paths[0].String() != "spec.replicas" {
+			t.Errorf("expected only spec.replicas to be reported for the Deployment, but got: %v", drifts)
+		}
+	})
+}
+
+func TestDriftString(t *testing.T) {
+	testCases := []struct {
+		drift    Drift
+		expected string
+	}{
+		{
+			drift:    Drift{Kind: "Service", Namespace: "istio-system", Name: "istiod", Deleted: true},
+			expected: "Service istio-system/istiod deleted",
+		},
+		{
+			drift: Drift{
+				Kind:  "ClusterRole",
+				Name:  "istiod",
+				Paths: []FieldPath{{"rules", "[0]", "verbs"}, {"metadata", "labels", "istio.io/rev"}},
+			},
+			expected: "ClusterRole istiod changed at rules[0].verbs, metadata.labels[istio.io/rev]",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.expected, func(t *testing.T) {
+			if s := tc.drift.String(); s != tc.expected {
+				t.Errorf("expected %q, but got %q", tc.expected, s)
+			}
+		})
+	}
+}
+
+func TestManifestsEqual(t *testing.T) {
+	reordered := `apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: deleted
+  namespace: istio-system
+---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  namespace: istio-system
+  name: unchanged
+data:
+  empty: ""
+  key: value
+---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: istiod
+  namespace: istio-system
+  labels:
+    app: istiod
+spec:
+  template:
+    spec:
+      containers:
+      - image: istio/pilot:1.20.3
+        name: discovery
+        resources:
+          requests:
+            memory: 2048Mi
+            cpu: 500m
+  replicas: 1
+`
+	withoutDeployment := reordered[:strings.Index(reordered, "---\napiVersion: apps/v1")]
+
+	testCases := []struct {
+		name     string
+		a        string
+		b        string
+		expected bool
+	}{
+		{name: "identical", a: driftManifests, b: driftManifests, expected: true},
+		{name: "reordered", a: driftManifests, b: reordered, expected: true},
+		{name: "object removed", a: driftManifests, b: withoutDeployment, expected: false},
+		{name: "field changed", a: driftManifests, b: strings.Replace(driftManifests, "replicas: 1", "replicas: 2", 1), expected: false},
+		{name: "both empty", a: "", b: "---\n", expected: true},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			equal, err := ManifestsEqual(tc.a, tc.b)
+			if err != nil {
+				t.Fatalf("expected no error, but got: %v", err)
+			}
+			if equal != tc.expected {
+				t.Errorf("expected %v, but got %v", tc.expected, equal)
+			}
+		})
+	}
+}
+
+func TestParseFieldPath(t *testing.T) {
+	testCases := []struct {
+		path      string
+		expected  FieldPath
+		expectErr bool
+	}{
+		{path: "spec.replicas", expected: FieldPath{"spec", "replicas"}},
+		{path: "spec.template.spec.containers[0].image", expected: FieldPath{"spec", "template", "spec", "containers", "[0]", "image"}},
+		{path: "webhooks[*].clientConfig.caBundle", expected: FieldPath{"webhooks", "[*]", "clientConfig", "caBundle"}},
+		{path: "metadata.labels[istio.io/rev]", expected: FieldPath{"metadata", "labels", "istio.io/rev"}},
+		{path: "data[mesh]", expected: FieldPath{"data", "mesh"}},
+		{path: "", expectErr: true},
+		{path: "spec..replicas", expectErr: true},
+		{path: ".spec", expectErr: true},
+		{path: "spec.", expectErr: true},
+		{path: "spec.containers[0", expectErr: true},
+		{path: "spec.containers[]", expectErr: true},
+		{path: "spec.containers[0]image", expectErr: true},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.path, func(t *testing.T) {
+			path, err := ParseFieldPath(tc.path)
+			if tc.expectErr {
+				if err == nil {
+					t.Errorf("expected error, but got none")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("expected no error, but got: %v", err)
+			}
+			if diff := cmp.Diff(tc.expected, path); diff != "" {
+				t.Errorf("unexpected path; diff (-expected, +actual):\n%v", diff)
+			}
+			if s := path.String(); s != tc.path && tc.path != "data[mesh]" {
+				t.Errorf("expected path to be formatted as %q, but got %q", tc.path, s)
+			}
+		})
+	}
+}
+
+func TestFieldPathMatches(t *testing.T) {
+	testCases := []struct {
+		path     string
+		pattern  string
+		expected bool
+	}{
+		{path: "spec.replicas", pattern: "spec.replicas", expected: true},
+		{path: "spec.template.spec.containers[1].image", pattern: "spec.template", expected: true},
+		{path: "spec.template.spec.containers[1].image", pattern: "spec.template.spec.containers[*].image", expected: true},
+		{path: "spec.template.spec.containers[1].image", pattern: "spec.template.spec.containers[0].image", expected: false},
+		{path: "spec.template.spec.containers[1].image", pattern: "spec.template.spec.containers[*].resources", expected: false},
+		{path: "spec", pattern: "spec.replicas", expected: false},
+		{path: "metadata.labels.app", pattern: "metadata.labels[*]", expected: false},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.path+" "+tc.pattern, func(t *testing.T) {
+			path, err := ParseFieldPath(tc.path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			pattern, err := ParseFieldPath(tc.pattern)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if matches := path.Matches(pattern); matches != tc.expected {
+				t.Errorf("expected %v, but got %v", tc.expected, matches)
+			}
+		})
+	}
+}
@@ -0,0 +1,122 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package helm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"helm.sh/helm/v3/pkg/action"
+	"helm.sh/helm/v3/pkg/chart"
+	"helm.sh/helm/v3/pkg/chartutil"
+	"helm.sh/helm/v3/pkg/release"
+	"helm.sh/helm/v3/pkg/storage/driver"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+)
+
+// RenderCharts renders the given charts the same way UpgradeOrInstallCharts installs them, including the
+// owner references added by the OwnerReferencePostRenderer, but without contacting the cluster. The manifests
+// of all charts are returned as a single multi-document YAML string. The kubeVersion is exposed to the
+// templates as .Capabilities.KubeVersion; if empty, Helm's default version is used.
+func RenderCharts(charts []string, values HelmValues,
+	chartVersion, releaseNameBase, ns string, ownerReference metav1.OwnerReference, kubeVersion string,
+) (string, error) {
+	var manifests strings.Builder
+	for _, chartName := range charts {
+		releaseName := fmt.Sprintf("%s-%s", releaseNameBase, chartName)
+		chart, err := loadChart(chartVersion, chartName)
+		if err != nil {
+			return "", err
+		}
+		manifest, err := renderChart(chart, ns, releaseName, ownerReference, values, kubeVersion)
+		if err != nil {
+			return "", err
+		}
+		// the post renderer drops the leading document separator, so it must be added between charts
+		manifests.WriteString("---\n")
+		manifests.WriteString(manifest)
+	}
+	return manifests.String(), nil
+}
+
+// GetReleaseManifests returns the manifests of the currently installed releases of the given charts as a
+// single multi-document YAML string. Charts that aren't installed are skipped. The returned bool reports
+// whether all the installed releases are in the deployed state, i.e. none of them failed or is pending.
+func GetReleaseManifests(ctx context.Context, restClientGetter genericclioptions.RESTClientGetter,
+	charts []string, releaseNameBase, ns string,
+) (string, bool, error) {
+	actionConfig, err := newActionConfig(ctx, restClientGetter, ns)
+	if err != nil {
+		return "", false, err
+	}
+	var manifests strings.Builder
+	deployed := true
+	for _, chartName := range charts {
+		releaseName := fmt.Sprintf("%s-%s", releaseNameBase, chartName)
+		rel, err := action.NewGet(actionConfig).Run(releaseName)
+		if errors.Is(err, driver.ErrReleaseNotFound) {
+			continue
+		} else if err != nil {
+			return "", false, fmt.Errorf("failed to get helm release %s: %v", releaseName, err)
+		}
+		if rel.Info == nil || rel.Info.Status != release.StatusDeployed {
+			deployed = false
+		}
+		manifests.WriteString("---\n")
+		manifests.WriteString(rel.Manifest)
+	}
+	return manifests.String(), deployed, nil
+}
+
+// GetKubeVersion returns the version of the cluster's API server
+func GetKubeVersion(restClientGetter genericclioptions.RESTClientGetter) (string, error) {
+	discoveryClient, err := restClientGetter.ToDiscoveryClient()
+	if err != nil {
+		return "", err
+	}
+	serverVersion, err := discoveryClient.ServerVersion()
+	if err != nil {
+		return "", fmt.Errorf("failed to get the Kubernetes version: %v", err)
+	}
+	return serverVersion.GitVersion, nil
+}
+
+// renderChart renders a chart like `helm template` does
+func renderChart(chart *chart.Chart, namespace, releaseName string,
+	ownerReference metav1.OwnerReference, values HelmValues, kubeVersion string,
+) (string, error) {
+	installAction := action.NewInstall(&action.Configuration{Log: func(string, ...interface{}) {}})
+	installAction.PostRenderer = NewOwnerReferencePostRenderer(ownerReference, "")
+	installAction.Namespace = namespace
+	installAction.ReleaseName = releaseName
+	installAction.SkipCRDs = true
+	installAction.DryRun = true
+	installAction.ClientOnly = true
+	if kubeVersion != "" {
+		v, err := chartutil.ParseKubeVersion(kubeVersion)
+		if err != nil {
+			return "", err
+		}
+		installAction.KubeVersion = v
+	}
+	rel, err := installAction.Run(chart, values)
+	if err != nil {
+		return "", fmt.Errorf("failed to render helm chart %s: %v", chart.Name(), err)
+	}
+	return rel.Manifest, nil
+}
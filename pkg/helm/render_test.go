@@ -0,0 +1,88 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package helm
+
+import (
+	"os"
+	"path"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const testChartTemplate = `apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: {{ .Release.Name }}
+  namespace: {{ .Release.Namespace }}
+data:
+  message: {{ .Values.message }}
+  kubeVersion: {{ .Capabilities.KubeVersion.Version }}
+`
+
+func TestRenderCharts(t *testing.T) {
+	oldResourceDirectory := ResourceDirectory
+	ResourceDirectory = t.TempDir()
+	t.Cleanup(func() { ResourceDirectory = oldResourceDirectory })
+
+	chartDir := path.Join(ResourceDirectory, "my-version", "charts", "my-chart")
+	Must(t, os.MkdirAll(path.Join(chartDir, "templates"), 0o755))
+	Must(t, os.WriteFile(path.Join(chartDir, "Chart.yaml"), []byte("apiVersion: v2\nname: my-chart\nversion: 1.0.0\n"), 0o644))
+	Must(t, os.WriteFile(path.Join(chartDir, "templates", "configmap.yaml"), []byte(testChartTemplate), 0o644))
+
+	ownerReference := metav1.OwnerReference{
+		APIVersion: "operator.istio.io/v1alpha1",
+		Kind:       "IstioRevision",
+		Name:       "my-rev",
+		UID:        "123",
+	}
+
+	manifests, err := RenderCharts([]string{"my-chart"}, HelmValues{"message": "hello"},
+		"my-version", "my-rev", "istio-system", ownerReference, "v1.28.3")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := `---
+apiVersion: v1
+data:
+  kubeVersion: v1.28.3
+  message: hello
+kind: ConfigMap
+metadata:
+  name: my-rev-my-chart
+  namespace: istio-system
+  ownerReferences:
+    - apiVersion: operator.istio.io/v1alpha1
+      kind: IstioRevision
+      name: my-rev
+      uid: "123"
+`
+	if diff := cmp.Diff(expected, manifests); diff != "" {
+		t.Errorf("unexpected manifests; diff (-expected, +actual):\n%v", diff)
+	}
+
+	if _, err := RenderCharts([]string{"nonexistent"}, nil, "my-version", "my-rev", "istio-system", ownerReference, ""); err == nil {
+		t.Errorf("expected error for nonexistent chart, but got none")
+	}
+}
+
+func Must(t *testing.T, err error) {
+	t.Helper()
+	if err != nil {
+		t.Fatal(err)
+	}
+}
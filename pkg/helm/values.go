@@ -34,6 +34,12 @@ func (h HelmValues) GetString(key string) (string, bool, error) {
 	return unstructured.NestedString(h, toKeys(key)...)
 }
 
+// GetMap returns a deep copy of the map value of a nested field.
+// Returns false if value is not found and an error if not a map[string]any.
+func (h HelmValues) GetMap(key string) (map[string]any, bool, error) {
+	return unstructured.NestedMap(h, toKeys(key)...)
+}
+
 // Set sets the value of a nested field to a deep copy of the value provided.
 // Returns an error if value cannot be set because one of the nesting levels is not a map[string]any.
 func (h HelmValues) Set(key string, val any) error {
@@ -84,6 +84,75 @@ func TestGetString(t *testing.T) {
 	}
 }
 
+func TestGetMap(t *testing.T) {
+	testCases := []struct {
+		name        string
+		input       HelmValues
+		key         string
+		expectFound bool
+		expected    map[string]any
+		expectErr   bool
+	}{
+		{
+			name: "valid Key",
+			input: HelmValues{
+				"foo": map[string]any{
+					"bar": map[string]any{
+						"baz": "qux",
+					},
+				},
+			},
+			key:         "foo.bar",
+			expectFound: true,
+			expected: map[string]any{
+				"baz": "qux",
+			},
+		},
+		{
+			name: "invalid Key",
+			input: HelmValues{
+				"foo": map[string]any{
+					"bar": "baz",
+				},
+			},
+			key:         "foo.bar",
+			expectFound: false,
+			expectErr:   true,
+		},
+		{
+			name: "nonexistent key",
+			input: HelmValues{
+				"foo": map[string]any{},
+			},
+			key:         "foo.bar",
+			expectFound: false,
+			expectErr:   false,
+		},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.name, func(t *testing.T) {
+			result, found, err := test.input.GetMap(test.key)
+			if test.expectErr {
+				if err == nil {
+					t.Errorf("Expected an error, but got nil")
+				}
+			} else {
+				if err != nil {
+					t.Errorf("Expected no error, but got an error: %v", err)
+				}
+				if !reflect.DeepEqual(result, test.expected) {
+					t.Errorf("Expected %v, but got %v", test.expected, result)
+				}
+			}
+
+			if found != test.expectFound {
+				t.Errorf("Expected found to be %v, but it was %v", test.expectFound, found)
+			}
+		})
+	}
+}
+
 func TestSet(t *testing.T) {
 	tests := []struct {
 		name      string
@@ -0,0 +1,292 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package iopimport
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"maistra.io/istio-operator/api/v1alpha1"
+	"maistra.io/istio-operator/pkg/istiovalues"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// IstioOperatorGVK is the GroupVersionKind of the resources used by istioctl and the in-cluster Istio operator
+var IstioOperatorGVK = schema.GroupVersionKind{Group: "install.istio.io", Version: "v1alpha1", Kind: istiovalues.IstioOperatorKind}
+
+// ImportedFromAnnotation is set on the Istio resources that were imported from an IstioOperator resource. Its value
+// is the namespace and name of the IstioOperator.
+const ImportedFromAnnotation = "operator.istio.io/imported-from"
+
+// ImportedGenerationAnnotation is set on the Istio resources that were imported from an IstioOperator resource in the
+// cluster. Its value is the IstioOperator's metadata.generation, so that the Istio is only updated when the
+// IstioOperator changes, and changes made to the Istio after the import are kept otherwise.
+const ImportedGenerationAnnotation = "operator.istio.io/imported-generation"
+
+const (
+	defaultIstioNamespace = "istio-system"
+	defaultProfile        = "default"
+)
+
+// patch versions in spec.tag, e.g. "1.20.3", map to the corresponding spec.version, e.g. "v1.20.3"
+var tagVersionRegexp = regexp.MustCompile(`^\d+\.\d+\.\d+$`)
+
+var jsonUnmarshalerType = reflect.TypeOf((*json.Unmarshaler)(nil)).Elem()
+
+// Options customizes the conversion of an IstioOperator
+type Options struct {
+	// Version to set in the Istio resource. If empty, the version is derived from spec.tag of the IstioOperator.
+	// If that isn't a patch version either, spec.version is left empty, so that the operator's default is used.
+	Version string
+}
+
+// Result is the result of the conversion of an IstioOperator
+type Result struct {
+	// Istio is the Istio resource equivalent to the IstioOperator
+	Istio *v1alpha1.Istio
+
+	// Unsupported lists the paths of the IstioOperator fields that have no equivalent in the Istio resource
+	Unsupported []string
+}
+
+// Convert returns the Istio resource that is equivalent to the given IstioOperator. The Istio resource is named
+// after the IstioOperator's revision and uses the InPlace update strategy, so that the revision it creates has
+// the same name and the existing istio.io/rev labels keep working. The default revision maps to an Istio named
+// "default".
+func Convert(iop *unstructured.Unstructured, opts Options) (*Result, error) {
+	if iop.GroupVersionKind().GroupKind() != IstioOperatorGVK.GroupKind() {
+		return nil, fmt.Errorf("expected an %s, but got a %s", IstioOperatorGVK.Kind, iop.GetKind())
+	}
+	spec, _, err := unstructured.NestedMap(iop.Object, "spec")
+	if err != nil {
+		return nil, err
+	}
+	if spec == nil {
+		spec = map[string]any{}
+	}
+
+	// the fields that are handled here are removed from the spec before the rest of it is converted to values
+	revision, err := popString(spec, "revision")
+	if err != nil {
+		return nil, err
+	}
+	profile, err := popString(spec, "profile")
+	if err != nil {
+		return nil, err
+	}
+	namespace, err := popString(spec, "namespace")
+	if err != nil {
+		return nil, err
+	}
+
+	version := opts.Version
+	if tag, ok := spec["tag"].(string); ok && tagVersionRegexp.MatchString(tag) {
+		if version == "" {
+			version = "v" + tag
+		}
+		if version == "v"+tag {
+			// the images are determined by spec.version; pinning the tag would prevent future upgrades
+			delete(spec, "tag")
+		}
+	}
+
+	helmValues, unsupported, err := istiovalues.ConvertIstioOperator(spec)
+	if err != nil {
+		return nil, err
+	}
+	unsupported = append(unsupported, unknownFields(map[string]any(helmValues), reflect.TypeOf(v1alpha1.Values{}), "spec.values")...)
+	slices.Sort(unsupported)
+
+	// unknown fields were reported above, so they're ignored here rather than causing an error
+	data, err := json.Marshal(helmValues)
+	if err != nil {
+		return nil, err
+	}
+	values := &v1alpha1.Values{}
+	if err := json.Unmarshal(data, values); err != nil {
+		return nil, fmt.Errorf("failed to convert values: %v", err)
+	}
+
+	if values.Global != nil && values.Global.IstioNamespace != "" {
+		namespace = values.Global.IstioNamespace
+	} else if namespace == "" {
+		namespace = iop.GetNamespace()
+	}
+	if namespace == "" {
+		namespace = defaultIstioNamespace
+	}
+
+	name := revision
+	if name == "" {
+		name = v1alpha1.DefaultRevision
+	}
+	if profile == defaultProfile {
+		// the default profile is always applied
+		profile = ""
+	}
+	if reflect.DeepEqual(values, &v1alpha1.Values{}) {
+		values = nil
+	}
+
+	annotations := map[string]string{
+		ImportedFromAnnotation: client.ObjectKeyFromObject(iop).String(),
+	}
+	// IstioOperators read from a file have no generation; they're imported whenever they're applied
+	if generation := iop.GetGeneration(); generation > 0 {
+		annotations[ImportedGenerationAnnotation] = strconv.FormatInt(generation, 10)
+	}
+
+	return &Result{
+		Istio: &v1alpha1.Istio{
+			TypeMeta: metav1.TypeMeta{
+				APIVersion: v1alpha1.GroupVersion.String(),
+				Kind:       v1alpha1.IstioKind,
+			},
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        name,
+				Annotations: annotations,
+			},
+			Spec: v1alpha1.IstioSpec{
+				Version:        version,
+				Profile:        profile,
+				Namespace:      namespace,
+				Values:         values,
+				UpdateStrategy: &v1alpha1.IstioUpdateStrategy{Type: v1alpha1.UpdateStrategyTypeInPlace},
+			},
+		},
+		Unsupported: unsupported,
+	}, nil
+}
+
+// Apply creates the given Istio resource or, if it already exists, updates its spec. Returns an error if the
+// existing Istio resource wasn't imported from the same IstioOperator, so that resources created by the user
+// are never overwritten. An Istio that was already imported from the same generation of the IstioOperator isn't
+// updated, so that the changes made to it since then are kept. Returns whether the Istio was created or updated.
+func Apply(ctx context.Context, cl client.Client, istio *v1alpha1.Istio) (bool, error) {
+	existing := &v1alpha1.Istio{}
+	if err := cl.Get(ctx, client.ObjectKeyFromObject(istio), existing); errors.IsNotFound(err) {
+		return true, cl.Create(ctx, istio)
+	} else if err != nil {
+		return false, err
+	}
+
+	importedFrom := istio.Annotations[ImportedFromAnnotation]
+	if existing.Annotations[ImportedFromAnnotation] != importedFrom {
+		return false, fmt.Errorf("an Istio named %s already exists and wasn't imported from IstioOperator %s", istio.Name, importedFrom)
+	}
+	generation := istio.Annotations[ImportedGenerationAnnotation]
+	if generation != "" && existing.Annotations[ImportedGenerationAnnotation] == generation {
+		return false, nil
+	}
+	if equality.Semantic.DeepEqual(existing.Spec, istio.Spec) && existing.Annotations[ImportedGenerationAnnotation] == generation {
+		return false, nil
+	}
+	existing.Spec = istio.Spec
+	if generation != "" {
+		existing.Annotations[ImportedGenerationAnnotation] = generation
+	}
+	return true, cl.Update(ctx, existing)
+}
+
+func popString(m map[string]any, key string) (string, error) {
+	value, found := m[key]
+	delete(m, key)
+	if !found || value == nil {
+		return "", nil
+	}
+	s, ok := value.(string)
+	if !ok {
+		return "", fmt.Errorf("spec.%s is not a string", key)
+	}
+	return s, nil
+}
+
+// unknownFields returns the paths of the fields in the given value that have no corresponding field in the
+// given type, which is traversed through the names in the fields' json tags
+func unknownFields(value any, t reflect.Type, path string) []string {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if reflect.PointerTo(t).Implements(jsonUnmarshalerType) {
+		// types with custom unmarshalling, e.g. resource.Quantity, are treated as opaque values
+		return nil
+	}
+
+	var unknown []string
+	switch t.Kind() {
+	case reflect.Struct:
+		m, ok := value.(map[string]any)
+		if !ok {
+			return nil
+		}
+		fields := jsonFields(t)
+		for _, key := range sortedKeys(m) {
+			if field, found := fields[key]; found {
+				unknown = append(unknown, unknownFields(m[key], field.Type, path+"."+key)...)
+			} else {
+				unknown = append(unknown, path+"."+key)
+			}
+		}
+	case reflect.Map:
+		m, ok := value.(map[string]any)
+		if !ok {
+			return nil
+		}
+		for _, key := range sortedKeys(m) {
+			unknown = append(unknown, unknownFields(m[key], t.Elem(), path+"."+key)...)
+		}
+	case reflect.Slice:
+		list, ok := value.([]any)
+		if !ok {
+			return nil
+		}
+		for i, item := range list {
+			unknown = append(unknown, unknownFields(item, t.Elem(), fmt.Sprintf("%s[%d]", path, i))...)
+		}
+	}
+	return unknown
+}
+
+func jsonFields(t reflect.Type) map[string]reflect.StructField {
+	fields := map[string]reflect.StructField{}
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name != "" && name != "-" {
+			fields[name] = field
+		}
+	}
+	return fields
+}
+
+func sortedKeys(m map[string]any) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+	return keys
+}
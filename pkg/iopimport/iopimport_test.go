@@ -0,0 +1,331 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package iopimport
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	k8sv1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/kubectl/pkg/scheme"
+	"maistra.io/istio-operator/api/v1alpha1"
+	"maistra.io/istio-operator/pkg/test"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/yaml"
+
+	"istio.io/istio/pkg/ptr"
+)
+
+func TestConvert(t *testing.T) {
+	inPlace := &v1alpha1.IstioUpdateStrategy{Type: v1alpha1.UpdateStrategyTypeInPlace}
+
+	tests := []struct {
+		name              string
+		iop               string
+		opts              Options
+		expectName        string
+		expectSpec        v1alpha1.IstioSpec
+		expectUnsupported []string
+		expectErr         bool
+	}{
+		{
+			name: "default revision",
+			iop: `
+apiVersion: install.istio.io/v1alpha1
+kind: IstioOperator
+metadata:
+  name: installed-state
+  namespace: istio-system
+spec:
+  profile: default
+  tag: 1.20.3`,
+			expectName: "default",
+			expectSpec: v1alpha1.IstioSpec{Version: "v1.20.3", Namespace: "istio-system", UpdateStrategy: inPlace},
+		},
+		{
+			name: "revision and profile are preserved",
+			iop: `
+apiVersion: install.istio.io/v1alpha1
+kind: IstioOperator
+metadata:
+  name: canary
+  namespace: istio-system
+spec:
+  revision: canary
+  profile: demo
+  hub: quay.io/example
+  tag: 1.20.3`,
+			expectName: "canary",
+			expectSpec: v1alpha1.IstioSpec{
+				Version:        "v1.20.3",
+				Namespace:      "istio-system",
+				Profile:        "demo",
+				UpdateStrategy: inPlace,
+				Values:         &v1alpha1.Values{Global: &v1alpha1.GlobalConfig{Hub: "quay.io/example"}},
+			},
+		},
+		{
+			name: "version option",
+			iop: `
+apiVersion: install.istio.io/v1alpha1
+kind: IstioOperator
+metadata:
+  name: example
+  namespace: istio-system
+spec:
+  tag: 1.19.7`,
+			opts:       Options{Version: "v1.20.3"},
+			expectName: "default",
+			expectSpec: v1alpha1.IstioSpec{
+				Version:        "v1.20.3",
+				Namespace:      "istio-system",
+				UpdateStrategy: inPlace,
+				Values:         &v1alpha1.Values{Global: &v1alpha1.GlobalConfig{Tag: ptr.Of(intstr.FromString("1.19.7"))}},
+			},
+		},
+		{
+			name: "values, meshConfig, and components",
+			iop: `
+apiVersion: install.istio.io/v1alpha1
+kind: IstioOperator
+metadata:
+  name: example
+  namespace: istio-operator
+spec:
+  meshConfig:
+    accessLogFile: /dev/stdout
+  components:
+    pilot:
+      k8s:
+        resources:
+          requests:
+            cpu: 500m
+  values:
+    global:
+      istioNamespace: istio-control
+    pilot:
+      autoscaleEnabled: true`,
+			expectName: "default",
+			expectSpec: v1alpha1.IstioSpec{
+				Namespace:      "istio-control",
+				UpdateStrategy: inPlace,
+				Values: &v1alpha1.Values{
+					Global:     &v1alpha1.GlobalConfig{IstioNamespace: "istio-control"},
+					MeshConfig: &v1alpha1.MeshConfig{AccessLogFile: "/dev/stdout"},
+					Pilot: &v1alpha1.PilotConfig{
+						AutoscaleEnabled: true,
+						Resources: &k8sv1.ResourceRequirements{
+							Requests: k8sv1.ResourceList{k8sv1.ResourceCPU: resource.MustParse("500m")},
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "unsupported fields",
+			iop: `
+apiVersion: install.istio.io/v1alpha1
+kind: IstioOperator
+metadata:
+  name: example
+  namespace: istio-system
+spec:
+  installPackagePath: /tmp/charts
+  components:
+    ingressGateways:
+    - name: istio-ingressgateway
+      enabled: true
+  values:
+    gateways:
+      istio-ingressgateway:
+        type: LoadBalancer
+    pilot:
+      unknownField: true
+      autoscaleEnabled: false
+    meshConfig:
+      unknownMeshField: true`,
+			expectName: "default",
+			expectSpec: v1alpha1.IstioSpec{
+				Namespace:      "istio-system",
+				UpdateStrategy: inPlace,
+				Values:         &v1alpha1.Values{MeshConfig: &v1alpha1.MeshConfig{}, Pilot: &v1alpha1.PilotConfig{}},
+			},
+			expectUnsupported: []string{
+				"spec.components.ingressGateways",
+				"spec.installPackagePath",
+				"spec.values.gateways",
+				"spec.values.meshConfig.unknownMeshField",
+				"spec.values.pilot.unknownField",
+			},
+		},
+		{
+			name: "not an IstioOperator",
+			iop: `
+apiVersion: operator.istio.io/v1alpha1
+kind: Istio
+metadata:
+  name: default`,
+			expectErr: true,
+		},
+		{
+			name: "invalid revision",
+			iop: `
+apiVersion: install.istio.io/v1alpha1
+kind: IstioOperator
+metadata:
+  name: example
+spec:
+  revision: [canary]`,
+			expectErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			iop := &unstructured.Unstructured{}
+			if err := yaml.Unmarshal([]byte(tt.iop), &iop.Object); err != nil {
+				t.Fatalf("failed to parse IstioOperator: %v", err)
+			}
+
+			result, err := Convert(iop, tt.opts)
+			if (err != nil) != tt.expectErr {
+				t.Fatalf("Convert() error = %v, expectErr %v", err, tt.expectErr)
+			}
+			if err != nil {
+				return
+			}
+			if result.Istio.Name != tt.expectName {
+				t.Errorf("expected Istio to be named %q, but got %q", tt.expectName, result.Istio.Name)
+			}
+			if importedFrom := result.Istio.Annotations[ImportedFromAnnotation]; importedFrom != iop.GetNamespace()+"/"+iop.GetName() {
+				t.Errorf("unexpected %s annotation: %q", ImportedFromAnnotation, importedFrom)
+			}
+			if diff := cmp.Diff(tt.expectSpec, result.Istio.Spec); diff != "" {
+				t.Errorf("unexpected spec; diff (-expected, +actual):\n%v", diff)
+			}
+			if diff := cmp.Diff(tt.expectUnsupported, result.Unsupported); diff != "" {
+				t.Errorf("unexpected unsupported fields; diff (-expected, +actual):\n%v", diff)
+			}
+		})
+	}
+}
+
+func TestApply(t *testing.T) {
+	test.SetupScheme()
+	ctx := context.Background()
+
+	newIstio := func(importedFrom, generation, version string) *v1alpha1.Istio {
+		istio := &v1alpha1.Istio{
+			ObjectMeta: metav1.ObjectMeta{Name: "default"},
+			Spec:       v1alpha1.IstioSpec{Version: version, Namespace: "istio-system"},
+		}
+		if importedFrom != "" {
+			istio.Annotations = map[string]string{ImportedFromAnnotation: importedFrom}
+			if generation != "" {
+				istio.Annotations[ImportedGenerationAnnotation] = generation
+			}
+		}
+		return istio
+	}
+
+	tests := []struct {
+		name             string
+		existing         *v1alpha1.Istio
+		generation       string
+		expectErr        bool
+		expectApplied    bool
+		expectVersion    string
+		expectGeneration string
+	}{
+		{
+			name:          "creates Istio",
+			expectApplied: true,
+			expectVersion: "v1.20.3",
+		},
+		{
+			name:          "updates previously imported Istio",
+			existing:      newIstio("istio-system/example", "", "v1.19.7"),
+			expectApplied: true,
+			expectVersion: "v1.20.3",
+		},
+		{
+			name:             "updates Istio imported from previous generation",
+			existing:         newIstio("istio-system/example", "1", "v1.19.7"),
+			generation:       "2",
+			expectApplied:    true,
+			expectVersion:    "v1.20.3",
+			expectGeneration: "2",
+		},
+		{
+			name:             "keeps changes made to Istio imported from same generation",
+			existing:         newIstio("istio-system/example", "2", "v1.21.0"),
+			generation:       "2",
+			expectVersion:    "v1.21.0",
+			expectGeneration: "2",
+		},
+		{
+			name:          "doesn't update Istio that is up to date",
+			existing:      newIstio("istio-system/example", "", "v1.20.3"),
+			expectVersion: "v1.20.3",
+		},
+		{
+			name:          "doesn't overwrite Istio created by user",
+			existing:      newIstio("", "", "v1.19.7"),
+			expectErr:     true,
+			expectVersion: "v1.19.7",
+		},
+		{
+			name:          "doesn't overwrite Istio imported from another IstioOperator",
+			existing:      newIstio("istio-system/other", "", "v1.19.7"),
+			expectErr:     true,
+			expectVersion: "v1.19.7",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			builder := fake.NewClientBuilder().WithScheme(scheme.Scheme)
+			if tt.existing != nil {
+				builder = builder.WithObjects(tt.existing)
+			}
+			cl := builder.Build()
+
+			applied, err := Apply(ctx, cl, newIstio("istio-system/example", tt.generation, "v1.20.3"))
+			if (err != nil) != tt.expectErr {
+				t.Fatalf("Apply() error = %v, expectErr %v", err, tt.expectErr)
+			}
+			if applied != tt.expectApplied {
+				t.Errorf("expected Apply() to return %v, but got %v", tt.expectApplied, applied)
+			}
+
+			istio := &v1alpha1.Istio{}
+			if err := cl.Get(ctx, client.ObjectKey{Name: "default"}, istio); err != nil {
+				t.Fatalf("failed to get Istio: %v", err)
+			}
+			if istio.Spec.Version != tt.expectVersion {
+				t.Errorf("expected version %s, but got %s", tt.expectVersion, istio.Spec.Version)
+			}
+			if generation := istio.Annotations[ImportedGenerationAnnotation]; generation != tt.expectGeneration {
+				t.Errorf("expected imported generation %q, but got %q", tt.expectGeneration, generation)
+			}
+		})
+	}
+}
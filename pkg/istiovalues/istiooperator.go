@@ -0,0 +1,135 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package istiovalues
+
+import (
+	"fmt"
+	"slices"
+
+	"maistra.io/istio-operator/pkg/helm"
+)
+
+// IstioOperatorKind is the kind of the resources used by istioctl and the in-cluster Istio operator. Profiles of
+// this kind are converted to helm values when they are loaded.
+const IstioOperatorKind = "IstioOperator"
+
+// istioOperatorComponents maps the IstioOperator components that can be converted to the keys of their helm values.
+// Enabling or disabling a component sets the enabled value under each of the keys; the other component settings
+// are set under the first key.
+var istioOperatorComponents = map[string][]string{
+	"pilot":   {"pilot"},
+	"cni":     {"cni", "istio_cni"},
+	"ztunnel": {"ztunnel"},
+}
+
+// ConvertIstioOperator converts the spec of an IstioOperator resource to the equivalent helm values. As in
+// istioctl, spec.values takes precedence over the values derived from spec.hub, spec.tag, and spec.components,
+// while spec.meshConfig takes precedence over spec.values.meshConfig. The paths of the fields that can't be
+// converted are returned, so that they can be reported to the user.
+func ConvertIstioOperator(spec map[string]any) (helm.HelmValues, []string, error) {
+	values := helm.HelmValues{}
+	var unsupported []string
+	for _, field := range sortedKeys(spec) {
+		switch value := spec[field]; field {
+		case "hub", "tag":
+			values = MergeOverwrite(values, map[string]any{"global": map[string]any{field: value}})
+		case "components":
+			components, ok := value.(map[string]any)
+			if !ok {
+				return nil, nil, fmt.Errorf("spec.components is not a map[string]any")
+			}
+			componentValues, unsupportedComponentFields, err := convertIstioOperatorComponents(components)
+			if err != nil {
+				return nil, nil, err
+			}
+			values = MergeOverwrite(values, componentValues)
+			unsupported = append(unsupported, unsupportedComponentFields...)
+		case "values", "meshConfig":
+			// applied below, since they take precedence over the other fields
+		default:
+			unsupported = append(unsupported, "spec."+field)
+		}
+	}
+
+	if specValues, found := spec["values"]; found && specValues != nil {
+		m, ok := specValues.(map[string]any)
+		if !ok {
+			return nil, nil, fmt.Errorf("spec.values is not a map[string]any")
+		}
+		values = MergeOverwrite(values, m)
+	}
+	if meshConfig, found := spec["meshConfig"]; found && meshConfig != nil {
+		m, ok := meshConfig.(map[string]any)
+		if !ok {
+			return nil, nil, fmt.Errorf("spec.meshConfig is not a map[string]any")
+		}
+		values = MergeOverwrite(values, map[string]any{"meshConfig": m})
+	}
+	return values, unsupported, nil
+}
+
+func convertIstioOperatorComponents(components map[string]any) (helm.HelmValues, []string, error) {
+	values := helm.HelmValues{}
+	var unsupported []string
+	for _, name := range sortedKeys(components) {
+		keys, supported := istioOperatorComponents[name]
+		if !supported {
+			unsupported = append(unsupported, "spec.components."+name)
+			continue
+		}
+		if components[name] == nil {
+			continue
+		}
+		component, ok := components[name].(map[string]any)
+		if !ok {
+			return nil, nil, fmt.Errorf("spec.components.%s is not a map[string]any", name)
+		}
+
+		for _, field := range sortedKeys(component) {
+			switch value := component[field]; field {
+			case "enabled":
+				for _, key := range keys {
+					values = MergeOverwrite(values, map[string]any{key: map[string]any{"enabled": value}})
+				}
+			case "hub", "tag":
+				values = MergeOverwrite(values, map[string]any{keys[0]: map[string]any{field: value}})
+			case "k8s":
+				k8s, ok := value.(map[string]any)
+				if !ok {
+					return nil, nil, fmt.Errorf("spec.components.%s.k8s is not a map[string]any", name)
+				}
+				for _, k8sField := range sortedKeys(k8s) {
+					if k8sField == "resources" {
+						values = MergeOverwrite(values, map[string]any{keys[0]: map[string]any{"resources": k8s[k8sField]}})
+					} else {
+						unsupported = append(unsupported, fmt.Sprintf("spec.components.%s.k8s.%s", name, k8sField))
+					}
+				}
+			default:
+				unsupported = append(unsupported, fmt.Sprintf("spec.components.%s.%s", name, field))
+			}
+		}
+	}
+	return values, unsupported, nil
+}
+
+func sortedKeys(m map[string]any) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+	return keys
+}
@@ -0,0 +1,164 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package istiovalues
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"gopkg.in/yaml.v3"
+	"maistra.io/istio-operator/pkg/helm"
+)
+
+func TestConvertIstioOperator(t *testing.T) {
+	tests := []struct {
+		name              string
+		spec              string
+		expectValues      helm.HelmValues
+		expectUnsupported []string
+		expectErr         bool
+	}{
+		{
+			name:         "empty spec",
+			spec:         `{}`,
+			expectValues: helm.HelmValues{},
+		},
+		{
+			name: "hub and tag",
+			spec: `
+hub: quay.io/example
+tag: 1.20.3`,
+			expectValues: helm.HelmValues{
+				"global": map[string]any{"hub": "quay.io/example", "tag": "1.20.3"},
+			},
+		},
+		{
+			name: "meshConfig takes precedence over values.meshConfig",
+			spec: `
+meshConfig:
+  accessLogFile: /dev/stdout
+values:
+  meshConfig:
+    accessLogFile: /dev/null
+    enablePrometheusMerge: true`,
+			expectValues: helm.HelmValues{
+				"meshConfig": map[string]any{"accessLogFile": "/dev/stdout", "enablePrometheusMerge": true},
+			},
+		},
+		{
+			name: "components",
+			spec: `
+components:
+  pilot:
+    hub: quay.io/pilot
+    k8s:
+      resources:
+        requests:
+          cpu: 500m
+  cni:
+    enabled: true
+  ztunnel:
+    enabled: false`,
+			expectValues: helm.HelmValues{
+				"pilot": map[string]any{
+					"hub":       "quay.io/pilot",
+					"resources": map[string]any{"requests": map[string]any{"cpu": "500m"}},
+				},
+				"cni":       map[string]any{"enabled": true},
+				"istio_cni": map[string]any{"enabled": true},
+				"ztunnel":   map[string]any{"enabled": false},
+			},
+		},
+		{
+			name: "values take precedence over components and hub",
+			spec: `
+hub: quay.io/example
+components:
+  pilot:
+    enabled: true
+values:
+  global:
+    hub: docker.io/istio
+  pilot:
+    enabled: false`,
+			expectValues: helm.HelmValues{
+				"global": map[string]any{"hub": "docker.io/istio"},
+				"pilot":  map[string]any{"enabled": false},
+			},
+		},
+		{
+			name: "unsupported fields",
+			spec: `
+profile: demo
+revision: canary
+components:
+  egressGateways:
+  - name: istio-egressgateway
+    enabled: true
+  pilot:
+    namespace: istio-system
+    k8s:
+      hpaSpec:
+        minReplicas: 2
+      resources:
+        limits:
+          memory: 1Gi
+values:
+  pilot:
+    autoscaleEnabled: false`,
+			expectValues: helm.HelmValues{
+				"pilot": map[string]any{
+					"autoscaleEnabled": false,
+					"resources":        map[string]any{"limits": map[string]any{"memory": "1Gi"}},
+				},
+			},
+			expectUnsupported: []string{
+				"spec.components.egressGateways",
+				"spec.components.pilot.k8s.hpaSpec",
+				"spec.components.pilot.namespace",
+				"spec.profile",
+				"spec.revision",
+			},
+		},
+		{
+			name:      "invalid component",
+			spec:      `components: {pilot: true}`,
+			expectErr: true,
+		},
+		{
+			name:      "invalid meshConfig",
+			spec:      `meshConfig: [accessLogFile]`,
+			expectErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var spec map[string]any
+			Must(t, yaml.Unmarshal([]byte(tt.spec), &spec))
+
+			values, unsupported, err := ConvertIstioOperator(spec)
+			if (err != nil) != tt.expectErr {
+				t.Fatalf("ConvertIstioOperator() error = %v, expectErr %v", err, tt.expectErr)
+			}
+			if diff := cmp.Diff(tt.expectValues, values); diff != "" {
+				t.Errorf("unexpected values; diff (-expected, +actual):\n%v", diff)
+			}
+			if diff := cmp.Diff(tt.expectUnsupported, unsupported); diff != "" {
+				t.Errorf("unexpected unsupported fields; diff (-expected, +actual):\n%v", diff)
+			}
+		})
+	}
+}
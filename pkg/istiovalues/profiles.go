@@ -0,0 +1,261 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package istiovalues
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path"
+
+	"gopkg.in/yaml.v3"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"maistra.io/istio-operator/pkg/helm"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"istio.io/istio/pkg/util/sets"
+)
+
+const (
+	// ProfileLabel marks a ConfigMap in the operator namespace as a user-defined profile. The label's value is
+	// the name of the profile.
+	ProfileLabel = "operator.istio.io/profile"
+
+	// ProfileVersionLabel restricts a user-defined profile to the Istio version in the label's value. Profiles
+	// without this label apply to all versions.
+	ProfileVersionLabel = "operator.istio.io/version"
+
+	// ProfileConfigMapKey is the key of the ConfigMap entry that contains a user-defined profile. Its format is the
+	// same as that of the built-in profile files.
+	ProfileConfigMapKey = "profile.yaml"
+)
+
+// MergedProfiles contains the values obtained by merging a list of profiles
+type MergedProfiles struct {
+	// Values contains the merged values of all profiles
+	Values helm.HelmValues
+
+	// Applied lists the profiles in the order in which they were applied. A profile that is listed more than
+	// once is only applied the first time.
+	Applied []string
+
+	// Sources maps each top-level key of Values to the last profile that set it
+	Sources map[string]string
+
+	// Warnings lists the fields of IstioOperator profiles that couldn't be converted to helm values
+	Warnings []string
+}
+
+// GetValuesFromProfiles reads the values from the given profiles and merges them in order, so that values from
+// later profiles overwrite values from earlier ones. Each profile is read from the profiles directory if it
+// contains a file for it, and otherwise from the given profile ConfigMaps, as returned by ListProfileConfigMaps.
+// Built-in profiles thus can't be replaced by user-defined ones.
+func GetValuesFromProfiles(profilesDir string, profiles []string, configMaps []corev1.ConfigMap) (helm.HelmValues, error) {
+	merged, err := MergeProfiles(profilesDir, profiles, configMaps)
+	if err != nil {
+		return nil, err
+	}
+	return merged.Values, nil
+}
+
+// MergeProfiles merges the given profiles like GetValuesFromProfiles does, but also reports the profiles that
+// were applied and the profile each top-level key of the merged values comes from.
+func MergeProfiles(profilesDir string, profiles []string, configMaps []corev1.ConfigMap) (*MergedProfiles, error) {
+	// start with an empty values map
+	merged := &MergedProfiles{
+		Values:  helm.HelmValues{},
+		Sources: map[string]string{},
+	}
+
+	// apply profiles in order, overwriting values from previous profiles
+	alreadyApplied := sets.New[string]()
+	for _, profile := range profiles {
+		if profile == "" {
+			return nil, fmt.Errorf("profile name cannot be empty")
+		}
+		if alreadyApplied.Contains(profile) {
+			continue
+		}
+		alreadyApplied.Insert(profile)
+
+		file := path.Join(profilesDir, profile+".yaml")
+		// prevent path traversal attacks
+		if path.Dir(file) != profilesDir {
+			return nil, fmt.Errorf("invalid profile name %s", profile)
+		}
+
+		profileValues, unsupported, err := getProfileValuesFromFileOrConfigMap(file, configMaps, profile)
+		if err != nil {
+			return nil, err
+		}
+		for _, field := range unsupported {
+			merged.Warnings = append(merged.Warnings, fmt.Sprintf("profile %s: %s is not supported", profile, field))
+		}
+		merged.Values = MergeOverwrite(merged.Values, profileValues)
+		merged.Applied = append(merged.Applied, profile)
+		for key := range profileValues {
+			merged.Sources[key] = profile
+		}
+	}
+
+	return merged, nil
+}
+
+// ListProfileConfigMaps returns the ConfigMaps in the given namespace that contain the user-defined profiles
+// available for the given version
+func ListProfileConfigMaps(ctx context.Context, cl client.Reader, namespace, version string) ([]corev1.ConfigMap, error) {
+	cmList := corev1.ConfigMapList{}
+	if err := cl.List(ctx, &cmList, client.InNamespace(namespace), client.HasLabels{ProfileLabel}); err != nil {
+		return nil, fmt.Errorf("failed to list profile ConfigMaps: %w", err)
+	}
+	var configMaps []corev1.ConfigMap
+	for _, cm := range cmList.Items {
+		if v, found := cm.Labels[ProfileVersionLabel]; !found || v == version {
+			configMaps = append(configMaps, cm)
+		}
+	}
+	return configMaps, nil
+}
+
+// FindProfileConfigMap returns the ConfigMap that contains the given user-defined profile, or nil if there's
+// none. A ConfigMap that is restricted to a version takes precedence over one that applies to all versions.
+// Returns an error if there's more than one candidate with the same precedence.
+func FindProfileConfigMap(configMaps []corev1.ConfigMap, profile string) (*corev1.ConfigMap, error) {
+	var versioned, unversioned []*corev1.ConfigMap
+	for i, cm := range configMaps {
+		if cm.Labels[ProfileLabel] != profile {
+			continue
+		}
+		if _, found := cm.Labels[ProfileVersionLabel]; found {
+			versioned = append(versioned, &configMaps[i])
+		} else {
+			unversioned = append(unversioned, &configMaps[i])
+		}
+	}
+	for _, candidates := range [][]*corev1.ConfigMap{versioned, unversioned} {
+		if len(candidates) > 1 {
+			return nil, fmt.Errorf("profile %s is defined in more than one ConfigMap: %s and %s", profile, candidates[0].Name, candidates[1].Name)
+		} else if len(candidates) == 1 {
+			return candidates[0], nil
+		}
+	}
+	return nil, nil
+}
+
+// ProfileExists returns true if the profiles directory contains a file for the given profile
+func ProfileExists(profilesDir, profile string) bool {
+	file := path.Join(profilesDir, profile+".yaml")
+	return path.Dir(file) == profilesDir && isFile(file)
+}
+
+func isFile(file string) bool {
+	info, err := os.Stat(file)
+	return err == nil && !info.IsDir()
+}
+
+func getProfileValuesFromFileOrConfigMap(file string, configMaps []corev1.ConfigMap, profile string) (helm.HelmValues, []string, error) {
+	if isFile(file) {
+		return getProfileValues(file)
+	}
+	cm, err := FindProfileConfigMap(configMaps, profile)
+	if err != nil {
+		return nil, nil, err
+	} else if cm == nil {
+		// reading the file fails, which is reported as an error
+		return getProfileValues(file)
+	}
+	return getProfileConfigMapValues(cm)
+}
+
+func getProfileValues(file string) (helm.HelmValues, []string, error) {
+	fileContents, err := os.ReadFile(file)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read profile file %v: %v", file, err)
+	}
+	return parseProfile(file, fileContents)
+}
+
+func getProfileConfigMapValues(cm *corev1.ConfigMap) (helm.HelmValues, []string, error) {
+	contents, found := cm.Data[ProfileConfigMapKey]
+	if !found {
+		return nil, nil, fmt.Errorf("profile ConfigMap %s has no %s entry", cm.Name, ProfileConfigMapKey)
+	}
+	return parseProfile("ConfigMap "+cm.Name, []byte(contents))
+}
+
+// parseProfile returns the helm values in the given profile. Profiles in the IstioOperator format are converted
+// to helm values; the paths of the fields that can't be converted are returned along with them.
+func parseProfile(source string, contents []byte) (helm.HelmValues, []string, error) {
+	var profile map[string]any
+	err := yaml.Unmarshal(contents, &profile)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to unmarshal profile YAML %s: %v", source, err)
+	}
+
+	if profile["kind"] == IstioOperatorKind {
+		spec, _, err := unstructured.NestedFieldNoCopy(profile, "spec")
+		if err != nil || spec == nil {
+			return nil, nil, err
+		}
+		m, ok := spec.(map[string]any)
+		if !ok {
+			return nil, nil, fmt.Errorf("spec is not a map[string]any")
+		}
+		values, unsupported, err := ConvertIstioOperator(m)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to convert IstioOperator profile %s: %v", source, err)
+		}
+		return values, unsupported, nil
+	}
+
+	val, found, err := unstructured.NestedFieldNoCopy(profile, "spec", "values")
+	if !found || err != nil {
+		return nil, nil, err
+	}
+	m, ok := val.(map[string]any)
+	if !ok {
+		return nil, nil, fmt.Errorf("spec.values is not a map[string]any")
+	}
+	return m, nil, nil
+}
+
+// MergeOverwrite recursively merges the overrides into the base map. Values from overrides take precedence.
+func MergeOverwrite(base map[string]any, overrides map[string]any) map[string]any {
+	if base == nil {
+		base = make(map[string]any, 1)
+	}
+
+	for key, value := range overrides {
+		// if the key doesn't already exist, add it
+		if _, exists := base[key]; !exists {
+			base[key] = value
+			continue
+		}
+
+		// At this point, key exists in both base and overrides.
+		// If both are maps, recurse so that we override only specific values in the map.
+		// If only override value is a map, overwrite base value completely.
+		// If both are values, overwrite base.
+		childOverrides, overrideValueIsMap := value.(map[string]any)
+		childBase, baseValueIsMap := base[key].(map[string]any)
+		if baseValueIsMap && overrideValueIsMap {
+			base[key] = MergeOverwrite(childBase, childOverrides)
+		} else {
+			base[key] = value
+		}
+	}
+	return base
+}
@@ -0,0 +1,476 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package istiovalues
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"maistra.io/istio-operator/pkg/helm"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestGetValuesFromProfiles(t *testing.T) {
+	const version = "my-version"
+	resourceDir := t.TempDir()
+	profilesDir := path.Join(resourceDir, version, "profiles")
+	Must(t, os.MkdirAll(profilesDir, 0o755))
+
+	writeProfileFile := func(t *testing.T, path string, values ...string) {
+		yaml := `
+apiVersion: operator.istio.io/v1alpha1
+kind: IstioRevision
+spec:
+  values:`
+		for i, val := range values {
+			if val != "" {
+				yaml += fmt.Sprintf(`
+    value%d: %s`, i+1, val)
+			}
+		}
+		Must(t, os.WriteFile(path, []byte(yaml), 0o644))
+	}
+
+	writeProfileFile(t, path.Join(profilesDir, "default.yaml"), "1-from-default", "2-from-default")
+	writeProfileFile(t, path.Join(profilesDir, "overlay.yaml"), "", "2-from-overlay")
+	writeProfileFile(t, path.Join(profilesDir, "custom.yaml"), "1-from-custom")
+	writeProfileFile(t, path.Join(resourceDir, version, "not-in-profiles-dir.yaml"), "should-not-be-accessible")
+
+	tests := []struct {
+		name         string
+		profiles     []string
+		expectValues helm.HelmValues
+		expectErr    bool
+	}{
+		{
+			name:         "nil default profiles",
+			profiles:     nil,
+			expectValues: helm.HelmValues{},
+		},
+		{
+			name:     "default profile only",
+			profiles: []string{"default"},
+			expectValues: helm.HelmValues{
+				"value1": "1-from-default",
+				"value2": "2-from-default",
+			},
+		},
+		{
+			name:     "default and overlay",
+			profiles: []string{"default", "overlay"},
+			expectValues: helm.HelmValues{
+				"value1": "1-from-default",
+				"value2": "2-from-overlay",
+			},
+		},
+		{
+			name:     "default and overlay and custom",
+			profiles: []string{"default", "overlay", "custom"},
+			expectValues: helm.HelmValues{
+				"value1": "1-from-custom",
+				"value2": "2-from-overlay",
+			},
+		},
+		{
+			name:      "default profile empty",
+			profiles:  []string{""},
+			expectErr: true,
+		},
+		{
+			name:      "profile not found",
+			profiles:  []string{"invalid"},
+			expectErr: true,
+		},
+		{
+			name:      "path-traversal-attack",
+			profiles:  []string{"../not-in-profiles-dir"},
+			expectErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			actual, err := GetValuesFromProfiles(profilesDir, tt.profiles, nil)
+			if (err != nil) != tt.expectErr {
+				t.Errorf("applyProfile() error = %v, expectErr %v", err, tt.expectErr)
+			}
+
+			if err == nil {
+				if diff := cmp.Diff(tt.expectValues, actual); diff != "" {
+					t.Errorf("profile wasn't applied properly; diff (-expected, +actual):\n%v", diff)
+				}
+			}
+		})
+	}
+}
+
+func TestMergeProfiles(t *testing.T) {
+	profilesDir := path.Join(t.TempDir(), "profiles")
+	Must(t, os.MkdirAll(profilesDir, 0o755))
+	Must(t, os.WriteFile(path.Join(profilesDir, "default.yaml"), []byte(`
+spec:
+  values:
+    global:
+      hub: default-hub
+    pilot:
+      replicaCount: 1`), 0o644))
+	Must(t, os.WriteFile(path.Join(profilesDir, "openshift.yaml"), []byte(`
+spec:
+  values:
+    cni:
+      enabled: true
+    pilot:
+      replicaCount: 2`), 0o644))
+	Must(t, os.WriteFile(path.Join(profilesDir, "istiooperator.yaml"), []byte(`
+apiVersion: install.istio.io/v1alpha1
+kind: IstioOperator
+spec:
+  hub: quay.io/example
+  components:
+    ingressGateways:
+    - name: istio-ingressgateway
+      enabled: true`), 0o644))
+	configMaps := []corev1.ConfigMap{
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "tenant-a", Labels: map[string]string{ProfileLabel: "tenant-a"}},
+			Data: map[string]string{ProfileConfigMapKey: `
+spec:
+  values:
+    global:
+      logAsJson: true`},
+		},
+	}
+
+	tests := []struct {
+		name           string
+		profiles       []string
+		expectApplied  []string
+		expectSources  map[string]string
+		expectValues   helm.HelmValues
+		expectWarnings []string
+	}{
+		{
+			name:          "no profiles",
+			profiles:      nil,
+			expectSources: map[string]string{},
+			expectValues:  helm.HelmValues{},
+		},
+		{
+			name:          "layered profiles",
+			profiles:      []string{"default", "openshift", "tenant-a"},
+			expectApplied: []string{"default", "openshift", "tenant-a"},
+			expectSources: map[string]string{
+				"global": "tenant-a",
+				"pilot":  "openshift",
+				"cni":    "openshift",
+			},
+			expectValues: helm.HelmValues{
+				"global": map[string]any{"hub": "default-hub", "logAsJson": true},
+				"pilot":  map[string]any{"replicaCount": 2},
+				"cni":    map[string]any{"enabled": true},
+			},
+		},
+		{
+			name:          "IstioOperator profile",
+			profiles:      []string{"default", "istiooperator"},
+			expectApplied: []string{"default", "istiooperator"},
+			expectSources: map[string]string{
+				"global": "istiooperator",
+				"pilot":  "default",
+			},
+			expectValues: helm.HelmValues{
+				"global": map[string]any{"hub": "quay.io/example"},
+				"pilot":  map[string]any{"replicaCount": 1},
+			},
+			expectWarnings: []string{"profile istiooperator: spec.components.ingressGateways is not supported"},
+		},
+		{
+			name:          "duplicate profile is applied once",
+			profiles:      []string{"default", "openshift", "default"},
+			expectApplied: []string{"default", "openshift"},
+			expectSources: map[string]string{
+				"global": "default",
+				"pilot":  "openshift",
+				"cni":    "openshift",
+			},
+			expectValues: helm.HelmValues{
+				"global": map[string]any{"hub": "default-hub"},
+				"pilot":  map[string]any{"replicaCount": 2},
+				"cni":    map[string]any{"enabled": true},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			merged, err := MergeProfiles(profilesDir, tt.profiles, configMaps)
+			if err != nil {
+				t.Fatalf("MergeProfiles() returned an unexpected error: %v", err)
+			}
+			if diff := cmp.Diff(tt.expectApplied, merged.Applied); diff != "" {
+				t.Errorf("unexpected applied profiles; diff (-expected, +actual):\n%v", diff)
+			}
+			if diff := cmp.Diff(tt.expectSources, merged.Sources); diff != "" {
+				t.Errorf("unexpected values sources; diff (-expected, +actual):\n%v", diff)
+			}
+			if diff := cmp.Diff(tt.expectValues, merged.Values); diff != "" {
+				t.Errorf("unexpected values; diff (-expected, +actual):\n%v", diff)
+			}
+			if diff := cmp.Diff(tt.expectWarnings, merged.Warnings); diff != "" {
+				t.Errorf("unexpected warnings; diff (-expected, +actual):\n%v", diff)
+			}
+		})
+	}
+}
+
+func TestGetValuesFromProfileConfigMaps(t *testing.T) {
+	profilesDir := path.Join(t.TempDir(), "profiles")
+	Must(t, os.MkdirAll(profilesDir, 0o755))
+	Must(t, os.WriteFile(path.Join(profilesDir, "default.yaml"), []byte(`
+spec:
+  values:
+    value1: from-default-file`), 0o644))
+
+	newConfigMap := func(name, profile, version, value string) corev1.ConfigMap {
+		labels := map[string]string{ProfileLabel: profile}
+		if version != "" {
+			labels[ProfileVersionLabel] = version
+		}
+		return corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Labels: labels},
+			Data: map[string]string{ProfileConfigMapKey: `
+spec:
+  values:
+    value2: ` + value},
+		}
+	}
+
+	tests := []struct {
+		name         string
+		profiles     []string
+		configMaps   []corev1.ConfigMap
+		expectValues helm.HelmValues
+		expectErr    bool
+	}{
+		{
+			name:         "version-agnostic ConfigMap",
+			profiles:     []string{"default", "hardened"},
+			configMaps:   []corev1.ConfigMap{newConfigMap("hardened", "hardened", "", "from-configmap")},
+			expectValues: helm.HelmValues{"value1": "from-default-file", "value2": "from-configmap"},
+		},
+		{
+			name:     "version-scoped ConfigMap takes precedence over version-agnostic",
+			profiles: []string{"hardened"},
+			configMaps: []corev1.ConfigMap{
+				newConfigMap("hardened", "hardened", "", "from-version-agnostic"),
+				newConfigMap("hardened-v1.20.3", "hardened", "v1.20.3", "from-version-scoped"),
+			},
+			expectValues: helm.HelmValues{"value2": "from-version-scoped"},
+		},
+		{
+			name:         "built-in profile takes precedence over ConfigMap",
+			profiles:     []string{"default"},
+			configMaps:   []corev1.ConfigMap{newConfigMap("default", "default", "", "from-configmap")},
+			expectValues: helm.HelmValues{"value1": "from-default-file"},
+		},
+		{
+			name:     "ambiguous ConfigMaps",
+			profiles: []string{"hardened"},
+			configMaps: []corev1.ConfigMap{
+				newConfigMap("hardened", "hardened", "", "from-first"),
+				newConfigMap("hardened-2", "hardened", "", "from-second"),
+			},
+			expectErr: true,
+		},
+		{
+			name:     "ConfigMap without profile entry",
+			profiles: []string{"hardened"},
+			configMaps: []corev1.ConfigMap{
+				{ObjectMeta: metav1.ObjectMeta{Name: "hardened", Labels: map[string]string{ProfileLabel: "hardened"}}},
+			},
+			expectErr: true,
+		},
+		{
+			name:       "profile not found",
+			profiles:   []string{"high-scale"},
+			configMaps: []corev1.ConfigMap{newConfigMap("hardened", "hardened", "", "from-configmap")},
+			expectErr:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			actual, err := GetValuesFromProfiles(profilesDir, tt.profiles, tt.configMaps)
+			if (err != nil) != tt.expectErr {
+				t.Errorf("GetValuesFromProfiles() error = %v, expectErr %v", err, tt.expectErr)
+			}
+
+			if err == nil {
+				if diff := cmp.Diff(tt.expectValues, actual); diff != "" {
+					t.Errorf("profile wasn't applied properly; diff (-expected, +actual):\n%v", diff)
+				}
+			}
+		})
+	}
+}
+
+func TestListProfileConfigMaps(t *testing.T) {
+	newConfigMap := func(name, namespace string, labels map[string]string) *corev1.ConfigMap {
+		return &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace, Labels: labels}}
+	}
+	cl := fake.NewClientBuilder().
+		WithObjects(
+			newConfigMap("agnostic", "operator", map[string]string{ProfileLabel: "hardened"}),
+			newConfigMap("same-version", "operator", map[string]string{ProfileLabel: "hardened", ProfileVersionLabel: "v1.20.3"}),
+			newConfigMap("other-version", "operator", map[string]string{ProfileLabel: "hardened", ProfileVersionLabel: "v1.19.7"}),
+			newConfigMap("other-namespace", "other", map[string]string{ProfileLabel: "hardened"}),
+			newConfigMap("unlabeled", "operator", nil),
+		).
+		Build()
+
+	configMaps, err := ListProfileConfigMaps(context.TODO(), cl, "operator", "v1.20.3")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var names []string
+	for _, cm := range configMaps {
+		names = append(names, cm.Name)
+	}
+	if diff := cmp.Diff([]string{"agnostic", "same-version"}, names); diff != "" {
+		t.Errorf("unexpected ConfigMaps; diff (-expected, +actual):\n%v", diff)
+	}
+}
+
+func TestMergeOverwrite(t *testing.T) {
+	testCases := []struct {
+		name                    string
+		overrides, base, expect map[string]any
+	}{
+		{
+			name:      "both empty",
+			base:      make(map[string]any),
+			overrides: make(map[string]any),
+			expect:    make(map[string]any),
+		},
+		{
+			name:      "nil overrides",
+			base:      map[string]any{"key1": 42, "key2": "value"},
+			overrides: nil,
+			expect:    map[string]any{"key1": 42, "key2": "value"},
+		},
+		{
+			name:      "nil base",
+			base:      nil,
+			overrides: map[string]any{"key1": 42, "key2": "value"},
+			expect:    map[string]any{"key1": 42, "key2": "value"},
+		},
+		{
+			name: "adds toplevel keys",
+			base: map[string]any{
+				"key2": "from base",
+			},
+			overrides: map[string]any{
+				"key1": "from overrides",
+			},
+			expect: map[string]any{
+				"key1": "from overrides",
+				"key2": "from base",
+			},
+		},
+		{
+			name: "adds nested keys",
+			base: map[string]any{
+				"key1": map[string]any{
+					"nested2": "from base",
+				},
+			},
+			overrides: map[string]any{
+				"key1": map[string]any{
+					"nested1": "from overrides",
+				},
+			},
+			expect: map[string]any{
+				"key1": map[string]any{
+					"nested1": "from overrides",
+					"nested2": "from base",
+				},
+			},
+		},
+		{
+			name: "overrides overrides base",
+			base: map[string]any{
+				"key1": "from base",
+				"key2": map[string]any{
+					"nested1": "from base",
+				},
+			},
+			overrides: map[string]any{
+				"key1": "from overrides",
+				"key2": map[string]any{
+					"nested1": "from overrides",
+				},
+			},
+			expect: map[string]any{
+				"key1": "from overrides",
+				"key2": map[string]any{
+					"nested1": "from overrides",
+				},
+			},
+		},
+		{
+			name: "mismatched types",
+			base: map[string]any{
+				"key1": map[string]any{
+					"desc": "key1 is a map in base",
+				},
+				"key2": "key2 is a string in base",
+			},
+			overrides: map[string]any{
+				"key1": "key1 is a string in overrides",
+				"key2": map[string]any{
+					"desc": "key2 is a map in overrides",
+				},
+			},
+			expect: map[string]any{
+				"key1": "key1 is a string in overrides",
+				"key2": map[string]any{
+					"desc": "key2 is a map in overrides",
+				},
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			result := MergeOverwrite(tc.base, tc.overrides)
+			if diff := cmp.Diff(tc.expect, result); diff != "" {
+				t.Errorf("unexpected merge result; diff (-expected, +actual):\n%v", diff)
+			}
+		})
+	}
+}
+
+func Must(t *testing.T, err error) {
+	t.Helper()
+	if err != nil {
+		t.Fatal(err)
+	}
+}
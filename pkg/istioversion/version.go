@@ -0,0 +1,122 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package istioversion
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"regexp"
+	"sort"
+
+	"github.com/Masterminds/semver/v3"
+	"gopkg.in/yaml.v3"
+)
+
+// StableAlias refers to the most recent release (i.e. non-prerelease) version available in the resource directory.
+const StableAlias = "stable"
+
+// minorAliasRegexp matches minor version aliases such as "v1.20", which refer to the most recent patch release of that
+// minor version.
+var minorAliasRegexp = regexp.MustCompile(`^v\d+\.\d+$`)
+
+// Resolve returns the concrete version that the given version refers to. If the resource directory contains a
+// directory with the exact name of the given version, the version is returned as is. Otherwise, the version is
+// treated as an alias: "stable" resolves to the most recent release in the resource directory, while a minor
+// alias such as "v1.20" resolves to the most recent patch release of that minor version.
+func Resolve(resourceDir, version string) (string, error) {
+	if version == "" {
+		return "", fmt.Errorf("no version specified")
+	}
+
+	dir := path.Join(resourceDir, version)
+	// prevent path traversal attacks
+	if path.Dir(dir) != path.Clean(resourceDir) {
+		return "", fmt.Errorf("invalid version %s", version)
+	}
+	if info, err := os.Stat(dir); err == nil && info.IsDir() {
+		return version, nil
+	}
+
+	releases, err := listReleases(resourceDir)
+	if err != nil {
+		return "", err
+	}
+
+	if version == StableAlias {
+		if len(releases) > 0 {
+			return releases[0].Original(), nil
+		}
+	} else if minorAliasRegexp.MatchString(version) {
+		for _, v := range releases {
+			if fmt.Sprintf("v%d.%d", v.Major(), v.Minor()) == version {
+				return v.Original(), nil
+			}
+		}
+	}
+	return "", fmt.Errorf("unsupported version %s", version)
+}
+
+// Semver returns the semantic version of the given version. If the version name itself isn't a semantic version
+// (e.g. "latest"), the version is read from the istiod chart in the resource directory.
+func Semver(resourceDir, version string) (*semver.Version, error) {
+	if v, err := semver.NewVersion(version); err == nil {
+		return v, nil
+	}
+
+	file := path.Join(resourceDir, version, "charts", "istiod", "Chart.yaml")
+	// prevent path traversal attacks
+	if path.Dir(path.Join(resourceDir, version)) != path.Clean(resourceDir) {
+		return nil, fmt.Errorf("invalid version %s", version)
+	}
+	contents, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to determine semantic version of %s: %v", version, err)
+	}
+	var chart struct {
+		Version string `yaml:"version"`
+	}
+	if err := yaml.Unmarshal(contents, &chart); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal %s: %v", file, err)
+	}
+	v, err := semver.NewVersion(chart.Version)
+	if err != nil {
+		return nil, fmt.Errorf("failed to determine semantic version of %s: %v", version, err)
+	}
+	return v, nil
+}
+
+// listReleases returns the release versions in the resource directory, with the most recent version first.
+// Directories whose name isn't a semantic version (e.g. "latest") and prerelease versions are ignored.
+func listReleases(resourceDir string) ([]*semver.Version, error) {
+	entries, err := os.ReadDir(resourceDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read resource directory %s: %v", resourceDir, err)
+	}
+
+	var releases []*semver.Version
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		v, err := semver.NewVersion(entry.Name())
+		if err != nil || v.Prerelease() != "" {
+			continue
+		}
+		releases = append(releases, v)
+	}
+	sort.Sort(sort.Reverse(semver.Collection(releases)))
+	return releases, nil
+}
@@ -0,0 +1,119 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package istioversion
+
+import (
+	"os"
+	"path"
+	"testing"
+)
+
+func TestResolve(t *testing.T) {
+	resourceDir := t.TempDir()
+	for _, dir := range []string{"v1.19.6", "v1.19.10", "v1.20.1", "v1.20.3", "v1.21.0-alpha.1", "latest", "gwAPIControllerMode"} {
+		Must(t, os.MkdirAll(path.Join(resourceDir, dir), 0o755))
+	}
+	Must(t, os.WriteFile(path.Join(resourceDir, "v1.22.0"), []byte{}, 0o644))
+
+	tests := []struct {
+		version         string
+		expectedVersion string
+		expectErr       bool
+	}{
+		{version: "v1.20.1", expectedVersion: "v1.20.1"},
+		{version: "latest", expectedVersion: "latest"},
+		{version: "gwAPIControllerMode", expectedVersion: "gwAPIControllerMode"},
+		{version: "v1.21.0-alpha.1", expectedVersion: "v1.21.0-alpha.1"},
+		{version: "stable", expectedVersion: "v1.20.3"},
+		{version: "v1.20", expectedVersion: "v1.20.3"},
+		{version: "v1.19", expectedVersion: "v1.19.10"},
+		{version: "v1.21", expectErr: true},
+		{version: "v1.18", expectErr: true},
+		{version: "v1.22.0", expectErr: true},
+		{version: "v1.20.2", expectErr: true},
+		{version: "../" + path.Base(resourceDir), expectErr: true},
+		{version: "", expectErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.version, func(t *testing.T) {
+			version, err := Resolve(resourceDir, tt.version)
+			if tt.expectErr {
+				if err == nil {
+					t.Errorf("expected error, but got none")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("expected no error, but got: %v", err)
+			}
+			if version != tt.expectedVersion {
+				t.Errorf("expected version %q, but got %q", tt.expectedVersion, version)
+			}
+		})
+	}
+}
+
+func TestResolveWithoutReleases(t *testing.T) {
+	resourceDir := t.TempDir()
+	Must(t, os.MkdirAll(path.Join(resourceDir, "latest"), 0o755))
+
+	if _, err := Resolve(resourceDir, StableAlias); err == nil {
+		t.Errorf("expected error, but got none")
+	}
+}
+
+func TestSemver(t *testing.T) {
+	resourceDir := t.TempDir()
+	chartDir := path.Join(resourceDir, "latest", "charts", "istiod")
+	Must(t, os.MkdirAll(chartDir, 0o755))
+	Must(t, os.WriteFile(path.Join(chartDir, "Chart.yaml"), []byte("apiVersion: v1\nname: istiod\nversion: 1.22-alpha.d27e3e16\n"), 0o644))
+	Must(t, os.MkdirAll(path.Join(resourceDir, "nochart"), 0o755))
+
+	tests := []struct {
+		version         string
+		expectedVersion string
+		expectErr       bool
+	}{
+		{version: "v1.20.3", expectedVersion: "1.20.3"},
+		{version: "latest", expectedVersion: "1.22.0-alpha.d27e3e16"},
+		{version: "nochart", expectErr: true},
+		{version: "nonexistent", expectErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.version, func(t *testing.T) {
+			v, err := Semver(resourceDir, tt.version)
+			if tt.expectErr {
+				if err == nil {
+					t.Errorf("expected error, but got none")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("expected no error, but got: %v", err)
+			}
+			if v.String() != tt.expectedVersion {
+				t.Errorf("expected version %q, but got %q", tt.expectedVersion, v.String())
+			}
+		})
+	}
+}
+
+func Must(t *testing.T, err error) {
+	if err != nil {
+		t.Fatal(err)
+	}
+}
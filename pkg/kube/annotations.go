@@ -0,0 +1,32 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kube
+
+import (
+	"maistra.io/istio-operator/pkg/common"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// IsPaused returns true if the reconciliation of the given object has been paused by
+// setting the common.PausedKey annotation to "true".
+func IsPaused(obj client.Object) bool {
+	return obj.GetAnnotations()[common.PausedKey] == "true"
+}
+
+// IsDryRun returns true if the dry-run mode has been enabled for the given object by
+// setting the common.DryRunKey annotation to "true".
+func IsDryRun(obj client.Object) bool {
+	return obj.GetAnnotations()[common.DryRunKey] == "true"
+}
@@ -0,0 +1,115 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kube
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// DefaultEventDeduplicationInterval is the interval within which an Event identical to one that was already
+// recorded for the same object is suppressed
+const DefaultEventDeduplicationInterval = 30 * time.Minute
+
+// EventRecorder records Events for the resources reconciled by the operator. Unlike the recorder it wraps, it
+// suppresses an Event if an identical Event was recorded for the same object within the deduplication
+// interval, so that reconciling a resource whose state doesn't change doesn't produce a stream of Events.
+// A nil EventRecorder, or one that wraps a nil recorder, discards all Events.
+type EventRecorder struct {
+	recorder record.EventRecorder
+	interval time.Duration
+	now      func() time.Time
+
+	mu       sync.Mutex
+	recorded map[eventKey]time.Time
+}
+
+type eventKey struct {
+	uid       types.UID
+	eventType string
+	reason    string
+	message   string
+}
+
+// NewEventRecorder returns an EventRecorder that records the Events through the given recorder
+func NewEventRecorder(recorder record.EventRecorder) *EventRecorder {
+	return &EventRecorder{
+		recorder: recorder,
+		interval: DefaultEventDeduplicationInterval,
+		now:      time.Now,
+		recorded: map[eventKey]time.Time{},
+	}
+}
+
+// Event records an Event for the given object, unless an identical Event was recently recorded for it. Use it
+// for Events that are emitted on every reconciliation while a condition persists, e.g. a failure.
+func (r *EventRecorder) Event(obj client.Object, eventType, reason, message string) {
+	if r == nil || r.recorder == nil {
+		return
+	}
+
+	key := eventKey{uid: obj.GetUID(), eventType: eventType, reason: reason, message: message}
+	now := r.now()
+
+	r.mu.Lock()
+	if lastRecorded, found := r.recorded[key]; found && now.Sub(lastRecorded) < r.interval {
+		r.mu.Unlock()
+		return
+	}
+	r.recorded[key] = now
+	// forget the Events that can no longer suppress any other Event
+	for k, t := range r.recorded {
+		if now.Sub(t) >= r.interval {
+			delete(r.recorded, k)
+		}
+	}
+	r.mu.Unlock()
+
+	r.recorder.Event(obj, eventType, reason, message)
+}
+
+// Eventf is like Event, but formats the message using fmt.Sprintf
+func (r *EventRecorder) Eventf(obj client.Object, eventType, reason, messageFmt string, args ...any) {
+	r.Event(obj, eventType, reason, fmt.Sprintf(messageFmt, args...))
+}
+
+// TransitionEvent records an Event for a change of the object's state, e.g. a successful upgrade. Since such
+// Events are only emitted when the state changes, they aren't deduplicated. Recording one also forgets the
+// object's previously recorded Events, so that a recurring failure is reported again once the state changed.
+func (r *EventRecorder) TransitionEvent(obj client.Object, eventType, reason, message string) {
+	if r == nil || r.recorder == nil {
+		return
+	}
+
+	r.mu.Lock()
+	for k := range r.recorded {
+		if k.uid == obj.GetUID() {
+			delete(r.recorded, k)
+		}
+	}
+	r.mu.Unlock()
+
+	r.recorder.Event(obj, eventType, reason, message)
+}
+
+// TransitionEventf is like TransitionEvent, but formats the message using fmt.Sprintf
+func (r *EventRecorder) TransitionEventf(obj client.Object, eventType, reason, messageFmt string, args ...any) {
+	r.TransitionEvent(obj, eventType, reason, fmt.Sprintf(messageFmt, args...))
+}
@@ -0,0 +1,79 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kube
+
+import (
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+)
+
+func TestEventRecorder(t *testing.T) {
+	obj := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "test", UID: "uid-1"}}
+	other := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "other", UID: "uid-2"}}
+
+	fakeRecorder := record.NewFakeRecorder(10)
+	now := time.Now()
+	recorder := NewEventRecorder(fakeRecorder)
+	recorder.now = func() time.Time { return now }
+
+	recorder.Event(obj, corev1.EventTypeWarning, "Failed", "some error")
+	recorder.Event(obj, corev1.EventTypeWarning, "Failed", "some error")
+	recorder.Event(other, corev1.EventTypeWarning, "Failed", "some error")
+	recorder.Event(obj, corev1.EventTypeWarning, "Failed", "another error")
+
+	now = now.Add(DefaultEventDeduplicationInterval)
+	recorder.Event(obj, corev1.EventTypeWarning, "Failed", "some error")
+
+	recorder.TransitionEvent(obj, corev1.EventTypeNormal, "Succeeded", "done")
+	recorder.TransitionEvent(obj, corev1.EventTypeNormal, "Succeeded", "done")
+	recorder.Event(obj, corev1.EventTypeWarning, "Failed", "some error")
+
+	expected := []string{
+		"Warning Failed some error",
+		"Warning Failed some error",
+		"Warning Failed another error",
+		"Warning Failed some error",
+		"Normal Succeeded done",
+		"Normal Succeeded done",
+		"Warning Failed some error",
+	}
+	for _, e := range expected {
+		select {
+		case event := <-fakeRecorder.Events:
+			if event != e {
+				t.Errorf("expected event %q, but got %q", e, event)
+			}
+		default:
+			t.Fatalf("expected event %q, but no more events were recorded", e)
+		}
+	}
+	if len(fakeRecorder.Events) > 0 {
+		t.Errorf("expected no more events, but got %q", <-fakeRecorder.Events)
+	}
+}
+
+func TestNilEventRecorder(t *testing.T) {
+	obj := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "test"}}
+
+	var recorder *EventRecorder
+	recorder.Event(obj, corev1.EventTypeNormal, "Reason", "message")
+	recorder.TransitionEvent(obj, corev1.EventTypeNormal, "Reason", "message")
+
+	NewEventRecorder(nil).Eventf(obj, corev1.EventTypeNormal, "Reason", "message %d", 1)
+}
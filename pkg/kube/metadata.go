@@ -0,0 +1,69 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kube
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	toolscache "k8s.io/client-go/tools/cache"
+)
+
+// the kinds whose metadata is cached by the operator
+var (
+	PodGVK       = corev1.SchemeGroupVersion.WithKind("Pod")
+	NamespaceGVK = corev1.SchemeGroupVersion.WithKind("Namespace")
+)
+
+// NewMetadata returns an empty PartialObjectMetadata of the given kind. When it's read through the manager's
+// client, it's served by a metadata-only informer, which doesn't cache the spec and status of the objects.
+func NewMetadata(gvk schema.GroupVersionKind) *metav1.PartialObjectMetadata {
+	obj := &metav1.PartialObjectMetadata{}
+	obj.SetGroupVersionKind(gvk)
+	return obj
+}
+
+// NewMetadataList returns an empty PartialObjectMetadataList for objects of the given kind
+func NewMetadataList(gvk schema.GroupVersionKind) *metav1.PartialObjectMetadataList {
+	list := &metav1.PartialObjectMetadataList{}
+	list.SetGroupVersionKind(gvk.GroupVersion().WithKind(gvk.Kind + "List"))
+	return list
+}
+
+// StripMetadata returns a cache transform that drops the managed fields of an object and all its annotations
+// except the given ones. It's meant for informers whose consumers only read the labels and a few annotations,
+// where it reduces the memory used by the cache.
+func StripMetadata(keepAnnotations ...string) toolscache.TransformFunc {
+	return func(obj any) (any, error) {
+		accessor, err := meta.Accessor(obj)
+		if err != nil {
+			// e.g. a DeletedFinalStateUnknown tombstone, which we leave as is
+			return obj, nil
+		}
+		accessor.SetManagedFields(nil)
+		var annotations map[string]string
+		for _, key := range keepAnnotations {
+			if value, found := accessor.GetAnnotations()[key]; found {
+				if annotations == nil {
+					annotations = map[string]string{}
+				}
+				annotations[key] = value
+			}
+		}
+		accessor.SetAnnotations(annotations)
+		return obj, nil
+	}
+}
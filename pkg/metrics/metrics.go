@@ -0,0 +1,159 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package metrics defines the operator's Prometheus metrics. They are registered with the controller-runtime
+// registry and thus exposed on the manager's metrics endpoint alongside the controller-runtime metrics.
+package metrics
+
+import (
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+const metricsNamespace = "sail_operator"
+
+// operations reported in the operation label of the Helm metrics
+const (
+	HelmOperationInstall   = "install"
+	HelmOperationUpgrade   = "upgrade"
+	HelmOperationUninstall = "uninstall"
+)
+
+// states reported in the state label of the IstioRevisions metric
+const (
+	RevisionStateTotal = "total"
+	RevisionStateReady = "ready"
+	RevisionStateInUse = "in_use"
+)
+
+var (
+	// IstioRevisions is the number of IstioRevisions of each Istio, by state. It mirrors status.revisions.
+	IstioRevisions = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Name:      "istio_revisions",
+		Help:      "Number of IstioRevisions associated with an Istio, by state",
+	}, []string{"istio", "state"})
+
+	// HelmOperationDuration is the duration of the Helm operations performed on the charts
+	HelmOperationDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: metricsNamespace,
+		Name:      "helm_operation_duration_seconds",
+		Help:      "Duration of the Helm operations performed by the operator, by chart, chart version and operation",
+		Buckets:   []float64{0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60, 120},
+	}, []string{"chart", "version", "operation"})
+
+	// HelmOperationFailures is the number of Helm operations that failed
+	HelmOperationFailures = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "helm_operation_failures_total",
+		Help:      "Number of failed Helm operations, by chart, chart version and operation",
+	}, []string{"chart", "version", "operation"})
+
+	// IstioRevisionTimeToReady is the time it takes an IstioRevision to become Ready after its spec changed
+	IstioRevisionTimeToReady = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Namespace: metricsNamespace,
+		Name:      "istiorevision_time_to_ready_seconds",
+		Help:      "Time between the operator observing a change of an IstioRevision's spec and the IstioRevision becoming Ready",
+		Buckets:   []float64{5, 10, 30, 60, 120, 300, 600, 1200, 1800, 3600},
+	})
+
+	// IstioRevisionPruneDeadline is the time at which an inactive IstioRevision that is no longer in use is pruned
+	IstioRevisionPruneDeadline = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Name:      "istiorevision_prune_deadline_timestamp_seconds",
+		Help:      "Unix time at which an inactive IstioRevision that is no longer in use will be pruned",
+	}, []string{"istio", "revision"})
+
+	// IstioRevisionPods is the number of pods that reference each IstioRevision
+	IstioRevisionPods = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Name:      "istiorevision_pods",
+		Help:      "Number of pods that reference an IstioRevision, either directly or through an IstioRevisionTag",
+	}, []string{"revision"})
+)
+
+func init() {
+	metrics.Registry.MustRegister(
+		IstioRevisions,
+		HelmOperationDuration,
+		HelmOperationFailures,
+		IstioRevisionTimeToReady,
+		IstioRevisionPruneDeadline,
+		IstioRevisionPods,
+	)
+}
+
+// ObserveHelmOperation records the duration and the outcome of a Helm operation that started at the given time
+func ObserveHelmOperation(chart, version, operation string, start time.Time, err error) {
+	HelmOperationDuration.WithLabelValues(chart, version, operation).Observe(time.Since(start).Seconds())
+	if err != nil {
+		HelmOperationFailures.WithLabelValues(chart, version, operation).Inc()
+	}
+}
+
+// SetIstioRevisions records the number of IstioRevisions of the given Istio
+func SetIstioRevisions(istio string, total, ready, inUse int32) {
+	IstioRevisions.WithLabelValues(istio, RevisionStateTotal).Set(float64(total))
+	IstioRevisions.WithLabelValues(istio, RevisionStateReady).Set(float64(ready))
+	IstioRevisions.WithLabelValues(istio, RevisionStateInUse).Set(float64(inUse))
+}
+
+// ForgetIstio removes the metrics of the given Istio, e.g. after it was deleted
+func ForgetIstio(istio string) {
+	IstioRevisions.DeletePartialMatch(prometheus.Labels{"istio": istio})
+	IstioRevisionPruneDeadline.DeletePartialMatch(prometheus.Labels{"istio": istio})
+}
+
+// specChange records when the operator first observed a generation of an IstioRevision's spec
+type specChange struct {
+	generation int64
+	observed   time.Time
+}
+
+var specChanges = struct {
+	sync.Mutex
+	revisions map[string]specChange
+}{revisions: map[string]specChange{}}
+
+// RevisionSpecChanged records that the operator observed the given generation of the IstioRevision's spec for
+// the first time. The time it takes the revision to become Ready is measured from this point on.
+func RevisionSpecChanged(revision string, generation int64) {
+	specChanges.Lock()
+	defer specChanges.Unlock()
+	if change, found := specChanges.revisions[revision]; !found || change.generation != generation {
+		specChanges.revisions[revision] = specChange{generation: generation, observed: time.Now()}
+	}
+}
+
+// RevisionReady records that the given generation of the IstioRevision is Ready. If the operator observed the
+// change of the spec to this generation, the time it took the revision to become Ready is recorded.
+func RevisionReady(revision string, generation int64) {
+	specChanges.Lock()
+	defer specChanges.Unlock()
+	if change, found := specChanges.revisions[revision]; found && change.generation == generation {
+		IstioRevisionTimeToReady.Observe(time.Since(change.observed).Seconds())
+		delete(specChanges.revisions, revision)
+	}
+}
+
+// ForgetRevision removes the metrics of the given IstioRevision, e.g. after it was deleted
+func ForgetRevision(revision string) {
+	IstioRevisionPods.DeleteLabelValues(revision)
+	specChanges.Lock()
+	delete(specChanges.revisions, revision)
+	specChanges.Unlock()
+}
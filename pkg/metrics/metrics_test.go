@@ -0,0 +1,97 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package metrics
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	dto "github.com/prometheus/client_model/go"
+)
+
+func TestObserveHelmOperation(t *testing.T) {
+	HelmOperationDuration.Reset()
+	HelmOperationFailures.Reset()
+
+	ObserveHelmOperation("istiod", "1.20.0", HelmOperationInstall, time.Now(), nil)
+	ObserveHelmOperation("istiod", "1.20.0", HelmOperationUpgrade, time.Now(), errors.New("some error"))
+
+	if count := testutil.CollectAndCount(HelmOperationDuration); count != 2 {
+		t.Errorf("expected durations of 2 operations, but got %d", count)
+	}
+	if value := testutil.ToFloat64(HelmOperationFailures.WithLabelValues("istiod", "1.20.0", HelmOperationUpgrade)); value != 1 {
+		t.Errorf("expected 1 failed upgrade, but got %v", value)
+	}
+	if count := testutil.CollectAndCount(HelmOperationFailures); count != 1 {
+		t.Errorf("expected only the failed upgrade to be counted, but got %d series", count)
+	}
+}
+
+func TestIstioMetrics(t *testing.T) {
+	IstioRevisions.Reset()
+	IstioRevisionPruneDeadline.Reset()
+
+	SetIstioRevisions("default", 3, 2, 1)
+	SetIstioRevisions("other", 1, 1, 1)
+	IstioRevisionPruneDeadline.WithLabelValues("default", "default-v1-20-0").Set(1)
+
+	if value := testutil.ToFloat64(IstioRevisions.WithLabelValues("default", RevisionStateReady)); value != 2 {
+		t.Errorf("expected 2 ready revisions, but got %v", value)
+	}
+
+	ForgetIstio("default")
+	if count := testutil.CollectAndCount(IstioRevisions); count != 3 {
+		t.Errorf("expected only the metrics of the other Istio to remain, but got %d series", count)
+	}
+	if count := testutil.CollectAndCount(IstioRevisionPruneDeadline); count != 0 {
+		t.Errorf("expected no prune deadlines, but got %d series", count)
+	}
+}
+
+func TestTimeToReady(t *testing.T) {
+	countObservations := func() uint64 {
+		metric := &dto.Metric{}
+		if err := IstioRevisionTimeToReady.Write(metric); err != nil {
+			t.Fatal(err)
+		}
+		return metric.GetHistogram().GetSampleCount()
+	}
+
+	RevisionReady("my-rev", 1)
+	if count := countObservations(); count != 0 {
+		t.Fatalf("expected no observations for a revision whose spec change wasn't observed, but got %d", count)
+	}
+
+	RevisionSpecChanged("my-rev", 1)
+	RevisionReady("my-rev", 2)
+	if count := countObservations(); count != 0 {
+		t.Fatalf("expected no observations for a different generation, but got %d", count)
+	}
+
+	RevisionReady("my-rev", 1)
+	RevisionReady("my-rev", 1)
+	if count := countObservations(); count != 1 {
+		t.Fatalf("expected 1 observation, but got %d", count)
+	}
+
+	RevisionSpecChanged("my-rev", 2)
+	ForgetRevision("my-rev")
+	RevisionReady("my-rev", 2)
+	if count := countObservations(); count != 1 {
+		t.Fatalf("expected no observations for a forgotten revision, but got %d", count)
+	}
+}
@@ -0,0 +1,67 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package revision
+
+import (
+	"context"
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"maistra.io/istio-operator/api/v1alpha1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// NotFoundError is returned when the referenced object doesn't exist, or the referenced Istio has no active
+// revision yet. Retrying won't help until the referenced objects change.
+type NotFoundError struct {
+	message string
+}
+
+func (e *NotFoundError) Error() string {
+	return e.message
+}
+
+// GetTargetRevision returns the IstioRevision that the given reference points to. If it references an Istio,
+// this is the Istio's active revision.
+func GetTargetRevision(ctx context.Context, cl client.Reader, ref v1alpha1.TargetReference) (*v1alpha1.IstioRevision, error) {
+	revName := ref.Name
+	switch ref.Kind {
+	case v1alpha1.IstioKind:
+		istio := v1alpha1.Istio{}
+		if err := cl.Get(ctx, types.NamespacedName{Name: ref.Name}, &istio); err != nil {
+			if errors.IsNotFound(err) {
+				return nil, &NotFoundError{message: fmt.Sprintf("referenced Istio %q not found", ref.Name)}
+			}
+			return nil, err
+		}
+		if istio.Status.ActiveRevisionName == "" {
+			return nil, &NotFoundError{message: fmt.Sprintf("referenced Istio %q has no active revision", istio.Name)}
+		}
+		revName = istio.Status.ActiveRevisionName
+	case v1alpha1.IstioRevisionKind:
+	default:
+		return nil, fmt.Errorf("unsupported targetRef kind %q", ref.Kind)
+	}
+
+	rev := v1alpha1.IstioRevision{}
+	if err := cl.Get(ctx, types.NamespacedName{Name: revName}, &rev); err != nil {
+		if errors.IsNotFound(err) {
+			return nil, &NotFoundError{message: fmt.Sprintf("IstioRevision %q not found", revName)}
+		}
+		return nil, err
+	}
+	return &rev, nil
+}
@@ -0,0 +1,113 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package revision
+
+import (
+	"context"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/kubectl/pkg/scheme"
+	"maistra.io/istio-operator/api/v1alpha1"
+	"maistra.io/istio-operator/pkg/test"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestGetTargetRevision(t *testing.T) {
+	test.SetupScheme()
+
+	cl := fake.NewClientBuilder().
+		WithScheme(scheme.Scheme).
+		WithObjects(
+			&v1alpha1.Istio{
+				ObjectMeta: metav1.ObjectMeta{Name: "my-istio"},
+				Status:     v1alpha1.IstioStatus{ActiveRevisionName: "my-istio-1-21-0"},
+			},
+			&v1alpha1.Istio{ObjectMeta: metav1.ObjectMeta{Name: "new-istio"}},
+			&v1alpha1.IstioRevision{ObjectMeta: metav1.ObjectMeta{Name: "my-istio-1-20-0"}},
+			&v1alpha1.IstioRevision{ObjectMeta: metav1.ObjectMeta{Name: "my-istio-1-21-0"}},
+		).
+		Build()
+
+	testCases := []struct {
+		name             string
+		ref              v1alpha1.TargetReference
+		expectedRevision string
+		expectedNotFound string
+		expectedErr      bool
+	}{
+		{
+			name:             "IstioRevision reference",
+			ref:              v1alpha1.TargetReference{Kind: v1alpha1.IstioRevisionKind, Name: "my-istio-1-20-0"},
+			expectedRevision: "my-istio-1-20-0",
+		},
+		{
+			name:             "Istio reference resolves to active revision",
+			ref:              v1alpha1.TargetReference{Kind: v1alpha1.IstioKind, Name: "my-istio"},
+			expectedRevision: "my-istio-1-21-0",
+		},
+		{
+			name:             "missing IstioRevision",
+			ref:              v1alpha1.TargetReference{Kind: v1alpha1.IstioRevisionKind, Name: "missing"},
+			expectedNotFound: `IstioRevision "missing" not found`,
+		},
+		{
+			name:             "missing Istio",
+			ref:              v1alpha1.TargetReference{Kind: v1alpha1.IstioKind, Name: "missing"},
+			expectedNotFound: `referenced Istio "missing" not found`,
+		},
+		{
+			name:             "Istio without active revision",
+			ref:              v1alpha1.TargetReference{Kind: v1alpha1.IstioKind, Name: "new-istio"},
+			expectedNotFound: `referenced Istio "new-istio" has no active revision`,
+		},
+		{
+			name:        "unsupported kind",
+			ref:         v1alpha1.TargetReference{Kind: "IstioCNI", Name: "default"},
+			expectedErr: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			rev, err := GetTargetRevision(context.TODO(), cl, tc.ref)
+			if tc.expectedNotFound != "" {
+				notFoundErr, ok := err.(*NotFoundError)
+				if !ok {
+					t.Fatalf("expected NotFoundError, but got: %v", err)
+				}
+				if notFoundErr.Error() != tc.expectedNotFound {
+					t.Errorf("expected message %q, but got %q", tc.expectedNotFound, notFoundErr.Error())
+				}
+				return
+			}
+			if tc.expectedErr {
+				if err == nil {
+					t.Fatal("expected an error, but got none")
+				}
+				if _, ok := err.(*NotFoundError); ok {
+					t.Errorf("expected an error other than NotFoundError, but got: %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if rev.Name != tc.expectedRevision {
+				t.Errorf("expected revision %s, but got %s", tc.expectedRevision, rev.Name)
+			}
+		})
+	}
+}
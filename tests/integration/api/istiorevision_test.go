@@ -48,6 +48,7 @@ var _ = Describe("IstioRevision resource", Ordered, func() {
 	}
 
 	revKey := client.ObjectKey{Name: revName}
+	cniResourceKey := client.ObjectKey{Name: v1alpha1.IstioCNIName}
 	istiodKey := client.ObjectKey{Name: "istiod-" + revName, Namespace: istioNamespace}
 	cniKey := client.ObjectKey{Name: "istio-cni-node", Namespace: operatorNamespace}
 	webhookKey := client.ObjectKey{Name: "istio-sidecar-injector-" + revName + "-" + istioNamespace}
@@ -69,6 +70,13 @@ var _ = Describe("IstioRevision resource", Ordered, func() {
 			g.Expect(k8sClient.List(ctx, list)).To(Succeed())
 			g.Expect(list.Items).To(BeEmpty())
 		}).Should(Succeed())
+
+		Expect(k8sClient.DeleteAllOf(ctx, &v1alpha1.IstioCNI{})).To(Succeed())
+		Eventually(func(g Gomega) {
+			list := &v1alpha1.IstioCNIList{}
+			g.Expect(k8sClient.List(ctx, list)).To(Succeed())
+			g.Expect(list.Items).To(BeEmpty())
+		}).Should(Succeed())
 	})
 
 	rev := &v1alpha1.IstioRevision{}
@@ -195,6 +203,25 @@ var _ = Describe("IstioRevision resource", Ordered, func() {
 			g.Expect(k8sClient.Get(ctx, revKey, rev)).To(Succeed())
 			g.Expect(rev.Status.ObservedGeneration).To(Equal(rev.ObjectMeta.Generation))
 		}).Should(Succeed())
+
+		Step("Checking that the Ready condition reports the missing IstioCNI")
+		Eventually(func(g Gomega) {
+			g.Expect(k8sClient.Get(ctx, revKey, rev)).To(Succeed())
+			readyCondition := rev.Status.GetCondition(v1alpha1.IstioRevisionConditionTypeReady)
+			g.Expect(readyCondition.Reason).To(Equal(v1alpha1.IstioRevisionConditionReasonCNINotReady))
+		}).Should(Succeed())
+
+		Step("Creating the IstioCNI resource")
+		cni := &v1alpha1.IstioCNI{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: cniResourceKey.Name,
+			},
+			Spec: v1alpha1.IstioCNISpec{
+				Version: defaultVersion,
+			},
+		}
+		Expect(k8sClient.Create(ctx, cni)).To(Succeed())
+		Eventually(k8sClient.Get).WithArguments(ctx, cniKey, &appsv1.DaemonSet{}).Should(Succeed())
 	})
 
 	When("istiod and istio-cni-node readiness changes", func() {
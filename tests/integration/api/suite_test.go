@@ -26,11 +26,15 @@ import (
 	"k8s.io/client-go/rest"
 	"k8s.io/kubectl/pkg/scheme"
 	"maistra.io/istio-operator/controllers/istio"
+	"maistra.io/istio-operator/controllers/istiocni"
+	"maistra.io/istio-operator/controllers/istiogateway"
 	"maistra.io/istio-operator/controllers/istiorevision"
+	"maistra.io/istio-operator/controllers/istiorevisiontag"
 	"maistra.io/istio-operator/pkg/common"
 	"maistra.io/istio-operator/pkg/helm"
 	"maistra.io/istio-operator/pkg/test"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/envtest"
 	logf "sigs.k8s.io/controller-runtime/pkg/log"
@@ -60,20 +64,34 @@ var _ = BeforeSuite(func() {
 
 	helm.ResourceDirectory = path.Join(common.RepositoryRoot, "resources")
 
+	// the reconcilers use the manager's cached client, since they look up namespaces and pods through the
+	// field indexes, which only the cache supports
 	mgr, err := ctrl.NewManager(cfg, ctrl.Options{
 		Scheme: scheme.Scheme,
-		NewClient: func(config *rest.Config, options client.Options) (client.Client, error) {
-			return k8sClient, nil
-		},
+		Cache:  cache.Options{ByObject: istiorevision.CacheByObject()},
 	})
 	if err != nil {
 		panic(err)
 	}
 
-	Expect(istio.NewIstioReconciler(mgr.GetClient(), mgr.GetScheme(), path.Join(common.RepositoryRoot, "resources"), []string{"default"}).
+	Expect(istiorevision.SetupFieldIndexes(context.Background(), mgr.GetFieldIndexer())).To(Succeed())
+
+	Expect(istio.NewIstioReconciler(mgr.GetClient(), mgr.GetScheme(), path.Join(common.RepositoryRoot, "resources"), []string{"default"}, operatorNamespace,
+		mgr.GetEventRecorderFor("istio-controller")).
+		SetupWithManager(mgr)).To(Succeed())
+
+	Expect(istiorevision.NewIstioRevisionReconciler(mgr.GetClient(), mgr.GetScheme(), mgr.GetConfig(),
+		mgr.GetEventRecorderFor("istiorevision-controller")).
+		SetupWithManager(mgr)).To(Succeed())
+
+	Expect(istiocni.NewIstioCNIReconciler(mgr.GetClient(), mgr.GetScheme(), mgr.GetConfig(), path.Join(common.RepositoryRoot, "resources"),
+		[]string{"default"}, operatorNamespace).
+		SetupWithManager(mgr)).To(Succeed())
+
+	Expect(istiorevisiontag.NewIstioRevisionTagReconciler(mgr.GetClient(), mgr.GetScheme(), mgr.GetConfig()).
 		SetupWithManager(mgr)).To(Succeed())
 
-	Expect(istiorevision.NewIstioRevisionReconciler(mgr.GetClient(), mgr.GetScheme(), mgr.GetConfig(), operatorNamespace).
+	Expect(istiogateway.NewIstioGatewayReconciler(mgr.GetClient(), mgr.GetScheme(), mgr.GetConfig()).
 		SetupWithManager(mgr)).To(Succeed())
 
 	// create new cancellable context